@@ -0,0 +1,190 @@
+package wanf
+
+import "sync"
+
+// arenaSlabSize 是每块 slab 容纳的节点数.
+const arenaSlabSize = 64
+
+// nodeArena 按 slab 批量分配解析器创建的常见节点, 使一次解析只产生少量大对象,
+// 而不是每个节点一个小对象. program.Release 把 slab 清零后放回池中, 供之后的
+// 解析复用. 没有调用 Release 的 slab 与普通内存一样由 GC 回收.
+type nodeArena struct {
+	roots     []RootNode
+	assigns   []AssignStatement
+	blocks    []BlockStatement
+	idents    []Identifier
+	strings   []StringLiteral
+	ints      []IntegerLiteral
+	floats    []FloatLiteral
+	bools     []BoolLiteral
+	durations []DurationLiteral
+	comments  []Comment
+
+	slabs []interface{} // 已取出的全部 slab, 由 release 归还
+}
+
+var (
+	rootSlabs     = sync.Pool{New: func() interface{} { return new([arenaSlabSize]RootNode) }}
+	assignSlabs   = sync.Pool{New: func() interface{} { return new([arenaSlabSize]AssignStatement) }}
+	blockSlabs    = sync.Pool{New: func() interface{} { return new([arenaSlabSize]BlockStatement) }}
+	identSlabs    = sync.Pool{New: func() interface{} { return new([arenaSlabSize]Identifier) }}
+	stringSlabs   = sync.Pool{New: func() interface{} { return new([arenaSlabSize]StringLiteral) }}
+	intSlabs      = sync.Pool{New: func() interface{} { return new([arenaSlabSize]IntegerLiteral) }}
+	floatSlabs    = sync.Pool{New: func() interface{} { return new([arenaSlabSize]FloatLiteral) }}
+	boolSlabs     = sync.Pool{New: func() interface{} { return new([arenaSlabSize]BoolLiteral) }}
+	durationSlabs = sync.Pool{New: func() interface{} { return new([arenaSlabSize]DurationLiteral) }}
+	commentSlabs  = sync.Pool{New: func() interface{} { return new([arenaSlabSize]Comment) }}
+)
+
+func (a *nodeArena) root() *RootNode {
+	if len(a.roots) == 0 {
+		s := rootSlabs.Get().(*[arenaSlabSize]RootNode)
+		a.slabs, a.roots = append(a.slabs, s), s[:]
+	}
+	n := &a.roots[0]
+	a.roots = a.roots[1:]
+	return n
+}
+
+func (a *nodeArena) assign() *AssignStatement {
+	if len(a.assigns) == 0 {
+		s := assignSlabs.Get().(*[arenaSlabSize]AssignStatement)
+		a.slabs, a.assigns = append(a.slabs, s), s[:]
+	}
+	n := &a.assigns[0]
+	a.assigns = a.assigns[1:]
+	return n
+}
+
+func (a *nodeArena) block() *BlockStatement {
+	if len(a.blocks) == 0 {
+		s := blockSlabs.Get().(*[arenaSlabSize]BlockStatement)
+		a.slabs, a.blocks = append(a.slabs, s), s[:]
+	}
+	n := &a.blocks[0]
+	a.blocks = a.blocks[1:]
+	return n
+}
+
+func (a *nodeArena) ident() *Identifier {
+	if len(a.idents) == 0 {
+		s := identSlabs.Get().(*[arenaSlabSize]Identifier)
+		a.slabs, a.idents = append(a.slabs, s), s[:]
+	}
+	n := &a.idents[0]
+	a.idents = a.idents[1:]
+	return n
+}
+
+func (a *nodeArena) stringLit() *StringLiteral {
+	if len(a.strings) == 0 {
+		s := stringSlabs.Get().(*[arenaSlabSize]StringLiteral)
+		a.slabs, a.strings = append(a.slabs, s), s[:]
+	}
+	n := &a.strings[0]
+	a.strings = a.strings[1:]
+	return n
+}
+
+func (a *nodeArena) intLit() *IntegerLiteral {
+	if len(a.ints) == 0 {
+		s := intSlabs.Get().(*[arenaSlabSize]IntegerLiteral)
+		a.slabs, a.ints = append(a.slabs, s), s[:]
+	}
+	n := &a.ints[0]
+	a.ints = a.ints[1:]
+	return n
+}
+
+func (a *nodeArena) floatLit() *FloatLiteral {
+	if len(a.floats) == 0 {
+		s := floatSlabs.Get().(*[arenaSlabSize]FloatLiteral)
+		a.slabs, a.floats = append(a.slabs, s), s[:]
+	}
+	n := &a.floats[0]
+	a.floats = a.floats[1:]
+	return n
+}
+
+func (a *nodeArena) boolLit() *BoolLiteral {
+	if len(a.bools) == 0 {
+		s := boolSlabs.Get().(*[arenaSlabSize]BoolLiteral)
+		a.slabs, a.bools = append(a.slabs, s), s[:]
+	}
+	n := &a.bools[0]
+	a.bools = a.bools[1:]
+	return n
+}
+
+func (a *nodeArena) durationLit() *DurationLiteral {
+	if len(a.durations) == 0 {
+		s := durationSlabs.Get().(*[arenaSlabSize]DurationLiteral)
+		a.slabs, a.durations = append(a.slabs, s), s[:]
+	}
+	n := &a.durations[0]
+	a.durations = a.durations[1:]
+	return n
+}
+
+func (a *nodeArena) comment() *Comment {
+	if len(a.comments) == 0 {
+		s := commentSlabs.Get().(*[arenaSlabSize]Comment)
+		a.slabs, a.comments = append(a.slabs, s), s[:]
+	}
+	n := &a.comments[0]
+	a.comments = a.comments[1:]
+	return n
+}
+
+// release 清零并归还全部 slab.
+func (a *nodeArena) release() {
+	for _, slab := range a.slabs {
+		switch s := slab.(type) {
+		case *[arenaSlabSize]RootNode:
+			*s = [arenaSlabSize]RootNode{}
+			rootSlabs.Put(s)
+		case *[arenaSlabSize]AssignStatement:
+			*s = [arenaSlabSize]AssignStatement{}
+			assignSlabs.Put(s)
+		case *[arenaSlabSize]BlockStatement:
+			*s = [arenaSlabSize]BlockStatement{}
+			blockSlabs.Put(s)
+		case *[arenaSlabSize]Identifier:
+			*s = [arenaSlabSize]Identifier{}
+			identSlabs.Put(s)
+		case *[arenaSlabSize]StringLiteral:
+			*s = [arenaSlabSize]StringLiteral{}
+			stringSlabs.Put(s)
+		case *[arenaSlabSize]IntegerLiteral:
+			*s = [arenaSlabSize]IntegerLiteral{}
+			intSlabs.Put(s)
+		case *[arenaSlabSize]FloatLiteral:
+			*s = [arenaSlabSize]FloatLiteral{}
+			floatSlabs.Put(s)
+		case *[arenaSlabSize]BoolLiteral:
+			*s = [arenaSlabSize]BoolLiteral{}
+			boolSlabs.Put(s)
+		case *[arenaSlabSize]DurationLiteral:
+			*s = [arenaSlabSize]DurationLiteral{}
+			durationSlabs.Put(s)
+		case *[arenaSlabSize]Comment:
+			*s = [arenaSlabSize]Comment{}
+			commentSlabs.Put(s)
+		}
+	}
+	*a = nodeArena{}
+}
+
+// Release 将解析 p 时分配的节点归还, 供之后的解析复用, 以减少大量解析文件的
+// 工具 (如格式化整个目录) 的 GC 压力. 调用后 p 以及从中得到的任何节点都不能
+// 再使用, 包括通过 Merge、Set 等移入其他语法树的节点, 以及 Get 返回的表达式.
+// Release 只对 ParseProgram 返回的根节点有效, 对其他节点、nil 或重复调用不做任何事.
+func (p *RootNode) Release() {
+	if p == nil || p.arena == nil {
+		return
+	}
+	a := p.arena
+	// 先断开引用, 使之后误用 p 时立即出错, 而不是读到被复用的节点.
+	p.Statements, p.TrailingComments, p.arena = nil, nil, nil
+	a.release()
+}
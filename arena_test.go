@@ -0,0 +1,56 @@
+package wanf
+
+import (
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	src := []byte(`// header
+name = "svc"
+port = 8080
+ratio = 0.5
+debug = true
+timeout = 5s
+server "main" {
+	host = "localhost" // inline
+}
+`)
+	var want string
+	for i := 0; i < 3; i++ {
+		program, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true}))
+		if i == 0 {
+			want = got
+		} else if got != want {
+			t.Fatalf("parse %d after Release formatted as\n%s\nwant\n%s", i, got, want)
+		}
+		program.Release()
+		if program.Statements != nil {
+			t.Fatal("Release kept the statements")
+		}
+		program.Release() // 重复调用无效果
+	}
+
+	// 块体与手工创建的节点不持有 arena.
+	program, _ := Parse([]byte("a { b = 1 }\n"))
+	body := program.Statements[0].(*BlockStatement).Body
+	body.Release()
+	if len(body.Statements) != 1 {
+		t.Error("Release on a block body changed it")
+	}
+	(&RootNode{}).Release()
+}
+
+func BenchmarkParseRelease(b *testing.B) {
+	if benchmarkWanfData == nil {
+		b.Skip("Cannot read benchmark data file")
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		program, _ := Parse(benchmarkWanfData)
+		program.Release()
+	}
+}
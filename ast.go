@@ -3,7 +3,6 @@ package wanf
 import (
 	"bytes"
 	"reflect"
-	"sort"
 	"sync"
 )
 
@@ -18,6 +17,10 @@ type Node interface {
 	TokenLiteral() string
 	String() string
 	Format(w *bytes.Buffer, indent string, opts FormatOptions)
+	// Pos 与 End 返回节点在源文本中的范围 [Pos, End). 前置注释与行尾注释
+	// 不计入语句的范围.
+	Pos() Position
+	End() Position
 }
 
 // Statement 代表一个语句.
@@ -44,12 +47,20 @@ func (c *Comment) statementNode()       {}
 func (c *Comment) TokenLiteral() string { return string(c.Token.Literal) }
 func (c *Comment) String() string       { return string(c.Text) }
 func (c *Comment) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	w.Write(c.Text)
+	writeComment(w, c, opts)
 }
 
 // RootNode 是每个WANF文件AST的根节点.
 type RootNode struct {
-	Statements []Statement
+	Statements       []Statement
+	TrailingComments []*Comment // 最后一条语句之后, 文件结尾或块的 `}` 之前的注释
+
+	arena *nodeArena // 解析时分配节点的 arena, 只有顶层节点持有, 见 Release
+}
+
+// isEmpty 报告 p 是否既没有语句也没有注释.
+func (p *RootNode) isEmpty() bool {
+	return len(p.Statements) == 0 && len(p.TrailingComments) == 0
 }
 
 func (p *RootNode) TokenLiteral() string {
@@ -84,44 +95,83 @@ func (p *RootNode) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
 		return false
 	}
 
+	if opts.SortImports && indent == "" {
+		p.Statements = sortImports(p.Statements)
+	}
 	statements := p.Statements
-	// 排序逻辑
+	// 排序逻辑, `wanf:sort off` 区域内的语句保持原位
+	frozen := sortRegions(statements)
 	if !opts.NoSort {
 		if opts.Style == StyleAllSorted || (opts.Style == StyleBlockSorted && indent != "") {
-			sort.SliceStable(statements, func(i, j int) bool {
-				iIsBlock := isBlockLike(statements[i])
-				jIsBlock := isBlockLike(statements[j])
-				if iIsBlock != jIsBlock {
-					return !iIsBlock
+			// 获取语句的名称以进行字母排序
+			getName := func(s Statement) []byte {
+				if as, ok := s.(*AssignStatement); ok {
+					return as.Name.Value
 				}
-				// 获取语句的名称以进行字母排序
-				getName := func(s Statement) []byte {
-					if as, ok := s.(*AssignStatement); ok {
-						return as.Name.Value
-					}
-					if bs, ok := s.(*BlockStatement); ok {
-						return bs.Name.Value
-					}
-					return nil
+				if bs, ok := s.(*BlockStatement); ok {
+					return bs.Name.Value
 				}
-				return bytes.Compare(getName(statements[i]), getName(statements[j])) < 0
+				return nil
+			}
+			sortRuns(statements, frozen, func(a, b Statement) bool {
+				aIsBlock := isBlockLike(a)
+				bIsBlock := isBlockLike(b)
+				if aIsBlock != bIsBlock {
+					return !aIsBlock
+				}
+				return bytes.Compare(getName(a), getName(b)) < 0
 			})
 		}
 	}
 
+	var widths []int
+	if opts.AlignAssignments && opts.Style != StyleSingleLine {
+		widths = alignWidths(len(statements), func(i int) (string, bool) {
+			as, ok := statements[i].(*AssignStatement)
+			if !ok || isBlockLike(as) {
+				return "", false
+			}
+			return BytesToString(as.Name.Value), true
+		}, func(i int) bool {
+			return opts.PreserveBlankLines && blankLinesBefore(statements[i]) > 0
+		})
+	}
+
 	for i, s := range statements {
 		if i > 0 {
 			if opts.Style == StyleSingleLine {
 				w.WriteString(";")
 			} else {
 				w.WriteString("\n")
-				// 仅在顶级添加空行
+				// 仅在顶级添加空行, 但保留作者自己留下的空行
+				_, prevIsImport := statements[i-1].(*ImportStatement)
+				_, isImport := s.(*ImportStatement)
 				if indent == "" && opts.EmptyLines && (isBlockLike(statements[i-1]) || isBlockLike(s)) {
 					w.WriteString("\n")
+				} else if opts.SortImports && indent == "" && prevIsImport != isImport {
+					// 导入语句自成一组
+					w.WriteString("\n")
+				} else if opts.PreserveBlankLines && blankLinesBefore(s) > 0 && !(opts.SortImports && prevIsImport && isImport) {
+					w.WriteString("\n")
 				}
 			}
 		}
-		s.Format(w, indent, opts)
+		stmtOpts := opts
+		if (frozen != nil && frozen[i]) || hasNoSortDirective(s) {
+			stmtOpts.NoSort = true
+		}
+		if as, ok := s.(*AssignStatement); ok && widths != nil {
+			as.formatAligned(w, indent, stmtOpts, widths[i])
+			continue
+		}
+		s.Format(w, indent, stmtOpts)
+	}
+	if len(p.TrailingComments) > 0 && opts.Style != StyleSingleLine {
+		if len(statements) > 0 {
+			w.WriteString("\n")
+		}
+		writeCommentLines(w, indent, p.TrailingComments, opts)
+		w.Truncate(w.Len() - 1) // 与语句一样, 最后一行不带换行
 	}
 }
 
@@ -129,11 +179,12 @@ func (p *RootNode) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
 
 // AssignStatement 表示一个赋值语句, 如 `key = value`.
 type AssignStatement struct {
-	Token           Token
-	Name            *Identifier
-	Value           Expression
-	LeadingComments []*Comment // 前置注释
-	LineComment     *Comment   // 行尾注释
+	Token            Token
+	Name             *Identifier
+	Value            Expression
+	LeadingComments  []*Comment // 前置注释
+	LineComment      *Comment   // 行尾注释
+	BlankLinesBefore int        // 源文件中该语句 (含前置注释) 之前的空行数
 }
 
 func (as *AssignStatement) statementNode() {}
@@ -149,30 +200,54 @@ func (as *AssignStatement) String() string {
 	return buf.String()
 }
 func (as *AssignStatement) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	for _, c := range as.LeadingComments {
-		w.WriteString(indent)
-		w.Write(c.Text)
-		w.WriteString("\n")
-	}
+	as.formatAligned(w, indent, opts, 0)
+}
+
+// formatAligned 与 Format 相同, 但会将键名补齐到 width 个字符以对齐 `=`.
+func (as *AssignStatement) formatAligned(w *bytes.Buffer, indent string, opts FormatOptions, width int) {
+	as.formatEntry(w, indent, opts, width, "")
+}
+
+// formatEntry 与 formatAligned 相同, 并在行尾注释之前写入分隔符 sep (映射条目使用 ",").
+func (as *AssignStatement) formatEntry(w *bytes.Buffer, indent string, opts FormatOptions, width int, sep string) {
+	writeCommentLines(w, indent, as.LeadingComments, opts)
 	w.WriteString(indent)
 	as.Name.Format(w, indent, opts)
+	writePadding(w, len(as.Name.Value), width)
 	w.WriteString(" = ")
 	if as.Value != nil {
 		as.Value.Format(w, indent, opts)
 	}
+	w.WriteString(sep)
 	if as.LineComment != nil {
 		w.WriteString(" ")
-		w.Write(as.LineComment.Text)
+		writeComment(w, as.LineComment, opts)
 	}
 }
 
-// BlockStatement 表示一个块, 如 `database { ... }`.
+// BlockStatement 表示一个块, 如 `database { ... }` 或 `route "api" "v2" { ... }`.
 type BlockStatement struct {
-	Token           Token
-	Name            *Identifier
-	Label           *StringLiteral
-	Body            *RootNode
-	LeadingComments []*Comment // 前置注释
+	Token            Token
+	Name             *Identifier
+	Label            *StringLiteral
+	ExtraLabels      []*StringLiteral // 第一个标签之后的其余标签
+	Body             *RootNode
+	Rbrace           Token      // 结尾的 `}`
+	LeadingComments  []*Comment // 前置注释
+	BlankLinesBefore int        // 源文件中该语句 (含前置注释) 之前的空行数
+}
+
+// Labels 按声明顺序返回块的全部标签.
+func (bs *BlockStatement) Labels() []string {
+	if bs.Label == nil {
+		return nil
+	}
+	labels := make([]string, 0, 1+len(bs.ExtraLabels))
+	labels = append(labels, string(bs.Label.Value))
+	for _, l := range bs.ExtraLabels {
+		labels = append(labels, string(l.Value))
+	}
+	return labels
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -188,24 +263,24 @@ func (bs *BlockStatement) String() string {
 	return buf.String()
 }
 func (bs *BlockStatement) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	for _, c := range bs.LeadingComments {
-		w.WriteString(indent)
-		w.Write(c.Text)
-		w.WriteString("\n")
-	}
+	writeCommentLines(w, indent, bs.LeadingComments, opts)
 	w.WriteString(indent)
 	bs.Name.Format(w, indent, opts)
 	if bs.Label != nil {
 		w.WriteString(" ")
 		bs.Label.Format(w, indent, opts)
 	}
+	for _, l := range bs.ExtraLabels {
+		w.WriteString(" ")
+		l.Format(w, indent, opts)
+	}
 	if opts.Style == StyleSingleLine {
 		w.WriteString("{")
 		bs.Body.Format(w, "", opts)
 		w.WriteString("}")
 	} else {
 		w.WriteString(" {")
-		if len(bs.Body.Statements) > 0 {
+		if !bs.Body.isEmpty() {
 			w.WriteString("\n")
 			bs.Body.Format(w, indent+"\t", opts)
 		}
@@ -215,11 +290,12 @@ func (bs *BlockStatement) Format(w *bytes.Buffer, indent string, opts FormatOpti
 
 // VarStatement 表示一个变量声明, 如 `var name = value`.
 type VarStatement struct {
-	Token           Token
-	Name            *Identifier
-	Value           Expression
-	LeadingComments []*Comment // 前置注释
-	LineComment     *Comment   // 行尾注释
+	Token            Token
+	Name             *Identifier
+	Value            Expression
+	LeadingComments  []*Comment // 前置注释
+	LineComment      *Comment   // 行尾注释
+	BlankLinesBefore int        // 源文件中该语句 (含前置注释) 之前的空行数
 }
 
 func (vs *VarStatement) statementNode() {}
@@ -235,11 +311,7 @@ func (vs *VarStatement) String() string {
 	return buf.String()
 }
 func (vs *VarStatement) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	for _, c := range vs.LeadingComments {
-		w.WriteString(indent)
-		w.Write(c.Text)
-		w.WriteString("\n")
-	}
+	writeCommentLines(w, indent, vs.LeadingComments, opts)
 	w.WriteString(indent)
 	w.WriteString(vs.TokenLiteral() + " ")
 	vs.Name.Format(w, indent, opts)
@@ -249,16 +321,17 @@ func (vs *VarStatement) Format(w *bytes.Buffer, indent string, opts FormatOption
 	}
 	if vs.LineComment != nil {
 		w.WriteString(" ")
-		w.Write(vs.LineComment.Text)
+		writeComment(w, vs.LineComment, opts)
 	}
 }
 
 // ImportStatement 表示一个导入语句, 如 `import "path/to/file.wanf"`.
 type ImportStatement struct {
-	Token           Token
-	Path            *StringLiteral
-	LeadingComments []*Comment // 前置注释
-	LineComment     *Comment   // 行尾注释
+	Token            Token
+	Path             *StringLiteral
+	LeadingComments  []*Comment // 前置注释
+	LineComment      *Comment   // 行尾注释
+	BlankLinesBefore int        // 源文件中该语句 (含前置注释) 之前的空行数
 }
 
 func (is *ImportStatement) statementNode() {}
@@ -274,18 +347,51 @@ func (is *ImportStatement) String() string {
 	return buf.String()
 }
 func (is *ImportStatement) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	for _, c := range is.LeadingComments {
-		w.WriteString(indent)
-		w.Write(c.Text)
-		w.WriteString("\n")
-	}
+	writeCommentLines(w, indent, is.LeadingComments, opts)
 	w.WriteString(indent)
 	w.WriteString(is.TokenLiteral() + " ")
 	is.Path.Format(w, indent, opts)
 	if is.LineComment != nil {
 		w.WriteString(" ")
-		w.Write(is.LineComment.Text)
+		writeComment(w, is.LineComment, opts)
+	}
+}
+
+// writeCommentLines writes each comment on its own line at indent.
+func writeCommentLines(w *bytes.Buffer, indent string, comments []*Comment, opts FormatOptions) {
+	for _, c := range comments {
+		w.WriteString(indent)
+		if opts.NormalizeComments {
+			w.Write(reflowBlockComment(normalizeComment(c.Text), indent, opts))
+		} else {
+			w.Write(c.Text)
+		}
+		w.WriteString("\n")
+	}
+}
+
+// writeComment writes a comment that follows other code on the same line.
+func writeComment(w *bytes.Buffer, c *Comment, opts FormatOptions) {
+	if opts.NormalizeComments {
+		w.Write(normalizeComment(c.Text))
+		return
+	}
+	w.Write(c.Text)
+}
+
+// blankLinesBefore 返回源文件中语句之前的空行数.
+func blankLinesBefore(s Statement) int {
+	switch s := s.(type) {
+	case *AssignStatement:
+		return s.BlankLinesBefore
+	case *BlockStatement:
+		return s.BlankLinesBefore
+	case *VarStatement:
+		return s.BlankLinesBefore
+	case *ImportStatement:
+		return s.BlankLinesBefore
 	}
+	return 0
 }
 
 // --- 表达式 (Expressions) ---
@@ -394,8 +500,41 @@ func (dl *DurationLiteral) Format(w *bytes.Buffer, indent string, opts FormatOpt
 
 // ListLiteral 表示一个列表, 如 `[el1, el2]`.
 type ListLiteral struct {
-	Token    Token
-	Elements []Expression
+	Token            Token
+	Rbrack           Token // 结尾的 `]`
+	Elements         []Expression
+	Comments         []ElementComments // 与 Elements 一一对应, 没有注释时为 nil
+	TrailingComments []*Comment        // 最后一个元素之后, `]` 之前的注释
+}
+
+// ElementComments 保存列表元素的前置注释和行尾注释.
+type ElementComments struct {
+	Leading []*Comment
+	Line    *Comment
+}
+
+// setComments records the comments of element i.
+func (ll *ListLiteral) setComments(i int, c ElementComments) {
+	if c.Leading == nil && c.Line == nil {
+		return
+	}
+	for len(ll.Comments) <= i {
+		ll.Comments = append(ll.Comments, ElementComments{})
+	}
+	ll.Comments[i] = c
+}
+
+// elementComments returns the comments of element i.
+func (ll *ListLiteral) elementComments(i int) ElementComments {
+	if i < len(ll.Comments) {
+		return ll.Comments[i]
+	}
+	return ElementComments{}
+}
+
+// hasComments reports whether any comment is attached to the list.
+func (ll *ListLiteral) hasComments() bool {
+	return len(ll.Comments) > 0 || len(ll.TrailingComments) > 0
 }
 
 func (ll *ListLiteral) expressionNode()      {}
@@ -420,20 +559,33 @@ func (ll *ListLiteral) Format(w *bytes.Buffer, indent string, opts FormatOptions
 		return
 	}
 
+	if writeInline(w, ll, opts) {
+		return
+	}
+
 	w.WriteString("[\n")
 	newIndent := indent + "\t"
-	for _, el := range ll.Elements {
+	for i, el := range ll.Elements {
+		c := ll.elementComments(i)
+		writeCommentLines(w, newIndent, c.Leading, opts)
 		w.WriteString(newIndent)
 		el.Format(w, newIndent, opts)
-		w.WriteString(",\n")
+		w.WriteString(",")
+		if c.Line != nil {
+			w.WriteString(" ")
+			writeComment(w, c.Line, opts)
+		}
+		w.WriteString("\n")
 	}
+	writeCommentLines(w, newIndent, ll.TrailingComments, opts)
 	w.WriteString(indent + "]")
 }
 
 // BlockLiteral 表示一个匿名的块, 通常用作值, 例如在列表中.
 type BlockLiteral struct {
-	Token Token
-	Body  *RootNode
+	Token  Token
+	Body   *RootNode
+	Rbrace Token // 结尾的 `}`
 }
 
 func (bl *BlockLiteral) expressionNode()      {}
@@ -453,7 +605,7 @@ func (bl *BlockLiteral) Format(w *bytes.Buffer, indent string, opts FormatOption
 		return
 	}
 
-	if len(bl.Body.Statements) == 0 {
+	if bl.Body.isEmpty() {
 		w.WriteString("{}")
 	} else {
 		w.WriteString("{\n")
@@ -464,8 +616,9 @@ func (bl *BlockLiteral) Format(w *bytes.Buffer, indent string, opts FormatOption
 
 // VarExpression 表示一个变量引用, 如 `${var}`.
 type VarExpression struct {
-	Token Token
-	Name  []byte
+	Token  Token
+	Name   []byte
+	Rbrace Token // 结尾的 `}`
 }
 
 func (ve *VarExpression) expressionNode()      {}
@@ -482,6 +635,7 @@ type EnvExpression struct {
 	Token        Token
 	Name         *StringLiteral
 	DefaultValue *StringLiteral
+	Rparen       Token // 结尾的 `)`
 }
 
 func (ee *EnvExpression) expressionNode()      {}
@@ -503,10 +657,35 @@ func (ee *EnvExpression) Format(w *bytes.Buffer, indent string, opts FormatOptio
 	w.WriteString(")")
 }
 
+// SecretExpression 表示对 `secret()` 函数的调用, 例如 `secret("vault:db#password")`.
+// 引用在解码时交给 WithSecretResolver 注册的解析器, 机密本身不出现在文件中.
+type SecretExpression struct {
+	Token  Token
+	Ref    *StringLiteral
+	Rparen Token // 结尾的 `)`
+}
+
+func (se *SecretExpression) expressionNode()      {}
+func (se *SecretExpression) TokenLiteral() string { return string(se.Token.Literal) }
+func (se *SecretExpression) String() string {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.Reset()
+	se.Format(buf, "", FormatOptions{Style: StyleBlockSorted, EmptyLines: true})
+	return buf.String()
+}
+func (se *SecretExpression) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
+	w.WriteString("secret(")
+	se.Ref.Format(w, indent, opts)
+	w.WriteString(")")
+}
+
 // MapLiteral 表示一个映射字面量, 例如 `{[ key = "value" ]}`.
 type MapLiteral struct {
-	Token    Token // The LBRACE token
-	Elements []Statement
+	Token            Token // The LBRACE token
+	Rbrace           Token // The closing RBRACE token
+	Elements         []Statement
+	TrailingComments []*Comment // 最后一个条目之后, `]` 之前的注释
 }
 
 func (ml *MapLiteral) expressionNode()      {}
@@ -519,14 +698,7 @@ func (ml *MapLiteral) String() string {
 	return buf.String()
 }
 func (ml *MapLiteral) Format(w *bytes.Buffer, indent string, opts FormatOptions) {
-	// Sort elements by key for deterministic output.
-	if !opts.NoSort {
-		sort.SliceStable(ml.Elements, func(i, j int) bool {
-			iName := ml.Elements[i].(*AssignStatement).Name.Value
-			jName := ml.Elements[j].(*AssignStatement).Name.Value
-			return bytes.Compare(iName, jName) < 0
-		})
-	}
+	ml.sortElements(opts)
 
 	if opts.Style == StyleSingleLine {
 		w.WriteString("{[")
@@ -539,13 +711,124 @@ func (ml *MapLiteral) Format(w *bytes.Buffer, indent string, opts FormatOptions)
 			st.(*AssignStatement).Value.Format(w, "", opts)
 		}
 		w.WriteString("]}")
-	} else {
+	} else if !writeInline(w, ml, opts) {
 		w.WriteString("{[\n")
 		newIndent := indent + "\t"
-		for _, st := range ml.Elements {
-			st.Format(w, newIndent, opts)
-			w.WriteString(",\n")
+		var widths []int
+		if opts.AlignAssignments {
+			widths = alignWidths(len(ml.Elements), func(i int) (string, bool) {
+				as := ml.Elements[i].(*AssignStatement)
+				switch as.Value.(type) {
+				case *MapLiteral, *ListLiteral, *BlockLiteral:
+					return "", false
+				}
+				return BytesToString(as.Name.Value), true
+			}, func(i int) bool {
+				return opts.PreserveBlankLines && blankLinesBefore(ml.Elements[i]) > 0
+			})
+		}
+		frozen := sortRegions(ml.Elements)
+		for i, st := range ml.Elements {
+			if i > 0 && opts.PreserveBlankLines && blankLinesBefore(st) > 0 {
+				w.WriteString("\n")
+			}
+			stOpts := opts
+			if (frozen != nil && frozen[i]) || hasNoSortDirective(st) {
+				stOpts.NoSort = true
+			}
+			width := 0
+			if widths != nil {
+				width = widths[i]
+			}
+			st.(*AssignStatement).formatEntry(w, newIndent, stOpts, width, ",")
+			w.WriteString("\n")
 		}
+		writeCommentLines(w, newIndent, ml.TrailingComments, opts)
 		w.WriteString(indent + "]}")
 	}
 }
+
+// sortElements sorts the entries by key for deterministic output.
+func (ml *MapLiteral) sortElements(opts FormatOptions) {
+	if opts.NoSort {
+		return
+	}
+	sortRuns(ml.Elements, sortRegions(ml.Elements), func(a, b Statement) bool {
+		return bytes.Compare(a.(*AssignStatement).Name.Value, b.(*AssignStatement).Name.Value) < 0
+	})
+}
+
+// writeInline writes a list or map on a single line, e.g. `[80, 443]`, if it
+// fits within opts.MaxWidth at the current position of w. It reports whether
+// anything was written.
+func writeInline(w *bytes.Buffer, expr Expression, opts FormatOptions) bool {
+	if opts.MaxWidth <= 0 {
+		return false
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+	buf.Reset()
+	if !formatInline(buf, expr, opts) || lineWidth(w.Bytes())+buf.Len() > opts.MaxWidth {
+		return false
+	}
+	w.Write(buf.Bytes())
+	return true
+}
+
+// formatInline writes the single-line form of expr to w. It reports false if
+// expr cannot be written on one line, e.g. because it contains a non-empty
+// block, a multi-line string or a comment.
+func formatInline(w *bytes.Buffer, expr Expression, opts FormatOptions) bool {
+	switch e := expr.(type) {
+	case nil:
+		return false
+	case *ListLiteral:
+		if e.hasComments() {
+			return false
+		}
+		w.WriteString("[")
+		for i, el := range e.Elements {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if !formatInline(w, el, opts) {
+				return false
+			}
+		}
+		w.WriteString("]")
+	case *MapLiteral:
+		if len(e.TrailingComments) > 0 {
+			return false
+		}
+		e.sortElements(opts)
+		w.WriteString("{[")
+		for i, st := range e.Elements {
+			as := st.(*AssignStatement)
+			if len(as.LeadingComments) > 0 || as.LineComment != nil {
+				return false
+			}
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			as.Name.Format(w, "", opts)
+			w.WriteString(" = ")
+			if !formatInline(w, as.Value, opts) {
+				return false
+			}
+		}
+		w.WriteString("]}")
+	case *BlockLiteral:
+		if !e.Body.isEmpty() {
+			return false
+		}
+		w.WriteString("{}")
+	case *StringLiteral:
+		if bytes.Contains(e.Value, []byte("\n")) {
+			return false
+		}
+		e.Format(w, "", opts)
+	default:
+		expr.Format(w, "", opts)
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package wanf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// WANF-B 是与 ToValue/Eval 相同数据模型的二进制编码, 用于缓存求值后的配置
+// 或在解析开销敏感的场合传输. 文档以 "WANFB" 与一个版本字节开头, 之后是
+// 顶层映射. 每个值以一个类型字节开头:
+//
+//	'S' 字符串   uvarint 长度 + 字节
+//	'I' 整数     zigzag varint
+//	'F' 浮点数   8 字节小端 IEEE 754
+//	'T' / 'f'    true / false
+//	'D' duration 以纳秒计的 zigzag varint
+//	'L' 列表     uvarint 元素数 + 各元素
+//	'M' 映射     uvarint 键数 + 按字典序排列的 (uvarint 键长 + 键 + 值)
+//
+// 映射的键有序, 因此同一个值树总是得到相同的字节.
+
+const (
+	binaryMagic   = "WANFB"
+	binaryVersion = 1
+)
+
+const (
+	binString   = 'S'
+	binInt      = 'I'
+	binFloat    = 'F'
+	binTrue     = 'T'
+	binFalse    = 'f'
+	binDuration = 'D'
+	binList     = 'L'
+	binMap      = 'M'
+)
+
+// maxBinaryDepth 限制解码时的嵌套深度, 防止恶意输入耗尽栈.
+const maxBinaryDepth = 10000
+
+// MarshalBinary 将值树编码为 WANF-B. 支持的值类型与 FromValue 相同;
+// time.Time 按 RFC 3339 编码为字符串. 使用 Eval 的结果可以保留 duration.
+func MarshalBinary(v map[string]interface{}) ([]byte, error) {
+	buf := append(make([]byte, 0, 256), binaryMagic...)
+	buf = append(buf, binaryVersion)
+	return appendBinaryValue(buf, reflect.ValueOf(v), "")
+}
+
+// UnmarshalBinary 解码 WANF-B 文档, 返回的值树与 Eval 的结构相同: 整数为
+// int64, 浮点数为 float64, duration 为 time.Duration.
+func UnmarshalBinary(data []byte) (map[string]interface{}, error) {
+	if len(data) < len(binaryMagic)+1 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return nil, errors.New("wanf: not a WANF-B document")
+	}
+	if v := data[len(binaryMagic)]; v != binaryVersion {
+		return nil, fmt.Errorf("wanf: unsupported WANF-B version %d", v)
+	}
+	r := &binaryReader{data: data, pos: len(binaryMagic) + 1}
+	if r.pos >= len(data) || data[r.pos] != binMap {
+		return nil, errors.New("wanf: WANF-B document must start with a map")
+	}
+	v, err := r.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("wanf: invalid WANF-B at offset %d: %w", r.pos, err)
+	}
+	if r.pos != len(data) {
+		return nil, fmt.Errorf("wanf: invalid WANF-B: %d trailing bytes", len(data)-r.pos)
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// IsBinary 报告 data 是否以 WANF-B 的文件头开始.
+func IsBinary(data []byte) bool {
+	return len(data) >= len(binaryMagic) && string(data[:len(binaryMagic)]) == binaryMagic
+}
+
+func appendBinaryValue(buf []byte, v reflect.Value, path string) ([]byte, error) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("wanf: %s: null values are not supported", path)
+	}
+	switch v.Type() {
+	case durationValueType:
+		buf = append(buf, binDuration)
+		return binary.AppendVarint(buf, v.Int()), nil
+	case timeValueType:
+		return appendBinaryString(buf, v.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return appendBinaryString(buf, v.String()), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return append(buf, binTrue), nil
+		}
+		return append(buf, binFalse), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf = append(buf, binInt)
+		return binary.AppendVarint(buf, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("wanf: %s: integer %d overflows int64", path, u)
+		}
+		buf = append(buf, binInt)
+		return binary.AppendVarint(buf, int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		buf = append(buf, binFloat)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Float())), nil
+	case reflect.Slice, reflect.Array:
+		buf = append(buf, binList)
+		buf = binary.AppendUvarint(buf, uint64(v.Len()))
+		var err error
+		for i := 0; i < v.Len(); i++ {
+			if buf, err = appendBinaryValue(buf, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("wanf: %s: map keys must be strings, got %s", path, v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		buf = append(buf, binMap)
+		buf = binary.AppendUvarint(buf, uint64(len(keys)))
+		var err error
+		for _, k := range keys {
+			buf = binary.AppendUvarint(buf, uint64(len(k)))
+			buf = append(buf, k...)
+			if buf, err = appendBinaryValue(buf, v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), joinPath(path, k)); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("wanf: %s: unsupported value of type %s", path, v.Type())
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = append(buf, binString)
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+type binaryReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *binaryReader) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(r.data[r.pos:])
+	if size <= 0 {
+		return 0, errors.New("malformed length")
+	}
+	r.pos += size
+	return n, nil
+}
+
+func (r *binaryReader) varint() (int64, error) {
+	n, size := binary.Varint(r.data[r.pos:])
+	if size <= 0 {
+		return 0, errors.New("malformed integer")
+	}
+	r.pos += size
+	return n, nil
+}
+
+// bytes 读取一个长度前缀的字节串.
+func (r *binaryReader) bytes() (string, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		return "", errors.New("length exceeds input")
+	}
+	s := string(r.data[r.pos : r.pos+int(n)])
+	r.pos += int(n)
+	return s, nil
+}
+
+// count 读取元素数. 每个元素至少占一个字节, 超出剩余输入的数目无效,
+// 这样恶意的长度不会导致巨大的预分配.
+func (r *binaryReader) count() (int, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		return 0, errors.New("element count exceeds input")
+	}
+	return int(n), nil
+}
+
+func (r *binaryReader) value(depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	if r.pos >= len(r.data) {
+		return nil, errors.New("unexpected end of input")
+	}
+	tag := r.data[r.pos]
+	r.pos++
+	switch tag {
+	case binString:
+		return r.bytes()
+	case binInt:
+		return r.varint()
+	case binFloat:
+		if len(r.data)-r.pos < 8 {
+			return nil, errors.New("unexpected end of input")
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return f, nil
+	case binTrue:
+		return true, nil
+	case binFalse:
+		return false, nil
+	case binDuration:
+		n, err := r.varint()
+		return time.Duration(n), err
+	case binList:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case binMap:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = r.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown type byte 0x%02x", tag)
+}
@@ -0,0 +1,111 @@
+package wanf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	root, err := Parse([]byte(`var region = "eu"
+name = "app"
+port = 8080
+ratio = 0.25
+debug = false
+timeout = 90s
+tags = ["a", "${region}"]
+server "main" {
+	hosts = [{addr = "10.0.0.1"}]
+	limits = {[cpu = 2]}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Eval(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalBinary(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsBinary(data) {
+		t.Fatal("IsBinary = false for encoded document")
+	}
+	got, err := UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch.\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got["timeout"] != 90*time.Second {
+		t.Errorf("timeout = %#v, want 90s", got["timeout"])
+	}
+	again, err := MarshalBinary(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("encoding is not deterministic")
+	}
+}
+
+func TestMarshalBinaryGoValues(t *testing.T) {
+	data, err := MarshalBinary(map[string]interface{}{
+		"n":     uint8(7),
+		"f":     float32(1.5),
+		"names": []string{"x"},
+		"env":   map[string]string{"A": "1"},
+		"at":    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"n":     int64(7),
+		"f":     1.5,
+		"names": []interface{}{"x"},
+		"env":   map[string]interface{}{"A": "1"},
+		"at":    "2024-01-02T03:04:05Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if _, err := MarshalBinary(map[string]interface{}{"x": nil}); err == nil {
+		t.Error("expected error for null value")
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	valid, err := MarshalBinary(map[string]interface{}{"name": "app", "list": []interface{}{int64(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"text", []byte("name = 1"), "not a WANF-B document"},
+		{"version", []byte("WANFB\x09M\x00"), "unsupported WANF-B version"},
+		{"not a map", []byte("WANFB\x01S\x00"), "must start with a map"},
+		{"truncated", valid[:len(valid)-1], "invalid WANF-B"},
+		{"trailing", append(append([]byte{}, valid...), 0), "trailing bytes"},
+		{"huge count", []byte("WANFB\x01M\xff\xff\xff\xff\x0f"), "exceeds input"},
+		{"unknown type", []byte("WANFB\x01M\x01\x01kX"), "unknown type byte"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalBinary(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
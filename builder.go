@@ -0,0 +1,121 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NewValue 将 Go 值转换为表达式节点. 支持的类型与 FromValue 相同; 以字符串为键
+// 的 map 转换为块字面量. v 本身是 Expression 时原样返回.
+func NewValue(v interface{}) (Expression, error) {
+	if expr, ok := v.(Expression); ok {
+		return expr, nil
+	}
+	var w valueWriter
+	w.buf.WriteString("v = ")
+	if err := w.writeValue(reflect.ValueOf(v), "value"); err != nil {
+		return nil, err
+	}
+	// 借助解析器生成节点, 保证 Token 与解析源码得到的完全一致.
+	program, err := Parse(w.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("wanf: value %v: %w", v, err)
+	}
+	return program.Statements[0].(*AssignStatement).Value, nil
+}
+
+// NewBlock 创建一个空块, 如 NewBlock("server", "main") 对应 `server "main" {}`.
+// name 不是合法的标识符或标签包含双引号与换行时 panic.
+func NewBlock(name string, labels ...string) *BlockStatement {
+	if !isValidIdentifier(name) {
+		panic(fmt.Sprintf("wanf: invalid block name %q", name))
+	}
+	var src strings.Builder
+	src.WriteString(name)
+	for _, label := range labels {
+		if strings.ContainsAny(label, "\"\n") {
+			panic(fmt.Sprintf("wanf: invalid block label %q", label))
+		}
+		src.WriteString(` "` + label + `"`)
+	}
+	src.WriteString(" {}")
+	program, err := Parse([]byte(src.String()))
+	if err != nil {
+		panic(fmt.Sprintf("wanf: NewBlock(%q): %v", name, err))
+	}
+	return program.Statements[0].(*BlockStatement)
+}
+
+// NewAssign 创建赋值语句 `name = value`, value 的转换规则同 NewValue.
+func NewAssign(name string, value interface{}) (*AssignStatement, error) {
+	if !isValidIdentifier(name) {
+		return nil, fmt.Errorf("wanf: invalid key %q", name)
+	}
+	expr, err := NewValue(value)
+	if err != nil {
+		return nil, err
+	}
+	ident := &Identifier{Token: Token{Type: IDENT, Literal: []byte(name)}, Value: []byte(name)}
+	return &AssignStatement{Token: ident.Token, Name: ident, Value: expr}, nil
+}
+
+// Set 将 key 赋值为 value. key 已有赋值时替换最后一次赋值的值并保留其注释,
+// 否则在末尾追加一条赋值.
+func (p *RootNode) Set(key string, value interface{}) error {
+	stmt, err := NewAssign(key, value)
+	if err != nil {
+		return err
+	}
+	for i := len(p.Statements) - 1; i >= 0; i-- {
+		if as, ok := p.Statements[i].(*AssignStatement); ok && string(as.Name.Value) == key {
+			as.Value = stmt.Value
+			return nil
+		}
+	}
+	p.Statements = append(p.Statements, stmt)
+	return nil
+}
+
+// Remove 删除名为 key 的全部赋值与块 (连同其注释), 返回删除的语句数.
+func (p *RootNode) Remove(key string) int {
+	n := 0
+	out := p.Statements[:0]
+	for _, stmt := range p.Statements {
+		var name []byte
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			name = s.Name.Value
+		case *BlockStatement:
+			name = s.Name.Value
+		}
+		if name != nil && string(name) == key {
+			n++
+			continue
+		}
+		out = append(out, stmt)
+	}
+	p.Statements = out
+	return n
+}
+
+// Append 在末尾追加语句, 例如由 NewBlock 创建的块.
+func (p *RootNode) Append(stmts ...Statement) {
+	p.Statements = append(p.Statements, stmts...)
+}
+
+// Set 在块体中设置 key, 规则同 RootNode.Set.
+func (bs *BlockStatement) Set(key string, value interface{}) error {
+	if bs.Body == nil {
+		bs.Body = &RootNode{}
+	}
+	return bs.Body.Set(key, value)
+}
+
+// Remove 从块体中删除 key, 规则同 RootNode.Remove.
+func (bs *BlockStatement) Remove(key string) int {
+	if bs.Body == nil {
+		return 0
+	}
+	return bs.Body.Remove(key)
+}
@@ -0,0 +1,78 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	program := parseForMerge(t, "// 旧配置\nold_key = 1\nname = \"a\" // 名称\n")
+	if n := program.Remove("old_key"); n != 1 {
+		t.Errorf("Remove = %d, want 1", n)
+	}
+	if err := program.Set("name", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewBlock("server", "main")
+	for _, kv := range []struct {
+		key   string
+		value interface{}
+	}{
+		{"port", 8080},
+		{"timeout", 90 * time.Second},
+		{"hosts", []string{"a", "b"}},
+		{"tls", map[string]interface{}{"enabled": true}},
+	} {
+		if err := server.Set(kv.key, kv.value); err != nil {
+			t.Fatalf("Set(%q): %v", kv.key, err)
+		}
+	}
+	program.Append(server)
+
+	got := string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, NoSort: true}))
+	want := `name = "b" // 名称
+
+server "main" {
+	port = 8080
+	timeout = 90s
+	hosts = [
+		"a",
+		"b",
+	]
+	tls = {
+		enabled = true
+	}
+}`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 生成的节点与解析得到的节点一致, 可以直接解码.
+	var cfg struct {
+		Server map[string]struct {
+			Port    int           `wanf:"port"`
+			Timeout time.Duration `wanf:"timeout"`
+		} `wanf:"server"`
+	}
+	if err := Decode([]byte(got), &cfg); err != nil || cfg.Server["main"].Timeout != 90*time.Second {
+		t.Errorf("Decode = %+v, %v", cfg, err)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	block := NewBlock("a")
+	if err := block.Set("bad key", 1); err == nil {
+		t.Error("Set with an invalid key succeeded")
+	}
+	if err := block.Set("ch", make(chan int)); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Set with a channel = %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("NewBlock with an invalid name did not panic")
+		}
+	}()
+	NewBlock("1x")
+}
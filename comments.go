@@ -0,0 +1,106 @@
+package wanf
+
+import (
+	"bytes"
+	"strings"
+)
+
+// defaultCommentWidth 是未设置 MaxWidth 时重排块注释的目标宽度.
+const defaultCommentWidth = 80
+
+// normalizeComment rewrites a `#` comment as a `//` comment and inserts a
+// space after `//`, e.g. `//note` and `# note` both become `// note`. Comments
+// starting with `///` are left alone, as are block comments.
+func normalizeComment(text []byte) []byte {
+	var body []byte
+	switch {
+	case bytes.HasPrefix(text, []byte("#")):
+		body = bytes.TrimLeft(text[1:], " \t")
+	case bytes.HasPrefix(text, []byte("//")):
+		body = text[2:]
+		if len(body) == 0 || body[0] == ' ' || body[0] == '\t' || body[0] == '/' {
+			return text
+		}
+	default:
+		return text
+	}
+	if len(body) == 0 {
+		return []byte("//")
+	}
+	out := make([]byte, 0, len(body)+3)
+	out = append(out, "// "...)
+	return append(out, body...)
+}
+
+// reflowBlockComment rewraps a block comment written at indent if any of its
+// lines is wider than opts.MaxWidth (or defaultCommentWidth when unset).
+// Blank lines separate paragraphs; a leading `*` on each line is dropped.
+// The result has the form
+//
+//	/*
+//	 wrapped text
+//	*/
+func reflowBlockComment(text []byte, indent string, opts FormatOptions) []byte {
+	if !bytes.HasPrefix(text, []byte("/*")) || !bytes.HasSuffix(text, []byte("*/")) || len(text) < 4 {
+		return text
+	}
+	width := opts.MaxWidth
+	if width <= 0 {
+		width = defaultCommentWidth
+	}
+	lines := strings.Split(string(text), "\n")
+	tooWide := lineWidth([]byte(indent+lines[0])) > width
+	for _, line := range lines[1:] {
+		if lineWidth([]byte(line)) > width {
+			tooWide = true
+		}
+	}
+	if !tooWide {
+		return text
+	}
+
+	var paragraphs [][]string
+	var words []string
+	for _, line := range strings.Split(string(text[2:len(text)-2]), "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		if line == "" {
+			if len(words) > 0 {
+				paragraphs = append(paragraphs, words)
+				words = nil
+			}
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	if len(words) > 0 {
+		paragraphs = append(paragraphs, words)
+	}
+
+	avail := width - lineWidth([]byte(indent)) - 1
+	var b strings.Builder
+	b.WriteString("/*\n")
+	for i, para := range paragraphs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		n := 0
+		for j, word := range para {
+			if j > 0 && n+1+len(word) > avail {
+				b.WriteString("\n")
+				n = 0
+			}
+			if n == 0 {
+				b.WriteString(indent + " ")
+			} else {
+				b.WriteString(" ")
+				n++
+			}
+			b.WriteString(word)
+			n += len(word)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "*/")
+	return []byte(b.String())
+}
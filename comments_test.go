@@ -0,0 +1,92 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat_NormalizeComments(t *testing.T) {
+	input := `# legacy header
+//tight
+server {
+	port = 8080 #inline
+	/* This block comment is far too long to fit on a single line, so it gets reflowed to the width. */
+	host = "localhost" ///keep
+}`
+	want := `// legacy header
+// tight
+server {
+	/*
+	 This block comment is far too long to fit on a single line, so it gets
+	 reflowed to the width.
+	*/
+	host = "localhost" ///keep
+	port = 8080 // inline
+}`
+	program, errs := Lint([]byte(input))
+	var rules []string
+	for _, e := range errs {
+		rules = append(rules, e.Rule)
+	}
+	if got := strings.Join(rules, ","); got != "WANF017,WANF017" {
+		t.Errorf("got rules %s, want two WANF017 issues", got)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, NormalizeComments: true})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+func TestFix_HashComment(t *testing.T) {
+	fixed, remaining, err := Fix([]byte("# note\na = 1 # inline\n"))
+	if err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	if want := "// note\na = 1 // inline\n"; string(fixed) != want {
+		t.Errorf("fixed = %q, want %q", fixed, want)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("unexpected remaining issues: %v", remaining)
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	input := `server {
+	port = 1
+	// end of block
+}
+
+todo {
+	// nothing yet
+}
+
+b = {
+	x = 1
+	// end of block literal
+}
+// end of file`
+	program, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(program.TrailingComments) != 1 || len(program.Statements[1].(*BlockStatement).Body.TrailingComments) != 1 {
+		t.Errorf("trailing comments were not attached: %+v", program)
+	}
+	got := string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, NoSort: true}))
+	if got != input {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", input, got)
+	}
+
+	var cfg struct {
+		Server struct {
+			Port int `wanf:"port"`
+		} `wanf:"server"`
+	}
+	if err := Decode([]byte(input), &cfg); err != nil || cfg.Server.Port != 1 {
+		t.Errorf("Decode = %+v, %v", cfg, err)
+	}
+	min, err := Minify([]byte(input))
+	if err != nil || strings.Contains(string(min), "//") {
+		t.Errorf("Minify = %s, %v", min, err)
+	}
+}
@@ -0,0 +1,186 @@
+package wanf
+
+import (
+	"sort"
+)
+
+// Completion 是光标处可以书写的一个键或块.
+type Completion struct {
+	Name     string
+	Block    bool          // 是块而不是键值对
+	Type     string        // 键的类型, 取值同 FieldSchema.Type; 块为空
+	Labels   int           // 块的标签数量, -1 表示不限
+	Required bool          // 键必填或块至少出现一次
+	Present  bool          // 已在光标所在的块中出现
+	Enum     []interface{} // 键允许的取值
+}
+
+// SchemaFromStruct 由带标签的结构体 (或其指针) 生成 Schema, 规则与 SchemaOf
+// 及 ParseJSONSchema 相同, 供 CompleteAt 与 ValidateAgainstSchema 直接使用 Go 类型.
+func SchemaFromStruct(v interface{}) (*Schema, error) {
+	data, err := SchemaOf(v)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSONSchema(data)
+}
+
+// CompleteAt 返回 data 中光标 (line 与 column 从 1 开始) 所在的块按 schema
+// 允许出现的键与块, 按名称排序. 光标位于 schema 未描述的位置 (未知的块, 列表,
+// map 字面量或 ${...} 中) 时返回 nil.
+//
+// CompleteAt 只做词法分析, 因此适用于正在编辑, 尚不能解析的文档.
+func CompleteAt(data []byte, line, column int, schema *Schema) []Completion {
+	toks := completionTokens(data)
+
+	// stack 记录光标之前未闭合的括号; 元素为其中适用的 schema, nil 表示未知.
+	type frame struct {
+		schema *Schema
+		brace  TokenType
+	}
+	stack := []frame{{schema: schema}}
+	i := 0
+	for ; i < len(toks) && tokenBefore(toks[i], line, column); i++ {
+		top := stack[len(stack)-1]
+		switch toks[i].Type {
+		case LBRACE:
+			stack = append(stack, frame{schema: childSchema(toks, i, top.schema, len(stack) == 1), brace: LBRACE})
+		case LBRACK, DOLLAR_LBRACE, LPAREN:
+			stack = append(stack, frame{brace: toks[i].Type})
+		case RBRACE, RBRACK, RPAREN:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	cur := stack[len(stack)-1]
+	if cur.schema == nil || cur.brace == LBRACK || cur.brace == DOLLAR_LBRACE || cur.brace == LPAREN {
+		return nil
+	}
+
+	// 收集当前块中已出现的键, 包括光标之后直到块结束的部分.
+	present := make(map[string]bool)
+	for _, name := range keysBefore(toks, i) {
+		present[name] = true
+	}
+	depth := 0
+	for j := i; j < len(toks) && depth >= 0; j++ {
+		switch toks[j].Type {
+		case LBRACE, LBRACK, DOLLAR_LBRACE, LPAREN:
+			depth++
+		case RBRACE, RBRACK, RPAREN:
+			depth--
+		case IDENT:
+			if depth == 0 {
+				if name, ok := statementKey(toks, j); ok {
+					present[name] = true
+				}
+			}
+		}
+	}
+
+	var out []Completion
+	fields, blocks := cur.schema.names()
+	for _, name := range fields {
+		fs := cur.schema.Fields[name]
+		out = append(out, Completion{Name: name, Type: fs.Type, Required: fs.Required, Present: present[name], Enum: fs.Enum})
+	}
+	for _, name := range blocks {
+		bs := cur.schema.Blocks[name]
+		out = append(out, Completion{Name: name, Block: true, Labels: bs.Labels, Required: bs.Min > 0, Present: present[name]})
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
+
+// completionTokens 返回 data 中除注释外的全部标记.
+func completionTokens(data []byte) []Token {
+	l := NewLexer(data)
+	var toks []Token
+	for {
+		tok := l.NextToken()
+		if tok.Type == EOF {
+			return toks
+		}
+		if tok.Type == COMMENT || tok.Type == ILLEGAL_COMMENT {
+			continue
+		}
+		tok.Literal = append([]byte(nil), tok.Literal...)
+		toks = append(toks, tok)
+	}
+}
+
+// tokenBefore 报告 tok 是否在光标之前开始.
+func tokenBefore(tok Token, line, column int) bool {
+	return tok.Line < line || (tok.Line == line && tok.Column < column)
+}
+
+// statementKey 在 toks[j] 是语句的键 (`key =`) 或块名 (`name "label" {`) 时返回其名称.
+func statementKey(toks []Token, j int) (string, bool) {
+	k := j + 1
+	if k < len(toks) && toks[k].Type == ASSIGN {
+		return string(toks[j].Literal), true
+	}
+	for k < len(toks) && toks[k].Type == STRING {
+		k++
+	}
+	if k < len(toks) && toks[k].Type == LBRACE {
+		return string(toks[j].Literal), true
+	}
+	return "", false
+}
+
+// childSchema 返回 toks[i] 处的 `{` 打开的块体所适用的 schema. `{` 可以属于
+// 块语句 (`name "label" {`) 或块字面量 (`name = {`); `{[` 开始的 map 字面量
+// 以及 schema 未声明的块返回 nil. root 表示 `{` 位于顶层, 其中的 profile 块
+// 沿用顶层的 schema.
+func childSchema(toks []Token, i int, parent *Schema, root bool) *Schema {
+	if parent == nil || (i+1 < len(toks) && toks[i+1].Type == LBRACK) {
+		return nil
+	}
+	j := i - 1
+	if j >= 0 && toks[j].Type == ASSIGN {
+		j--
+	} else {
+		labels := 0
+		for j >= 0 && toks[j].Type == STRING {
+			j--
+			labels++
+		}
+		if root && labels > 0 && j >= 0 && string(toks[j].Literal) == profileBlockName {
+			return parent
+		}
+	}
+	if j < 0 || toks[j].Type != IDENT {
+		return nil
+	}
+	if bs, ok := parent.Blocks[string(toks[j].Literal)]; ok {
+		return &bs.Schema
+	}
+	return nil
+}
+
+// keysBefore 返回 toks[:i] (光标之前的标记) 中与光标位于同一块的语句的键.
+func keysBefore(toks []Token, i int) []string {
+	var keys []string
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch toks[j].Type {
+		case RBRACE, RBRACK, RPAREN:
+			depth++
+		case LBRACE, LBRACK, DOLLAR_LBRACE, LPAREN:
+			if depth == 0 {
+				return keys
+			}
+			depth--
+		case IDENT:
+			if depth != 0 {
+				continue
+			}
+			if name, ok := statementKey(toks, j); ok {
+				keys = append(keys, name)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const completionTestSchema = `
+field "name" {
+	type = "string"
+	required = true
+}
+field "level" {
+	enum = ["debug", "info"]
+}
+block "server" {
+	labels = 1
+	field "port" {
+		type = "int"
+	}
+	field "host" {
+		type = "string"
+	}
+	block "tls" {
+		field "cert" {}
+	}
+}
+`
+
+// completionNames 返回补全结果的名称, 已出现的键加上 * 前缀.
+func completionNames(cs []Completion) string {
+	var names []string
+	for _, c := range cs {
+		if c.Present {
+			names = append(names, "*"+c.Name)
+		} else {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func TestCompleteAt(t *testing.T) {
+	schema, err := ParseSchema([]byte(completionTestSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	// | 标记光标位置.
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"root", "name = \"a\"\n|\n", "level *name server"},
+		{"block", "server \"a\" {\n\tport = 80\n\t|\n\thost = \"h\"\n}\nlevel = \"info\"\n", "*host *port tls"},
+		{"nested", "server \"a\" {\n\ttls {\n\t\t|\n\t}\n}\n", "cert"},
+		{"after block", "server \"a\" {\n\ttls {}\n}\n|", "level name *server"},
+		{"incomplete", "server \"a\" {\n\tport = ${p}\n\t|", "host *port tls"},
+		{"profile", "profile \"dev\" {\n\t|\n}\n", "level name server"},
+		{"unknown block", "other {\n\t|\n}\n", ""},
+		{"list", "level = [\n\t|\n]\n", ""},
+		{"map literal", "level = {[\n\t|\n]}\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, column := 1, 1
+			idx := strings.Index(tt.doc, "|")
+			for _, r := range tt.doc[:idx] {
+				if r == '\n' {
+					line, column = line+1, 1
+				} else {
+					column++
+				}
+			}
+			doc := strings.Replace(tt.doc, "|", "", 1)
+			got := completionNames(CompleteAt([]byte(doc), line, column, schema))
+			if got != tt.want {
+				t.Errorf("CompleteAt(%d:%d) = %q, want %q", line, column, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteAtDetails(t *testing.T) {
+	schema, err := ParseSchema([]byte(completionTestSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	cs := CompleteAt(nil, 1, 1, schema)
+	if len(cs) != 3 {
+		t.Fatalf("got %d completions, want 3", len(cs))
+	}
+	if c := cs[1]; c.Name != "name" || c.Type != "string" || !c.Required || c.Block {
+		t.Errorf("name completion = %+v", c)
+	}
+	if c := cs[0]; len(c.Enum) != 2 {
+		t.Errorf("level completion = %+v", c)
+	}
+	if c := cs[2]; !c.Block || c.Labels != 1 {
+		t.Errorf("server completion = %+v", c)
+	}
+}
+
+type completionTestConfig struct {
+	Timeout time.Duration `wanf:"timeout"`
+	DB      struct {
+		URL string `wanf:"url"`
+	} `wanf:"db"`
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct(&completionTestConfig{})
+	if err != nil {
+		t.Fatalf("SchemaFromStruct failed: %v", err)
+	}
+	cs := CompleteAt([]byte("db {\n\t\n}"), 2, 2, schema)
+	if got := completionNames(cs); got != "url" {
+		t.Errorf("completions in db = %q, want url", got)
+	}
+	cs = CompleteAt([]byte(""), 1, 1, schema)
+	if len(cs) != 2 || cs[1].Name != "timeout" || cs[1].Type != "duration" {
+		t.Errorf("root completions = %+v", cs)
+	}
+}
@@ -0,0 +1,418 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// 文档与通用值树之间的转换, 用于 JSON/YAML/TOML 等格式的迁移.
+//
+// WANF -> 值树:
+//   - 赋值语句成为键值对, 块成为嵌套对象;
+//   - 带标签的块按标签逐层嵌套, `server "a" {...}` 成为 {"server": {"a": {...}}};
+//   - 同名的块会被合并;
+//   - duration 成为字符串 (如 "1m30s"), 变量与 env() 被求值, import 被展开.
+//
+// 值树 -> WANF:
+//   - 顶层与块内的对象成为块, 列表中的对象成为块字面量 `{...}`;
+//   - 整数与浮点数保持原样, 但 WANF 没有负数字面量, 负数会返回错误;
+//   - 键必须是合法的标识符, null 不受支持.
+
+// ToValue 解析并求值 WANF 文档, 返回其通用值树. opts 与 NewDecoder 相同,
+// 例如使用 WithBasePath 解析相对 import.
+func ToValue(data []byte, opts ...DecoderOption) (map[string]interface{}, error) {
+	dec, err := NewDecoder(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, err
+	}
+	m, err := dec.d.statementsToValue(dec.program.Statements)
+	if err != nil {
+		return nil, dec.d.fileError(err)
+	}
+	plainValue(m)
+	return m, nil
+}
+
+// Eval 按解码的流程 (迁移、import、profile、变量与 env()) 求值 root, 返回
+// 通用值树, 供不需要 Go 结构体的调用方使用. 值树的结构与 ToValue 相同,
+// 但 duration 保持为 time.Duration. opts 与 NewDecoder 相同.
+//
+// 与解码一样, 已注册的迁移会直接修改 root.
+func Eval(root *RootNode, opts ...DecoderOption) (map[string]interface{}, error) {
+	dec, err := newProgramDecoder(&RootNode{Statements: root.Statements}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m, err := dec.d.statementsToValue(dec.program.Statements)
+	if err != nil {
+		return nil, dec.d.fileError(err)
+	}
+	return m, nil
+}
+
+// ToJSON 将 WANF 文档转换为缩进的 JSON 对象, 键按字典序排列.
+func ToJSON(data []byte, opts ...DecoderOption) ([]byte, error) {
+	v, err := ToValue(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(v, json.Deterministic(true), jsontext.Multiline(true), jsontext.WithIndent("  "))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// FromJSON 将 JSON 对象转换为格式化后的 WANF 文档. 整数保持为整数,
+// 不会经过 float64 损失精度.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := jsontext.NewDecoder(bytes.NewReader(data))
+	v, err := readJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("wanf: invalid JSON: %w", err)
+	}
+	if _, err := dec.ReadToken(); err != io.EOF {
+		return nil, fmt.Errorf("wanf: invalid JSON: unexpected data after top-level value")
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("wanf: top-level JSON value must be an object, got %s", describeValue(v))
+	}
+	return FromValue(m)
+}
+
+// FromValue 将通用值树转换为格式化后的 WANF 文档. 支持的值类型有
+// string, bool, 各种整数与浮点数, time.Duration, time.Time,
+// 以字符串为键的 map 以及 slice.
+func FromValue(v map[string]interface{}) ([]byte, error) {
+	var w valueWriter
+	if err := w.writeStatements(reflect.ValueOf(v), ""); err != nil {
+		return nil, err
+	}
+	out, err := lintAndFormat(w.buf.Bytes(), FormatOptions{Style: StyleBlockSorted, EmptyLines: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func (d *internalDecoder) statementsToValue(stmts []Statement) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(stmts))
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			val, err := d.evalExpression(s.Value)
+			if err != nil {
+				return nil, fieldError(err, s, s.Name.Token)
+			}
+			m[string(s.Name.Value)] = val
+		case *BlockStatement:
+			body, err := d.statementsToValue(s.Body.Statements)
+			if err != nil {
+				return nil, blockError(err, s)
+			}
+			if err := insertBlockValue(m, append([]string{string(s.Name.Value)}, s.Labels()...), body); err != nil {
+				return nil, fmt.Errorf("block %q (line %d): %w", string(s.Name.Value), s.Token.Line, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// insertBlockValue 将块体存入 m 中 keys 对应的路径, 已存在的对象会被合并.
+func insertBlockValue(m map[string]interface{}, keys []string, body map[string]interface{}) error {
+	cur := m
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := cur[k].(map[string]interface{})
+		if !ok {
+			if _, exists := cur[k]; exists {
+				return fmt.Errorf("key %q is already set to a non-object value", k)
+			}
+			next = make(map[string]interface{})
+			cur[k] = next
+		}
+		cur = next
+	}
+	last := keys[len(keys)-1]
+	existing, ok := cur[last].(map[string]interface{})
+	if !ok {
+		if _, exists := cur[last]; exists {
+			return fmt.Errorf("key %q is already set to a non-object value", last)
+		}
+		cur[last] = body
+		return nil
+	}
+	for k, v := range body {
+		existing[k] = v
+	}
+	return nil
+}
+
+// plainValue 将求值结果中的 time.Duration 转为字符串, 其余值保持不变.
+func plainValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case time.Duration:
+		return val.String()
+	case []interface{}:
+		for i := range val {
+			val[i] = plainValue(val[i])
+		}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = plainValue(val[k])
+		}
+	}
+	return v
+}
+
+// readJSONValue 读取一个完整的 JSON 值. 数字中不含小数点或指数时解析为 int64.
+func readJSONValue(dec *jsontext.Decoder) (interface{}, error) {
+	tok, err := dec.ReadToken()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.Kind() {
+	case 'n':
+		return nil, nil
+	case 't', 'f':
+		return tok.Bool(), nil
+	case '"':
+		return tok.String(), nil
+	case '0':
+		raw := tok.String()
+		if !strings.ContainsAny(raw, ".eE") {
+			if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+		return tok.Float(), nil
+	case '[':
+		list := []interface{}{}
+		for dec.PeekKind() != ']' {
+			v, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.ReadToken()
+		return list, err
+	case '{':
+		m := make(map[string]interface{})
+		for dec.PeekKind() != '}' {
+			tok, err := dec.ReadToken()
+			if err != nil {
+				return nil, err
+			}
+			key := tok.String()
+			v, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		_, err := dec.ReadToken()
+		return m, err
+	}
+	return nil, fmt.Errorf("unexpected token %s", tok)
+}
+
+// valueWriter 将值树写成 WANF 源码, 结果随后交给格式化器整理.
+type valueWriter struct {
+	buf bytes.Buffer
+}
+
+var (
+	durationValueType = reflect.TypeOf(time.Duration(0))
+	timeValueType     = reflect.TypeOf(time.Time{})
+)
+
+// writeStatements 将对象 m 的每个键写成一条语句, 对象值写成块.
+func (w *valueWriter) writeStatements(m reflect.Value, path string) error {
+	keys, err := sortedKeys(m, path)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		v := indirectValue(m.MapIndex(reflect.ValueOf(k).Convert(m.Type().Key())))
+		if !v.IsValid() {
+			return fmt.Errorf("wanf: %s: null values are not supported", joinPath(path, k))
+		}
+		w.buf.WriteString(k)
+		if v.Kind() == reflect.Map {
+			w.buf.WriteString(" {\n")
+			if err := w.writeStatements(v, joinPath(path, k)); err != nil {
+				return err
+			}
+			w.buf.WriteString("}\n")
+			continue
+		}
+		w.buf.WriteString(" = ")
+		if err := w.writeValue(v, joinPath(path, k)); err != nil {
+			return err
+		}
+		w.buf.WriteByte('\n')
+	}
+	return nil
+}
+
+func (w *valueWriter) writeValue(v reflect.Value, path string) error {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return fmt.Errorf("wanf: %s: null values are not supported", path)
+	}
+	switch v.Type() {
+	case durationValueType:
+		d := time.Duration(v.Int())
+		if d < 0 {
+			return fmt.Errorf("wanf: %s: negative duration %s is not supported", path, d)
+		}
+		w.buf.WriteString(durationLiteral(d))
+		return nil
+	case timeValueType:
+		return w.writeString(v.Interface().(time.Time).Format(time.RFC3339Nano), path)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return w.writeString(v.String(), path)
+	case reflect.Bool:
+		w.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return fmt.Errorf("wanf: %s: negative number %d is not supported", path, v.Int())
+		}
+		w.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		w.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("wanf: %s: number %v is not supported", path, f)
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		w.buf.WriteString(s)
+	case reflect.Slice, reflect.Array:
+		w.buf.WriteString("[\n")
+		for i := 0; i < v.Len(); i++ {
+			if err := w.writeValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+			w.buf.WriteString(",\n")
+		}
+		w.buf.WriteByte(']')
+	case reflect.Map:
+		w.buf.WriteString("{\n")
+		if err := w.writeStatements(v, path); err != nil {
+			return err
+		}
+		w.buf.WriteByte('}')
+	default:
+		return fmt.Errorf("wanf: %s: unsupported value of type %s", path, v.Type())
+	}
+	return nil
+}
+
+// durationLiteral 使用能整除 d 的最大单位写出 duration, 因为 WANF 的
+// duration 字面量只能带一个单位, 如 90s 而不是 1m30s.
+func durationLiteral(d time.Duration) string {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}, {"ms", time.Millisecond}, {"us", time.Microsecond}}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
+// writeString 写出字符串字面量. WANF 字符串不支持转义, 含换行的字符串
+// 使用反引号, 其余使用双引号; 无法用任何一种形式表示的字符串返回错误.
+func (w *valueWriter) writeString(s, path string) error {
+	quote := byte('"')
+	if strings.Contains(s, "\n") {
+		quote = '`'
+	}
+	if strings.IndexByte(s, quote) >= 0 {
+		return fmt.Errorf("wanf: %s: string %q cannot be represented without escapes", path, s)
+	}
+	w.buf.WriteByte(quote)
+	w.buf.WriteString(s)
+	w.buf.WriteByte(quote)
+	return nil
+}
+
+// sortedKeys 返回 m 的键, 并检查每个键都是可以用作 WANF 标识符的字符串.
+func sortedKeys(m reflect.Value, path string) ([]string, error) {
+	if m.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("wanf: %s: map keys must be strings, got %s", path, m.Type().Key())
+	}
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		key := k.String()
+		if !isValidIdentifier(key) {
+			return nil, fmt.Errorf("wanf: %s: key %q is not a valid identifier", path, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func isValidIdentifier(s string) bool {
+	if s == "" || !isIdentifierStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentifierChar(s[i]) {
+			return false
+		}
+	}
+	return LookupIdentifier([]byte(s)) == IDENT
+}
+
+// indirectValue 解开 interface 与指针, nil 返回零值 reflect.Value.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describeValue(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "number"
+}
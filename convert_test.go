@@ -0,0 +1,179 @@
+package wanf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToJSON(t *testing.T) {
+	src := `var region = "eu"
+timeout = 90s
+name = "svc-${region}"
+ports = [80, 443]
+labels = {[team = "core"]}
+
+server "a" {
+	port = 1
+}
+server "b" {
+	port = 2
+}
+database {
+	host = "localhost"
+}
+database {
+	user = "admin"
+}
+`
+	got, err := ToJSON([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "database": {
+    "host": "localhost",
+    "user": "admin"
+  },
+  "labels": {
+    "team": "core"
+  },
+  "name": "svc-eu",
+  "ports": [
+    80,
+    443
+  ],
+  "server": {
+    "a": {
+      "port": 1
+    },
+    "b": {
+      "port": 2
+    }
+  },
+  "timeout": "1m30s"
+}
+`
+	if string(got) != want {
+		t.Errorf("ToJSON mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	src := `{
+  "name": "svc",
+  "big": 9007199254740993,
+  "ratio": 0.5,
+  "enabled": true,
+  "motd": "hello\n\"world\"",
+  "servers": [{"host": "a", "port": 1}],
+  "database": {"user": "admin", "pool": {"size": 4}}
+}`
+	got, err := FromJSON([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "big = 9007199254740993\n" +
+		"\n" +
+		"database {\n" +
+		"\tuser = \"admin\"\n" +
+		"\tpool {\n" +
+		"\t\tsize = 4\n" +
+		"\t}\n" +
+		"}\n" +
+		"\n" +
+		"enabled = true\n" +
+		"motd = `hello\n\"world\"`\n" +
+		"name = \"svc\"\n" +
+		"ratio = 0.5\n" +
+		"\n" +
+		"servers = [\n" +
+		"\t{\n" +
+		"\t\thost = \"a\"\n" +
+		"\t\tport = 1\n" +
+		"\t},\n" +
+		"]\n"
+	if string(got) != want {
+		t.Errorf("FromJSON mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 转换结果可以再次转换回相同的 JSON 结构.
+	back, err := ToJSON(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(back), `"big": 9007199254740993`) || !strings.Contains(string(back), `"size": 4`) {
+		t.Errorf("round trip lost data:\n%s", back)
+	}
+}
+
+func TestFromJSON_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`[1, 2]`, "top-level JSON value must be an object, got array"},
+		{`{"a": 1} {}`, "unexpected data after top-level value"},
+		{`{"my-key": 1}`, `key "my-key" is not a valid identifier`},
+		{`{"var": 1}`, `key "var" is not a valid identifier`},
+		{`{"a": {"b": null}}`, "a.b: null values are not supported"},
+		{`{"a": [1, -2]}`, "a[1]: negative number -2 is not supported"},
+		{`{"a": "x\"y` + "`" + `\nz"}`, "cannot be represented without escapes"},
+	}
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("FromJSON(%s) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestFromValue_Duration(t *testing.T) {
+	got, err := FromValue(map[string]interface{}{"a": 90 * time.Second, "b": 2 * time.Hour, "c": 1500 * time.Microsecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a = 90s\nb = 2h\nc = 1500us\n"
+	if string(got) != want {
+		t.Errorf("FromValue mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEval(t *testing.T) {
+	t.Setenv("WANF_EVAL_USER", "admin")
+	program := parseForMerge(t, `
+var region = "eu"
+name = "svc-${region}"
+timeout = 90s
+server "a" {
+	user = env("WANF_EVAL_USER")
+	ports = [80, 443]
+}
+`)
+	got, err := Eval(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":    "svc-eu",
+		"timeout": 90 * time.Second,
+		"server": map[string]interface{}{
+			"a": map[string]interface{}{
+				"user":  "admin",
+				"ports": []interface{}{int64(80), int64(443)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Eval = %#v, want %#v", got, want)
+	}
+	// Eval 不改变 program 本身的语句.
+	if _, ok := program.Statements[0].(*VarStatement); !ok {
+		t.Errorf("Eval modified the program: %v", program.Statements[0])
+	}
+
+	if _, err := Eval(program, WithSandbox()); err == nil {
+		t.Error("Eval with env() in sandbox mode succeeded")
+	}
+}
@@ -0,0 +1,180 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// corpusSpec 描述一个合成文档的形状. 同一个 spec 总是生成相同的文档,
+// 因此不同提交之间的基准结果可以直接比较.
+type corpusSpec struct {
+	name     string
+	services int // 带标签的 service 块数
+	rules    int // 每个 service 中的 rule 块数
+	env      int // 每个 service 中 env 块的键数
+	list     int // 顶层列表的长度
+	depth    int // node 块的嵌套深度
+}
+
+// corpora 是基准测试使用的文档集合, 从小到大覆盖多块、长列表与深嵌套.
+var corpora = []corpusSpec{
+	{name: "small", services: 10, rules: 2, env: 2, list: 10, depth: 4},
+	{name: "wide", services: 2000, rules: 3, env: 4, list: 100, depth: 4},
+	{name: "lists", services: 10, rules: 1, env: 1, list: 50000, depth: 4},
+	{name: "deep", services: 10, rules: 1, env: 1, list: 10, depth: 500},
+}
+
+type corpusConfig struct {
+	Name     string                   `wanf:"name"`
+	Version  int                      `wanf:"version"`
+	Tags     []string                 `wanf:"tags"`
+	Ports    []int                    `wanf:"ports"`
+	Services map[string]corpusService `wanf:"service"`
+	Node     []corpusNode             `wanf:"node"`
+}
+
+type corpusService struct {
+	Host    string            `wanf:"host"`
+	Port    int               `wanf:"port"`
+	Timeout time.Duration     `wanf:"timeout"`
+	Ratio   float64           `wanf:"ratio"`
+	Enabled bool              `wanf:"enabled"`
+	Env     map[string]string `wanf:"env"`
+	Rules   []corpusRule      `wanf:"rule"`
+}
+
+type corpusRule struct {
+	Labels []string `wanf:",labels"`
+	Path   string   `wanf:"path"`
+	Weight int      `wanf:"weight"`
+}
+
+type corpusNode struct {
+	Level int          `wanf:"level"`
+	Label string       `wanf:"label"`
+	Node  []corpusNode `wanf:"node"`
+}
+
+// generate 生成 spec 描述的文档. 文档只使用所有解码方式都支持的写法:
+// StreamDecoder 不支持结构体指针块与无标签的 map 块, 因此嵌套用切片表示,
+// env 用 map 字面量.
+func (spec corpusSpec) generate() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// synthetic corpus %q\nname = %q\nversion = 3\n", spec.name, spec.name)
+	b.WriteString("tags = [\n")
+	for i := 0; i < spec.list; i++ {
+		fmt.Fprintf(&b, "\t\"tag-%d\",\n", i)
+	}
+	b.WriteString("]\nports = [")
+	for i := 0; i < spec.list; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d", 1024+i)
+	}
+	b.WriteString("]\n")
+	for i := 0; i < spec.services; i++ {
+		fmt.Fprintf(&b, "\nservice \"svc-%d\" {\n", i)
+		fmt.Fprintf(&b, "\thost = \"host-%d.internal\"\n\tport = %d\n\ttimeout = %dms\n", i, 8000+i%1000, 100+i%900)
+		fmt.Fprintf(&b, "\tratio = %d.%d\n\tenabled = %t\n", i%10, i%100, i%2 == 0)
+		b.WriteString("\tenv = {[\n")
+		for j := 0; j < spec.env; j++ {
+			fmt.Fprintf(&b, "\t\tKEY_%d = \"value-%d-%d\",\n", j, i, j)
+		}
+		b.WriteString("\t]}\n")
+		for j := 0; j < spec.rules; j++ {
+			fmt.Fprintf(&b, "\trule \"r%d\" {\n\t\tpath = \"/api/%d/%d\"\n\t\tweight = %d\n\t}\n", j, i, j, j+1)
+		}
+		b.WriteString("}\n")
+	}
+	b.WriteString("\n")
+	for d := 0; d < spec.depth; d++ {
+		indent := strings.Repeat("\t", d)
+		fmt.Fprintf(&b, "%snode {\n%s\tlevel = %d\n%s\tlabel = \"level-%d\"\n", indent, indent, d, indent, d)
+	}
+	for d := spec.depth - 1; d >= 0; d-- {
+		fmt.Fprintf(&b, "%s}\n", strings.Repeat("\t", d))
+	}
+	return b.Bytes()
+}
+
+// corpusDecoders 是参与对比的解码方式. 新的快速路径加入这里即可与其余方式
+// 在同一批文档上比较结果与性能.
+var corpusDecoders = []struct {
+	name   string
+	decode func(data []byte, v *corpusConfig) error
+}{
+	{"Decode", func(data []byte, v *corpusConfig) error {
+		return Decode(data, v)
+	}},
+	{"StreamDecode", func(data []byte, v *corpusConfig) error {
+		dec, err := NewStreamDecoder(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return dec.Decode(v)
+	}},
+	{"Plan", func(data []byte, v *corpusConfig) error {
+		return corpusPlan.Decode(data, v)
+	}},
+}
+
+var corpusPlan = CompileType(reflect.TypeOf(corpusConfig{}))
+
+// TestCorpusDecoders 确认每种解码方式对每个合成文档给出相同的结果.
+func TestCorpusDecoders(t *testing.T) {
+	for _, spec := range corpora {
+		if testing.Short() && spec.name != "small" {
+			continue
+		}
+		data := spec.generate()
+		var want corpusConfig
+		if err := Decode(data, &want); err != nil {
+			t.Fatalf("%s: %v", spec.name, err)
+		}
+		if len(want.Services) != spec.services || len(want.Tags) != spec.list {
+			t.Fatalf("%s: decoded %d services and %d tags", spec.name, len(want.Services), len(want.Tags))
+		}
+		depth := 0
+		for n := want.Node; len(n) > 0; n = n[0].Node {
+			depth++
+		}
+		if depth != spec.depth {
+			t.Fatalf("%s: nesting depth = %d, want %d", spec.name, depth, spec.depth)
+		}
+		for _, dec := range corpusDecoders[1:] {
+			var got corpusConfig
+			if err := dec.decode(data, &got); err != nil {
+				t.Errorf("%s/%s: %v", spec.name, dec.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s/%s: result differs from Decode", spec.name, dec.name)
+			}
+		}
+	}
+}
+
+// BenchmarkCorpus 在每个合成文档上比较各解码方式, 结果按 文档/解码方式 命名,
+// 例如 go test -bench 'Corpus/wide/' -benchmem.
+func BenchmarkCorpus(b *testing.B) {
+	for _, spec := range corpora {
+		data := spec.generate()
+		for _, dec := range corpusDecoders {
+			b.Run(spec.name+"/"+dec.name, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var cfg corpusConfig
+					if err := dec.decode(data, &cfg); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
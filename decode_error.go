@@ -0,0 +1,136 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// 解码失败的类别, 可以用 errors.Is 判断. 错误信息不变, 它们通常被包装在
+// *DecodeError 中.
+var (
+	// ErrTypeMismatch 表示值不能写入字段的类型, 如把列表赋给整数字段.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrLossyNumber 表示 WithStrictNumbers 拒绝了会改变数值的转换.
+	ErrLossyNumber = errors.New("lossy number conversion")
+	// ErrUnknownField 表示使用 WithDisallowUnknownFields 时, 文档中的键或块
+	// 没有对应的字段.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrEnvNotSet 表示 env() 引用的环境变量未设置且没有默认值.
+	ErrEnvNotSet = errors.New("environment variable not set")
+	// ErrUndefinedVariable 表示 ${name} 引用了未定义的变量.
+	ErrUndefinedVariable = errors.New("undefined variable")
+	// ErrVariableCycle 表示变量的定义互相引用.
+	ErrVariableCycle = errors.New("variable cycle")
+	// ErrSandbox 表示 WithSandbox 拒绝了 env()、secret() 或 import.
+	ErrSandbox = errors.New("not allowed in sandbox mode")
+)
+
+// kindError 是属于类别 kind (如 ErrTypeMismatch) 的错误, 信息与 err 相同.
+type kindError struct {
+	kind error
+	err  error
+}
+
+// errorOf 按 fmt.Errorf 的格式创建属于类别 kind 的错误.
+func errorOf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+func (e *kindError) Error() string        { return e.err.Error() }
+func (e *kindError) Unwrap() error        { return e.err }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// DecodeError 是解码时无法为字段赋值的错误, 如值的类型与字段不符或 env()
+// 引用的环境变量未设置. 它指出值所在的位置与字段的路径, 原始错误可以用
+// errors.Unwrap 取得. 标签约束与 Validate 的错误仍为 *ValidationError.
+type DecodeError struct {
+	// File 是值所在的文件: DecodeFile 的路径或导入的文件的绝对路径. 通过
+	// NewDecoder 与 StreamDecoder 读取的主文档为空.
+	File string
+	// Path 是字段的路径, 语法与 Lookup 相同, 如 server."main".port.
+	Path   string
+	Line   int
+	Column int
+	Err    error
+
+	stmt Statement // 出错的语句
+	top  Statement // 包含 stmt 的顶层语句, 用于确定 File
+}
+
+func (e *DecodeError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: line %d:%d: %s: %v", e.File, e.Line, e.Column, e.Path, e.Err)
+	}
+	return fmt.Sprintf("line %d:%d: %s: %v", e.Line, e.Column, e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError 将为键 tok 赋值时的错误包装为 *DecodeError, stmt 是所在的语句.
+// 已带有位置的 *DecodeError、*ValidationError 与 StreamDecoder 的语法错误
+// *ParseError 原样返回.
+func fieldError(err error, stmt Statement, tok Token) error {
+	return wrapDecodeError(err, string(tok.Literal), stmt, tok)
+}
+
+// blockError 在 err 中的 *DecodeError 的路径前加上块 stmt 的名称与标签;
+// 块本身的错误 (如字段类型不能接收块) 包装为指向块名的 *DecodeError.
+func blockError(err error, stmt *BlockStatement) error {
+	if err == nil {
+		return nil
+	}
+	path := blockPath(string(stmt.Name.Value), stmt.Labels())
+	if prefixDecodeError(err, path, stmt) {
+		return err
+	}
+	return wrapDecodeError(err, path, stmt, stmt.Name.Token)
+}
+
+func wrapDecodeError(err error, path string, stmt Statement, tok Token) error {
+	if err == nil {
+		return nil
+	}
+	var derr *DecodeError
+	var verr *ValidationError
+	var perr *ParseError
+	if errors.As(err, &derr) || errors.As(err, &verr) || errors.As(err, &perr) {
+		return err
+	}
+	return &DecodeError{Path: path, Line: tok.Line, Column: tok.Column, Err: err, stmt: stmt, top: stmt}
+}
+
+// prefixDecodeError 在 err 中的 *DecodeError 的路径前加上 path, 报告 err
+// 是否包含 *DecodeError.
+func prefixDecodeError(err error, path string, stmt Statement) bool {
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		return false
+	}
+	derr.Path = joinPath(path, derr.Path)
+	derr.top = stmt
+	return true
+}
+
+// blockPath 返回块在字段路径中的写法: 名称后跟带引号的标签, 如 server."main".
+func blockPath(name string, labels []string) string {
+	for _, label := range labels {
+		name += "." + strconv.Quote(label)
+	}
+	return name
+}
+
+// fileError 为 Decode 返回的 err 中的 *DecodeError 填写所在的文件.
+func (d *internalDecoder) fileError(err error) error {
+	var derr *DecodeError
+	if errors.As(err, &derr) && derr.File == "" {
+		if file, ok := d.files[derr.top]; ok {
+			derr.File = file
+		} else {
+			derr.File = d.file
+		}
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+package wanf
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decodeErrorConfig struct {
+	Name   string `wanf:"name"`
+	Server map[string]struct {
+		Port int `wanf:"port"`
+	} `wanf:"server"`
+	Database struct {
+		Pool struct {
+			Size int `wanf:"size"`
+		} `wanf:"pool"`
+	} `wanf:"database"`
+}
+
+func TestDecodeErrorPosition(t *testing.T) {
+	tests := []struct {
+		src  string
+		path string
+		line int
+		col  int
+	}{
+		{"name = [1]\n", "name", 1, 1},
+		{"server \"main\" {\n\tport = true\n}\n", `server."main".port`, 2, 2},
+		{"database {\n\tpool {\n\t\tsize = true\n\t}\n}\n", "database.pool.size", 3, 3},
+		{"name = env(\"DECODE_ERROR_UNSET\")\n", "name", 1, 1},
+		{"server {\n\tport = 80\n}\n", "server", 1, 1},
+	}
+	for _, tt := range tests {
+		var plain, planned, streamed decodeErrorConfig
+		errs := map[string]error{
+			"Decode":     Decode([]byte(tt.src), &plain),
+			"Plan":       CompileType(reflect.TypeOf(planned)).Decode([]byte(tt.src), &planned),
+			"StreamMode": decodeStream(t, tt.src, &streamed),
+		}
+		for name, err := range errs {
+			var derr *DecodeError
+			if !errors.As(err, &derr) {
+				t.Errorf("%s(%q) error = %v, want a *DecodeError", name, tt.src, err)
+				continue
+			}
+			if derr.Path != tt.path || derr.Line != tt.line || derr.Column != tt.col || derr.File != "" {
+				t.Errorf("%s(%q) = %s %d:%d in %q, want %s %d:%d", name, tt.src, derr.Path, derr.Line, derr.Column, derr.File, tt.path, tt.line, tt.col)
+			}
+		}
+	}
+}
+
+func decodeStream(t *testing.T, src string, v interface{}) error {
+	t.Helper()
+	dec, err := NewStreamDecoder(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dec.Decode(v)
+}
+
+func TestDecodeErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	common := writeLayer(t, dir, "common.wanf", "database {\n\tpool {\n\t\tsize = \"big\"\n\t}\n}\n")
+	main := writeLayer(t, dir, "app.wanf", "import \"common.wanf\"\nname = \"app\"\n")
+
+	var cfg decodeErrorConfig
+	err := DecodeFile(main, &cfg)
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("DecodeFile error = %v, want a *DecodeError", err)
+	}
+	absCommon, _ := filepath.Abs(common)
+	if derr.File != absCommon || derr.Path != "database.pool.size" || derr.Line != 3 {
+		t.Errorf("DecodeFile error = %v", err)
+	}
+	if want := absCommon + ": line 3:3: database.pool.size: cannot set field of type int"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error() = %q, want prefix %q", err.Error(), want)
+	}
+
+	if err := DecodeFile(writeLayer(t, dir, "bad.wanf", "name = [1]\n"), &cfg); !errors.As(err, &derr) || derr.File != filepath.Join(dir, "bad.wanf") {
+		t.Errorf("DecodeFile error = %v, want the main file", err)
+	}
+
+	base := writeLayer(t, dir, "layers/base.wanf", "name = \"app\"\n")
+	override := writeLayer(t, dir, "layers/prod.wanf", "server \"main\" {\n\tport = true\n}\n")
+	if err := DecodeFiles(&cfg, base, override); !errors.As(err, &derr) || derr.File != override || derr.Path != `server."main".port` {
+		t.Errorf("DecodeFiles error = %v, want it in %s", err, override)
+	}
+}
+
+func TestDecodeErrorKinds(t *testing.T) {
+	t.Setenv("DECODE_ERROR_SET", "x")
+	tests := []struct {
+		src    string
+		opts   []DecoderOption
+		kind   error
+		stream bool // StreamDecoder 返回相同的类别
+	}{
+		{"name = [1]\n", nil, ErrTypeMismatch, true},
+		{"server {\n\tport = 80\n}\n", nil, ErrTypeMismatch, true},
+		{"database {\n\tpool {\n\t\tsize = 1.5\n\t}\n}\n", []DecoderOption{WithStrictNumbers()}, ErrLossyNumber, true},
+		{"name = env(\"DECODE_ERROR_UNSET\")\n", nil, ErrEnvNotSet, true},
+		{"name = env(\"DECODE_ERROR_SET\")\n", []DecoderOption{WithSandbox()}, ErrSandbox, true},
+		{"name = secret(\"vault:x\")\n", []DecoderOption{WithSandbox()}, ErrSandbox, true},
+		{"name = ${missing}\n", nil, ErrUndefinedVariable, true},
+		{"var a = ${b}\nvar b = ${a}\nname = ${a}\n", nil, ErrVariableCycle, false},
+		{"nmae = \"x\"\n", []DecoderOption{WithDisallowUnknownFields()}, ErrUnknownField, true},
+	}
+	for _, tt := range tests {
+		var cfg decodeErrorConfig
+		dec, err := NewDecoder(strings.NewReader(tt.src), tt.opts...)
+		if err == nil {
+			err = dec.Decode(&cfg)
+		}
+		if !errors.Is(err, tt.kind) {
+			t.Errorf("Decode(%q) error = %v, want %v", tt.src, err, tt.kind)
+		}
+		if !tt.stream {
+			continue
+		}
+		sdec, err := NewStreamDecoder(strings.NewReader(tt.src), tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sdec.Decode(&cfg); !errors.Is(err, tt.kind) {
+			t.Errorf("StreamDecoder(%q) error = %v, want %v", tt.src, err, tt.kind)
+		}
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	tests := []struct {
+		src  string
+		path string
+		line int
+	}{
+		{"name = \"a\"\nnmae = \"b\"\n", "nmae", 2},
+		{"database {\n\tpol {\n\t\tsize = 1\n\t}\n}\n", "database.pol", 2},
+		{"server \"main\" {\n\tprot = 80\n}\n", `server."main".prot`, 2},
+	}
+	for _, tt := range tests {
+		var plain, planned, streamed decodeErrorConfig
+		errs := map[string]error{
+			"Decode": func() error {
+				dec, err := NewDecoder(strings.NewReader(tt.src), WithDisallowUnknownFields())
+				if err != nil {
+					return err
+				}
+				return dec.Decode(&plain)
+			}(),
+			"Plan": CompileType(reflect.TypeOf(planned)).Decode([]byte(tt.src), &planned, WithDisallowUnknownFields()),
+			"StreamDecoder": func() error {
+				dec, err := NewStreamDecoder(strings.NewReader(tt.src), WithStreamStrict())
+				if err != nil {
+					return err
+				}
+				return dec.Decode(&streamed)
+			}(),
+		}
+		for name, err := range errs {
+			var derr *DecodeError
+			if !errors.As(err, &derr) || !errors.Is(err, ErrUnknownField) || derr.Path != tt.path || derr.Line != tt.line {
+				t.Errorf("%s(%q) error = %v, want unknown field %s on line %d", name, tt.src, err, tt.path, tt.line)
+			}
+		}
+		// 默认忽略未知的键.
+		var cfg decodeErrorConfig
+		if err := Decode([]byte(tt.src), &cfg); err != nil {
+			t.Errorf("Decode(%q) without the option: %v", tt.src, err)
+		}
+	}
+}
+
+func TestSyntaxErrorType(t *testing.T) {
+	src := "name = \"a\"\nname = )\n"
+	var cfg decodeErrorConfig
+	for name, err := range map[string]error{
+		"Decode":        Decode([]byte(src), &cfg),
+		"StreamDecoder": decodeStream(t, src, &cfg),
+	} {
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Line != 2 || perr.Column != 8 {
+			t.Errorf("%s error = %v, want a *ParseError at 2:8", name, err)
+		}
+	}
+}
@@ -3,8 +3,7 @@ package wanf
 import (
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -30,85 +29,227 @@ func WithBasePath(path string) DecoderOption {
 	}
 }
 
+// WithVariables makes the given values available to `${...}` references, as if
+// they had been declared with `var`. A `var` declared in the document with the
+// same name takes precedence over the injected value.
+func WithVariables(vars map[string]interface{}) DecoderOption {
+	return func(d *internalDecoder) {
+		if d.extVars == nil {
+			d.extVars = make(map[string]interface{}, len(vars))
+		}
+		for name, val := range vars {
+			d.extVars[name] = val
+		}
+	}
+}
+
+// WithSandbox 用于解码不受信任的输入: 文档中出现 `env()`、`secret()` 或 `import` 等
+// 访问进程环境或文件系统的功能时, 解码将返回错误而不是执行它们.
+func WithSandbox() DecoderOption {
+	return func(d *internalDecoder) {
+		d.sandbox = true
+	}
+}
+
+// WithStrictNumbers 使解码器拒绝会改变数值的转换: 超出字段范围的整数 (如把
+// 300 写入 int8 或把负数写入 uint)、带有小数部分或超出范围的浮点数写入整数
+// 字段、超出 float32 范围的浮点数, 以及浮点数字段无法精确表示的整数. 默认
+// 按 Go 的类型转换规则截断而不报错.
+func WithStrictNumbers() DecoderOption {
+	return func(d *internalDecoder) {
+		d.strictNumbers = true
+	}
+}
+
+// WithDisallowUnknownFields 使解码器在文档中出现没有对应字段的键或块时返回
+// 包装了 ErrUnknownField 的 *DecodeError, 而不是忽略它们, 便于发现拼错的键.
+// 对实现了 Unmarshaler 的类型与 map 字段没有作用.
+func WithDisallowUnknownFields() DecoderOption {
+	return func(d *internalDecoder) {
+		d.disallowUnknown = true
+	}
+}
+
+// WithStreamStrict 使 StreamDecoder 在遇到没有对应字段的键或块时返回带有
+// 位置的错误, 而不是跳过它们. 它与 WithDisallowUnknownFields 相同, 两者
+// 都适用于所有解码器. StreamDecoder.Blocks 仍会跳过其他顶层键与块.
+func WithStreamStrict() DecoderOption {
+	return WithDisallowUnknownFields()
+}
+
 type Decoder struct {
 	program *RootNode
 	d       *internalDecoder
 }
 
 func NewDecoder(r io.Reader, opts ...DecoderOption) (*Decoder, error) {
-	data, err := io.ReadAll(r)
+	d := newInternalDecoder(opts)
+	data, err := d.limits.readAll(r)
 	if err != nil {
 		return nil, err
 	}
-	l := NewLexer(data)
-	p := NewParser(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) > 0 {
-		var errs []string
-		for _, err := range p.Errors() {
-			errs = append(errs, err.Error())
-		}
-		return nil, fmt.Errorf("parser errors: %s", strings.Join(errs, "\n"))
+	program, err := d.parseFile(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("parser errors: %w", err)
 	}
+	return d.load(program)
+}
+
+// newProgramDecoder 对已解析的 program 执行迁移、导入、profile 与变量求值.
+func newProgramDecoder(program *RootNode, opts ...DecoderOption) (*Decoder, error) {
+	return newInternalDecoder(opts).load(program)
+}
+
+func newInternalDecoder(opts []DecoderOption) *internalDecoder {
 	d := &internalDecoder{vars: make(map[string]interface{})}
 	for _, opt := range opts {
 		opt(d)
 	}
-	finalStmts, err := processImports(program.Statements, d.basePath, make(map[string]bool))
-	if err != nil {
+	if d.prov == nil && d.hooks.wantsFields() {
+		d.prov = newProvenanceTracker("")
+	}
+	return d
+}
+
+// load 见 newProgramDecoder.
+func (d *internalDecoder) load(program *RootNode) (*Decoder, error) {
+	if _, _, err := Migrate(program); err != nil {
 		return nil, err
 	}
-	program.Statements = finalStmts
-	for _, stmt := range program.Statements {
-		if s, ok := stmt.(*VarStatement); ok {
-			val, err := d.evalExpression(s.Value)
-			if err != nil {
-				return nil, err
+	if d.sandbox {
+		for _, stmt := range program.Statements {
+			if imp, ok := stmt.(*ImportStatement); ok {
+				return nil, errorOf(ErrSandbox, "line %d:%d: import %q is not allowed in sandbox mode", imp.Token.Line, imp.Token.Column, string(imp.Path.Value))
 			}
-			d.vars[string(s.Name.Value)] = val
 		}
 	}
+	d.files = make(map[Statement]string)
+	obs := &importObserver{files: d.files, hooks: d.hooks, limits: d.limits}
+	finalStmts, err := expandImports(program.Statements, d.basePath, make(map[string]bool), d.importCache, obs)
+	if err != nil {
+		return nil, err
+	}
+	d.markProfile(finalStmts)
+	program.Statements = applyProfile(finalStmts, d.profile)
+	if err := d.resolveVars(program.Statements); err != nil {
+		return nil, err
+	}
 	return &Decoder{program: program, d: d}, nil
 }
 
-func processImports(stmts []Statement, basePath string, processed map[string]bool) ([]Statement, error) {
-	var finalStmts []Statement
+// markProfile 在 applyProfile 之前记录选中的 profile 中的语句所在的文件, 使用
+// WithProvenance 时还记录其 profile 名.
+func (d *internalDecoder) markProfile(stmts []Statement) {
+	if d.profile == "" {
+		return
+	}
 	for _, stmt := range stmts {
-		importStmt, ok := stmt.(*ImportStatement)
-		if !ok {
-			finalStmts = append(finalStmts, stmt)
+		bs, ok := isProfileBlock(stmt)
+		if !ok || string(bs.Label.Value) != d.profile {
 			continue
 		}
-		importPath := filepath.Join(basePath, string(importStmt.Path.Value))
-		absImportPath, err := filepath.Abs(importPath)
-		if err != nil {
-			return nil, fmt.Errorf("could not get absolute path for import %q: %w", string(importStmt.Path.Value), err)
-		}
-		if processed[absImportPath] {
-			continue
+		if d.prov != nil {
+			d.prov.shadow("", bs.Body.Statements)
 		}
-		processed[absImportPath] = true
-		data, err := os.ReadFile(absImportPath)
-		if err != nil {
-			return nil, fmt.Errorf("could not read imported file %q: %w", importPath, err)
+		for _, s := range bs.Body.Statements {
+			if d.prov != nil {
+				d.prov.profiles[s] = d.profile
+			}
+			if file, ok := d.files[bs]; ok {
+				d.files[s] = file
+			}
 		}
-		l := NewLexer(data)
-		p := NewParser(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) > 0 {
-			var errs []string
-			for _, err := range p.Errors() {
-				errs = append(errs, err.Error())
+	}
+}
+
+// resolveVars registers every var declaration and evaluates them all. Variables
+// may reference each other in any order; each one is evaluated on first use.
+func (d *internalDecoder) resolveVars(stmts []Statement) error {
+	d.varDecls = make(map[string]*VarStatement)
+	var names []string
+	for _, stmt := range stmts {
+		if s, ok := stmt.(*VarStatement); ok {
+			name := string(s.Name.Value)
+			if _, ok := d.varDecls[name]; !ok {
+				names = append(names, name)
 			}
-			return nil, fmt.Errorf("parser errors in imported file %q: %s", importPath, strings.Join(errs, "\n"))
+			d.varDecls[name] = s
+		}
+	}
+	for _, name := range names {
+		if _, err := d.lookupVar(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupVar returns the value of a variable, evaluating its declaration if needed.
+func (d *internalDecoder) lookupVar(name string) (interface{}, error) {
+	if val, ok := d.vars[name]; ok {
+		return val, nil
+	}
+	stmt, ok := d.varDecls[name]
+	if !ok {
+		if val, ok := d.extVars[name]; ok {
+			return val, nil
+		}
+		return nil, errorOf(ErrUndefinedVariable, "variable %q is not defined", name)
+	}
+	for i, n := range d.varStack {
+		if n == name {
+			cycle := append(append([]string{}, d.varStack[i:]...), name)
+			return nil, errorOf(ErrVariableCycle, "variable cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	d.varStack = append(d.varStack, name)
+	val, err := d.evalExpression(stmt.Value)
+	d.varStack = d.varStack[:len(d.varStack)-1]
+	if err != nil {
+		// 与字段的错误一样指出位置, 路径为变量名.
+		return nil, d.fileError(wrapDecodeError(err, name, stmt, stmt.Name.Token))
+	}
+	d.vars[name] = val
+	return val, nil
+}
+
+func (d *internalDecoder) hasVar(name string) bool {
+	if _, ok := d.vars[name]; ok {
+		return true
+	}
+	if _, ok := d.varDecls[name]; ok {
+		return true
+	}
+	_, ok := d.extVars[name]
+	return ok
+}
+
+// interpolate replaces `${name}` references to known variables inside a string.
+// References to unknown names are left untouched, so templates such as
+// "${level}: ${message}" that are meant for other tools survive decoding.
+func (d *internalDecoder) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var firstErr error
+	out := varRegex.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[2 : len(m)-1]
+		if !d.hasVar(name) {
+			return m
 		}
-		importedStmts, err := processImports(program.Statements, filepath.Dir(absImportPath), processed)
+		val, err := d.lookupVar(name)
 		if err != nil {
-			return nil, err
+			if firstErr == nil {
+				firstErr = err
+			}
+			return m
 		}
-		finalStmts = append(finalStmts, importedStmts...)
+		return fmt.Sprint(val)
+	})
+	if firstErr != nil {
+		return "", firstErr
 	}
-	return finalStmts, nil
+	return out, nil
 }
 
 func getOrCacheDecoderFields(typ reflect.Type) map[string]decoderCachedField {
@@ -148,23 +289,67 @@ func getOrCacheDecoderFields(typ reflect.Type) map[string]decoderCachedField {
 }
 
 func (dec *Decoder) Decode(v interface{}) error {
+	start := time.Now()
+	err := dec.decode(v)
+	dec.d.hooks.decoded(time.Since(start), err)
+	return err
+}
+
+func (dec *Decoder) decode(v interface{}) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("v must be a pointer to a struct")
 	}
-	return dec.d.decodeRoot(dec.program, rv.Elem())
+	dec.d.beginProvenance(dec.program)
+	err := dec.d.decodeRoot(dec.program, rv.Elem())
+	dec.d.endProvenance()
+	if err != nil {
+		return dec.d.fileError(err)
+	}
+	return callValidate(rv.Elem())
 }
 
 type internalDecoder struct {
 	vars     map[string]interface{}
+	varDecls map[string]*VarStatement
+	varStack []string               // variables currently being evaluated, for cycle detection
+	extVars  map[string]interface{} // values injected with WithVariables
 	basePath string
+	profile  string
+	file     string               // 主文档的路径, 见 DecodeFile
+	files    map[Statement]string // 被导入的顶层语句 -> 文件的绝对路径
+	sandbox  bool                 // 禁止 env()、secret() 与 import, 见 WithSandbox
+	secrets  SecretResolver       // 见 WithSecretResolver
+	prov     *provenanceTracker   // 见 WithProvenance
+	hooks    hookList             // 见 WithHooks
+
+	importCache   *ImportCache // 见 WithImportCache
+	mmap          bool         // 见 WithMmap
+	strictNumbers bool         // 见 WithStrictNumbers
+	limits        inputLimits  // 见 WithMaxDepth 与 WithMaxSize
+
+	disallowUnknown bool // 见 WithDisallowUnknownFields
 }
 
 func (d *internalDecoder) decodeRoot(root *RootNode, rv reflect.Value) error {
 	if rv.Kind() != reflect.Struct {
 		return fmt.Errorf("can only decode root into a struct, got %s", rv.Kind())
 	}
+	if rv.Type() == rawBlockType && rv.CanAddr() {
+		rv.Addr().Interface().(*RawBlock).capture(d, root)
+		d.recordOpaque(root)
+		return nil
+	}
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(Unmarshaler); ok {
+			return d.unmarshalBody(u, root)
+		}
+		if plan := getFlatPlan(rv.Type()); plan != nil {
+			return d.decodeFlat(plan, root, rv)
+		}
+	}
 	for _, stmt := range root.Statements {
+		d.statementProvenance(root, stmt)
 		switch s := stmt.(type) {
 		case *AssignStatement:
 			if err := d.decodeAssign(s, rv); err != nil {
@@ -182,55 +367,186 @@ func (d *internalDecoder) decodeRoot(root *RootNode, rv reflect.Value) error {
 func (d *internalDecoder) decodeAssign(stmt *AssignStatement, rv reflect.Value) error {
 	field, tag, ok := findFieldAndTag(rv, stmt.Name.Value)
 	if !ok {
+		return d.unknownField(stmt)
+	}
+	return d.decodeAssignField(stmt, field, tag)
+}
+
+func (d *internalDecoder) decodeAssignField(stmt *AssignStatement, field reflect.Value, tag wanfTag) error {
+	if d.captureRawBlock(field, stmt.Value) {
+		d.recordField(stmt.Name.Value, stmt.Value)
 		return nil
 	}
 	val, err := d.evalExpression(stmt.Value)
 	if err != nil {
-		return err
+		return fieldError(err, stmt, stmt.Name.Token)
 	}
 	if tag.KeyField != "" {
-		return d.setMapFromList(field, val, tag.KeyField)
+		err = d.setMapFromList(field, val, tag.KeyField)
+	} else {
+		err = d.setField(field, val)
+	}
+	if err != nil {
+		return fieldError(positionValidationError(err, stmt.Name.Token), stmt, stmt.Name.Token)
+	}
+	if err := checkField(field, tag, stmt.Name.Token); err != nil {
+		return err
 	}
-	return d.setField(field, val)
+	d.recordField(stmt.Name.Value, stmt.Value)
+	return nil
 }
 
 func (d *internalDecoder) decodeBlock(stmt *BlockStatement, rv reflect.Value) error {
 	field, _, ok := findFieldAndTag(rv, stmt.Name.Value)
 	if !ok {
+		return d.unknownField(stmt)
+	}
+	return d.decodeBlockField(stmt, field)
+}
+
+// unknownField 处理没有对应字段的语句 stmt: 默认忽略, 使用
+// WithDisallowUnknownFields 时返回 ErrUnknownField.
+func (d *internalDecoder) unknownField(stmt Statement) error {
+	if !d.disallowUnknown {
 		return nil
 	}
+	err := errorOf(ErrUnknownField, "unknown field")
+	if bs, ok := stmt.(*BlockStatement); ok {
+		return blockError(err, bs)
+	}
+	as := stmt.(*AssignStatement)
+	return fieldError(err, as, as.Name.Token)
+}
+
+func (d *internalDecoder) decodeBlockField(stmt *BlockStatement, field reflect.Value) error {
+	return blockError(d.decodeBlockValue(stmt, field), stmt)
+}
+
+// decodeBlockValue 即 decodeBlockField, 错误尚未带有块的路径.
+func (d *internalDecoder) decodeBlockValue(stmt *BlockStatement, field reflect.Value) error {
+	if d.prov != nil {
+		defer d.leaveBlockProvenance(d.enterBlockProvenance(stmt))
+	}
 	if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
 		if field.IsNil() {
 			field.Set(reflect.New(field.Type().Elem()))
 		}
-		return d.decodeRoot(stmt.Body, field.Elem())
+		if err := d.decodeRoot(stmt.Body, field.Elem()); err != nil {
+			return err
+		}
+		return validateBlock(field, stmt.Name.Token)
 	}
 	if field.Kind() == reflect.Struct {
-		return d.decodeRoot(stmt.Body, field)
+		setBlockLabels(field, stmt.Labels())
+		if err := d.decodeRoot(stmt.Body, field); err != nil {
+			return err
+		}
+		return validateBlock(field, stmt.Name.Token)
+	}
+	decodeBody := func(v reflect.Value) error {
+		if err := d.decodeRoot(stmt.Body, v); err != nil {
+			return err
+		}
+		return validateBlock(v, stmt.Name.Token)
 	}
-	if field.Kind() == reflect.Map {
+	switch field.Kind() {
+	case reflect.Map:
 		mapType := field.Type()
-		if mapType.Key().Kind() == reflect.String && mapType.Elem().Kind() == reflect.String {
+		if mapType.Key().Kind() == reflect.String && mapType.Elem().Kind() == reflect.String && stmt.Label == nil {
 			if field.IsNil() {
 				field.Set(reflect.MakeMap(mapType))
 			}
 			return d.decodeMapStringString(stmt.Body, field)
 		}
 		if stmt.Label == nil {
-			return fmt.Errorf("block %q is for a map, but is missing a label", string(stmt.Name.Value))
+			return errorOf(ErrTypeMismatch, "block %q is for a map, but is missing a label", string(stmt.Name.Value))
+		}
+		return decodeLabeledBlock(field, string(stmt.Name.Value), stmt.Labels(), decodeBody)
+	case reflect.Slice:
+		return decodeLabeledBlock(field, string(stmt.Name.Value), stmt.Labels(), decodeBody)
+	}
+	return nil
+}
+
+// decodeLabeledBlock 将带标签的块解码到 field 中.
+// 对于 map, 每个标签对应一层嵌套, 如 `route "api" "v2"` 对应 map[string]map[string]Route;
+// 对于结构体切片, 块被追加为新元素, 其标签写入带有 `wanf:",labels"` 的字段.
+func decodeLabeledBlock(field reflect.Value, name string, labels []string, decodeBody func(reflect.Value) error) error {
+	return decodeLabeledBlockAt(field, name, labels, 0, decodeBody)
+}
+
+// decodeLabeledBlockAt 处理 labels[i:], 用于逐层展开嵌套 map.
+func decodeLabeledBlockAt(field reflect.Value, name string, labels []string, i int, decodeBody func(reflect.Value) error) error {
+	switch field.Kind() {
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String {
+			return errorOf(ErrTypeMismatch, "block %q cannot be decoded into map with key type %s", name, field.Type().Key())
 		}
-		mapVal := field
-		if mapVal.IsNil() {
-			mapVal.Set(reflect.MakeMap(mapVal.Type()))
+		if i >= len(labels) {
+			return errorOf(ErrTypeMismatch, "block %q is for a map, but is missing a label", name)
 		}
-		elemType := mapVal.Type().Elem()
-		newStruct := reflect.New(elemType).Elem()
-		if err := d.decodeRoot(stmt.Body, newStruct); err != nil {
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		key := reflect.ValueOf(labels[i]).Convert(field.Type().Key())
+		elemType := field.Type().Elem()
+		if i < len(labels)-1 {
+			if elemType.Kind() != reflect.Map && elemType.Kind() != reflect.Slice {
+				return errorOf(ErrTypeMismatch, "block %q has %d labels, but field of type %s accepts fewer", name, len(labels), field.Type())
+			}
+			inner := reflect.New(elemType).Elem()
+			if existing := field.MapIndex(key); existing.IsValid() {
+				inner.Set(existing)
+			}
+			if err := decodeLabeledBlockAt(inner, name, labels, i+1, decodeBody); err != nil {
+				return err
+			}
+			field.SetMapIndex(key, inner)
+			return nil
+		}
+		elem := reflect.New(elemType).Elem()
+		setBlockLabels(elem, labels)
+		if err := decodeBody(elem); err != nil {
+			return err
+		}
+		field.SetMapIndex(key, elem)
+		return nil
+	case reflect.Slice:
+		elemType := field.Type().Elem()
+		elem := reflect.New(elemType).Elem()
+		target := elem
+		if elemType.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elemType.Elem()))
+			target = elem.Elem()
+		}
+		if target.Kind() != reflect.Struct {
+			return errorOf(ErrTypeMismatch, "block %q cannot be decoded into slice of %s", name, elemType)
+		}
+		setBlockLabels(target, labels)
+		if err := decodeBody(target); err != nil {
 			return err
 		}
-		mapVal.SetMapIndex(reflect.ValueOf(string(stmt.Label.Value)), newStruct)
+		field.Set(reflect.Append(field, elem))
+		return nil
+	}
+	return errorOf(ErrTypeMismatch, "block %q cannot be decoded into field of type %s", name, field.Type())
+}
+
+// setBlockLabels 将块标签写入结构体中带有 `wanf:",labels"` 标记的 []string 字段.
+func setBlockLabels(structVal reflect.Value, labels []string) {
+	if structVal.Kind() != reflect.Struct || len(labels) == 0 {
+		return
+	}
+	for _, f := range getOrCacheDecoderFields(structVal.Type()) {
+		if !f.Tag.Labels {
+			continue
+		}
+		field := structVal.Field(f.Index)
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+			field.Set(reflect.ValueOf(labels).Convert(field.Type()))
+		}
+		return
 	}
-	return nil
 }
 
 func (d *internalDecoder) setField(field reflect.Value, val interface{}) error {
@@ -243,6 +559,14 @@ func (d *internalDecoder) setField(field reflect.Value, val interface{}) error {
 		}
 		return d.setField(field.Elem(), val)
 	}
+	if et := lookupEnum(field.Type()); et != nil {
+		ev, err := et.parse(val)
+		if err != nil {
+			return err
+		}
+		field.Set(ev)
+		return nil
+	}
 
 	v := reflect.ValueOf(val)
 
@@ -284,7 +608,11 @@ func (d *internalDecoder) setField(field reflect.Value, val interface{}) error {
 	}
 
 	if v.Type().ConvertibleTo(field.Type()) {
-		field.Set(v.Convert(field.Type()))
+		cv, err := d.convert(v, field.Type())
+		if err != nil {
+			return err
+		}
+		field.Set(cv)
 		return nil
 	}
 	if field.Kind() == reflect.Map && v.Kind() == reflect.Map {
@@ -293,7 +621,79 @@ func (d *internalDecoder) setField(field reflect.Value, val interface{}) error {
 	if field.Kind() == reflect.Slice && v.Kind() == reflect.Slice {
 		return d.setSliceField(field, v)
 	}
-	return fmt.Errorf("cannot set field of type %s with value of type %T", field.Type(), val)
+	return errorOf(ErrTypeMismatch, "cannot set field of type %s with value of type %T", field.Type(), val)
+}
+
+// convert 将 v 转换为 typ. 使用 WithStrictNumbers 时, 会改变数值的转换返回错误.
+func (d *internalDecoder) convert(v reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	if d.strictNumbers {
+		if err := checkNumber(v, typ); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v.Convert(typ), nil
+}
+
+// checkNumber 报告把数值 v 转换为数值类型 typ 是否会改变其值.
+func checkNumber(v reflect.Value, typ reflect.Type) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if reflect.Zero(typ).OverflowInt(i) {
+				return errorOf(ErrLossyNumber, "value %d overflows %s", i, typ)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if i < 0 || reflect.Zero(typ).OverflowUint(uint64(i)) {
+				return errorOf(ErrLossyNumber, "value %d overflows %s", i, typ)
+			}
+		case reflect.Float32, reflect.Float64:
+			f := v.Convert(typ).Float()
+			// 2^63 超出 int64 的范围, 只可能由更大的整数舍入得到.
+			if f >= 0x1p63 || int64(f) != i {
+				return errorOf(ErrLossyNumber, "value %d cannot be represented exactly as %s", i, typ)
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if f != math.Trunc(f) {
+				return errorOf(ErrLossyNumber, "value %v has a fractional part and cannot be stored in %s", f, typ)
+			}
+			if f < -0x1p63 || f >= 0x1p63 || reflect.Zero(typ).OverflowInt(int64(f)) {
+				return errorOf(ErrLossyNumber, "value %v overflows %s", f, typ)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if f != math.Trunc(f) {
+				return errorOf(ErrLossyNumber, "value %v has a fractional part and cannot be stored in %s", f, typ)
+			}
+			if f < 0 || f >= 0x1p64 || reflect.Zero(typ).OverflowUint(uint64(f)) {
+				return errorOf(ErrLossyNumber, "value %v overflows %s", f, typ)
+			}
+		case reflect.Float32:
+			if reflect.Zero(typ).OverflowFloat(f) {
+				return errorOf(ErrLossyNumber, "value %v overflows %s", f, typ)
+			}
+		}
+	}
+	return nil
+}
+
+// isScalarString 报告 v 是否为可以由 setField 解析为 typ 的字符串, 如列表中
+// 的 "NaN" 或 "+Inf" (见 WithNonFiniteFloatsAsStrings).
+func isScalarString(v reflect.Value, typ reflect.Type) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return true
+	}
+	return false
 }
 
 func (d *internalDecoder) setMapField(field, v reflect.Value) error {
@@ -315,7 +715,7 @@ func (d *internalDecoder) setMapField(field, v reflect.Value) error {
 
 			sourceMap, ok := val.(map[string]interface{})
 			if !ok {
-				return fmt.Errorf("value for struct map must be a map object, got %T", val)
+				return errorOf(ErrTypeMismatch, "value for struct map must be a map object, got %T", val)
 			}
 			newStruct := reflect.New(elemType).Elem()
 			if err := d.decodeMapToStruct(sourceMap, newStruct); err != nil {
@@ -325,12 +725,32 @@ func (d *internalDecoder) setMapField(field, v reflect.Value) error {
 			continue
 		}
 
+		if et := lookupEnum(elemType); et != nil {
+			ev, err := et.parse(val)
+			if err != nil {
+				return err
+			}
+			field.SetMapIndex(key, ev)
+			continue
+		}
+
 		if valV.Type().ConvertibleTo(elemType) {
-			field.SetMapIndex(key, valV.Convert(elemType))
+			cv, err := d.convert(valV, elemType)
+			if err != nil {
+				return fmt.Errorf("map value %q: %w", key.String(), err)
+			}
+			field.SetMapIndex(key, cv)
 			continue
 		}
+		if isScalarString(valV, elemType) {
+			elem := reflect.New(elemType).Elem()
+			if d.setField(elem, val) == nil {
+				field.SetMapIndex(key, elem)
+				continue
+			}
+		}
 
-		return fmt.Errorf("cannot convert map value %v to %s", val, elemType)
+		return errorOf(ErrTypeMismatch, "cannot convert map value %v to %s", val, elemType)
 	}
 	return nil
 }
@@ -353,11 +773,24 @@ func (d *internalDecoder) setSliceField(field, v reflect.Value) error {
 			}
 		}
 
+		if et := lookupEnum(elemType); et != nil {
+			ev, err := et.parse(val)
+			if err != nil {
+				return err
+			}
+			newSlice.Index(i).Set(ev)
+			continue
+		}
+
 		valV := reflect.ValueOf(val)
 		if valV.Type().ConvertibleTo(elemType) {
-			newSlice.Index(i).Set(valV.Convert(elemType))
-		} else {
-			return fmt.Errorf("cannot convert slice element of type %T to %s", val, elemType)
+			cv, err := d.convert(valV, elemType)
+			if err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+			newSlice.Index(i).Set(cv)
+		} else if !isScalarString(valV, elemType) || d.setField(newSlice.Index(i), val) != nil {
+			return errorOf(ErrTypeMismatch, "cannot convert slice element of type %T to %s", val, elemType)
 		}
 	}
 	field.Set(newSlice)
@@ -371,26 +804,27 @@ func (d *internalDecoder) evalExpression(expr Expression) (interface{}, error) {
 	case *FloatLiteral:
 		return e.Value, nil
 	case *StringLiteral:
-		return string(e.Value), nil
+		return d.interpolate(string(e.Value))
 	case *BoolLiteral:
 		return e.Value, nil
 	case *DurationLiteral:
 		return time.ParseDuration(string(e.Value))
 	case *VarExpression:
-		val, ok := d.vars[string(e.Name)]
-		if !ok {
-			return nil, fmt.Errorf("variable %q is not defined", string(e.Name))
-		}
-		return val, nil
+		return d.lookupVar(string(e.Name))
 	case *EnvExpression:
-		val, found := os.LookupEnv(string(e.Name.Value))
+		if d.sandbox {
+			return nil, errorOf(ErrSandbox, "env(%q) is not allowed in sandbox mode", string(e.Name.Value))
+		}
+		val, found := d.lookupEnv(string(e.Name.Value))
 		if !found {
 			if e.DefaultValue != nil {
-				return string(e.DefaultValue.Value), nil
+				return d.interpolate(string(e.DefaultValue.Value))
 			}
-			return nil, fmt.Errorf("environment variable %q not set", string(e.Name.Value))
+			return nil, errorOf(ErrEnvNotSet, "environment variable %q not set", string(e.Name.Value))
 		}
 		return val, nil
+	case *SecretExpression:
+		return d.resolveSecret(string(e.Ref.Value))
 	case *ListLiteral:
 		list := make([]interface{}, len(e.Elements))
 		for i, elemExpr := range e.Elements {
@@ -447,31 +881,38 @@ func (d *internalDecoder) decodeBlockToMap(body *RootNode) (map[string]interface
 }
 
 func findFieldAndTag(structVal reflect.Value, name []byte) (reflect.Value, wanfTag, bool) {
-	typ := structVal.Type()
-	cachedFields := getOrCacheDecoderFields(typ)
+	f, ok := lookupDecoderField(structVal.Type(), string(name))
+	if !ok {
+		return reflect.Value{}, wanfTag{}, false
+	}
+	return structVal.Field(f.Index), f.Tag, true
+}
 
-	sName := string(name)
-	if f, ok := cachedFields[sName]; ok {
-		return structVal.Field(f.Index), f.Tag, true
+// lookupDecoderField 返回结构体类型 typ 中与键 name 对应的字段. 没有标签的字段
+// 按名称忽略大小写匹配.
+func lookupDecoderField(typ reflect.Type, name string) (decoderCachedField, bool) {
+	cachedFields := getOrCacheDecoderFields(typ)
+	if f, ok := cachedFields[name]; ok {
+		return f, true
 	}
 
-	lowerName := strings.ToLower(sName)
+	lowerName := strings.ToLower(name)
 	for _, f := range cachedFields {
 		if f.Tag.Name == f.FieldTyp.Name && strings.ToLower(f.FieldTyp.Name) == lowerName {
-			return structVal.Field(f.Index), f.Tag, true
+			return f, true
 		}
 	}
 
-	return reflect.Value{}, wanfTag{}, false
+	return decoderCachedField{}, false
 }
 
 func (d *internalDecoder) setMapFromList(mapField reflect.Value, listVal interface{}, keyField string) error {
 	if mapField.Kind() != reflect.Map {
-		return fmt.Errorf("cannot set list to non-map field %s", mapField.Type())
+		return errorOf(ErrTypeMismatch, "cannot set list to non-map field %s", mapField.Type())
 	}
 	sourceList, ok := listVal.([]interface{})
 	if !ok {
-		return fmt.Errorf("value for map field with 'key' tag must be a list")
+		return errorOf(ErrTypeMismatch, "value for map field with 'key' tag must be a list")
 	}
 	if mapField.IsNil() {
 		mapField.Set(reflect.MakeMap(mapField.Type()))
@@ -480,7 +921,7 @@ func (d *internalDecoder) setMapFromList(mapField reflect.Value, listVal interfa
 	for _, item := range sourceList {
 		sourceMap, ok := item.(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("items in list for keyed map must be objects")
+			return errorOf(ErrTypeMismatch, "items in list for keyed map must be objects")
 		}
 		keyVal, ok := sourceMap[keyField]
 		if !ok {
@@ -488,7 +929,7 @@ func (d *internalDecoder) setMapFromList(mapField reflect.Value, listVal interfa
 		}
 		keyString, ok := keyVal.(string)
 		if !ok {
-			return fmt.Errorf("key field %q must be a string", keyField)
+			return errorOf(ErrTypeMismatch, "key field %q must be a string", keyField)
 		}
 		newStruct := reflect.New(elemType).Elem()
 		if err := d.decodeMapToStruct(sourceMap, newStruct); err != nil {
@@ -509,6 +950,9 @@ func (d *internalDecoder) decodeMapToStruct(sourceMap map[string]interface{}, ta
 			return fmt.Errorf("error setting field %q: %w", key, err)
 		}
 	}
+	if err := callValidate(targetStruct); err != nil {
+		return &ValidationError{Message: err.Error(), Err: err}
+	}
 	return nil
 }
 
@@ -524,9 +968,10 @@ func (d *internalDecoder) decodeMapStringString(body *RootNode, mapField reflect
 		}
 		strVal, ok := val.(string)
 		if !ok {
-			return fmt.Errorf("value for key %q in map must be a string", string(assign.Name.Value))
+			return errorOf(ErrTypeMismatch, "value for key %q in map must be a string", string(assign.Name.Value))
 		}
 		mapField.SetMapIndex(reflect.ValueOf(string(assign.Name.Value)), reflect.ValueOf(strVal))
+		d.recordField(assign.Name.Value, assign.Value)
 	}
 	return nil
 }
@@ -0,0 +1,222 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// flatPlanCache 缓存每个结构体类型的 flatPlan. 不适用快速路径的类型保存 nil.
+var flatPlanCache sync.Map // map[reflect.Type]*flatPlan
+
+// flatPlan 是只含标量字段的结构体的解码计划. 键通过完美哈希表查找, 字面量
+// 直接写入由字段偏移计算出的地址, 不经过 evalExpression 的 interface{} 装箱
+// 与反射赋值. 数值、布尔值与 duration 不分配内存, 字符串只分配其内容.
+type flatPlan struct {
+	seed  uint32
+	mask  uint32
+	slots []flatSlot // 长度为 2 的幂, 空槽的 key 为空
+}
+
+type flatSlot struct {
+	key    string
+	offset uintptr
+	kind   reflect.Kind
+	isDur  bool // time.Duration, 接受 duration 字面量
+}
+
+// maxPerfectHashTries 是为一个类型寻找无冲突种子的最大尝试次数.
+const maxPerfectHashTries = 64
+
+// getFlatPlan 返回 typ 的解码计划, typ 含有非标量字段、枚举或带约束的字段时返回 nil.
+func getFlatPlan(typ reflect.Type) *flatPlan {
+	if cached, ok := flatPlanCache.Load(typ); ok {
+		return cached.(*flatPlan)
+	}
+	plan := buildFlatPlan(typ)
+	flatPlanCache.Store(typ, plan)
+	return plan
+}
+
+// resetFlatPlans 清空缓存的计划, 在注册枚举后调用, 因为枚举字段不能走快速路径.
+func resetFlatPlans() {
+	flatPlanCache.Range(func(key, _ interface{}) bool {
+		flatPlanCache.Delete(key)
+		return true
+	})
+}
+
+func buildFlatPlan(typ reflect.Type) *flatPlan {
+	fields := getOrCacheDecoderFields(typ)
+	if len(fields) == 0 {
+		return nil
+	}
+	entries := make([]flatSlot, 0, len(fields))
+	for key, f := range fields {
+		ft := f.FieldTyp.Type
+		if f.Tag.hasRules() || f.Tag.KeyField != "" || f.Tag.Labels || lookupEnum(ft) != nil {
+			return nil
+		}
+		switch ft.Kind() {
+		case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil
+		}
+		entries = append(entries, flatSlot{
+			key:    key,
+			offset: f.FieldTyp.Offset,
+			kind:   ft.Kind(),
+			isDur:  ft == reflect.TypeOf(time.Duration(0)),
+		})
+	}
+
+	size := uint32(1)
+	for size < uint32(len(entries)) {
+		size <<= 1
+	}
+	// 先尝试最小的表, 找不到无冲突的种子时加倍.
+	for ; size <= 4*uint32(len(entries))+4; size <<= 1 {
+		for seed := uint32(0); seed < maxPerfectHashTries; seed++ {
+			if plan := tryFlatPlan(entries, seed, size); plan != nil {
+				return plan
+			}
+		}
+	}
+	return nil
+}
+
+func tryFlatPlan(entries []flatSlot, seed, size uint32) *flatPlan {
+	plan := &flatPlan{seed: seed, mask: size - 1, slots: make([]flatSlot, size)}
+	for _, e := range entries {
+		slot := &plan.slots[flatHash([]byte(e.key), seed)&plan.mask]
+		if slot.key != "" {
+			return nil
+		}
+		*slot = e
+	}
+	return plan
+}
+
+// flatHash 是带种子的 FNV-1a.
+func flatHash(b []byte, seed uint32) uint32 {
+	h := uint32(2166136261) ^ seed
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+
+func (p *flatPlan) lookup(name []byte) *flatSlot {
+	slot := &p.slots[flatHash(name, p.seed)&p.mask]
+	if slot.key == "" || slot.key != string(name) {
+		return nil
+	}
+	return slot
+}
+
+// decodeFlat 按计划解码 root 中的赋值. 无法直接写入的语句 (变量引用、env()、
+// 类型不一致的值、大小写不同的键等) 交给常规的反射解码处理, 结果保持一致.
+func (d *internalDecoder) decodeFlat(plan *flatPlan, root *RootNode, rv reflect.Value) error {
+	if rv.Kind() != reflect.Struct || !rv.CanAddr() {
+		return fmt.Errorf("can only decode root into a struct, got %s", rv.Kind())
+	}
+	base := unsafe.Pointer(rv.UnsafeAddr())
+	for _, stmt := range root.Statements {
+		d.statementProvenance(root, stmt)
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			if slot := plan.lookup(s.Name.Value); slot != nil && !d.strictNumbers && setFlat(unsafe.Add(base, slot.offset), slot, s.Value) {
+				d.recordField(s.Name.Value, s.Value)
+				continue
+			}
+			if err := d.decodeAssign(s, rv); err != nil {
+				return err
+			}
+		case *BlockStatement:
+			if err := d.decodeBlock(s, rv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setFlat 将字面量 expr 写入 ptr 指向的字段, 报告是否已写入.
+func setFlat(ptr unsafe.Pointer, slot *flatSlot, expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return setFlatInt(ptr, slot.kind, e.Value)
+	case *FloatLiteral:
+		switch slot.kind {
+		case reflect.Float64:
+			*(*float64)(ptr) = e.Value
+		case reflect.Float32:
+			*(*float32)(ptr) = float32(e.Value)
+		default:
+			return false
+		}
+		return true
+	case *BoolLiteral:
+		if slot.kind != reflect.Bool {
+			return false
+		}
+		*(*bool)(ptr) = e.Value
+		return true
+	case *DurationLiteral:
+		if !slot.isDur {
+			return false
+		}
+		dur, err := time.ParseDuration(BytesToString(e.Value))
+		if err != nil {
+			return false
+		}
+		*(*time.Duration)(ptr) = dur
+		return true
+	case *StringLiteral:
+		if slot.kind != reflect.String || bytes.Contains(e.Value, []byte("${")) {
+			return false
+		}
+		*(*string)(ptr) = string(e.Value)
+		return true
+	}
+	return false
+}
+
+// setFlatInt 按字段大小写入整数, 截断规则与 reflect.Value.Convert 相同.
+func setFlatInt(ptr unsafe.Pointer, kind reflect.Kind, v int64) bool {
+	switch kind {
+	case reflect.Int:
+		*(*int)(ptr) = int(v)
+	case reflect.Int8:
+		*(*int8)(ptr) = int8(v)
+	case reflect.Int16:
+		*(*int16)(ptr) = int16(v)
+	case reflect.Int32:
+		*(*int32)(ptr) = int32(v)
+	case reflect.Int64:
+		*(*int64)(ptr) = v
+	case reflect.Uint:
+		*(*uint)(ptr) = uint(v)
+	case reflect.Uint8:
+		*(*uint8)(ptr) = uint8(v)
+	case reflect.Uint16:
+		*(*uint16)(ptr) = uint16(v)
+	case reflect.Uint32:
+		*(*uint32)(ptr) = uint32(v)
+	case reflect.Uint64:
+		*(*uint64)(ptr) = uint64(v)
+	case reflect.Float64:
+		*(*float64)(ptr) = float64(v)
+	case reflect.Float32:
+		*(*float32)(ptr) = float32(v)
+	default:
+		return false
+	}
+	return true
+}
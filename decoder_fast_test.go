@@ -0,0 +1,81 @@
+package wanf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flatConfig struct {
+	Name    string        `wanf:"name"`
+	Port    int           `wanf:"port"`
+	Small   int8          `wanf:"small"`
+	Count   uint32        `wanf:"count"`
+	Ratio   float64       `wanf:"ratio"`
+	Scale   float32       `wanf:"scale"`
+	Debug   bool          `wanf:"debug"`
+	Timeout time.Duration `wanf:"timeout"`
+	Mode    string
+}
+
+func TestDecodeFlat(t *testing.T) {
+	if getFlatPlan(reflect.TypeOf(flatConfig{})) == nil {
+		t.Fatal("flatConfig has no flat plan")
+	}
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(struct{ Tags []string }{}),
+		reflect.TypeOf(struct {
+			Port int `wanf:"port,min=1"`
+		}{}),
+	} {
+		if getFlatPlan(typ) != nil {
+			t.Errorf("%v has a flat plan, want none", typ)
+		}
+	}
+
+	src := `var host = "db"
+name = "svc-${host}"
+port = 8080
+small = 300
+count = 7
+ratio = 2
+scale = 0.5
+debug = "true"
+timeout = 5s
+MODE = "fast"
+unknown = [1, 2]
+`
+	var got flatConfig
+	if err := decodeString(src, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := flatConfig{Name: "svc-db", Port: 8080, Small: 44, Count: 7, Ratio: 2, Scale: 0.5, Debug: true, Timeout: 5 * time.Second, Mode: "fast"}
+	if got != want {
+		t.Errorf("Decode =\n%+v\nwant\n%+v", got, want)
+	}
+
+	var bad flatConfig
+	err := decodeString("port = \"abc\"\n", &bad)
+	if err == nil || !strings.Contains(err.Error(), "cannot set field of type int") {
+		t.Errorf("Decode(port = \"abc\") error = %v", err)
+	}
+}
+
+func TestDecodeFlatAllocs(t *testing.T) {
+	program, err := Parse([]byte("port = 8080\nratio = 1.5\ndebug = true\ntimeout = 3s\ncount = 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c flatConfig
+	rv := reflect.ValueOf(&c).Elem()
+	d := &internalDecoder{}
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := d.decodeRoot(program, rv); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("decodeRoot allocated %v times per run, want 0", allocs)
+	}
+}
@@ -0,0 +1,273 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecode_VarReferences(t *testing.T) {
+	t.Setenv("WANF_TEST_HOME", "/home/wanf")
+	data := `
+var cfg = "${base}/app"
+var base = env("WANF_TEST_HOME")
+var port = 8080
+var copy = ${port}
+
+path = "${cfg}/config"
+port = ${copy}
+template = "${level}: ${message}"
+`
+	var cfg struct {
+		Path     string `wanf:"path"`
+		Port     int    `wanf:"port"`
+		Template string `wanf:"template"`
+	}
+	if err := Decode([]byte(data), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Path != "/home/wanf/app/config" {
+		t.Errorf("Path = %q, want %q", cfg.Path, "/home/wanf/app/config")
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("Port = %d, want 8080", cfg.Port)
+	}
+	if cfg.Template != "${level}: ${message}" {
+		t.Errorf("unknown references should be left untouched, got %q", cfg.Template)
+	}
+}
+
+func TestDecode_VarCycle(t *testing.T) {
+	data := `
+var a = "${b}"
+var b = "${c}"
+var c = ${a}
+`
+	var cfg struct{}
+	err := Decode([]byte(data), &cfg)
+	if err == nil {
+		t.Fatal("expected a cycle error, got nil")
+	}
+	if !strings.Contains(err.Error(), "variable cycle detected: a -> b -> c -> a") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecode_WithVariables(t *testing.T) {
+	type config struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port"`
+	}
+	vars := map[string]interface{}{"region": "eu-west", "port": 9000}
+
+	t.Run("Decoder", func(t *testing.T) {
+		data := `
+var port = 8080
+name = "${region}-svc"
+port = ${port}
+`
+		dec, err := NewDecoder(strings.NewReader(data), WithVariables(vars))
+		if err != nil {
+			t.Fatalf("NewDecoder failed: %v", err)
+		}
+		var cfg config
+		if err := dec.Decode(&cfg); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		// The document's own declaration shadows the injected value.
+		if cfg.Name != "eu-west-svc" || cfg.Port != 8080 {
+			t.Errorf("got %+v", cfg)
+		}
+	})
+
+	t.Run("StreamDecoder", func(t *testing.T) {
+		data := `
+name = "${region}-svc"
+port = ${port}
+`
+		dec, err := NewStreamDecoder(strings.NewReader(data), WithVariables(vars))
+		if err != nil {
+			t.Fatalf("NewStreamDecoder failed: %v", err)
+		}
+		var cfg config
+		if err := dec.Decode(&cfg); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if cfg.Name != "eu-west-svc" || cfg.Port != 9000 {
+			t.Errorf("got %+v", cfg)
+		}
+	})
+}
+
+func TestDecode_MultipleLabels(t *testing.T) {
+	type route struct {
+		Labels  []string `wanf:",labels"`
+		Backend string   `wanf:"backend"`
+	}
+	data := `
+route "api" "v1" {
+	backend = "legacy"
+}
+route "api" "v2" {
+	backend = "core"
+}
+route "web" "v1" {
+	backend = "static"
+}
+`
+	want := map[string]map[string]string{
+		"api": {"v1": "legacy", "v2": "core"},
+		"web": {"v1": "static"},
+	}
+
+	t.Run("NestedMap", func(t *testing.T) {
+		var cfg struct {
+			Route map[string]map[string]route `wanf:"route"`
+		}
+		if err := Decode([]byte(data), &cfg); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		for group, versions := range want {
+			for version, backend := range versions {
+				if got := cfg.Route[group][version].Backend; got != backend {
+					t.Errorf("route[%q][%q].Backend = %q, want %q", group, version, got, backend)
+				}
+			}
+		}
+		if got := cfg.Route["api"]["v2"].Labels; len(got) != 2 || got[0] != "api" || got[1] != "v2" {
+			t.Errorf("Labels = %v, want [api v2]", got)
+		}
+	})
+
+	t.Run("LabelsSlice", func(t *testing.T) {
+		var cfg struct {
+			Route []route `wanf:"route"`
+		}
+		dec, err := NewStreamDecoder(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewStreamDecoder failed: %v", err)
+		}
+		if err := dec.Decode(&cfg); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if len(cfg.Route) != 3 {
+			t.Fatalf("expected 3 routes, got %d", len(cfg.Route))
+		}
+		last := cfg.Route[2]
+		if last.Backend != "static" || len(last.Labels) != 2 || last.Labels[0] != "web" || last.Labels[1] != "v1" {
+			t.Errorf("unexpected route: %+v", last)
+		}
+	})
+
+	t.Run("TooManyLabels", func(t *testing.T) {
+		var cfg struct {
+			Route map[string]route `wanf:"route"`
+		}
+		if err := Decode([]byte(data), &cfg); err == nil {
+			t.Error("expected an error for extra labels, got nil")
+		}
+	})
+}
+
+func TestDecode_Sandbox(t *testing.T) {
+	t.Setenv("WANF_TEST_SECRET", "hunter2")
+	var cfg struct {
+		Name   string `wanf:"name"`
+		Secret string `wanf:"secret"`
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"env", `secret = env("WANF_TEST_SECRET")`, `line 1:1: secret: env("WANF_TEST_SECRET") is not allowed in sandbox mode`},
+		{"env in var", "var s = env(\"WANF_TEST_SECRET\", \"x\")\nsecret = ${s}", `line 1:5: s: env("WANF_TEST_SECRET") is not allowed in sandbox mode`},
+		{"import", `import "/etc/passwd"`, `line 1:1: import "/etc/passwd" is not allowed in sandbox mode`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(strings.NewReader(tt.data), WithSandbox())
+			if err == nil {
+				// Expressions are evaluated lazily outside of var declarations.
+				dec, _ := NewDecoder(strings.NewReader(tt.data), WithSandbox())
+				err = dec.Decode(&cfg)
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+			if tt.name == "import" {
+				return // StreamDecoder does not support imports at all.
+			}
+			// StreamDecoder reports the same error.
+			dec, err := NewStreamDecoder(strings.NewReader(tt.data), WithSandbox())
+			if err != nil {
+				t.Fatalf("NewStreamDecoder failed: %v", err)
+			}
+			if err := dec.Decode(&cfg); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("StreamDecoder: got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Plain", func(t *testing.T) {
+		dec, err := NewDecoder(strings.NewReader(`name = "ok"`), WithSandbox())
+		if err != nil {
+			t.Fatalf("NewDecoder failed: %v", err)
+		}
+		if err := dec.Decode(&cfg); err != nil || cfg.Name != "ok" {
+			t.Errorf("got %+v, %v", cfg, err)
+		}
+	})
+}
+
+func TestDecode_StrictNumbers(t *testing.T) {
+	type config struct {
+		Small int8             `wanf:"small"`
+		Count uint             `wanf:"count"`
+		Port  int              `wanf:"port"`
+		Ratio float32          `wanf:"ratio"`
+		Big   float64          `wanf:"big"`
+		Bytes []uint8          `wanf:"bytes"`
+		Limit map[string]int16 `wanf:"limit"`
+	}
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{"small = 300", "line 1:1: small: value 300 overflows int8"},
+		{"count = ${neg}", "count: value -1 overflows uint"},
+		{"port = 80.5", "port: value 80.5 has a fractional part and cannot be stored in int"},
+		{"port = ${huge}", "port: value 1e+30 overflows int"},
+		{"ratio = ${huger}", "ratio: value 1e+300 overflows float32"},
+		{"big = 9007199254740993", "big: value 9007199254740993 cannot be represented exactly as float64"},
+		{"bytes = [1, 256]", "bytes: element 1: value 256 overflows uint8"},
+		{"limit = {[ a = 70000 ]}", `limit: map value "a": value 70000 overflows int16`},
+	}
+	vars := WithVariables(map[string]interface{}{"neg": -1, "huge": 1e30, "huger": 1e300})
+	for _, tt := range tests {
+		var lenient config
+		if err := decodeWith([]byte(tt.data), &lenient, vars); err != nil {
+			t.Errorf("%s: lenient decode failed: %v", tt.data, err)
+		}
+		var strict config
+		err := decodeWith([]byte(tt.data), &strict, vars, WithStrictNumbers())
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+		if strings.Contains(tt.data, "${") {
+			continue // StreamDecoder 不支持变量
+		}
+		dec, _ := NewStreamDecoder(strings.NewReader(tt.data), WithStrictNumbers())
+		if err := dec.Decode(&strict); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: stream error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+
+	var cfg config
+	if err := decodeWith([]byte("small = 127\ncount = 7\nport = 80.0\nratio = 0.5\nbig = 9007199254740992\nbytes = [0, 255]"), &cfg, WithStrictNumbers()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Small != 127 || cfg.Count != 7 || cfg.Port != 80 || cfg.Ratio != 0.5 || cfg.Big != 1<<53 || cfg.Bytes[1] != 255 {
+		t.Errorf("got %+v", cfg)
+	}
+}
@@ -0,0 +1,214 @@
+package wanf
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+)
+
+// ChangeKind 表示 Change 的类型.
+type ChangeKind int
+
+const (
+	// ChangeAdded 表示键只存在于新文档中.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved 表示键只存在于旧文档中.
+	ChangeRemoved
+	// ChangeModified 表示键在两侧的值不同.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "changed"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change 描述 Diff 发现的一处差异.
+type Change struct {
+	Kind ChangeKind
+	// Path 是键的路径, 语法与 Lookup 相同, 如 server."main".port 或 hosts[1].
+	// var 声明的路径为 ${name}.
+	Path string
+	// Old 与 New 是两侧的值, 新增时 Old 为 nil, 删除时 New 为 nil.
+	// 块以 *BlockLiteral 表示.
+	Old, New Expression
+}
+
+// String 以 `+ path = new`, `- path = old` 或 `~ path: old -> new` 的形式返回差异.
+// 值尽量写在一行内, 含注释或非空块的值会占用多行.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + c.Path + " = " + formatChangeValue(c.New)
+	case ChangeRemoved:
+		return "- " + c.Path + " = " + formatChangeValue(c.Old)
+	}
+	return "~ " + c.Path + ": " + formatChangeValue(c.Old) + " -> " + formatChangeValue(c.New)
+}
+
+func formatChangeValue(expr Expression) string {
+	var buf bytes.Buffer
+	opts := editFormatOptions
+	opts.MaxWidth = math.MaxInt
+	expr.Format(&buf, "", opts)
+	return buf.String()
+}
+
+// Diff 比较两个文档的语义差异, 按 a 中的键序、其后 b 中新增的键序返回.
+// 比较不受格式、注释与键序影响:
+//   - 同名同标签的块与块字面量、map 字面量按键递归比较, 重复的块按解码规则合并;
+//   - 长度相同的列表逐元素比较, 否则整体视为修改;
+//   - 字符串、数字按值比较, 如 "a" 与 `a`、1.0 与 1.00 相同;
+//   - 变量引用与 env() 不会求值, 按其文本比较.
+//
+// import 语句不会展开, 也不参与比较.
+func Diff(a, b *RootNode) []Change {
+	var changes []Change
+	diffStatements(&changes, "", a.Statements, b.Statements)
+	return changes
+}
+
+// diffEntry 是同一作用域中某个键最终的值.
+type diffEntry struct {
+	value Expression
+	// body 在值为块、块字面量或 map 字面量时保存其语句, 重复的块会追加到此处.
+	body    []Statement
+	isBlock bool
+}
+
+func (e *diffEntry) expression() Expression {
+	if e.value == nil {
+		return &BlockLiteral{Body: &RootNode{Statements: e.body}}
+	}
+	return e.value
+}
+
+// diffScope 按声明顺序收集 stmts 中的键.
+func diffScope(stmts []Statement) ([]string, map[string]*diffEntry) {
+	var keys []string
+	entries := make(map[string]*diffEntry)
+	set := func(key string, e *diffEntry) {
+		if _, ok := entries[key]; !ok {
+			keys = append(keys, key)
+		}
+		entries[key] = e
+	}
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			e := &diffEntry{value: s.Value}
+			e.body, e.isBlock = containerBody(s.Value)
+			set(string(s.Name.Value), e)
+		case *BlockStatement:
+			key := string(s.Name.Value)
+			for _, label := range s.Labels() {
+				key += "." + strconv.Quote(label)
+			}
+			var body []Statement
+			if s.Body != nil {
+				body = s.Body.Statements
+			}
+			if prev, ok := entries[key]; ok && prev.isBlock && prev.value == nil {
+				prev.body = append(prev.body[:len(prev.body):len(prev.body)], body...)
+				continue
+			}
+			set(key, &diffEntry{body: body, isBlock: true})
+		case *VarStatement:
+			set("${"+string(s.Name.Value)+"}", &diffEntry{value: s.Value})
+		}
+	}
+	return keys, entries
+}
+
+// containerBody 返回块字面量或 map 字面量的语句.
+func containerBody(expr Expression) ([]Statement, bool) {
+	switch e := expr.(type) {
+	case *BlockLiteral:
+		if e.Body == nil {
+			return nil, true
+		}
+		return e.Body.Statements, true
+	case *MapLiteral:
+		return e.Elements, true
+	}
+	return nil, false
+}
+
+func diffStatements(changes *[]Change, path string, a, b []Statement) {
+	aKeys, aEntries := diffScope(a)
+	bKeys, bEntries := diffScope(b)
+	for _, key := range aKeys {
+		old := aEntries[key]
+		p := joinPath(path, key)
+		if nw, ok := bEntries[key]; ok {
+			diffEntries(changes, p, old, nw)
+		} else {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: p, Old: old.expression()})
+		}
+	}
+	for _, key := range bKeys {
+		if _, ok := aEntries[key]; !ok {
+			nw := bEntries[key]
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: joinPath(path, key), New: nw.expression()})
+		}
+	}
+}
+
+func diffEntries(changes *[]Change, path string, a, b *diffEntry) {
+	if a.isBlock && b.isBlock {
+		diffStatements(changes, path, a.body, b.body)
+		return
+	}
+	diffValues(changes, path, a.expression(), b.expression())
+}
+
+func diffValues(changes *[]Change, path string, a, b Expression) {
+	aBody, aOK := containerBody(a)
+	bBody, bOK := containerBody(b)
+	if aOK && bOK {
+		diffStatements(changes, path, aBody, bBody)
+		return
+	}
+	al, aList := a.(*ListLiteral)
+	bl, bList := b.(*ListLiteral)
+	if aList && bList && len(al.Elements) == len(bl.Elements) {
+		for i := range al.Elements {
+			diffValues(changes, path+"["+strconv.Itoa(i)+"]", al.Elements[i], bl.Elements[i])
+		}
+		return
+	}
+	if !equalValues(a, b) {
+		*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+	}
+}
+
+// equalValues 比较两个非容器的值.
+func equalValues(a, b Expression) bool {
+	switch x := a.(type) {
+	case *StringLiteral:
+		y, ok := b.(*StringLiteral)
+		return ok && string(x.Value) == string(y.Value)
+	case *IntegerLiteral:
+		y, ok := b.(*IntegerLiteral)
+		return ok && x.Value == y.Value
+	case *FloatLiteral:
+		y, ok := b.(*FloatLiteral)
+		return ok && x.Value == y.Value
+	case *BoolLiteral:
+		y, ok := b.(*BoolLiteral)
+		return ok && x.Value == y.Value
+	case nil:
+		return b == nil
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	return formatExpression(a, "") == formatExpression(b, "")
+}
@@ -0,0 +1,80 @@
+package wanf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := parseForMerge(t, `// old
+name = "svc"
+port = 80
+ratio = 1.0
+legacy = true
+var env = "dev"
+hosts = ["a", "b"]
+tags = ["x"]
+server "main" {
+	timeout = 5s
+}
+database {
+	host = "localhost"
+}
+database {
+	user = "admin"
+}
+limits = {[ rate = 10 ]}
+`)
+	b := parseForMerge(t, `var env = "prod"
+name = `+"`svc`"+`
+ratio = 1.00
+port   = 8080
+hosts = [
+	"a",
+	"c", // changed
+]
+tags = ["x", "y"]
+server "main" {
+	timeout = 10s
+}
+server "admin" {}
+database { user = "admin", host = "localhost" }
+limits = {[
+	rate = 10,
+	burst = 20,
+]}
+`)
+	var got []string
+	for _, c := range Diff(a, b) {
+		got = append(got, c.Kind.String()+" "+c.String())
+	}
+	want := []string{
+		`changed ~ port: 80 -> 8080`,
+		`removed - legacy = true`,
+		`changed ~ ${env}: "dev" -> "prod"`,
+		`changed ~ hosts[1]: "b" -> "c"`,
+		`changed ~ tags: ["x"] -> ["x", "y"]`,
+		`changed ~ server."main".timeout: 5s -> 10s`,
+		`added + limits.burst = 20`,
+		`added + server."admin" = {}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff mismatch:\n got: %q\nwant: %q", got, want)
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("Diff of identical documents = %v, want none", changes)
+	}
+}
+
+func TestDiffTypeChange(t *testing.T) {
+	a := parseForMerge(t, "server = \"x\"\n")
+	b := parseForMerge(t, "server {\n\tport = 1\n}\n")
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0].Kind != ChangeModified || changes[0].Path != "server" {
+		t.Fatalf("Diff = %v, want one change at server", changes)
+	}
+	if _, ok := changes[0].New.(*BlockLiteral); !ok {
+		t.Errorf("New = %T, want *BlockLiteral", changes[0].New)
+	}
+}
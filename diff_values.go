@@ -0,0 +1,123 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FieldChange 描述 DiffValues 发现的一处字段变化.
+type FieldChange struct {
+	Kind ChangeKind
+	// Path 是字段的路径, 语法与 Lookup 相同: 结构体字段使用 wanf 标签中的键,
+	// map 的键带引号 (与块标签一致), 如 server."main".port 或 hosts[1].
+	Path string
+	// Old 与 New 是两侧的值, 新增时 Old 为 nil, 删除时 New 为 nil.
+	Old, New interface{}
+	// Secret 表示字段带有 `wanf:",secret"` 标记, String 不会输出其值.
+	Secret bool
+}
+
+// String 以 Change.String 的形式返回变化.
+func (c FieldChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + c.Path + " = " + c.format(c.New)
+	case ChangeRemoved:
+		return "- " + c.Path + " = " + c.format(c.Old)
+	}
+	return "~ " + c.Path + ": " + c.format(c.Old) + " -> " + c.format(c.New)
+}
+
+func (c FieldChange) format(v interface{}) string {
+	if c.Secret {
+		return redactedValue
+	}
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// DiffValues 比较两个解码得到的值 (通常是同一结构体类型的新旧配置), 返回
+// 变化的字段, 供热加载时只处理实际改变的部分:
+//
+//	for _, c := range wanf.DiffValues(oldCfg, newCfg) {
+//		if c.Path == "log_level" { ... }
+//	}
+//
+// 结构体逐字段比较, 没有 wanf 标签的字段以字段名为键, 未导出字段与
+// `wanf:",labels"` 字段被忽略. map 按键比较, 结果按键排序; 长度相同的切片
+// 逐元素比较, 否则整体视为修改. 指针与接口比较其指向的值, nil 与非 nil 视为
+// 删除或新增. time.Time 按 Equal 比较, 其他值按 reflect.DeepEqual 比较.
+func DiffValues(old, new interface{}) []FieldChange {
+	var changes []FieldChange
+	diffReflect(&changes, "", reflect.ValueOf(old), reflect.ValueOf(new), false)
+	return changes
+}
+
+func diffReflect(changes *[]FieldChange, path string, a, b reflect.Value, secret bool) {
+	a, b = indirectValue(a), indirectValue(b)
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return
+	case !a.IsValid():
+		*changes = append(*changes, FieldChange{Kind: ChangeAdded, Path: path, New: b.Interface(), Secret: secret})
+		return
+	case !b.IsValid():
+		*changes = append(*changes, FieldChange{Kind: ChangeRemoved, Path: path, Old: a.Interface(), Secret: secret})
+		return
+	}
+	if a.Type() != b.Type() {
+		*changes = append(*changes, FieldChange{Kind: ChangeModified, Path: path, Old: a.Interface(), New: b.Interface(), Secret: secret})
+		return
+	}
+	switch {
+	case a.Type() == timeValueType:
+		if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+			*changes = append(*changes, FieldChange{Kind: ChangeModified, Path: path, Old: a.Interface(), New: b.Interface(), Secret: secret})
+		}
+		return
+	case a.Kind() == reflect.Struct && a.Type() != rawBlockType:
+		typ := a.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			tag := parseWanfTag(field.Tag.Get("wanf"), field.Name)
+			if tag.Labels {
+				continue
+			}
+			diffReflect(changes, joinPath(path, tag.Name), a.Field(i), b.Field(i), secret || tag.Secret)
+		}
+		return
+	case a.Kind() == reflect.Map && a.Type().Key().Kind() == reflect.String:
+		keys := make(map[string]reflect.Value)
+		for _, m := range []reflect.Value{a, b} {
+			for _, k := range m.MapKeys() {
+				keys[k.String()] = k
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			k := keys[name]
+			diffReflect(changes, joinPath(path, strconv.Quote(name)), a.MapIndex(k), b.MapIndex(k), secret)
+		}
+		return
+	case a.Kind() == reflect.Slice && a.Len() == b.Len() || a.Kind() == reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			diffReflect(changes, path+"["+strconv.Itoa(i)+"]", a.Index(i), b.Index(i), secret)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		*changes = append(*changes, FieldChange{Kind: ChangeModified, Path: path, Old: a.Interface(), New: b.Interface(), Secret: secret})
+	}
+}
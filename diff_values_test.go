@@ -0,0 +1,79 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type diffValuesServer struct {
+	Labels []string `wanf:",labels"`
+	Port   int      `wanf:"port"`
+}
+
+type diffValuesConfig struct {
+	LogLevel string                      `wanf:"log_level"`
+	Timeout  time.Duration               `wanf:"timeout"`
+	Password string                      `wanf:"password,secret"`
+	Hosts    []string                    `wanf:"hosts"`
+	Server   map[string]diffValuesServer `wanf:"server"`
+	TLS      *struct {
+		Cert string `wanf:"cert"`
+	} `wanf:"tls"`
+	Untagged bool
+	internal int
+}
+
+func TestDiffValues(t *testing.T) {
+	old := diffValuesConfig{
+		LogLevel: "info",
+		Timeout:  5 * time.Second,
+		Password: "a",
+		Hosts:    []string{"a", "b"},
+		Server: map[string]diffValuesServer{
+			"main":  {Labels: []string{"main"}, Port: 80},
+			"admin": {Port: 81},
+		},
+		internal: 1,
+	}
+	cur := old
+	cur.LogLevel = "debug"
+	cur.Password = "b"
+	cur.Hosts = []string{"a", "c"}
+	cur.Server = map[string]diffValuesServer{
+		"main": {Labels: []string{"other"}, Port: 8080},
+		"api":  {Port: 90},
+	}
+	cur.TLS = &struct {
+		Cert string `wanf:"cert"`
+	}{Cert: "x.pem"}
+	cur.Untagged = true
+	cur.internal = 2
+
+	var got []string
+	for _, c := range DiffValues(&old, &cur) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`~ log_level: "info" -> "debug"`,
+		`~ password: <redacted> -> <redacted>`,
+		`~ hosts[1]: "b" -> "c"`,
+		`- server."admin" = {[] 81}`,
+		`+ server."api" = {[] 90}`,
+		`~ server."main".port: 80 -> 8080`,
+		`+ tls = {x.pem}`,
+		`~ Untagged: false -> true`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffValues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if changes := DiffValues(old, old); len(changes) != 0 {
+		t.Errorf("DiffValues(old, old) = %v, want none", changes)
+	}
+	cur = old
+	cur.Hosts = []string{"a"}
+	if changes := DiffValues(old, cur); len(changes) != 1 || changes[0].Path != "hosts" || changes[0].Kind != ChangeModified {
+		t.Errorf("resized list: got %v, want one change of hosts", changes)
+	}
+}
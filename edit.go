@@ -0,0 +1,377 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SetPath 将 path 处的值设为 value 并返回修改后的源码. 只有被修改的值所在
+// 的字节范围会改变, 其余行的格式与注释保持原样.
+//
+// value 是一个 WANF 表达式, 如 `9090`, `"text"` 或 `[1, 2]`; 无法解析为
+// 表达式且不含引号与换行的值按字符串处理, 因此 `set name hello` 等同于
+// `set name "hello"`.
+//
+// path 的语法与 Lookup 相同. 已存在的赋值、map 条目和列表元素会被替换;
+// 不存在的键被追加到最深的已有块的末尾, 缺失的中间层级创建为不带标签的块.
+// 块本身不能被替换, 也不能向 map 字面量或列表添加新的键.
+func SetPath(data []byte, path, value string) ([]byte, error) {
+	ed, err := NewEditor(data)
+	if err != nil {
+		return nil, err
+	}
+	value, err = editValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := ed.set(path, func(string) string { return value }); err != nil {
+		return nil, err
+	}
+	return ed.Bytes()
+}
+
+// UnsetPath 删除 path 处的赋值、块、map 条目或列表元素, 连同其前置注释与
+// 行尾注释. 独占整行的元素会删除整行, 其余行保持原样.
+func UnsetPath(data []byte, path string) ([]byte, error) {
+	ed, err := NewEditor(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ed.Unset(path); err != nil {
+		return nil, err
+	}
+	return ed.Bytes()
+}
+
+// editValue 检查 value 是一个合法的表达式, 否则将其作为字符串.
+func editValue(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	p := NewParser(NewLexer([]byte("v = " + value + "\n")))
+	program := p.ParseProgram()
+	if len(p.Errors()) == 0 && len(program.Statements) == 1 {
+		if s, ok := program.Statements[0].(*AssignStatement); ok && exprToken(s.Value).Type != "" {
+			return value, nil
+		}
+	}
+	if strings.ContainsAny(value, "\"\n") {
+		return "", fmt.Errorf("wanf: %q is not a valid WANF expression", value)
+	}
+	return `"` + value + `"`, nil
+}
+
+// checkEdit 确认修改后的源码仍能被解析.
+func checkEdit(out []byte) ([]byte, error) {
+	p := NewParser(NewLexer(out))
+	p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("wanf: edit would produce invalid WANF: %w", p.Errors()[0])
+	}
+	return out, nil
+}
+
+// editTarget 是 resolveEdit 的结果. 路径存在时 assign, block 或 list 之一
+// 非空; 不存在时 missing 为尚未匹配的部分, parent 为最深的已有块
+// (*BlockStatement 或 *BlockLiteral, nil 表示根节点).
+type editTarget struct {
+	assign *AssignStatement
+	block  *BlockStatement
+	list   *ListLiteral
+	index  int
+
+	parent     Node
+	parentBody *RootNode
+	missing    []pathSegment
+}
+
+func (t *editTarget) expr() Expression {
+	if t.assign != nil {
+		return t.assign.Value
+	}
+	return t.list.Elements[t.index]
+}
+
+// resolveEdit 按 Lookup 的规则解析 segs, 但返回语句本身而不是表达式,
+// 并在路径不存在时记录插入位置.
+func resolveEdit(stmts []Statement, segs []pathSegment, parent Node) (*editTarget, error) {
+	seg := segs[0]
+	if seg.isIndex {
+		return nil, fmt.Errorf("cannot index %s into a block", seg)
+	}
+	var fallback *editTarget
+	for i := len(stmts) - 1; i >= 0; i-- {
+		switch s := stmts[i].(type) {
+		case *AssignStatement:
+			if string(s.Name.Value) != seg.key {
+				continue
+			}
+			if len(segs) == 1 {
+				return &editTarget{assign: s}, nil
+			}
+			return resolveEditExpression(s.Value, segs[1:])
+		case *BlockStatement:
+			if string(s.Name.Value) != seg.key {
+				continue
+			}
+			rest, ok := matchLabels(s.Labels(), segs[1:])
+			if !ok {
+				continue
+			}
+			if len(rest) == 0 {
+				return &editTarget{block: s}, nil
+			}
+			t, err := resolveEdit(s.Body.Statements, rest, s)
+			if err != nil {
+				return nil, err
+			}
+			if t.missing == nil {
+				return t, nil
+			}
+			if fallback == nil {
+				fallback = t
+			}
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	t := &editTarget{parent: parent, missing: segs}
+	switch p := parent.(type) {
+	case *BlockStatement:
+		t.parentBody = p.Body
+	case *BlockLiteral:
+		t.parentBody = p.Body
+	}
+	return t, nil
+}
+
+func resolveEditExpression(expr Expression, segs []pathSegment) (*editTarget, error) {
+	seg := segs[0]
+	switch e := expr.(type) {
+	case *ListLiteral:
+		if !seg.isIndex {
+			return nil, fmt.Errorf("cannot look up key %s in a list", seg)
+		}
+		if seg.index >= len(e.Elements) {
+			return nil, fmt.Errorf("index %d out of range (list has %d elements)", seg.index, len(e.Elements))
+		}
+		if len(segs) == 1 {
+			return &editTarget{list: e, index: seg.index}, nil
+		}
+		return resolveEditExpression(e.Elements[seg.index], segs[1:])
+	case *MapLiteral:
+		if seg.isIndex {
+			return nil, fmt.Errorf("cannot index %s into a map", seg)
+		}
+		for i := len(e.Elements) - 1; i >= 0; i-- {
+			if assign, ok := e.Elements[i].(*AssignStatement); ok && string(assign.Name.Value) == seg.key {
+				if len(segs) == 1 {
+					return &editTarget{assign: assign}, nil
+				}
+				return resolveEditExpression(assign.Value, segs[1:])
+			}
+		}
+		return nil, fmt.Errorf("key %s not found; new keys cannot be added to a map literal", seg)
+	case *BlockLiteral:
+		return resolveEdit(e.Body.Statements, segs, e)
+	}
+	return nil, fmt.Errorf("cannot look up %s in a value of type %s", seg, expressionKind(expr))
+}
+
+// insertEdit 在 t.parent 的末尾插入 t.missing 对应的赋值, 缺失的中间层级写成块.
+func insertEdit(data []byte, toks tokenSpans, t *editTarget, value func(indent string) string) (textEdit, error) {
+	for _, seg := range t.missing {
+		if seg.isIndex || !isValidIdentifier(seg.key) {
+			return textEdit{}, fmt.Errorf("cannot create %s: not a valid key", seg)
+		}
+	}
+	return insertAt(data, toks, t.parent, t.parentBody, func(indent string) string {
+		return buildAssignment(t.missing, value, indent)
+	})
+}
+
+// insertAt 返回在 parent (nil 表示根节点) 的末尾插入文本的编辑. body 是
+// parent 的块体, text 按给定的缩进生成以换行结尾的文本.
+func insertAt(data []byte, toks tokenSpans, parent Node, body *RootNode, text func(indent string) string) (textEdit, error) {
+	if parent == nil {
+		s := text("")
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			s = "\n" + s
+		}
+		return textEdit{start: len(data), end: len(data), text: s}, nil
+	}
+
+	var open int
+	switch p := parent.(type) {
+	case *BlockStatement:
+		i, err := toks.indexOf(p.Token)
+		if err != nil {
+			return textEdit{}, err
+		}
+		for open = i; toks[open].typ != LBRACE; open++ {
+		}
+	case *BlockLiteral:
+		i, err := toks.indexOf(p.Token)
+		if err != nil {
+			return textEdit{}, err
+		}
+		open = i
+	}
+	closing := toks.expressionEnd(open)
+	closeStart := toks[closing].start
+	outer := lineIndent(data, toks[open].start)
+
+	indent := outer + "\t"
+	if len(body.Statements) > 0 {
+		tok := statementToken(body.Statements[0])
+		if c := body.Statements[0].GetLeadingComments(); len(c) > 0 {
+			tok = c[0].Token
+		}
+		if i, err := toks.indexOf(tok); err == nil {
+			indent = lineIndent(data, toks[i].start)
+		}
+	}
+	s := text(indent)
+	lineStart := bytes.LastIndexByte(data[:closeStart], '\n') + 1
+	if strings.TrimSpace(string(data[lineStart:closeStart])) == "" {
+		// `}` 独占一行: 插入到该行之前.
+		return textEdit{start: lineStart, end: lineStart, text: s}, nil
+	}
+	return textEdit{start: closeStart, end: closeStart, text: "\n" + s + outer}, nil
+}
+
+// buildAssignment 返回 `a { b = value }` 形式的多行文本, 每行以 indent 开头.
+// value 按赋值所在行的缩进生成值的文本.
+func buildAssignment(segs []pathSegment, value func(indent string) string, indent string) string {
+	var b strings.Builder
+	for i, seg := range segs[:len(segs)-1] {
+		b.WriteString(indent + strings.Repeat("\t", i) + seg.key + " {\n")
+	}
+	depth := len(segs) - 1
+	inner := indent + strings.Repeat("\t", depth)
+	b.WriteString(inner + segs[depth].key + " = " + value(inner) + "\n")
+	for i := depth - 1; i >= 0; i-- {
+		b.WriteString(indent + strings.Repeat("\t", i) + "}\n")
+	}
+	return b.String()
+}
+
+// deleteEdit 删除 toks[first..last] 以及紧随的逗号. 如果它们独占若干整行
+// (行尾注释除外), 删除这些整行; 否则只删除标记本身.
+func deleteEdit(data []byte, toks tokenSpans, first, last int) textEdit {
+	if last+1 < len(toks) && toks[last+1].typ == COMMA {
+		last++
+	}
+	start, end := toks[first].start, toks[last].end
+	lineStart := bytes.LastIndexByte(data[:start], '\n') + 1
+	lineEnd := len(data)
+	if i := bytes.IndexByte(data[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	rest := data[end:lineEnd]
+	if last+1 < len(toks) && toks[last+1].typ == COMMENT && toks[last+1].start < lineEnd {
+		rest = data[toks[last+1].end:lineEnd]
+	}
+	if strings.TrimSpace(string(data[lineStart:start])) == "" && strings.TrimSpace(string(rest)) == "" {
+		return textEdit{start: lineStart, end: lineEnd}
+	}
+	if toks[last].typ != COMMA && first > 0 && toks[first-1].typ == COMMA {
+		// 最后一个元素: 删除它之前的逗号.
+		start = toks[first-1].start
+	}
+	for end < len(data) && data[end] == ' ' {
+		end++
+	}
+	return textEdit{start: start, end: end}
+}
+
+// lineIndent 返回 offset 所在行开头的空白.
+func lineIndent(data []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	i := lineStart
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t') {
+		i++
+	}
+	return string(data[lineStart:i])
+}
+
+// indexOf 返回 tok 在 spans 中的下标.
+func (s tokenSpans) indexOf(tok Token) (int, error) {
+	i, ok := s.index(tok.Line, tok.Column)
+	if !ok {
+		return 0, fmt.Errorf("wanf: cannot locate token at line %d:%d", tok.Line, tok.Column)
+	}
+	return i, nil
+}
+
+// expressionEnd 返回从 s[first] 开始的表达式的最后一个标记的下标,
+// 括号按嵌套层级匹配.
+func (s tokenSpans) expressionEnd(first int) int {
+	depth := 0
+	for i := first; i < len(s); i++ {
+		switch s[i].typ {
+		case LBRACK, LBRACE, LPAREN, DOLLAR_LBRACE:
+			depth++
+		case RBRACK, RBRACE, RPAREN:
+			depth--
+		case IDENT:
+			// env(...) 的参数属于同一个表达式.
+			if depth == 0 && i+1 < len(s) && s[i+1].typ == LPAREN {
+				continue
+			}
+		}
+		if depth == 0 {
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// blockEnd 返回从 s[first] 开始的块语句的 `}` 的下标.
+func (s tokenSpans) blockEnd(first int) int {
+	i := first
+	for i < len(s) && s[i].typ != LBRACE {
+		i++
+	}
+	return s.expressionEnd(i)
+}
+
+// withComments 在存在前置注释时返回第一个注释的下标, 否则返回 first.
+func (s tokenSpans) withComments(first int, comments []*Comment) int {
+	if len(comments) == 0 {
+		return first
+	}
+	if i, ok := s.index(comments[0].Token.Line, comments[0].Token.Column); ok {
+		return i
+	}
+	return first
+}
+
+// exprToken 返回表达式的第一个标记.
+func exprToken(expr Expression) Token {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return e.Token
+	case *IntegerLiteral:
+		return e.Token
+	case *FloatLiteral:
+		return e.Token
+	case *BoolLiteral:
+		return e.Token
+	case *DurationLiteral:
+		return e.Token
+	case *VarExpression:
+		return e.Token
+	case *EnvExpression:
+		return e.Token
+	case *SecretExpression:
+		return e.Token
+	case *ListLiteral:
+		return e.Token
+	case *MapLiteral:
+		return e.Token
+	case *BlockLiteral:
+		return e.Token
+	}
+	return Token{}
+}
@@ -0,0 +1,94 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+const editSrc = `// service config
+name   = "svc" // aligned on purpose
+ports = [80, 443]
+
+server "main" {
+    // listen port
+    port = 8080
+    tags = [
+        "a",
+        "b", // second
+    ]
+}
+
+limits = {[rate = 10, burst = 20]}
+empty {}
+`
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		path, value string
+		want        string
+	}{
+		{"server.main.port", "9090", strings.Replace(editSrc, "port = 8080", "port = 9090", 1)},
+		{"name", "other", strings.Replace(editSrc, `"svc"`, `"other"`, 1)},
+		{"ports[1]", "8443", strings.Replace(editSrc, "[80, 443]", "[80, 8443]", 1)},
+		{"ports", "[1, 2]", strings.Replace(editSrc, "[80, 443]", "[1, 2]", 1)},
+		{"limits.burst", "30", strings.Replace(editSrc, "burst = 20", "burst = 30", 1)},
+		{"server.main.host", `"0.0.0.0"`, strings.Replace(editSrc, "    ]\n}", "    ]\n    host = \"0.0.0.0\"\n}", 1)},
+		{"server.main.tls.enabled", "true", strings.Replace(editSrc, "    ]\n}", "    ]\n    tls {\n    \tenabled = true\n    }\n}", 1)},
+		{"empty.x", "1", strings.Replace(editSrc, "empty {}", "empty {\n\tx = 1\n}", 1)},
+		{"debug", "true", editSrc + "debug = true\n"},
+	}
+	for _, tt := range tests {
+		got, err := SetPath([]byte(editSrc), tt.path, tt.value)
+		if err != nil {
+			t.Errorf("SetPath(%q) error: %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("SetPath(%q, %q) mismatch.\ngot:\n%s\nwant:\n%s", tt.path, tt.value, got, tt.want)
+		}
+	}
+
+	errTests := []struct {
+		path, value, want string
+	}{
+		{"server.main", "1", "path refers to a block"},
+		{"limits.new", "1", "new keys cannot be added to a map literal"},
+		{"ports[5]", "1", "index 5 out of range"},
+		{"name", `a"b`, "is not a valid WANF expression"},
+	}
+	for _, tt := range errTests {
+		_, err := SetPath([]byte(editSrc), tt.path, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetPath(%q, %q) error = %v, want %q", tt.path, tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestUnsetPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"name", strings.Replace(editSrc, "// service config\nname   = \"svc\" // aligned on purpose\n", "", 1)},
+		{"server.main.port", strings.Replace(editSrc, "    // listen port\n    port = 8080\n", "", 1)},
+		{"server.main.tags[1]", strings.Replace(editSrc, "        \"b\", // second\n", "", 1)},
+		{"ports[1]", strings.Replace(editSrc, "[80, 443]", "[80]", 1)},
+		{"ports[0]", strings.Replace(editSrc, "[80, 443]", "[443]", 1)},
+		{"limits.rate", strings.Replace(editSrc, "rate = 10, ", "", 1)},
+		{"empty", strings.Replace(editSrc, "empty {}\n", "", 1)},
+	}
+	for _, tt := range tests {
+		got, err := UnsetPath([]byte(editSrc), tt.path)
+		if err != nil {
+			t.Errorf("UnsetPath(%q) error: %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("UnsetPath(%q) mismatch.\ngot:\n%s\nwant:\n%s", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := UnsetPath([]byte(editSrc), "server.main.missing"); err == nil || !strings.Contains(err.Error(), `key "missing" not found`) {
+		t.Errorf("UnsetPath of a missing key error = %v", err)
+	}
+}
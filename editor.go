@@ -0,0 +1,227 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Editor 把对文档 AST 的修改转换为对原始源码的文本编辑 (替换、插入或删除
+// 一个字节范围). 未被修改的部分, 包括格式、注释与空行, 保持原样, 因此
+// 自动化的修改只产生最小的差异.
+//
+// 所有修改都基于原始源码与 Program 返回的原始 AST: Editor 不会更新 AST,
+// 记录的编辑范围不能重叠, 例如不能在删除一个块之后再修改其中的键.
+type Editor struct {
+	src     []byte
+	program *RootNode
+	toks    tokenSpans
+	edits   []textEdit
+}
+
+// TextEdit 将源码中 [Start, End) 字节范围替换为 Text. Start == End 表示插入.
+type TextEdit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// NewEditor 解析 data 并返回它的 Editor. 有语法错误时返回 *ParseErrorList.
+func NewEditor(data []byte) (*Editor, error) {
+	program, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Editor{src: data, program: program, toks: scanTokenSpans(data)}, nil
+}
+
+// Program 返回原始 AST, 用于查找要修改的节点. 不要直接修改它.
+func (e *Editor) Program() *RootNode {
+	return e.program
+}
+
+// Set 将 path 处的值设为 value, 规则同 SetPath; value 的转换规则同 NewValue.
+func (e *Editor) Set(path string, value interface{}) error {
+	expr, err := NewValue(value)
+	if err != nil {
+		return err
+	}
+	return e.set(path, func(indent string) string { return formatExpression(expr, indent) })
+}
+
+func (e *Editor) set(path string, value func(indent string) string) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return fmt.Errorf("wanf: invalid path %q: %w", path, err)
+	}
+	t, err := resolveEdit(e.program.Statements, segs, nil)
+	if err != nil {
+		return fmt.Errorf("wanf: set %q: %w", path, err)
+	}
+	switch {
+	case t.block != nil:
+		return fmt.Errorf("wanf: set %q: path refers to a block; set its keys individually", path)
+	case t.assign != nil || t.list != nil:
+		return e.replace(t.expr(), value)
+	}
+	edit, err := insertEdit(e.src, e.toks, t, value)
+	if err != nil {
+		return fmt.Errorf("wanf: set %q: %w", path, err)
+	}
+	e.edits = append(e.edits, edit)
+	return nil
+}
+
+// Unset 删除 path 处的元素, 规则同 UnsetPath.
+func (e *Editor) Unset(path string) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return fmt.Errorf("wanf: invalid path %q: %w", path, err)
+	}
+	t, err := resolveEdit(e.program.Statements, segs, nil)
+	if err != nil {
+		return fmt.Errorf("wanf: unset %q: %w", path, err)
+	}
+	switch {
+	case t.missing != nil:
+		return errorOf(ErrKeyNotFound, "wanf: unset %q: key %s not found", path, t.missing[0])
+	case t.block != nil:
+		return e.Delete(t.block)
+	case t.assign != nil:
+		return e.Delete(t.assign)
+	}
+	first, err := e.toks.indexOf(exprToken(t.list.Elements[t.index]))
+	if err != nil {
+		return err
+	}
+	last := e.toks.expressionEnd(first)
+	if t.index < len(t.list.Comments) {
+		first = e.toks.withComments(first, t.list.Comments[t.index].Leading)
+	}
+	e.edits = append(e.edits, deleteEdit(e.src, e.toks, first, last))
+	return nil
+}
+
+// Replace 将表达式 old 替换为 value, value 的转换规则同 NewValue.
+// 多行的值按 old 所在行的缩进排版.
+func (e *Editor) Replace(old Expression, value interface{}) error {
+	expr, err := NewValue(value)
+	if err != nil {
+		return err
+	}
+	return e.replace(old, func(indent string) string { return formatExpression(expr, indent) })
+}
+
+func (e *Editor) replace(old Expression, value func(indent string) string) error {
+	first, err := e.toks.indexOf(exprToken(old))
+	if err != nil {
+		return err
+	}
+	last := e.toks.expressionEnd(first)
+	start := e.toks[first].start
+	e.edits = append(e.edits, textEdit{start: start, end: e.toks[last].end, text: value(lineIndent(e.src, start))})
+	return nil
+}
+
+// Delete 删除语句 stmt 连同其前置注释与行尾注释, stmt 可以是 map 字面量
+// 中的条目. 语句独占若干整行时删除这些整行.
+func (e *Editor) Delete(stmt Statement) error {
+	first, err := e.toks.indexOf(statementToken(stmt))
+	if err != nil {
+		return err
+	}
+	var last int
+	switch s := stmt.(type) {
+	case *BlockStatement:
+		last = e.toks.blockEnd(first)
+	case *AssignStatement:
+		last = e.toks.expressionEnd(first + 2) // name =
+	case *VarStatement:
+		last = e.toks.expressionEnd(first + 3) // var name =
+	case *ImportStatement:
+		last = first + 1
+	default:
+		return fmt.Errorf("wanf: cannot delete %T", s)
+	}
+	first = e.toks.withComments(first, stmt.GetLeadingComments())
+	e.edits = append(e.edits, deleteEdit(e.src, e.toks, first, last))
+	return nil
+}
+
+// Append 将 stmt 追加到 parent 的末尾. parent 为 nil 或 Program 时追加到
+// 文档末尾, 否则应为 *BlockStatement 或 *BlockLiteral. 新语句使用块内
+// 已有语句的缩进.
+func (e *Editor) Append(parent Node, stmt Statement) error {
+	var body *RootNode
+	switch p := parent.(type) {
+	case nil:
+	case *RootNode:
+		if p != e.program {
+			return fmt.Errorf("wanf: cannot append to a *RootNode other than Program")
+		}
+		parent = nil
+	case *BlockStatement:
+		body = p.Body
+	case *BlockLiteral:
+		body = p.Body
+	default:
+		return fmt.Errorf("wanf: cannot append to %T", parent)
+	}
+	edit, err := insertAt(e.src, e.toks, parent, body, func(indent string) string {
+		var buf bytes.Buffer
+		stmt.Format(&buf, indent, editFormatOptions)
+		buf.WriteByte('\n')
+		return buf.String()
+	})
+	if err != nil {
+		return err
+	}
+	e.edits = append(e.edits, edit)
+	return nil
+}
+
+// Edits 返回已记录的编辑, 按起始位置排序.
+func (e *Editor) Edits() []TextEdit {
+	edits := e.sortedEdits()
+	out := make([]TextEdit, len(edits))
+	for i, edit := range edits {
+		out[i] = TextEdit{Start: edit.start, End: edit.end, Text: edit.text}
+	}
+	return out
+}
+
+// Bytes 返回应用全部编辑后的源码. 编辑范围重叠或结果无法解析时返回错误.
+func (e *Editor) Bytes() ([]byte, error) {
+	edits := e.sortedEdits()
+	for i := 1; i < len(edits); i++ {
+		if edits[i].start < edits[i-1].end {
+			return nil, fmt.Errorf("wanf: overlapping edits at offsets %d and %d", edits[i-1].start, edits[i].start)
+		}
+	}
+	return checkEdit(applyEdits(e.src, edits))
+}
+
+// ApplyEdits 返回对 data 应用 edits 后的结果, 用于应用 Edits 返回的或自行
+// 构造的编辑. edits 可以无序; 范围重叠时只保留起始位置靠前的编辑.
+func ApplyEdits(data []byte, edits []TextEdit) []byte {
+	internal := make([]textEdit, len(edits))
+	for i, edit := range edits {
+		internal[i] = textEdit{start: edit.Start, end: edit.End, text: edit.Text}
+	}
+	return applyEdits(data, internal)
+}
+
+func (e *Editor) sortedEdits() []textEdit {
+	edits := append([]textEdit(nil), e.edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	return edits
+}
+
+// editFormatOptions 是 Editor 生成新文本时使用的格式, 保持值中条目的顺序.
+var editFormatOptions = FormatOptions{Style: StyleBlockSorted, EmptyLines: true, NoSort: true}
+
+func formatExpression(expr Expression, indent string) string {
+	var buf bytes.Buffer
+	expr.Format(&buf, indent, editFormatOptions)
+	return buf.String()
+}
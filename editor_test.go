@@ -0,0 +1,81 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	ed, err := NewEditor([]byte(editSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program := ed.Program()
+	server := program.Statements[2].(*BlockStatement)
+
+	if err := ed.Set("server.main.port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Replace(program.Statements[1].(*AssignStatement).Value, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Delete(program.Statements[0]); err != nil {
+		t.Fatal(err)
+	}
+	block := NewBlock("tls")
+	if err := block.Set("enabled", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Append(server, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Set("hosts", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ed.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := editSrc
+	want = strings.Replace(want, "// service config\nname   = \"svc\" // aligned on purpose\n", "", 1)
+	want = strings.Replace(want, "[80, 443]", "[\n\t1,\n\t2,\n]", 1)
+	want = strings.Replace(want, "port = 8080", "port = 9090", 1)
+	want = strings.Replace(want, "    ]\n}", "    ]\n    tls {\n    \tenabled = true\n    }\n}", 1)
+	want += "hosts = [\n\t\"a\",\n\t\"b\",\n]\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if n := len(ed.Edits()); n != 5 {
+		t.Errorf("recorded %d edits, want 5", n)
+	}
+}
+
+func TestEditorOverlappingEdits(t *testing.T) {
+	ed, err := NewEditor([]byte(editSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Unset("server.main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ed.Set("server.main.port", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ed.Bytes(); err == nil || !strings.Contains(err.Error(), "overlapping") {
+		t.Errorf("Bytes() error = %v, want an overlap error", err)
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	src := []byte("a = 1\nb = 2\n")
+	got := ApplyEdits(src, []TextEdit{
+		{Start: 10, End: 11, Text: "3"},
+		{Start: 0, End: 0, Text: "// head\n"},
+		{Start: 4, End: 5, Text: "10"},
+		{Start: 4, End: 5, Text: "ignored"},
+	})
+	if want := "// head\na = 10\nb = 3\n"; string(got) != want {
+		t.Errorf("ApplyEdits = %q, want %q", got, want)
+	}
+}
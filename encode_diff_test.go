@@ -0,0 +1,75 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type encodeDiffConfig struct {
+	Name    string        `wanf:"name"`
+	Timeout time.Duration `wanf:"timeout"`
+	Tags    []string      `wanf:"tags"`
+	Server  struct {
+		Host string `wanf:"host"`
+		Port int    `wanf:"port"`
+	} `wanf:"server"`
+	Log struct {
+		Level string `wanf:"level"`
+	} `wanf:"log"`
+}
+
+func TestEncodeDiff(t *testing.T) {
+	var defaults encodeDiffConfig
+	defaults.Name = "app"
+	defaults.Timeout = 5 * time.Second
+	defaults.Tags = []string{"a"}
+	defaults.Server.Host = "0.0.0.0"
+	defaults.Server.Port = 80
+	defaults.Log.Level = "info"
+
+	cfg := defaults
+	cfg.Timeout = 30 * time.Second
+	cfg.Tags = []string{"a", "b"}
+	cfg.Server.Port = 8080
+
+	data, err := EncodeDiff(&cfg, &defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `timeout = 30s
+
+tags = [
+	"a",
+	"b",
+]
+
+server {
+	port = 8080
+}
+`
+	if string(data) != want {
+		t.Errorf("EncodeDiff =\n%s\nwant:\n%s", data, want)
+	}
+
+	// 写回默认值之上应得到原配置.
+	got := defaults
+	got.Tags = nil
+	if err := decodeWith(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if changes := DiffValues(cfg, got); len(changes) != 0 {
+		t.Errorf("round trip differs: %v", changes)
+	}
+
+	if data, err := EncodeDiff(defaults, defaults); err != nil || len(data) != 0 {
+		t.Errorf("EncodeDiff(defaults, defaults) = %q, %v; want empty", data, err)
+	}
+	full, _ := Marshal(&cfg)
+	if data, err := EncodeDiff(&cfg, nil); err != nil || string(data) != string(full) {
+		t.Errorf("EncodeDiff with nil defaults = %q, %v; want Marshal output", data, err)
+	}
+	if _, err := EncodeDiff(&cfg, struct{}{}); err == nil || !strings.Contains(err.Error(), "do not match") {
+		t.Errorf("mismatched defaults: err = %v", err)
+	}
+}
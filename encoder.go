@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"sort"
 	"strconv"
@@ -66,6 +67,8 @@ func getEncoder() *internalEncoder {
 func putEncoder(e *internalEncoder) {
 	e.buf.Reset()
 	e.indent = 0
+	e.defaults = reflect.Value{}
+	e.err = nil
 	encoderPool.Put(e)
 }
 
@@ -78,6 +81,31 @@ func Marshal(v interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// EncodeDiff 与 Marshal 相同, 但只写出与 defaults 中对应字段不同的字段, 用于
+// 生成最小的用户覆盖文件, 与 DecodeFiles 的分层解码相对应:
+//
+//	data, err := wanf.EncodeDiff(&cfg, &defaultConfig)
+//
+// 字段是否相同按 DiffValues 判断. 块逐字段比较, 没有不同字段的块被省略;
+// 列表与 map 作为整体写出. defaults 为 nil 时等同于 Marshal, 否则必须与 v
+// 是同一结构体类型.
+func EncodeDiff(v, defaults interface{}, opts ...EncoderOption) ([]byte, error) {
+	rv, dv := indirectValue(reflect.ValueOf(v)), indirectValue(reflect.ValueOf(defaults))
+	if !rv.IsValid() || rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("wanf: can only encode a non-nil struct")
+	}
+	if dv.IsValid() && dv.Type() != rv.Type() {
+		return nil, fmt.Errorf("wanf: defaults of type %s do not match %s", dv.Type(), rv.Type())
+	}
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf, opts...)
+	encoder.e.defaults = dv
+	if err := encoder.Encode(rv.Interface()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 type EncoderOption func(*FormatOptions)
 
 func WithStyle(style OutputStyle) EncoderOption {
@@ -92,6 +120,78 @@ func WithoutEmptyLines() EncoderOption {
 	}
 }
 
+// WithAlignAssignments pads keys so that the '=' signs of consecutive
+// key-value pairs within a block line up.
+func WithAlignAssignments() EncoderOption {
+	return func(o *FormatOptions) {
+		o.AlignAssignments = true
+	}
+}
+
+// WithRedactedSecrets replaces the values of fields tagged `wanf:",secret"`
+// with the string "<redacted>", for showing a config without leaking secrets.
+func WithRedactedSecrets() EncoderOption {
+	return func(o *FormatOptions) {
+		o.RedactSecrets = true
+	}
+}
+
+// WithNonFiniteFloatsAsStrings writes NaN and infinite floats as the strings
+// "NaN", "+Inf" and "-Inf", which decode back into float fields. WANF has no
+// literals for them, so by default the encoder returns an error, like
+// encoding/json.
+func WithNonFiniteFloatsAsStrings() EncoderOption {
+	return func(o *FormatOptions) {
+		o.NonFiniteAsString = true
+	}
+}
+
+// appendFloat 按 opts 将 f 追加到 dst, f 为 NaN 或无穷大且未使用
+// WithNonFiniteFloatsAsStrings 时返回错误.
+func appendFloat(dst []byte, f float64, opts FormatOptions) ([]byte, error) {
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return strconv.AppendFloat(dst, f, 'f', -1, 64), nil
+	}
+	if !opts.NonFiniteAsString {
+		return dst, fmt.Errorf("wanf: unsupported float value %v; use WithNonFiniteFloatsAsStrings to encode it as a string", f)
+	}
+	return strconv.AppendQuote(dst, strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+// redactedValue replaces secret values in output meant for humans.
+const redactedValue = "<redacted>"
+
+// fieldWidths returns the alignment widths for fields, or nil when alignment is off.
+func fieldWidths(fields []fieldInfo, opts FormatOptions) []int {
+	if !opts.AlignAssignments || opts.Style == StyleSingleLine {
+		return nil
+	}
+	return alignWidths(len(fields), func(i int) (string, bool) {
+		return fields[i].name, !fields[i].isBlock && !fields[i].isBlockLike
+	}, nil)
+}
+
+// mapEntryWidths returns the alignment widths for map entries, or nil when alignment is off.
+func mapEntryWidths(entries []mapEntry, opts FormatOptions) []int {
+	if !opts.AlignAssignments || opts.Style == StyleSingleLine {
+		return nil
+	}
+	return alignWidths(len(entries), func(i int) (string, bool) {
+		v := entries[i].value
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return entries[i].key.String(), true
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+			return "", false
+		}
+		return entries[i].key.String(), true
+	}, nil)
+}
+
 type Encoder struct {
 	w io.Writer
 	e *internalEncoder
@@ -130,6 +230,9 @@ func (enc *Encoder) Encode(v interface{}) error {
 	if err := enc.e.encodeStruct(rv, 0); err != nil {
 		return err
 	}
+	if enc.e.err != nil {
+		return enc.e.err
+	}
 	if enc.e.opts.Style != StyleSingleLine && enc.e.buf.Len() > 0 {
 		enc.e.buf.WriteString("\n")
 	}
@@ -142,6 +245,11 @@ type internalEncoder struct {
 	indent int
 	opts   FormatOptions
 	tmpBuf []byte
+
+	// defaults 是正在编码的结构体在 EncodeDiff 中的默认值, 无效时写出全部字段.
+	defaults reflect.Value
+	// err 是编码值时遇到的第一个错误, 如不受支持的浮点数.
+	err error
 }
 
 type fieldInfo struct {
@@ -166,6 +274,10 @@ func (e *internalEncoder) encodeStruct(v reflect.Value, depth int) error {
 	fieldsPtr := fieldInfoSlicePool.Get().(*[]fieldInfo)
 	fields := *fieldsPtr
 	gatherFields(v, &fields)
+	def := e.defaults
+	if def.IsValid() {
+		fields = omitDefaults(fields, def)
+	}
 
 	if !e.opts.NoSort {
 		switch e.opts.Style {
@@ -182,12 +294,22 @@ func (e *internalEncoder) encodeStruct(v reflect.Value, depth int) error {
 		}
 	}
 
+	widths := fieldWidths(fields, e.opts)
 	var prevWasBlockLike bool
 	for i, f := range fields {
 		e.writeSeparator(i > 0, f.isBlockLike, prevWasBlockLike, depth)
-		e.encodeField(f, depth)
+		width := 0
+		if widths != nil {
+			width = widths[i]
+		}
+		e.defaults = reflect.Value{}
+		if f.isBlock && def.IsValid() {
+			e.defaults = indirectValue(def.Field(f.fieldType.Index[0]))
+		}
+		e.encodeField(f, depth, width)
 		prevWasBlockLike = f.isBlockLike
 	}
+	e.defaults = def
 
 	*fieldsPtr = fields[:0]
 	fieldInfoSlicePool.Put(fieldsPtr)
@@ -195,9 +317,10 @@ func (e *internalEncoder) encodeStruct(v reflect.Value, depth int) error {
 	return nil
 }
 
-func (e *internalEncoder) encodeField(f fieldInfo, depth int) {
+func (e *internalEncoder) encodeField(f fieldInfo, depth int, width int) {
 	e.writeIndent()
 	e.buf.Write(StringToBytes(f.name))
+	writePadding(e.buf, len(f.name), width)
 	e.writeSpace()
 
 	if f.isBlock {
@@ -216,6 +339,10 @@ func (e *internalEncoder) encodeField(f fieldInfo, depth int) {
 	} else {
 		e.buf.WriteString("=")
 		e.writeSpace()
+		if f.tag.Secret && e.opts.RedactSecrets {
+			e.writeQuotedString(redactedValue)
+			return
+		}
 		e.encodeValue(f.value, depth)
 	}
 }
@@ -231,6 +358,12 @@ func (e *internalEncoder) encodeValue(v reflect.Value, depth int) {
 		e.buf.WriteString(time.Duration(v.Int()).String())
 		return
 	}
+	if et := lookupEnum(v.Type()); et != nil {
+		if name, ok := et.name(v); ok {
+			e.writeQuotedString(name)
+			return
+		}
+	}
 	switch v.Kind() {
 	case reflect.String:
 		s := v.String()
@@ -244,7 +377,11 @@ func (e *internalEncoder) encodeValue(v reflect.Value, depth int) {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		e.buf.Write(strconv.AppendInt(e.tmpBuf[:0], v.Int(), 10))
 	case reflect.Float32, reflect.Float64:
-		e.buf.Write(strconv.AppendFloat(e.tmpBuf[:0], v.Float(), 'f', -1, 64))
+		b, err := appendFloat(e.tmpBuf[:0], v.Float(), e.opts)
+		if err != nil && e.err == nil {
+			e.err = err
+		}
+		e.buf.Write(b)
 	case reflect.Bool:
 		e.buf.Write(strconv.AppendBool(e.tmpBuf[:0], v.Bool()))
 	case reflect.Slice, reflect.Array:
@@ -327,9 +464,13 @@ func (e *internalEncoder) encodeMap(v reflect.Value, depth int) {
 	} else {
 		e.buf.WriteString("\n")
 		e.indent++
-		for _, entry := range entries {
+		widths := mapEntryWidths(entries, e.opts)
+		for i, entry := range entries {
 			e.writeIndent()
 			e.buf.Write(StringToBytes(entry.key.String()))
+			if widths != nil {
+				writePadding(e.buf, len(entry.key.String()), widths[i])
+			}
 			e.writeSpace()
 			e.buf.WriteString("=")
 			e.writeSpace()
@@ -518,10 +659,15 @@ func (e *streamInternalEncoder) encodeStruct(v reflect.Value, depth int) {
 		}
 	}
 
+	widths := fieldWidths(fields, e.opts)
 	var prevWasBlockLike bool
 	for i, f := range fields {
 		e.writeSeparator(i > 0, f.isBlockLike, prevWasBlockLike, depth)
-		e.encodeField(f, depth)
+		width := 0
+		if widths != nil {
+			width = widths[i]
+		}
+		e.encodeField(f, depth, width)
 		prevWasBlockLike = f.isBlockLike
 	}
 
@@ -529,12 +675,15 @@ func (e *streamInternalEncoder) encodeStruct(v reflect.Value, depth int) {
 	fieldInfoSlicePool.Put(fieldsPtr)
 }
 
-func (e *streamInternalEncoder) encodeField(f fieldInfo, depth int) {
+func (e *streamInternalEncoder) encodeField(f fieldInfo, depth int, width int) {
 	if e.err != nil {
 		return
 	}
 	e.writeIndent()
 	e.writeString(f.name)
+	for n := len(f.name); n < width; n++ {
+		e.writeByte(' ')
+	}
 	e.writeSpace()
 
 	if f.isBlock {
@@ -553,6 +702,10 @@ func (e *streamInternalEncoder) encodeField(f fieldInfo, depth int) {
 	} else {
 		e.writeString("=")
 		e.writeSpace()
+		if f.tag.Secret && e.opts.RedactSecrets {
+			e.writeQuotedString(redactedValue)
+			return
+		}
 		e.encodeValue(f.value, depth)
 	}
 }
@@ -571,6 +724,12 @@ func (e *streamInternalEncoder) encodeValue(v reflect.Value, depth int) {
 		e.writeString(time.Duration(v.Int()).String())
 		return
 	}
+	if et := lookupEnum(v.Type()); et != nil {
+		if name, ok := et.name(v); ok {
+			e.writeQuotedString(name)
+			return
+		}
+	}
 	switch v.Kind() {
 	case reflect.String:
 		s := v.String()
@@ -584,7 +743,12 @@ func (e *streamInternalEncoder) encodeValue(v reflect.Value, depth int) {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		e.write(strconv.AppendInt(e.tmpBuf[:0], v.Int(), 10))
 	case reflect.Float32, reflect.Float64:
-		e.write(strconv.AppendFloat(e.tmpBuf[:0], v.Float(), 'f', -1, 64))
+		b, err := appendFloat(e.tmpBuf[:0], v.Float(), e.opts)
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.write(b)
 	case reflect.Bool:
 		e.write(strconv.AppendBool(e.tmpBuf[:0], v.Bool()))
 	case reflect.Slice, reflect.Array:
@@ -673,9 +837,15 @@ func (e *streamInternalEncoder) encodeMap(v reflect.Value, depth int) {
 	} else {
 		e.writeNewLine()
 		e.indent++
-		for _, entry := range entries {
+		widths := mapEntryWidths(entries, e.opts)
+		for i, entry := range entries {
 			e.writeIndent()
 			e.writeString(entry.key.String())
+			if widths != nil {
+				for n := len(entry.key.String()); n < widths[i]; n++ {
+					e.writeByte(' ')
+				}
+			}
 			e.writeSpace()
 			e.writeString("=")
 			e.writeSpace()
@@ -723,6 +893,18 @@ func gatherFields(v reflect.Value, fields *[]fieldInfo) {
 	}
 }
 
+// omitDefaults 去掉 fields 中与结构体 def 的对应字段相同的字段.
+func omitDefaults(fields []fieldInfo, def reflect.Value) []fieldInfo {
+	n := 0
+	for _, f := range fields {
+		if len(DiffValues(f.value.Interface(), def.Field(f.fieldType.Index[0]).Interface())) > 0 {
+			fields[n] = f
+			n++
+		}
+	}
+	return fields[:n]
+}
+
 func cacheStructInfo(t reflect.Type) []cachedField {
 	var cachedFields []cachedField
 	for i := 0; i < t.NumField(); i++ {
@@ -0,0 +1,80 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// enumRegistry 保存 RegisterEnum 注册的枚举类型.
+var enumRegistry sync.Map // map[reflect.Type]*enumType
+
+// enumType 描述一个已注册的枚举类型.
+type enumType struct {
+	typ    reflect.Type
+	names  []string // 按注册顺序
+	values map[string]reflect.Value
+}
+
+// RegisterEnum 注册一个枚举类型, 使该类型的字段在配置中以名称书写, 例如
+//
+//	type Level int
+//	const (Debug Level = iota; Info; Warn)
+//	func (l Level) String() string { ... }
+//
+//	wanf.RegisterEnum([]Level{Debug, Info, Warn})
+//
+// values 是该类型全部取值组成的切片, 每个取值的名称为其 String() 的结果
+// (没有 String 方法时按 fmt.Sprint 格式化). 解码时字符串按名称转换为对应的
+// 常量, 不在其中的值报错并列出全部允许值; 编码时输出名称. 结构体字段, 列表
+// 元素与 map 值均适用. values 不是非空切片或名称重复时 panic, 因此通常在
+// init 中调用.
+func RegisterEnum(values interface{}) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		panic(fmt.Sprintf("wanf: RegisterEnum needs a non-empty slice of values, got %T", values))
+	}
+	et := &enumType{typ: v.Type().Elem(), values: make(map[string]reflect.Value)}
+	for i := 0; i < v.Len(); i++ {
+		name := fmt.Sprint(v.Index(i).Interface())
+		if _, dup := et.values[name]; dup {
+			panic(fmt.Sprintf("wanf: RegisterEnum: duplicate name %q for %s", name, et.typ))
+		}
+		et.names = append(et.names, name)
+		et.values[name] = v.Index(i)
+	}
+	enumRegistry.Store(et.typ, et)
+	resetFlatPlans()
+}
+
+// lookupEnum 返回类型 t 注册的枚举信息, 未注册时返回 nil.
+func lookupEnum(t reflect.Type) *enumType {
+	if et, ok := enumRegistry.Load(t); ok {
+		return et.(*enumType)
+	}
+	return nil
+}
+
+// parse 将解码得到的值转换为枚举常量.
+func (et *enumType) parse(val interface{}) (reflect.Value, error) {
+	s, ok := val.(string)
+	if !ok {
+		return reflect.Value{}, errorOf(ErrTypeMismatch, "value for %s must be a string, got %T", et.typ, val)
+	}
+	v, ok := et.values[s]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("invalid value %q for %s, allowed values: %s", s, et.typ, strings.Join(et.names, ", "))
+	}
+	return v, nil
+}
+
+// name 返回枚举值的名称. v 不是已注册的取值时返回 false.
+func (et *enumType) name(v reflect.Value) (string, bool) {
+	for _, name := range et.names {
+		if et.values[name].Interface() == v.Interface() {
+			return name, true
+		}
+	}
+	return "", false
+}
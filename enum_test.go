@@ -0,0 +1,114 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+type enumTestLevel int
+
+const (
+	enumTestDebug enumTestLevel = iota
+	enumTestInfo
+	enumTestWarn
+)
+
+func (l enumTestLevel) String() string {
+	switch l {
+	case enumTestDebug:
+		return "debug"
+	case enumTestInfo:
+		return "info"
+	case enumTestWarn:
+		return "warn"
+	}
+	return "unknown"
+}
+
+type enumTestConfig struct {
+	Level  enumTestLevel            `wanf:"level"`
+	Ptr    *enumTestLevel           `wanf:"ptr,omitempty"`
+	Levels []enumTestLevel          `wanf:"levels"`
+	ByName map[string]enumTestLevel `wanf:"by_name"`
+}
+
+func init() {
+	RegisterEnum([]enumTestLevel{enumTestDebug, enumTestInfo, enumTestWarn})
+}
+
+func TestDecodeEnum(t *testing.T) {
+	data := `
+level = "warn"
+ptr = "info"
+levels = ["debug", "warn"]
+by_name = {[ a = "info" ]}
+`
+	var cfg enumTestConfig
+	if err := Decode([]byte(data), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Level != enumTestWarn || cfg.Ptr == nil || *cfg.Ptr != enumTestInfo {
+		t.Errorf("Level = %v, Ptr = %v", cfg.Level, cfg.Ptr)
+	}
+	if len(cfg.Levels) != 2 || cfg.Levels[1] != enumTestWarn {
+		t.Errorf("Levels = %v", cfg.Levels)
+	}
+	if cfg.ByName["a"] != enumTestInfo {
+		t.Errorf("ByName = %v", cfg.ByName)
+	}
+}
+
+func TestDecodeEnumInvalid(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`level = "trace"`, `invalid value "trace" for wanf.enumTestLevel, allowed values: debug, info, warn`},
+		{`level = 1`, "must be a string, got int64"},
+		{`levels = ["info", "fatal"]`, `invalid value "fatal"`},
+	}
+	for _, tt := range tests {
+		var cfg enumTestConfig
+		err := Decode([]byte(tt.data), &cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) error = %v, want it to contain %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestMarshalEnum(t *testing.T) {
+	cfg := enumTestConfig{Level: enumTestInfo, Levels: []enumTestLevel{enumTestDebug}}
+	out, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(out), `level = "info"`) || !strings.Contains(string(out), `"debug"`) {
+		t.Errorf("enum values not encoded by name:\n%s", out)
+	}
+	var back enumTestConfig
+	if err := Decode(out, &back); err != nil {
+		t.Fatalf("Decode of marshaled output failed: %v\n%s", err, out)
+	}
+	if back.Level != enumTestInfo {
+		t.Errorf("round trip Level = %v", back.Level)
+	}
+}
+
+func TestSchemaOfEnum(t *testing.T) {
+	out, err := SchemaOf(enumTestConfig{})
+	if err != nil {
+		t.Fatalf("SchemaOf failed: %v", err)
+	}
+	if !strings.Contains(string(out), `"warn"`) {
+		t.Errorf("schema does not list enum names:\n%s", out)
+	}
+}
+
+func TestRegisterEnumPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterEnum with duplicate names did not panic")
+		}
+	}()
+	RegisterEnum([]enumTestLevel{enumTestDebug, enumTestDebug})
+}
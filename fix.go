@@ -0,0 +1,220 @@
+package wanf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// fixableRules 是 Fix 能够安全自动修复的规则.
+var fixableRules = []ErrorType{ErrRedundantComma, ErrRedundantLabel, ErrMissingComma, ErrNamingConvention, ErrHashComment, ErrFileEncoding}
+
+// Fix applies the safe automatic fixes (dropping redundant commas, removing
+// redundant labels, inserting missing commas, renaming keys that break the
+// naming convention, turning `#` comments into `//` comments and removing a
+// byte order mark and CRLF line endings) to data and returns the rewritten
+// source together with the issues that remain afterwards.
+//
+// Unlike Format, Fix only touches the bytes involved in each fix, so comments,
+// ordering and layout are preserved. rules restricts the fixes to the given
+// rule names or IDs; without rules every fixable rule is applied.
+// Naming the opt-in "naming-convention" rule explicitly enables it.
+// Documents with syntax errors are returned unchanged with an error, except
+// that commas missing between list elements, which are syntax errors, are
+// inserted when they are the only ones.
+func Fix(data []byte, rules ...string) ([]byte, []LintError, error) {
+	return FixWithConfig(data, nil, rules...)
+}
+
+// FixWithConfig is like Fix, but lints with cfg so that disabled rules are
+// left alone and rule options (such as the naming style) are honored.
+func FixWithConfig(data []byte, cfg *LintConfig, rules ...string) ([]byte, []LintError, error) {
+	enabled := make(map[ErrorType]bool)
+	if len(rules) == 0 {
+		for _, t := range fixableRules {
+			enabled[t] = true
+		}
+	}
+	for _, name := range rules {
+		t, ok := ParseErrorType(name)
+		if !ok {
+			return data, nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		if !isFixable(t) {
+			return data, nil, fmt.Errorf("rule %q has no automatic fix", name)
+		}
+		enabled[t] = true
+		cfg = cfg.withRuleEnabled(t)
+	}
+	opt := WithLintConfig(cfg)
+
+	p := NewParser(NewLexer(data))
+	p.SetLintMode(true)
+	p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		// 列表中缺少的逗号是语法错误; 如果只有这类错误, 先补上逗号再修复其余问题.
+		fixed, ok := insertListCommas(data, p.Errors(), enabled[ErrMissingComma])
+		if ok {
+			p = NewParser(NewLexer(fixed))
+			p.SetLintMode(true)
+			p.ParseProgram()
+		}
+		if !ok || len(p.Errors()) > 0 {
+			_, errs := Lint(data, opt)
+			return data, errs, fmt.Errorf("cannot fix a document with syntax errors")
+		}
+		data = fixed
+	}
+
+	_, errs := Lint(data, opt)
+	toks := scanTokenSpans(data)
+	var edits []textEdit
+	encodingFixed := false
+	for _, e := range errs {
+		if !enabled[e.Type] {
+			continue
+		}
+		if e.Type == ErrFileEncoding {
+			if !encodingFixed {
+				edits = append(edits, encodingEdits(data, toks)...)
+				encodingFixed = true
+			}
+			continue
+		}
+		i, ok := toks.index(e.Line, e.Column)
+		if !ok {
+			continue
+		}
+		switch e.Type {
+		case ErrRedundantComma:
+			if toks[i].typ == COMMA {
+				edits = append(edits, textEdit{start: toks[i].start, end: toks[i].end})
+			}
+		case ErrRedundantLabel:
+			// The issue points at the block name; remove everything up to the end of the label.
+			if i+1 < len(toks) && toks[i+1].typ == STRING {
+				edits = append(edits, textEdit{start: toks[i].end, end: toks[i+1].end})
+			}
+		case ErrMissingComma:
+			if edit, ok := commaEdit(toks, i); ok {
+				edits = append(edits, edit)
+			}
+		case ErrHashComment:
+			if toks[i].typ == ILLEGAL_COMMENT {
+				edits = append(edits, textEdit{start: toks[i].start, end: toks[i].start + 1, text: "//"})
+			}
+		case ErrNamingConvention:
+			if toks[i].typ == IDENT && len(e.Args) == 2 {
+				edits = append(edits, textEdit{start: toks[i].start, end: toks[i].end, text: e.Args[1]})
+			}
+		}
+	}
+	if len(edits) == 0 {
+		return data, errs, nil
+	}
+
+	fixed := applyEdits(data, edits)
+	_, remaining := Lint(fixed, opt)
+	return fixed, remaining, nil
+}
+
+// commaEdit returns the edit inserting a comma before the element that starts
+// at toks[i], after the last token that is not a comment.
+func commaEdit(toks tokenSpans, i int) (textEdit, bool) {
+	for j := i - 1; j >= 0; j-- {
+		if toks[j].typ != COMMENT {
+			return textEdit{start: toks[j].end, end: toks[j].end, text: ","}, true
+		}
+	}
+	return textEdit{}, false
+}
+
+// insertListCommas inserts the commas missing between list elements. It
+// reports false, leaving data alone, unless enabled is set and every syntax
+// error in errs is such a missing comma.
+func insertListCommas(data []byte, errs []LintError, enabled bool) ([]byte, bool) {
+	if !enabled {
+		return data, false
+	}
+	toks := scanTokenSpans(data)
+	edits := make([]textEdit, 0, len(errs))
+	for _, e := range errs {
+		if e.Type != ErrMissingComma {
+			return data, false
+		}
+		i, ok := toks.index(e.Line, e.Column)
+		if !ok {
+			return data, false
+		}
+		edit, ok := commaEdit(toks, i)
+		if !ok {
+			return data, false
+		}
+		edits = append(edits, edit)
+	}
+	return applyEdits(data, edits), true
+}
+
+func isFixable(t ErrorType) bool {
+	for _, f := range fixableRules {
+		if f == t {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenSpan 记录一个标记在源文本中的字节范围.
+type tokenSpan struct {
+	typ        TokenType
+	line, col  int
+	start, end int
+}
+
+type tokenSpans []tokenSpan
+
+// scanTokenSpans lexes data and records the byte range of every token, so that
+// positions reported by the lexer can be mapped back to source offsets.
+func scanTokenSpans(data []byte) tokenSpans {
+	var spans tokenSpans
+	l := NewLexer(data)
+	for {
+		tok := l.NextToken()
+		if tok.Type == EOF {
+			return spans
+		}
+		spans = append(spans, tokenSpan{typ: tok.Type, line: tok.Line, col: tok.Column, start: tok.Offset, end: tok.EndOffset})
+	}
+}
+
+// index returns the index of the token that starts at line:col.
+func (s tokenSpans) index(line, col int) (int, bool) {
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].line > line || (s[i].line == line && s[i].col >= col)
+	})
+	if i < len(s) && s[i].line == line && s[i].col == col {
+		return i, true
+	}
+	return 0, false
+}
+
+// textEdit 将 [start, end) 替换为 text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits applies non-overlapping edits to data.
+func applyEdits(data []byte, edits []textEdit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	out := make([]byte, 0, len(data)+len(edits))
+	last := 0
+	for _, e := range edits {
+		if e.start < last {
+			continue // overlapping edit, keep the first one
+		}
+		out = append(out, data[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, data[last:]...)
+}
@@ -0,0 +1,101 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	src := `// settings
+database "main" { // primary
+	host = "localhost", // inline
+	port = 5432
+}
+
+tags = {[
+	a = 1 // first
+	b = 2,
+]}
+`
+	want := `// settings
+database { // primary
+	host = "localhost" // inline
+	port = 5432
+}
+
+tags = {[
+	a = 1, // first
+	b = 2,
+]}
+`
+	fixed, remaining, err := Fix([]byte(src))
+	if err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	if string(fixed) != want {
+		t.Errorf("fixed output mismatch:\n--- want\n%s\n--- got\n%s", want, fixed)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected no remaining issues, got %v", remaining)
+	}
+}
+
+func TestFix_Rules(t *testing.T) {
+	src := "server \"only\" {\n\tport = 1,\n}\n"
+	fixed, remaining, err := Fix([]byte(src), "WANF002")
+	if err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	if string(fixed) != "server \"only\" {\n\tport = 1\n}\n" {
+		t.Errorf("unexpected output:\n%s", fixed)
+	}
+	if len(remaining) != 1 || remaining[0].Type != ErrRedundantLabel {
+		t.Errorf("expected the redundant label to remain, got %v", remaining)
+	}
+
+	if _, _, err := Fix([]byte(src), "unused-variable"); err == nil || !strings.Contains(err.Error(), "no automatic fix") {
+		t.Errorf("expected an error for a non-fixable rule, got %v", err)
+	}
+	if _, _, err := Fix([]byte("a = = 1")); err == nil {
+		t.Error("expected an error for a document with syntax errors")
+	}
+}
+
+func TestFix_ListCommas(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"a = [1 2]\n", "a = [1, 2]\n"},
+		{"a = [\"x\" \"y\" \"z\"]\n", "a = [\"x\", \"y\", \"z\"]\n"},
+		{"a = [\n\t1 // one\n\t// two\n\t2\n]\n", "a = [\n\t1, // one\n\t// two\n\t2\n]\n"},
+		{"a = [env(\"A\") [1 2]]\ns {\n\tb = 1,\n}\n", "a = [env(\"A\"), [1, 2]]\ns {\n\tb = 1\n}\n"},
+	}
+	for _, tt := range tests {
+		if _, errs := Lint([]byte(tt.src)); len(errs) == 0 || errs[0].Type != ErrMissingComma || errs[0].Severity != SeverityError {
+			t.Errorf("Lint(%q) = %v, want a missing-comma error", tt.src, errs)
+		}
+		fixed, remaining, err := Fix([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Fix(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if string(fixed) != tt.want {
+			t.Errorf("Fix(%q) = %q, want %q", tt.src, fixed, tt.want)
+		}
+		if len(remaining) != 0 {
+			t.Errorf("Fix(%q) left %v", tt.src, remaining)
+		}
+	}
+
+	for _, src := range []string{"a = [1 2\nb = 3\n", "a = [1 2]\nb = )\n"} {
+		if fixed, _, err := Fix([]byte(src)); err == nil || string(fixed) != src {
+			t.Errorf("Fix(%q) = %q, %v, want the document unchanged with an error", src, fixed, err)
+		}
+	}
+	if fixed, _, err := Fix([]byte("a = [1 2]\n"), "redundant-comma"); err == nil || string(fixed) != "a = [1 2]\n" {
+		t.Errorf("Fix without missing-comma = %q, %v, want an error", fixed, err)
+	}
+	if _, err := Parse([]byte("a = [1 2]\n")); err == nil {
+		t.Error("Parse accepted a list without commas")
+	}
+}
@@ -0,0 +1,97 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Flatten 将 root 展开为以 '.' 连接的键到字符串值的映射, 例如
+// `server "main" { port = 8080 }` 成为 "server.main.port" = "8080",
+// 便于导出给只接受 key=value 的旧系统. 块的标签作为键的一段, 列表元素以
+// 下标为键, 同名的键以最后一次为准. 空列表与空映射不产生键.
+//
+// 与 Get 相同, Flatten 不求值: 变量引用与 env() 保持其 WANF 源码形式,
+// var 与 import 语句被忽略. 需要求值后的结果时对 Eval 的返回值使用 FlattenValue.
+func Flatten(root *RootNode) map[string]string {
+	out := make(map[string]string)
+	d := &internalDecoder{vars: make(map[string]interface{}), sandbox: true}
+	d.flattenStatements(out, "", root.Statements)
+	return out
+}
+
+// FlattenValue 按 Flatten 的规则展开 ToValue 或 Eval 返回的值树.
+func FlattenValue(v map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	flattenValue(out, "", v)
+	return out
+}
+
+func (d *internalDecoder) flattenStatements(out map[string]string, prefix string, stmts []Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			d.flattenExpression(out, joinPath(prefix, string(s.Name.Value)), s.Value)
+		case *BlockStatement:
+			key := joinPath(prefix, string(s.Name.Value))
+			for _, label := range s.Labels() {
+				key = joinPath(key, label)
+			}
+			d.flattenStatements(out, key, s.Body.Statements)
+		}
+	}
+}
+
+func (d *internalDecoder) flattenExpression(out map[string]string, key string, expr Expression) {
+	switch e := expr.(type) {
+	case *ListLiteral:
+		for i, elem := range e.Elements {
+			d.flattenExpression(out, joinPath(key, strconv.Itoa(i)), elem)
+		}
+		return
+	case *MapLiteral:
+		d.flattenStatements(out, key, e.Elements)
+		return
+	case *BlockLiteral:
+		d.flattenStatements(out, key, e.Body.Statements)
+		return
+	}
+	if val, err := d.evalExpression(expr); err == nil && !containsVarRef(expr) {
+		out[key] = flatString(val)
+		return
+	}
+	var buf bytes.Buffer
+	expr.Format(&buf, "", FormatOptions{Style: StyleSingleLine})
+	out[key] = buf.String()
+}
+
+func flattenValue(out map[string]string, key string, v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range val {
+			flattenValue(out, joinPath(key, k), elem)
+		}
+	case []interface{}:
+		for i, elem := range val {
+			flattenValue(out, joinPath(key, strconv.Itoa(i)), elem)
+		}
+	default:
+		out[key] = flatString(val)
+	}
+}
+
+// flatString 返回标量值的文本形式: 字符串保持原样, duration 如 "1m30s".
+func flatString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Duration:
+		return val.String()
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,67 @@
+package wanf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	src := `var region = "eu"
+name = "app"
+timeout = 90s
+ratio = 0.5
+server "main" {
+	port = 8080
+	hosts = ["a", "b"]
+	zone = "${region}-1"
+	token = env("TOKEN")
+}
+server "main" {
+	port = 9090
+}
+limits = {[cpu = 2, mem = "1G"]}
+rules = [{path = "/"}]
+`
+	root, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"name":                "app",
+		"timeout":             "1m30s",
+		"ratio":               "0.5",
+		"server.main.port":    "9090",
+		"server.main.hosts.0": "a",
+		"server.main.hosts.1": "b",
+		"server.main.zone":    `"${region}-1"`,
+		"server.main.token":   `env("TOKEN")`,
+		"limits.cpu":          "2",
+		"limits.mem":          "1G",
+		"rules.0.path":        "/",
+	}
+	if got := Flatten(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten mismatch.\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestFlattenValue(t *testing.T) {
+	v, err := ToValue([]byte(`var region = "eu"
+server "main" {
+	zone = "${region}-1"
+	ports = [80, 443]
+	timeout = 2s
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"server.main.zone":    "eu-1",
+		"server.main.ports.0": "80",
+		"server.main.ports.1": "443",
+		"server.main.timeout": "2s",
+	}
+	if got := FlattenValue(v); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenValue mismatch.\ngot:  %v\nwant: %v", got, want)
+	}
+}
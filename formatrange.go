@@ -0,0 +1,86 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FormatRange formats only the top-level statements that intersect the
+// 1-based, inclusive line range [startLine, endLine] and leaves every other
+// byte of data unchanged, which is what editors need for "format selection".
+//
+// A selected statement is formatted as a whole, including its nested blocks
+// and leading comments. Top-level statements are never reordered, so
+// SortImports and the sorting of top-level statements in StyleAllSorted do
+// not apply. Documents with syntax errors are returned unchanged with an error.
+func FormatRange(data []byte, startLine, endLine int, opts FormatOptions) ([]byte, error) {
+	if startLine > endLine {
+		return data, fmt.Errorf("wanf: invalid line range %d-%d", startLine, endLine)
+	}
+	p := NewParser(NewLexer(data))
+	p.SetLintMode(true)
+	p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return data, p.Errors()[0]
+	}
+	program, _ := Lint(data)
+	toks := scanTokenSpans(data)
+
+	starts := make([]int, len(program.Statements))
+	for i, stmt := range program.Statements {
+		tok := statementToken(stmt)
+		if c := stmt.GetLeadingComments(); len(c) > 0 {
+			tok = c[0].Token
+		}
+		idx, ok := toks.index(tok.Line, tok.Column)
+		if !ok {
+			return data, fmt.Errorf("wanf: cannot locate statement at line %d:%d", tok.Line, tok.Column)
+		}
+		starts[i] = idx
+	}
+
+	var edits []textEdit
+	for i, stmt := range program.Statements {
+		first, last := starts[i], len(toks)-1
+		if i+1 < len(starts) {
+			last = starts[i+1] - 1
+		} else {
+			// 文件末尾独立成行的注释不属于最后一条语句.
+			for last > first && toks[last].typ == COMMENT && lineAt(data, toks[last].start) > lineAt(data, toks[last-1].end) {
+				last--
+			}
+		}
+		start, end := toks[first].start, toks[last].end
+		if lineAt(data, end) < startLine || lineAt(data, start) > endLine {
+			continue
+		}
+		// 语句前的缩进也一并替换.
+		for start > 0 && (data[start-1] == ' ' || data[start-1] == '\t') {
+			start--
+		}
+		var buf bytes.Buffer
+		stmt.Format(&buf, "", opts)
+		edits = append(edits, textEdit{start: start, end: end, text: buf.String()})
+	}
+	return applyEdits(data, edits), nil
+}
+
+// statementToken returns the first token of a statement.
+func statementToken(s Statement) Token {
+	switch s := s.(type) {
+	case *AssignStatement:
+		return s.Token
+	case *BlockStatement:
+		return s.Token
+	case *VarStatement:
+		return s.Token
+	case *ImportStatement:
+		return s.Token
+	}
+	return Token{}
+}
+
+// lineAt returns the 1-based line of the byte at offset in data.
+func lineAt(data []byte, offset int) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,45 @@
+package wanf
+
+import "testing"
+
+func TestFormatRange(t *testing.T) {
+	input := `a   =   1
+server {
+  port = 8080
+    host = "localhost"
+}
+// keeps its odd spacing
+b    =    2
+// trailing comment
+`
+	want := `a   =   1
+server {
+	host = "localhost"
+	port = 8080
+}
+// keeps its odd spacing
+b    =    2
+// trailing comment
+`
+	opts := FormatOptions{Style: StyleBlockSorted, EmptyLines: true}
+	got, err := FormatRange([]byte(input), 3, 3, opts)
+	if err != nil {
+		t.Fatalf("FormatRange failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+
+	// A range covering the last statement does not swallow the trailing comment.
+	got, err = FormatRange([]byte(input), 7, 8, opts)
+	if err != nil {
+		t.Fatalf("FormatRange failed: %v", err)
+	}
+	if want := "// keeps its odd spacing\nb = 2\n// trailing comment\n"; string(got[len(got)-len(want):]) != want {
+		t.Errorf("unexpected tail:\n%s", got)
+	}
+
+	if _, err := FormatRange([]byte("a = [1, 2"), 1, 1, opts); err == nil {
+		t.Error("expected an error for a document with syntax errors")
+	}
+}
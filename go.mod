@@ -2,4 +2,8 @@ module github.com/WJQSERVER/wanf
 
 go 1.24.5
 
-require github.com/go-json-experiment/json v0.0.0-20250725192818-e39067aee2d2
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/go-json-experiment/json v0.0.0-20250725192818-e39067aee2d2
+	gopkg.in/yaml.v3 v3.0.1
+)
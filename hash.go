@@ -0,0 +1,30 @@
+package wanf
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Hash 返回文档语义内容的 SHA-256 摘要 (十六进制), 与格式无关: 文档按解码的
+// 流程求值 (变量、env()、import 与 profile), 块按标签合并, 再以 WANF-B 编码
+// 得到规范形式后计算摘要. 因此注释、空白、语句顺序、字面量写法 (90s 与 90000ms)
+// 以及变量是否内联都不影响结果, 而列表顺序与值的类型 (1 与 1.0) 会影响结果.
+// opts 与 NewDecoder 相同, 例如使用 WithBasePath 解析相对 import.
+//
+// 引用了环境变量的文档, 其摘要随环境变化.
+func Hash(data []byte, opts ...DecoderOption) (string, error) {
+	root, err := Parse(data)
+	if err != nil {
+		return "", err
+	}
+	v, err := Eval(root, opts...)
+	if err != nil {
+		return "", err
+	}
+	canonical, err := MarshalBinary(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return fmt.Sprintf("%x", sum), nil
+}
@@ -0,0 +1,55 @@
+package wanf
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	base := `// service
+var p = 8080
+name = "app"
+timeout = 90s
+server "a" {
+	port = ${p}
+	tags = ["x", "y"]
+}
+`
+	want, err := Hash([]byte(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 64 {
+		t.Fatalf("Hash = %q, want 64 hex digits", want)
+	}
+	same := []string{
+		// 顺序、注释、空白与 duration 写法不同
+		"server \"a\" { tags = [\"x\",\"y\"]; port = 8080 }\ntimeout = 90000ms\nname = \"app\" // x\n",
+		// 同名的块被合并
+		"name = \"app\"\ntimeout = 90s\nserver \"a\" {\n\tport = 8080\n}\nserver \"a\" {\n\ttags = [\"x\", \"y\"]\n}\n",
+	}
+	for i, src := range same {
+		got, err := Hash([]byte(src))
+		if err != nil {
+			t.Fatalf("same[%d]: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("same[%d]: hash differs from base", i)
+		}
+	}
+	different := []string{
+		"name = \"app\"\ntimeout = 90s\nserver \"a\" {\n\tport = 8081\n\ttags = [\"x\", \"y\"]\n}\n",
+		"name = \"app\"\ntimeout = 90s\nserver \"a\" {\n\tport = 8080\n\ttags = [\"y\", \"x\"]\n}\n",
+		"name = \"app\"\ntimeout = 90s\nserver \"b\" {\n\tport = 8080\n\ttags = [\"x\", \"y\"]\n}\n",
+		"name = \"app\"\ntimeout = \"90s\"\nserver \"a\" {\n\tport = 8080\n\ttags = [\"x\", \"y\"]\n}\n",
+	}
+	for i, src := range different {
+		got, err := Hash([]byte(src))
+		if err != nil {
+			t.Fatalf("different[%d]: %v", i, err)
+		}
+		if got == want {
+			t.Errorf("different[%d]: hash equals base", i)
+		}
+	}
+	if _, err := Hash([]byte("name = ")); err == nil {
+		t.Error("expected error for invalid document")
+	}
+}
@@ -0,0 +1,157 @@
+package wanf
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Hooks 是解码过程中的回调, 用于监控配置加载的耗时与 env() 的使用. 未设置的
+// 回调被忽略. 回调在调用解码的 goroutine 中同步执行, 应当尽快返回.
+type Hooks struct {
+	// OnFileParsed 在主文档或导入的文件解析成功后调用. file 对主文档为 DecodeFile
+	// 的路径 (通过 NewDecoder 读取时为空), 对导入的文件为其绝对路径. 从
+	// ImportCache 取得的文件不会重新解析, 也不会触发此回调.
+	OnFileParsed func(file string, size int, elapsed time.Duration)
+	// OnEnvResolved 在每次求值 env() 时调用, found 报告环境变量是否已设置.
+	OnEnvResolved func(name string, found bool)
+	// OnFieldSet 在 Decode 写入一个结构体字段时调用, 早于 OnDecode. path 与 source
+	// 的含义见 Decoder.Provenance; 未使用 WithProvenance 时 source.File 为空.
+	// 被 profile 覆盖的字段只为 profile 中的值调用一次.
+	OnFieldSet func(path string, source Provenance)
+	// OnDecode 在每次 Decode 结束时调用.
+	OnDecode func(elapsed time.Duration, err error)
+}
+
+// WithHooks 为解码器注册回调. 可以多次使用, 回调按注册顺序调用.
+// 适用于 NewDecoder、DecodeFile 与 Plan; StreamDecoder 只调用 OnEnvResolved.
+func WithHooks(h *Hooks) DecoderOption {
+	return func(d *internalDecoder) {
+		if h != nil {
+			d.hooks = append(d.hooks, h)
+		}
+	}
+}
+
+// hookList 是通过 WithHooks 注册的回调.
+type hookList []*Hooks
+
+func (l hookList) fileParsed(file string, size int, elapsed time.Duration) {
+	for _, h := range l {
+		if h.OnFileParsed != nil {
+			h.OnFileParsed(file, size, elapsed)
+		}
+	}
+}
+
+func (l hookList) envResolved(name string, found bool) {
+	for _, h := range l {
+		if h.OnEnvResolved != nil {
+			h.OnEnvResolved(name, found)
+		}
+	}
+}
+
+// wantsFields 报告是否有 OnFieldSet 回调, 没有时解码器不必跟踪字段的来源.
+func (l hookList) wantsFields() bool {
+	for _, h := range l {
+		if h.OnFieldSet != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (l hookList) fieldSet(path string, source Provenance) {
+	for _, h := range l {
+		if h.OnFieldSet != nil {
+			h.OnFieldSet(path, source)
+		}
+	}
+}
+
+func (l hookList) decoded(elapsed time.Duration, err error) {
+	for _, h := range l {
+		if h.OnDecode != nil {
+			h.OnDecode(elapsed, err)
+		}
+	}
+}
+
+// parseFile 解析文件 file 的内容 data, 并调用 OnFileParsed.
+func (d *internalDecoder) parseFile(data []byte, file string) (*RootNode, error) {
+	if err := d.limits.checkSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	program, err := parse(data, "", d.limits.maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	d.hooks.fileParsed(file, len(data), time.Since(start))
+	return program, nil
+}
+
+// lookupEnv 读取 env() 引用的环境变量, 并调用 OnEnvResolved.
+func (d *internalDecoder) lookupEnv(name string) (string, bool) {
+	val, found := os.LookupEnv(name)
+	if d.prov != nil {
+		d.prov.envFound[name] = found
+	}
+	d.hooks.envResolved(name, found)
+	return val, found
+}
+
+// Metrics 累计解码的计数, 可以在多个解码器之间并发共享. 它实现了 expvar.Var,
+// 可以直接发布:
+//
+//	var metrics wanf.Metrics
+//	expvar.Publish("wanf", &metrics)
+//	dec, err := wanf.NewDecoder(r, wanf.WithHooks(metrics.Hooks()))
+type Metrics struct {
+	Decodes      atomic.Int64 // Decode 的调用次数
+	DecodeErrors atomic.Int64 // 其中返回错误的次数
+	DecodeNanos  atomic.Int64 // Decode 的累计耗时
+	FilesParsed  atomic.Int64 // 解析的文件数, 包括导入的文件
+	BytesParsed  atomic.Int64
+	ParseNanos   atomic.Int64 // 解析的累计耗时
+	EnvLookups   atomic.Int64 // env() 的求值次数
+	EnvMissing   atomic.Int64 // 其中环境变量未设置的次数
+	FieldsSet    atomic.Int64 // Decode 写入的字段数
+}
+
+// Hooks 返回更新 m 的回调.
+func (m *Metrics) Hooks() *Hooks {
+	return &Hooks{
+		OnFileParsed: func(file string, size int, elapsed time.Duration) {
+			m.FilesParsed.Add(1)
+			m.BytesParsed.Add(int64(size))
+			m.ParseNanos.Add(int64(elapsed))
+		},
+		OnEnvResolved: func(name string, found bool) {
+			m.EnvLookups.Add(1)
+			if !found {
+				m.EnvMissing.Add(1)
+			}
+		},
+		OnFieldSet: func(string, Provenance) {
+			m.FieldsSet.Add(1)
+		},
+		OnDecode: func(elapsed time.Duration, err error) {
+			m.Decodes.Add(1)
+			m.DecodeNanos.Add(int64(elapsed))
+			if err != nil {
+				m.DecodeErrors.Add(1)
+			}
+		},
+	}
+}
+
+// String 以 JSON 对象的形式返回当前的计数, 满足 expvar.Var.
+func (m *Metrics) String() string {
+	return fmt.Sprintf(`{"decodes": %d, "decode_errors": %d, "decode_ns": %d, "files_parsed": %d, "bytes_parsed": %d, "parse_ns": %d, "env_lookups": %d, "env_missing": %d, "fields_set": %d}`,
+		m.Decodes.Load(), m.DecodeErrors.Load(), m.DecodeNanos.Load(),
+		m.FilesParsed.Load(), m.BytesParsed.Load(), m.ParseNanos.Load(),
+		m.EnvLookups.Load(), m.EnvMissing.Load(), m.FieldsSet.Load())
+}
@@ -0,0 +1,128 @@
+package wanf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoderHooks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "common.wanf"), []byte("timeout = 5s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "app.wanf")
+	src := `import "common.wanf"
+name = env("HOOKS_NAME", "app")
+host = env("HOOKS_HOST")
+`
+	if err := os.WriteFile(main, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOOKS_HOST", "localhost")
+
+	var parsed, envs, fields []string
+	var decodes int
+	hooks := &Hooks{
+		OnFileParsed: func(file string, size int, elapsed time.Duration) {
+			parsed = append(parsed, filepath.Base(file))
+		},
+		OnEnvResolved: func(name string, found bool) {
+			if found {
+				name += "=set"
+			}
+			envs = append(envs, name)
+		},
+		OnFieldSet: func(path string, source Provenance) {
+			fields = append(fields, path+":"+string(source.Kind))
+		},
+		OnDecode: func(elapsed time.Duration, err error) {
+			if err != nil {
+				t.Errorf("OnDecode: %v", err)
+			}
+			decodes++
+		},
+	}
+	var metrics Metrics
+	var cfg struct {
+		Name    string        `wanf:"name"`
+		Host    string        `wanf:"host"`
+		Timeout time.Duration `wanf:"timeout"`
+	}
+	if err := DecodeFile(main, &cfg, WithHooks(hooks), WithHooks(metrics.Hooks())); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(parsed, " "), "app.wanf common.wanf"; got != want {
+		t.Errorf("parsed = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(envs, " "), "HOOKS_NAME HOOKS_HOST=set"; got != want {
+		t.Errorf("env = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(fields, " "), "timeout:literal name:default host:env"; got != want {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+	if decodes != 1 {
+		t.Errorf("OnDecode called %d times, want 1", decodes)
+	}
+
+	var counts map[string]int64
+	if err := json.Unmarshal([]byte(metrics.String()), &counts); err != nil {
+		t.Fatalf("Metrics.String() is not JSON: %v", err)
+	}
+	want := map[string]int64{"decodes": 1, "decode_errors": 0, "files_parsed": 2, "env_lookups": 2, "env_missing": 1, "fields_set": 3}
+	for k, v := range want {
+		if counts[k] != v {
+			t.Errorf("%s = %d, want %d", k, counts[k], v)
+		}
+	}
+	if counts["bytes_parsed"] != int64(len(src)+len("timeout = 5s\n")) {
+		t.Errorf("bytes_parsed = %d", counts["bytes_parsed"])
+	}
+}
+
+func TestDecoderHooksProfileOverride(t *testing.T) {
+	src := `name = "app"
+database {
+	host = "localhost"
+	port = 5432
+}
+profile "production" {
+	name = "app-prod"
+	database {
+		host = "db.internal"
+	}
+}
+`
+	var fields []string
+	decoded := false
+	hooks := &Hooks{
+		OnFieldSet: func(path string, source Provenance) {
+			if decoded {
+				t.Errorf("OnFieldSet(%q) called after OnDecode", path)
+			}
+			fields = append(fields, path+":"+source.Profile)
+		},
+		OnDecode: func(time.Duration, error) { decoded = true },
+	}
+	var cfg struct {
+		Name     string `wanf:"name"`
+		Database struct {
+			Host string `wanf:"host"`
+			Port int    `wanf:"port"`
+		} `wanf:"database"`
+	}
+	dec, err := NewDecoder(strings.NewReader(src), WithProfile("production"), WithHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(fields, " "), "database.port: name:production database.host:production"; got != want {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+}
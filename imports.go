@@ -0,0 +1,231 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ImportCache 缓存导入文件解析后的语法树, 以文件的绝对路径与修改时间 (及大小)
+// 为键, 文件变化后自动重新解析. 同一个 ImportCache 可以在多个 Decoder 之间
+// 并发共享; 缓存的语法树在解码之间共用, 不会被修改.
+type ImportCache struct {
+	mu      sync.Mutex
+	entries map[string]importCacheEntry
+}
+
+type importCacheEntry struct {
+	modTime time.Time
+	size    int64
+	program *RootNode
+}
+
+// NewImportCache 返回一个空的 ImportCache.
+func NewImportCache() *ImportCache {
+	return &ImportCache{entries: make(map[string]importCacheEntry)}
+}
+
+// WithImportCache 使解码器从 cache 中取用已解析的导入文件, 避免每次解码都
+// 重新读取与解析. 适合反复解码同一组配置的服务.
+func WithImportCache(cache *ImportCache) DecoderOption {
+	return func(d *internalDecoder) {
+		d.importCache = cache
+	}
+}
+
+// get 返回 path 在 info 所示版本下缓存的语法树, 没有时返回 nil.
+func (c *ImportCache) get(path string, info os.FileInfo) *RootNode {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || !e.modTime.Equal(info.ModTime()) || e.size != info.Size() {
+		return nil
+	}
+	return e.program
+}
+
+func (c *ImportCache) put(path string, info os.FileInfo, program *RootNode) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = importCacheEntry{modTime: info.ModTime(), size: info.Size(), program: program}
+}
+
+// loadedImport 是一条导入语句读取与解析的结果.
+type loadedImport struct {
+	absPath string
+	program *RootNode
+	err     error
+
+	// parsed 报告文件是否被解析 (而不是取自 ImportCache), size 与 elapsed 为
+	// 文件大小与解析耗时, 见 Hooks.OnFileParsed.
+	parsed  bool
+	size    int
+	elapsed time.Duration
+}
+
+// processImports 将 stmts 中的导入语句递归展开为被导入文件的语句. 每个文件只
+// 展开一次, 以第一次出现的位置为准. 同一层的导入并发读取与解析, 展开仍按
+// 源码顺序进行, 因此结果与错误都与逐个处理时相同.
+func processImports(stmts []Statement, basePath string, processed map[string]bool, cache *ImportCache) ([]Statement, error) {
+	return expandImports(stmts, basePath, processed, cache, nil)
+}
+
+// importObserver 收集 expandImports 展开导入时的信息.
+type importObserver struct {
+	files  map[Statement]string // 被导入的顶层语句 -> 文件的绝对路径
+	hooks  hookList
+	limits inputLimits // 对每个导入的文件的限制
+}
+
+// expandImports 即 processImports, 并将展开的过程报告给 obs (可以为 nil).
+func expandImports(stmts []Statement, basePath string, processed map[string]bool, cache *ImportCache, obs *importObserver) ([]Statement, error) {
+	var limits inputLimits
+	if obs != nil {
+		limits = obs.limits
+	}
+	loaded := loadImports(stmts, basePath, processed, cache, limits)
+	var finalStmts []Statement
+	for i, stmt := range stmts {
+		if _, ok := stmt.(*ImportStatement); !ok {
+			finalStmts = append(finalStmts, stmt)
+			continue
+		}
+		imp := loaded[i]
+		if imp.absPath == "" {
+			return nil, imp.err
+		}
+		if processed[imp.absPath] {
+			continue
+		}
+		processed[imp.absPath] = true
+		if imp.err != nil {
+			return nil, imp.err
+		}
+		if obs != nil && imp.parsed {
+			obs.hooks.fileParsed(imp.absPath, imp.size, imp.elapsed)
+		}
+		importedStmts, err := expandImports(imp.program.Statements, filepath.Dir(imp.absPath), processed, cache, obs)
+		if err != nil {
+			return nil, err
+		}
+		if obs != nil && obs.files != nil {
+			for _, s := range imp.program.Statements {
+				obs.files[s] = imp.absPath
+			}
+		}
+		finalStmts = append(finalStmts, importedStmts...)
+	}
+	return finalStmts, nil
+}
+
+// loadImports 使用有限数量的 goroutine 读取并解析 stmts 中尚未展开的导入,
+// 返回与 stmts 下标对应的结果. 同一文件只加载一次.
+func loadImports(stmts []Statement, basePath string, processed map[string]bool, cache *ImportCache, limits inputLimits) []*loadedImport {
+	loaded := make([]*loadedImport, len(stmts))
+	byPath := make(map[string]*loadedImport)
+	var jobs []*loadedImport
+	var importPaths []string
+	for i, stmt := range stmts {
+		importStmt, ok := stmt.(*ImportStatement)
+		if !ok {
+			continue
+		}
+		importPath := filepath.Join(basePath, string(importStmt.Path.Value))
+		absImportPath, err := filepath.Abs(importPath)
+		if err != nil {
+			loaded[i] = &loadedImport{err: fmt.Errorf("could not get absolute path for import %q: %w", string(importStmt.Path.Value), err)}
+			continue
+		}
+		imp, ok := byPath[absImportPath]
+		if !ok {
+			imp = &loadedImport{absPath: absImportPath}
+			byPath[absImportPath] = imp
+			if !processed[absImportPath] {
+				jobs = append(jobs, imp)
+				importPaths = append(importPaths, importPath)
+			}
+		}
+		loaded[i] = imp
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if workers <= 1 {
+		for j, imp := range jobs {
+			imp.load(importPaths[j], cache, limits)
+		}
+		return loaded
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range next {
+				imp := jobs[j]
+				imp.load(importPaths[j], cache, limits)
+			}
+		}()
+	}
+	for j := range jobs {
+		next <- j
+	}
+	close(next)
+	wg.Wait()
+	return loaded
+}
+
+// load 读取、解析并迁移导入的文件. importPath 用于错误信息.
+func (imp *loadedImport) load(importPath string, cache *ImportCache, limits inputLimits) {
+	imp.program, imp.err = imp.loadProgram(importPath, cache, limits)
+}
+
+func (imp *loadedImport) loadProgram(importPath string, cache *ImportCache, limits inputLimits) (*RootNode, error) {
+	var info os.FileInfo
+	if cache != nil {
+		var err error
+		if info, err = os.Stat(imp.absPath); err != nil {
+			return nil, fmt.Errorf("could not read imported file %q: %w", importPath, err)
+		}
+		if err := limits.checkSize(info.Size()); err != nil {
+			return nil, fmt.Errorf("imported file %q: %w", importPath, err)
+		}
+		// 缓存的语法树可能是在没有深度限制时解析的.
+		if limits.maxDepth <= 0 {
+			if program := cache.get(imp.absPath, info); program != nil {
+				return program, nil
+			}
+		}
+	}
+	data, err := limits.readFile(imp.absPath)
+	if errors.Is(err, ErrInputTooLarge) {
+		return nil, fmt.Errorf("imported file %q: %w", importPath, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read imported file %q: %w", importPath, err)
+	}
+	start := time.Now()
+	program, err := parse(data, "", limits.maxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("parser errors in imported file %q: %w", importPath, err)
+	}
+	imp.parsed, imp.size, imp.elapsed = true, len(data), time.Since(start)
+	if _, _, err := Migrate(program); err != nil {
+		return nil, fmt.Errorf("imported file %q: %w", importPath, err)
+	}
+	cache.put(imp.absPath, info, program)
+	return program, nil
+}
@@ -0,0 +1,109 @@
+package wanf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeImportFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProcessImportsOrder(t *testing.T) {
+	files := map[string]string{
+		"a.wanf":     "import \"sub/c.wanf\"\na = 1\n",
+		"b.wanf":     "b = 2\n",
+		"sub/c.wanf": "c = 3\n",
+	}
+	for i := 0; i < 20; i++ {
+		files["gen"+strings.Repeat("x", i)+".wanf"] = "n = " + string(rune('0'+i%10)) + "\n"
+	}
+	dir := writeImportFiles(t, files)
+
+	var src strings.Builder
+	src.WriteString("import \"a.wanf\"\nimport \"b.wanf\"\nimport \"sub/c.wanf\"\nimport \"a.wanf\"\n")
+	for i := 0; i < 20; i++ {
+		src.WriteString("import \"gen" + strings.Repeat("x", i) + ".wanf\"\n")
+	}
+	program, err := Parse([]byte(src.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := processImports(program.Statements, dir, make(map[string]bool), nil)
+	if err != nil {
+		t.Fatalf("processImports: %v", err)
+	}
+	var got []string
+	for _, stmt := range stmts {
+		as := stmt.(*AssignStatement)
+		got = append(got, string(as.Name.Value)+"="+as.Value.String())
+	}
+	want := "c=3 a=1 b=2 n=0 n=1 n=2 n=3 n=4 n=5 n=6 n=7 n=8 n=9 n=0 n=1 n=2 n=3 n=4 n=5 n=6 n=7 n=8 n=9"
+	if strings.Join(got, " ") != want {
+		t.Errorf("statements = %s\nwant %s", strings.Join(got, " "), want)
+	}
+
+	// 与逐个处理一样, 报告源码中第一个出错的导入.
+	program, _ = Parse([]byte("import \"b.wanf\"\nimport \"missing1.wanf\"\nimport \"missing2.wanf\"\n"))
+	_, err = processImports(program.Statements, dir, make(map[string]bool), nil)
+	if err == nil || !strings.Contains(err.Error(), "missing1.wanf") {
+		t.Errorf("processImports error = %v, want the error for missing1.wanf", err)
+	}
+}
+
+func TestImportCache(t *testing.T) {
+	dir := writeImportFiles(t, map[string]string{"shared.wanf": "port = 1\n"})
+	cache := NewImportCache()
+	type config struct {
+		Port int `wanf:"port"`
+	}
+	decode := func() int {
+		t.Helper()
+		dec, err := NewDecoder(strings.NewReader("import \"shared.wanf\"\n"), WithBasePath(dir), WithImportCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c config
+		if err := dec.Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+		return c.Port
+	}
+
+	if got := decode(); got != 1 {
+		t.Fatalf("port = %d, want 1", got)
+	}
+	path, _ := filepath.Abs(filepath.Join(dir, "shared.wanf"))
+	first := cache.entries[path].program
+	if first == nil {
+		t.Fatal("import was not cached")
+	}
+	if decode(); cache.entries[path].program != first {
+		t.Error("unchanged import was parsed again")
+	}
+
+	if err := os.WriteFile(path, []byte("port = 22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := decode(); got != 22 {
+		t.Errorf("port after change = %d, want 22", got)
+	}
+}
@@ -0,0 +1,101 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInputTooLarge 表示输入超出了 WithMaxSize 设置的大小.
+var ErrInputTooLarge = errors.New("wanf: input exceeds the maximum size")
+
+// WithMaxDepth 限制块、列表与映射的嵌套深度为 n 层, 更深的文档在解析时即被
+// 拒绝, 防止恶意或损坏的输入耗尽栈空间. 限制同样作用于导入的文件与
+// StreamDecoder. n <= 0 表示不限制, 这也是默认值.
+func WithMaxDepth(n int) DecoderOption {
+	return func(d *internalDecoder) {
+		d.limits.maxDepth = n
+	}
+}
+
+// WithMaxSize 限制输入的字节数, 超出时返回包装了 ErrInputTooLarge 的错误,
+// 且不会把超出的部分读入内存. 限制分别作用于主文档与每个导入的文件;
+// StreamDecoder 按整个流计算. n <= 0 表示不限制, 这也是默认值.
+//
+// 通过网络接收 WANF 时应与 WithMaxDepth 和 WithSandbox 一起使用.
+func WithMaxSize(n int64) DecoderOption {
+	return func(d *internalDecoder) {
+		d.limits.maxSize = n
+	}
+}
+
+// inputLimits 是 WithMaxDepth 与 WithMaxSize 设置的限制, 零值表示不限制.
+type inputLimits struct {
+	maxDepth int
+	maxSize  int64
+}
+
+// checkSize 检查 n 字节的输入是否超出大小限制.
+func (l inputLimits) checkSize(n int64) error {
+	if l.maxSize > 0 && n > l.maxSize {
+		return l.sizeError()
+	}
+	return nil
+}
+
+func (l inputLimits) sizeError() error {
+	return fmt.Errorf("%w of %d bytes", ErrInputTooLarge, l.maxSize)
+}
+
+// readAll 读取 r 的全部内容, 最多比大小限制多读 1 字节.
+func (l inputLimits) readAll(r io.Reader) ([]byte, error) {
+	if l.maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, l.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if err := l.checkSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readFile 读取文件 path, 超出大小限制的文件不会被读入.
+func (l inputLimits) readFile(path string) ([]byte, error) {
+	if l.maxSize <= 0 {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return l.readAll(f)
+}
+
+// sizeLimitReader 在读满 n 字节后报告 io.EOF, 并记录输入是否还有更多内容.
+// 流式词法分析器把 io.EOF 视为输入结束, 因此由 StreamDecoder 检查 exceeded.
+type sizeLimitReader struct {
+	r        io.Reader
+	n        int64 // 还可以读取的字节数
+	exceeded bool
+}
+
+func (s *sizeLimitReader) Read(p []byte) (int, error) {
+	if s.n <= 0 {
+		var b [1]byte
+		if n, _ := io.ReadFull(s.r, b[:]); n > 0 {
+			s.exceeded = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.n {
+		p = p[:s.n]
+	}
+	n, err := s.r.Read(p)
+	s.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,134 @@
+package wanf
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type limitsConfig struct {
+	Name  string `wanf:"name"`
+	Outer struct {
+		Inner struct {
+			Ports []int `wanf:"ports"`
+		} `wanf:"inner"`
+	} `wanf:"outer"`
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	ok := "outer {\n\tinner {\n\t\tports = [80]\n\t}\n}\n"
+	deep := "name = \"x\"\nvalues = " + strings.Repeat("[", 100000) + "\n"
+	tests := []struct {
+		src  string
+		max  int
+		want string
+	}{
+		{ok, 3, ""},
+		{ok, 0, ""},
+		{ok, 2, "nesting depth exceeds the maximum of 2"},
+		{deep, 10, "nesting depth exceeds the maximum of 10"},
+	}
+	for _, tt := range tests {
+		for name, decode := range map[string]func(v *limitsConfig) error{
+			"Decoder": func(v *limitsConfig) error {
+				dec, err := NewDecoder(strings.NewReader(tt.src), WithMaxDepth(tt.max))
+				if err != nil {
+					return err
+				}
+				return dec.Decode(v)
+			},
+			"StreamDecoder": func(v *limitsConfig) error {
+				dec, err := NewStreamDecoder(strings.NewReader(tt.src), WithMaxDepth(tt.max))
+				if err != nil {
+					return err
+				}
+				return dec.Decode(v)
+			},
+		} {
+			var cfg limitsConfig
+			err := decode(&cfg)
+			if tt.want == "" {
+				if err != nil || len(cfg.Outer.Inner.Ports) != 1 {
+					t.Errorf("%s with max %d: %v, %+v", name, tt.max, err, cfg)
+				}
+				continue
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s with max %d: error = %v, want %q", name, tt.max, err, tt.want)
+			}
+		}
+	}
+
+	// 超出限制后停止解析, 只报告一个错误.
+	p := NewParser(NewLexer([]byte(deep)))
+	p.SetMaxDepth(10)
+	p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 1 || errs[0].Line != 2 || errs[0].Column != 20 {
+		t.Errorf("Errors() = %v, want one error at 2:20", errs)
+	}
+}
+
+func TestWithMaxSize(t *testing.T) {
+	src := "name = \"app\"\n"
+	dir := t.TempDir()
+	small := writeLayer(t, dir, "small.wanf", src)
+	big := writeLayer(t, dir, "big.wanf", src+"// padding\n")
+	importsBig := writeLayer(t, dir, "main.wanf", "import \"big.wanf\"\n")
+
+	n := int64(len(src))
+	decodeReader := func(data string) error {
+		var cfg limitsConfig
+		dec, err := NewDecoder(strings.NewReader(data), WithMaxSize(n))
+		if err != nil {
+			return err
+		}
+		return dec.Decode(&cfg)
+	}
+	decodeStream := func(data string) error {
+		var cfg limitsConfig
+		dec, err := NewStreamDecoder(strings.NewReader(data), WithMaxSize(n))
+		if err != nil {
+			return err
+		}
+		return dec.Decode(&cfg)
+	}
+	decodeFile := func(path string) error {
+		var cfg limitsConfig
+		return DecodeFile(path, &cfg, WithMaxSize(n))
+	}
+	planDecode := func(data string) error {
+		var cfg limitsConfig
+		return CompileType(reflect.TypeOf(cfg)).Decode([]byte(data), &cfg, WithMaxSize(n))
+	}
+
+	for name, err := range map[string]error{
+		"NewDecoder":    decodeReader(src),
+		"StreamDecoder": decodeStream(src),
+		"DecodeFile":    decodeFile(small),
+		"Plan":          planDecode(src),
+	} {
+		if err != nil {
+			t.Errorf("%s at the limit: %v", name, err)
+		}
+	}
+	for name, err := range map[string]error{
+		"NewDecoder":    decodeReader(src + "\n"),
+		"StreamDecoder": decodeStream(src + "name = \"other\"\n"),
+		"DecodeFile":    decodeFile(big),
+		"import":        decodeFile(importsBig),
+		"Plan":          planDecode(src + "\n"),
+	} {
+		if !errors.Is(err, ErrInputTooLarge) {
+			t.Errorf("%s over the limit: error = %v, want ErrInputTooLarge", name, err)
+		}
+	}
+
+	// 截断处恰好在字符串中间时仍报告大小而不是语法错误.
+	var cfg limitsConfig
+	dec, _ := NewStreamDecoder(bytes.NewReader([]byte("name = \"a long value\"\n")), WithMaxSize(10))
+	if err := dec.Decode(&cfg); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("StreamDecoder error = %v, want ErrInputTooLarge", err)
+	}
+}
@@ -0,0 +1,260 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// jsonSchemaDraft 是 SchemaOf 输出的 JSON Schema 版本.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern 匹配 time.ParseDuration 接受的 duration 字符串.
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// SchemaOf 反射 v 的结构体类型 (v 可以是结构体或其指针), 输出描述其 WANF 数据
+// 模型的 JSON Schema (draft 2020-12), 供编辑器校验与文档工具使用. 键名取自
+// `wanf` 标签, 其中由解码器检查的约束 (min, max, oneof 与 regex) 写为 minimum,
+// enum, pattern 等关键字. 此外支持以下标签:
+//
+//	default:"8080"   默认值, 按字段类型解析
+//	desc:"监听端口"    字段说明
+//
+// min 与 max 对数值限制大小, 对字符串, 列表与 map 限制长度. time.Duration
+// 表示为 duration 字符串 (如 "30s"). 命名结构体类型放在 $defs 中引用, 因此
+// 递归类型也可以表示.
+func SchemaOf(v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("wanf: SchemaOf needs a struct, got %v", reflect.TypeOf(v))
+	}
+	g := &schemaGen{defs: make(map[string]interface{}), names: make(map[reflect.Type]string)}
+	root, err := g.structSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = jsonSchemaDraft
+	if t.Name() != "" {
+		root["title"] = t.Name()
+	}
+	if len(g.defs) > 0 {
+		root["$defs"] = g.defs
+	}
+	out, err := json.Marshal(root, json.Deterministic(true), jsontext.Multiline(true), jsontext.WithIndent("  "))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// schemaGen 记录已生成的命名结构体定义.
+type schemaGen struct {
+	defs  map[string]interface{}
+	names map[reflect.Type]string
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	props := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := parseWanfTag(f.Tag.Get("wanf"), f.Name)
+		if tag.Labels {
+			continue
+		}
+		s, err := g.typeSchema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if err := applySchemaTags(s, f, tag); err != nil {
+			return nil, fmt.Errorf("wanf: field %s.%s: %w", t.Name(), f.Name, err)
+		}
+		props[tag.Name] = s
+	}
+	s := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	return s, nil
+}
+
+// typeSchema 返回类型 t 的 schema. 返回的 map 是新建的, 调用方可以添加关键字.
+func (g *schemaGen) typeSchema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return map[string]interface{}{"type": "string", "format": "duration", "pattern": durationPattern}, nil
+	}
+	if et := lookupEnum(t); et != nil {
+		names := make([]interface{}, len(et.names))
+		for i, name := range et.names {
+			names[i] = name
+		}
+		return map[string]interface{}{"type": "string", "enum": names}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s is not supported", t.Key())
+		}
+		values, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.defName(t)
+			g.names[t] = name
+			g.defs[name] = nil // 占位, 使递归引用能找到名称
+			s, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.defs[name] = s
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}, nil
+	}
+	return nil, fmt.Errorf("type %s is not supported", t)
+}
+
+// defName 返回 t 在 $defs 中的名称, 不同包的同名类型加上数字后缀.
+func (g *schemaGen) defName(t reflect.Type) string {
+	name := t.Name()
+	for i := 2; ; i++ {
+		if _, taken := g.defs[name]; !taken {
+			return name
+		}
+		name = t.Name() + strconv.Itoa(i)
+	}
+}
+
+// applySchemaTags 将字段的 default 与 desc 标签以及 wanf 标签中的约束写入 s.
+func applySchemaTags(s map[string]interface{}, f reflect.StructField, tag wanfTag) error {
+	ft := f.Type
+	for ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if desc := f.Tag.Get("desc"); desc != "" {
+		s["description"] = desc
+	}
+	if def, ok := f.Tag.Lookup("default"); ok {
+		v, err := parseTagValue(ft, def)
+		if err != nil {
+			return fmt.Errorf("default %q: %w", def, err)
+		}
+		s["default"] = v
+	}
+	for _, bound := range []struct{ name, arg string }{{"min", tag.Min}, {"max", tag.Max}} {
+		if bound.arg == "" {
+			continue
+		}
+		if ft == durationType {
+			// duration 以字符串表示, JSON Schema 无法比较其大小.
+			if _, err := time.ParseDuration(bound.arg); err != nil {
+				return fmt.Errorf("%s=%q: not a duration", bound.name, bound.arg)
+			}
+			continue
+		}
+		n, err := strconv.ParseFloat(bound.arg, 64)
+		if err != nil {
+			return fmt.Errorf("%s=%q: not a number", bound.name, bound.arg)
+		}
+		s[boundKeyword(ft, bound.name)] = n
+	}
+	if len(tag.OneOf) > 0 {
+		var values []interface{}
+		for _, item := range tag.OneOf {
+			v, err := parseTagValue(ft, item)
+			if err != nil {
+				return fmt.Errorf("oneof value %q: %w", item, err)
+			}
+			values = append(values, v)
+		}
+		s["enum"] = values
+	}
+	if tag.Regex != "" {
+		s["pattern"] = tag.Regex
+	}
+	if tag.Secret {
+		// JSON Schema 以 writeOnly 标记密码等不应回显的值.
+		s["writeOnly"] = true
+	}
+	return nil
+}
+
+// boundKeyword 返回 min 或 max 对应的 JSON Schema 关键字.
+func boundKeyword(t reflect.Type, name string) string {
+	var suffix string
+	switch t.Kind() {
+	case reflect.String:
+		suffix = "Length"
+	case reflect.Slice, reflect.Array:
+		suffix = "Items"
+	case reflect.Map:
+		suffix = "Properties"
+	default:
+		if name == "min" {
+			return "minimum"
+		}
+		return "maximum"
+	}
+	return name + suffix
+}
+
+// parseTagValue 按字段类型解析标签中的值.
+func parseTagValue(t reflect.Type, s string) (interface{}, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return durationLiteral(d), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return s, nil
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	}
+	return nil, fmt.Errorf("values of type %s cannot be given in a tag", t)
+}
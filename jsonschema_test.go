@@ -0,0 +1,135 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaTestNode struct {
+	Name     string            `wanf:"name"`
+	Children []*schemaTestNode `wanf:"child"`
+}
+
+type schemaTestConfig struct {
+	Listen  string                    `wanf:"listen" default:":8080" desc:"监听地址"`
+	Port    int                       `wanf:"port,min=1,max=65535"`
+	Level   string                    `wanf:"level,oneof=debug info"`
+	Timeout time.Duration             `wanf:"timeout" default:"1m30s"`
+	Tags    []string                  `wanf:"tags,max=3"`
+	Ratio   float64                   `wanf:"ratio"`
+	Debug   bool                      `wanf:"debug" default:"true"`
+	Env     map[string]string         `wanf:"env"`
+	Routes  map[string]schemaTestNode `wanf:"route"`
+	Labels  []string                  `wanf:",labels"`
+	secret  string
+}
+
+func TestSchemaOf(t *testing.T) {
+	got, err := SchemaOf(&schemaTestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$defs": {
+    "schemaTestNode": {
+      "additionalProperties": false,
+      "properties": {
+        "child": {
+          "items": {
+            "$ref": "#/$defs/schemaTestNode"
+          },
+          "type": "array"
+        },
+        "name": {
+          "type": "string"
+        }
+      },
+      "type": "object"
+    }
+  },
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "additionalProperties": false,
+  "properties": {
+    "debug": {
+      "default": true,
+      "type": "boolean"
+    },
+    "env": {
+      "additionalProperties": {
+        "type": "string"
+      },
+      "type": "object"
+    },
+    "level": {
+      "enum": [
+        "debug",
+        "info"
+      ],
+      "type": "string"
+    },
+    "listen": {
+      "default": ":8080",
+      "description": "监听地址",
+      "type": "string"
+    },
+    "port": {
+      "maximum": 65535,
+      "minimum": 1,
+      "type": "integer"
+    },
+    "ratio": {
+      "type": "number"
+    },
+    "route": {
+      "additionalProperties": {
+        "$ref": "#/$defs/schemaTestNode"
+      },
+      "type": "object"
+    },
+    "tags": {
+      "items": {
+        "type": "string"
+      },
+      "maxItems": 3,
+      "type": "array"
+    },
+    "timeout": {
+      "default": "90s",
+      "format": "duration",
+      "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$",
+      "type": "string"
+    }
+  },
+  "title": "schemaTestConfig",
+  "type": "object"
+}
+`
+	if string(got) != want {
+		t.Errorf("SchemaOf() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchemaOf_Errors(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{42, "needs a struct"},
+		{struct {
+			Port int `default:"eighty"`
+		}{}, `default "eighty"`},
+		{struct {
+			Port int `wanf:"port,min=one"`
+		}{}, `min="one": not a number`},
+		{struct {
+			M map[int]string
+		}{}, "map key type int is not supported"},
+	}
+	for _, tt := range tests {
+		_, err := SchemaOf(tt.v)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SchemaOf(%T) error = %v, want containing %q", tt.v, err, tt.want)
+		}
+	}
+}
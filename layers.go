@@ -0,0 +1,176 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// DecodeFiles 按顺序读取 paths 中的文件, 以 Merge (MergeReplaceLists) 的
+// 规则深度合并后解码到 v, 后面的文件优先, 适用于 系统配置 -> 用户配置 ->
+// 本地覆盖 这样的分层配置:
+//
+//	err := wanf.DecodeFiles(&cfg, "/etc/app/app.wanf", userPath, "app.local.wanf")
+//
+// 不存在的文件被跳过, 因此可以列出可选的层; 其余读取或解析错误会返回.
+// 每个文件的相对 import 按其自身所在目录解析. var 在各层之间共享,
+// 后面的层中同名的声明覆盖前面的声明, 也会影响前面的层中引用它的值.
+func DecodeFiles(v interface{}, paths ...string) error {
+	_, err := decodeFiles(v, paths, false)
+	return err
+}
+
+// DecodeFilesSources 与 DecodeFiles 相同, 并返回每个最终值来自哪个文件.
+// 键是值的路径, 语法与 Lookup 相同, 如 server."main".port; 值是提供它的文件,
+// 即 paths 中的元素. 映射与块按键展开, 列表作为整体记录. 通过 import 引入
+// 的值记为导入它的文件.
+func DecodeFilesSources(v interface{}, paths ...string) (map[string]string, error) {
+	return decodeFiles(v, paths, true)
+}
+
+// DecodeDir 将目录 dir 中的每个 *.wanf 文件作为片段合并为一个文档后解码到 v,
+// 适用于 Kubernetes ConfigMap 挂载的目录 (每个键一个文件). 文件按名称排序后
+// 依次以 DecodeFiles 的规则合并, 同名的键以排在后面的文件为准. 以 '.' 开头的
+// 文件 (如 ConfigMap 的 ..data) 与子目录被忽略, 符号链接按其指向的文件读取.
+//
+// 读取、解析与导入的错误带有文件名; 标签约束等 *ValidationError 的 File
+// 字段指出出错的值所在的文件.
+func DecodeDir(dir string, v interface{}) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || filepath.Ext(name) != ".wanf" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+	_, err = decodeFiles(v, paths, false)
+	return err
+}
+
+func decodeFiles(v interface{}, paths []string, trackSources bool) (map[string]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("v must be a pointer to a struct")
+	}
+	merged := &RootNode{}
+	// origin 记录每个节点来自哪个文件, 用于 sources 与错误的归属.
+	origin := make(map[Node]string)
+	for _, path := range paths {
+		layer, err := loadLayer(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		Walk(layer, func(n Node) bool {
+			origin[n] = path
+			return true
+		})
+		Merge(merged, layer, MergeReplaceLists)
+	}
+	dec, err := newProgramDecoder(merged)
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(v); err != nil {
+		return nil, attributeError(err, dec.program, origin)
+	}
+	if !trackSources {
+		return nil, nil
+	}
+	sources := make(map[string]string)
+	Inspect(dec.program, func(c *Cursor) bool {
+		if _, ok := c.Node.(*ListLiteral); ok {
+			return false
+		}
+		if s, ok := c.Node.(*AssignStatement); ok {
+			switch s.Value.(type) {
+			case *MapLiteral, *BlockLiteral:
+			default:
+				sources[joinPath(c.Path, string(s.Name.Value))] = origin[s.Value]
+			}
+		}
+		return true
+	})
+	return sources, nil
+}
+
+// attributeError 为 err 中的 *DecodeError 与 *ValidationError 填写所在的文件.
+// 对 *ValidationError, 在合并后的 program 中查找位于其行列的同名键, 只有一个
+// 文件符合时才填写.
+func attributeError(err error, program *RootNode, origin map[Node]string) error {
+	var derr *DecodeError
+	if errors.As(err, &derr) {
+		if derr.File == "" {
+			derr.File = origin[derr.stmt]
+		}
+		return err
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.File != "" || verr.Line == 0 {
+		return err
+	}
+	var file string
+	ambiguous := false
+	Walk(program, func(n Node) bool {
+		var name *Identifier
+		switch s := n.(type) {
+		case *AssignStatement:
+			name = s.Name
+		case *BlockStatement:
+			name = s.Name
+		default:
+			return true
+		}
+		if name.Token.Line != verr.Line || name.Token.Column != verr.Column || string(name.Value) != verr.Key {
+			return true
+		}
+		if f := origin[n]; file == "" {
+			file = f
+		} else if f != file {
+			ambiguous = true
+		}
+		return true
+	})
+	if !ambiguous {
+		verr.File = file
+	}
+	return err
+}
+
+// loadLayer 读取并解析一个文件, 迁移其版本并展开其中的 import.
+func loadLayer(path string) (*RootNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := parse(data, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := Migrate(layer); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	layer.Statements, err = processImports(layer.Statements, filepath.Dir(path), make(map[string]bool), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return layer, nil
+}
@@ -0,0 +1,141 @@
+package wanf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type layeredConfig struct {
+	Name    string            `wanf:"name"`
+	Timeout time.Duration     `wanf:"timeout"`
+	Hosts   []string          `wanf:"hosts"`
+	Labels  map[string]string `wanf:"labels"`
+	Server  map[string]struct {
+		Port  int  `wanf:"port"`
+		Debug bool `wanf:"debug"`
+	} `wanf:"server"`
+}
+
+func writeLayer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDecodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	system := writeLayer(t, dir, "etc/app.wanf", `import "defaults.wanf"
+var env_name = "prod"
+name = "app-${env_name}"
+hosts = ["a", "b"]
+labels = {[team = "core", tier = "web"]}
+server "main" {
+	port = 80
+	debug = false
+}
+`)
+	writeLayer(t, dir, "etc/defaults.wanf", "timeout = 5s\n")
+	user := writeLayer(t, dir, "home/app.wanf", `var env_name = "dev"
+hosts = ["c"]
+labels = {[tier = "api"]}
+server "main" {
+	debug = true
+}
+`)
+	missing := filepath.Join(dir, "app.local.wanf")
+
+	var cfg layeredConfig
+	sources, err := DecodeFilesSources(&cfg, system, user, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "app-dev" || cfg.Timeout != 5*time.Second {
+		t.Errorf("name = %q, timeout = %v", cfg.Name, cfg.Timeout)
+	}
+	if !reflect.DeepEqual(cfg.Hosts, []string{"c"}) {
+		t.Errorf("hosts = %v, want [c]", cfg.Hosts)
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "core", "tier": "api"}) {
+		t.Errorf("labels = %v", cfg.Labels)
+	}
+	if s := cfg.Server["main"]; s.Port != 80 || !s.Debug {
+		t.Errorf("server main = %+v", s)
+	}
+	want := map[string]string{
+		"name":                system,
+		"timeout":             system,
+		"hosts":               user,
+		"labels.team":         system,
+		"labels.tier":         user,
+		`server."main".port`:  system,
+		`server."main".debug`: user,
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+}
+
+func TestDecodeFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeLayer(t, dir, "bad.wanf", "name = \n")
+	var cfg layeredConfig
+	if err := DecodeFiles(&cfg, bad); err == nil {
+		t.Error("expected parse error")
+	}
+	if err := DecodeFiles(cfg, bad); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+	if err := DecodeFiles(&cfg, filepath.Join(dir, "none.wanf")); err != nil {
+		t.Errorf("missing files should be skipped: %v", err)
+	}
+}
+
+func TestDecodeDir(t *testing.T) {
+	dir := t.TempDir()
+	writeLayer(t, dir, "10-base.wanf", "name = \"base\"\ntimeout = 5s\n")
+	writeLayer(t, dir, "20-server.wanf", "server \"main\" {\n\tport = 80\n}\n")
+	writeLayer(t, dir, "30-override.wanf", "name = \"override\"\n")
+	writeLayer(t, dir, "..2024_01_01/ignored.wanf", "name = \"hidden\"\n")
+	writeLayer(t, dir, ".hidden.wanf", "name = \"hidden\"\n")
+	writeLayer(t, dir, "README.txt", "not wanf")
+
+	var cfg layeredConfig
+	if err := DecodeDir(dir, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "override" || cfg.Timeout != 5*time.Second || cfg.Server["main"].Port != 80 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	writeLayer(t, dir, "40-bad.wanf", "name = \n")
+	err := DecodeDir(dir, &cfg)
+	if err == nil || !strings.Contains(err.Error(), "40-bad.wanf") {
+		t.Errorf("parse error = %v, want it to name 40-bad.wanf", err)
+	}
+}
+
+func TestDecodeDirValidationFile(t *testing.T) {
+	dir := t.TempDir()
+	writeLayer(t, dir, "a.wanf", "name = \"app\"\n")
+	bad := writeLayer(t, dir, "b.wanf", "\nport = 0\n")
+	var cfg struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port,min=1"`
+	}
+	err := DecodeDir(dir, &cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.File != bad || verr.Line != 2 {
+		t.Fatalf("err = %v, want a validation error in %s on line 2", err, bad)
+	}
+}
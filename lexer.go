@@ -1,16 +1,29 @@
 package wanf
 
-import (
-	"bytes"
-	"unicode"
-)
+import "bytes"
 
 var singleCharByteSlices [256][]byte
 
+// 字节分类, 用查表代替逐字节的范围比较与 unicode.IsDigit.
+const (
+	classIdentStart uint8 = 1 << iota // a-z, A-Z, _
+	classDigit                        // 0-9
+)
+
+var byteClass [256]uint8
+
 func init() {
 	for i := 0; i < 256; i++ {
 		singleCharByteSlices[i] = []byte{byte(i)}
 	}
+	for c := 'a'; c <= 'z'; c++ {
+		byteClass[c] |= classIdentStart
+		byteClass[c-'a'+'A'] |= classIdentStart
+	}
+	byteClass['_'] |= classIdentStart
+	for c := '0'; c <= '9'; c++ {
+		byteClass[c] |= classDigit
+	}
 }
 
 type Lexer struct {
@@ -22,8 +35,15 @@ type Lexer struct {
 	column       int
 }
 
+// utf8BOM 是 Windows 编辑器常在文件开头写入的 UTF-8 字节序标记, 词法分析时
+// 被跳过, 不计入列号.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 func NewLexer(input []byte) *Lexer {
 	l := &Lexer{input: input, line: 1}
+	if bytes.HasPrefix(input, utf8BOM) {
+		l.readPosition = len(utf8BOM)
+	}
 	l.readChar()
 	return l
 }
@@ -39,7 +59,21 @@ func (l *Lexer) readChar() {
 	l.column++
 }
 
+// NextToken 返回下一个标记, 并记录其起止位置.
 func (l *Lexer) NextToken() Token {
+	l.skipWhitespace()
+	offset := l.position
+	tok := l.nextToken()
+	tok.Offset = offset
+	if tok.Type == EOF {
+		tok.EndLine, tok.EndColumn, tok.EndOffset = tok.Line, tok.Column, offset
+		return tok
+	}
+	tok.EndLine, tok.EndColumn, tok.EndOffset = l.line, l.column, min(l.position, len(l.input))
+	return tok
+}
+
+func (l *Lexer) nextToken() Token {
 	var tok Token
 	l.skipWhitespace()
 	line, col := l.line, l.column
@@ -76,8 +110,14 @@ func (l *Lexer) NextToken() Token {
 			tok = l.newToken(ILLEGAL, l.ch, line, col)
 		}
 	case '"', '\'', '`':
-		tok.Type = STRING
-		tok.Literal = l.readString()
+		literal, ok := l.readString()
+		if ok {
+			tok.Type = STRING
+			tok.Literal = literal
+		} else {
+			tok.Type = ILLEGAL
+			tok.Literal = []byte("unterminated string literal")
+		}
 		tok.Line = line
 		tok.Column = col
 		return tok
@@ -106,7 +146,8 @@ func (l *Lexer) NextToken() Token {
 	case 0:
 		tok.Literal = []byte{}
 		tok.Type = EOF
-		l.readChar()
+		tok.Line = line
+		tok.Column = col
 		return tok
 	default:
 		if isIdentifierStart(l.ch) {
@@ -116,15 +157,15 @@ func (l *Lexer) NextToken() Token {
 			tok.Line = line
 			tok.Column = col
 			return tok
-		} else if unicode.IsDigit(rune(l.ch)) {
-			literal := l.readNumber()
+		} else if isDigit(l.ch) {
+			literal, isFloat := l.readNumber()
 			if l.ch == 's' || l.ch == 'm' || l.ch == 'h' || (l.ch == 'u' && l.peekChar() == 's') || (l.ch == 'n' && l.peekChar() == 's') || (l.ch == 'm' && l.peekChar() == 's') {
 				startPos := l.position - len(literal)
 				l.readDurationSuffix()
 				tok.Type = DUR
 				tok.Literal = l.input[startPos:l.position]
 			} else {
-				if bytes.Contains(literal, []byte(".")) {
+				if isFloat {
 					tok.Type = FLOAT
 				} else {
 					tok.Type = INT
@@ -161,7 +202,8 @@ func (l *Lexer) skipWhitespace() {
 }
 func (l *Lexer) readSingleLineComment() []byte {
 	position := l.position
-	for l.ch != '\n' && l.ch != 0 {
+	// 行尾的 \r\n 中的 \r 不属于注释.
+	for l.ch != '\n' && l.ch != 0 && (l.ch != '\r' || l.peekChar() != '\n') {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -189,36 +231,68 @@ func (l *Lexer) readMultiLineComment() ([]byte, bool) {
 	return l.input[position:l.position], true // closed
 }
 
+// advanceTo 将当前字符移到 input[end], 等价于逐个调用 readChar, 要求跳过的
+// 字节中没有换行.
+func (l *Lexer) advanceTo(end int) {
+	l.column += end - l.position
+	l.position = end
+	l.readPosition = end + 1
+	if end < len(l.input) {
+		l.ch = l.input[end]
+	} else {
+		l.ch = 0
+	}
+}
+
 func (l *Lexer) readIdentifier() []byte {
 	position := l.position
-	for isIdentifierChar(l.ch) {
-		l.readChar()
+	end := position
+	for end < len(l.input) && byteClass[l.input[end]]&(classIdentStart|classDigit) != 0 {
+		end++
 	}
-	return l.input[position:l.position]
+	l.advanceTo(end)
+	return l.input[position:end]
 }
-func (l *Lexer) readNumber() []byte {
+
+// readNumber 读取数字, 同时报告其中是否有小数点.
+func (l *Lexer) readNumber() ([]byte, bool) {
 	position := l.position
+	end := position
 	isFloat := false
-	for unicode.IsDigit(rune(l.ch)) || (l.ch == '.' && !isFloat) {
-		if l.ch == '.' {
+	for end < len(l.input) {
+		c := l.input[end]
+		if c == '.' && !isFloat {
 			isFloat = true
+		} else if byteClass[c]&classDigit == 0 {
+			break
 		}
-		l.readChar()
+		end++
 	}
-	return l.input[position:l.position]
+	l.advanceTo(end)
+	return l.input[position:end], isFloat
 }
-func (l *Lexer) readString() []byte {
+
+// readString 读取以当前字符为引号的字符串, 返回不含引号的内容. 到达输入
+// 末尾仍未遇到结尾的引号时 ok 为 false.
+func (l *Lexer) readString() (literal []byte, ok bool) {
 	quote := l.ch
 	position := l.position + 1
 	for {
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
+		}
 		l.readChar()
-		if l.ch == quote || l.ch == 0 {
+		if l.ch == quote {
 			break
 		}
+		if l.position >= len(l.input) {
+			return nil, false
+		}
 	}
-	literal := l.input[position:l.position]
+	literal = l.input[position:l.position]
 	l.readChar()
-	return literal
+	return literal, true
 }
 
 func (l *Lexer) readUntilEndOfLine() []byte {
@@ -241,8 +315,11 @@ func (l *Lexer) newToken(tokenType TokenType, ch byte, line, column int) Token {
 	return Token{Type: tokenType, Literal: singleCharByteSlices[ch], Line: line, Column: column}
 }
 func isIdentifierStart(ch byte) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+	return byteClass[ch]&classIdentStart != 0
 }
 func isIdentifierChar(ch byte) bool {
-	return isIdentifierStart(ch) || unicode.IsDigit(rune(ch))
+	return byteClass[ch]&(classIdentStart|classDigit) != 0
+}
+func isDigit(ch byte) bool {
+	return byteClass[ch]&classDigit != 0
 }
@@ -89,3 +89,101 @@ line2` + "`" + `
 		}
 	}
 }
+
+func TestNextTokenNumbersAndIdentifiers(t *testing.T) {
+	input := "a1_b = 42\nx=1.5.2 y2 3ms 7h\n_9 = 0.25"
+	tests := []struct {
+		typ          TokenType
+		lit          string
+		line, column int
+	}{
+		{IDENT, "a1_b", 1, 1},
+		{ASSIGN, "=", 1, 6},
+		{INT, "42", 1, 8},
+		{IDENT, "x", 2, 1},
+		{ASSIGN, "=", 2, 2},
+		{FLOAT, "1.5", 2, 3},
+		{ILLEGAL, ".", 2, 6},
+		{INT, "2", 2, 7},
+		{IDENT, "y2", 2, 9},
+		{DUR, "3ms", 2, 12},
+		{DUR, "7h", 2, 16},
+		{IDENT, "_9", 3, 1},
+		{ASSIGN, "=", 3, 4},
+		{FLOAT, "0.25", 3, 6},
+		{EOF, "", 3, 10},
+	}
+	for _, l := range []lexer{NewLexer([]byte(input)), newStreamLexer(strings.NewReader(input))} {
+		for i, tt := range tests {
+			tok := l.NextToken()
+			if tok.Type != tt.typ || string(tok.Literal) != tt.lit || tok.Line != tt.line || tok.Column != tt.column {
+				t.Fatalf("%T tests[%d] = %s %q at %d:%d, want %s %q at %d:%d",
+					l, i, tok.Type, tok.Literal, tok.Line, tok.Column, tt.typ, tt.lit, tt.line, tt.column)
+			}
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	for _, input := range []string{"a = 1\nb = \"abc\nc = 2\n", "a = 1\nb = 'abc", "a = 1\nb = `abc\n\n"} {
+		for _, l := range []lexer{NewLexer([]byte(input)), newStreamLexer(strings.NewReader(input))} {
+			var tok Token
+			for i := 0; i < 6; i++ {
+				tok = l.NextToken()
+			}
+			if tok.Type != ILLEGAL || string(tok.Literal) != "unterminated string literal" || tok.Line != 2 || tok.Column != 5 {
+				t.Errorf("%T %q: got %s %q at %d:%d", l, input, tok.Type, tok.Literal, tok.Line, tok.Column)
+			}
+			if tok = l.NextToken(); tok.Type != EOF {
+				t.Errorf("%T %q: got %s after unterminated string, want EOF", l, input, tok.Type)
+			}
+		}
+
+		_, err := Parse([]byte(input))
+		if err == nil || !strings.Contains(err.Error(), "line 2:5: parser error: unterminated string literal") {
+			t.Errorf("Parse(%q) error = %v", input, err)
+		}
+		var cfg struct {
+			B string `wanf:"b"`
+		}
+		dec, err := NewStreamDecoder(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.Decode(&cfg); err == nil || !strings.Contains(err.Error(), "line 2:5: unterminated string literal") {
+			t.Errorf("StreamDecoder(%q) error = %v", input, err)
+		}
+	}
+}
+
+func TestMultiLineTokenEnd(t *testing.T) {
+	input := "a = `x\ny`\n/* c\n  d */\nb = \"p\nq\" // e\n"
+	want := []struct {
+		typ                           TokenType
+		line, col, endLine, endColumn int
+	}{
+		{IDENT, 1, 1, 1, 2},
+		{ASSIGN, 1, 3, 1, 4},
+		{STRING, 1, 5, 2, 3},
+		{COMMENT, 3, 1, 4, 7},
+		{IDENT, 5, 1, 5, 2},
+		{ASSIGN, 5, 3, 5, 4},
+		{STRING, 5, 5, 6, 3},
+		{COMMENT, 6, 4, 6, 8},
+		{EOF, 7, 1, 7, 1},
+	}
+	for _, l := range []lexer{NewLexer([]byte(input)), newStreamLexer(strings.NewReader(input))} {
+		for i, w := range want {
+			tok := l.NextToken()
+			if tok.Type != w.typ || tok.Line != w.line || tok.Column != w.col || tok.EndLine != w.endLine || tok.EndColumn != w.endColumn {
+				t.Errorf("%T token %d = %s %d:%d-%d:%d, want %s %d:%d-%d:%d", l, i, tok.Type, tok.Line, tok.Column, tok.EndLine, tok.EndColumn, w.typ, w.line, w.col, w.endLine, w.endColumn)
+			}
+		}
+	}
+
+	// 诊断的范围覆盖整个多行标记.
+	_, errs := Lint([]byte("a = 1\n\"x\ny\"\n"))
+	if len(errs) == 0 || errs[0].Type != ErrUnexpectedToken || errs[0].Line != 2 || errs[0].Column != 1 || errs[0].EndLine != 3 || errs[0].EndColumn != 3 {
+		t.Errorf("Lint errors = %+v, want one spanning 2:1-3:3", errs)
+	}
+}
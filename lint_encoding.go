@@ -0,0 +1,78 @@
+package wanf
+
+import "bytes"
+
+// checkEncoding reports a leading UTF-8 byte order mark and CRLF line endings,
+// both typically left behind by Windows editors. The lexers accept them, so
+// they are only a notice suggesting to normalize the file; Fix removes them.
+// CRLF endings are reported once, at the first line that uses one; line breaks
+// inside string literals are part of the value and are not reported.
+func checkEncoding(data []byte) []LintError {
+	var errs []LintError
+	if bytes.HasPrefix(data, utf8BOM) {
+		errs = append(errs, LintError{
+			Line:      1,
+			Column:    1,
+			EndLine:   1,
+			EndColumn: 1,
+			Message:   "file starts with a UTF-8 byte order mark; save it without BOM",
+			Level:     ErrorLevelFmt,
+			Type:      ErrFileEncoding,
+		})
+	}
+	if !bytes.Contains(data, []byte("\r\n")) {
+		return errs
+	}
+	if crs := lineEndingCRs(data, scanTokenSpans(data)); len(crs) > 0 {
+		i := crs[0]
+		line := bytes.Count(data[:i], []byte("\n")) + 1
+		col := i - (bytes.LastIndexByte(data[:i], '\n') + 1) + 1
+		if line == 1 && bytes.HasPrefix(data, utf8BOM) {
+			col -= len(utf8BOM)
+		}
+		errs = append(errs, LintError{
+			Line:      line,
+			Column:    col,
+			EndLine:   line,
+			EndColumn: col + 1,
+			Message:   "file uses CRLF line endings; convert them to LF",
+			Level:     ErrorLevelFmt,
+			Type:      ErrFileEncoding,
+		})
+	}
+	return errs
+}
+
+// encodingEdits returns the edits that remove the byte order mark and turn
+// CRLF line endings into LF.
+func encodingEdits(data []byte, toks tokenSpans) []textEdit {
+	var edits []textEdit
+	if bytes.HasPrefix(data, utf8BOM) {
+		edits = append(edits, textEdit{start: 0, end: len(utf8BOM)})
+	}
+	for _, i := range lineEndingCRs(data, toks) {
+		edits = append(edits, textEdit{start: i, end: i + 1})
+	}
+	return edits
+}
+
+// lineEndingCRs returns the offsets of the '\r' of every CRLF line ending in
+// data. Line breaks inside string literals are part of the value and are
+// skipped.
+func lineEndingCRs(data []byte, toks tokenSpans) []int {
+	var crs []int
+	t := 0
+	for i := 0; i < len(data)-1; i++ {
+		if data[i] != '\r' || data[i+1] != '\n' {
+			continue
+		}
+		for t < len(toks) && toks[t].end <= i {
+			t++
+		}
+		if t < len(toks) && toks[t].typ == STRING && toks[t].start < i {
+			continue
+		}
+		crs = append(crs, i)
+	}
+	return crs
+}
@@ -0,0 +1,63 @@
+package wanf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLint_FileEncoding(t *testing.T) {
+	src := "\xef\xbb\xbfname = \"a\" // c\r\ntext = `x\r\ny`\r\ns {\r\n\tp = 2\r\n}\r\n"
+	_, errs := Lint([]byte(src))
+	var got []string
+	for _, e := range errs {
+		if e.Type != ErrFileEncoding || e.Severity != SeverityInfo || e.Rule != "WANF021" {
+			t.Errorf("unexpected issue %+v", e)
+		}
+		got = append(got, e.Error())
+	}
+	want := "line 1:1: file starts with a UTF-8 byte order mark; save it without BOM|line 1:16: file uses CRLF line endings; convert them to LF"
+	if strings.Join(got, "|") != want {
+		t.Errorf("got %q\nwant %q", strings.Join(got, "|"), want)
+	}
+
+	fixed, remaining, err := Fix([]byte(src))
+	if err != nil || len(remaining) != 0 {
+		t.Fatalf("Fix: %v, %v", remaining, err)
+	}
+	if want := "name = \"a\" // c\ntext = `x\r\ny`\ns {\n\tp = 2\n}\n"; string(fixed) != want {
+		t.Errorf("Fix = %q, want %q", fixed, want)
+	}
+
+	if _, errs := Lint([]byte("name = \"a\"\n")); len(errs) != 0 {
+		t.Errorf("LF file: %v", errs)
+	}
+}
+
+func TestDecode_BOMAndCRLF(t *testing.T) {
+	src := "\xef\xbb\xbf// config\r\nname = \"a\" // c\r\nport = 8080\r\n"
+	type config struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port"`
+	}
+	var cfg config
+	if err := Decode([]byte(src), &cfg); err != nil || cfg != (config{"a", 8080}) {
+		t.Errorf("Decode = %+v, %v", cfg, err)
+	}
+	var streamed config
+	dec, _ := NewStreamDecoder(strings.NewReader(src))
+	if err := dec.Decode(&streamed); err != nil || streamed != cfg {
+		t.Errorf("StreamDecoder = %+v, %v", streamed, err)
+	}
+
+	for _, l := range []lexer{NewLexer([]byte(src)), newStreamLexer(strings.NewReader(src))} {
+		var toks []string
+		for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+			toks = append(toks, fmt.Sprintf("%s@%d:%d", tok.Literal, tok.Line, tok.Column))
+		}
+		want := "// config@1:1 name@2:1 =@2:6 a@2:8 // c@2:12 port@3:1 =@3:6 8080@3:8"
+		if got := strings.Join(toks, " "); got != want {
+			t.Errorf("%T tokens = %q, want %q", l, got, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// checkImports reports imports whose file cannot be read and imports that
+// have no effect on the document. It only runs when a base path is set.
+//
+// An import has no effect when the imported file defines no keys or blocks,
+// or when every key it assigns is assigned again later in the importing
+// document (the later assignment wins).
+func (a *astAnalyzer) checkImports(root *RootNode) {
+	if a.opts.basePath == "" {
+		return
+	}
+	for i, stmt := range root.Statements {
+		imp, ok := stmt.(*ImportStatement)
+		if !ok {
+			continue
+		}
+		path := string(imp.Path.Value)
+		// 与 processImports 的路径解析方式保持一致.
+		data, err := os.ReadFile(filepath.Join(a.opts.basePath, path))
+		if err != nil {
+			a.addImportError(imp, ErrUnresolvedImport, fmt.Sprintf("cannot resolve import %q: %v", path, unwrapPathError(err)))
+			continue
+		}
+		p := NewParser(NewLexer(data))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			a.addImportError(imp, ErrUnresolvedImport, fmt.Sprintf("imported file %q has syntax errors: %v", path, p.Errors()[0]))
+			continue
+		}
+		if reason, unused := importUnused(program.Statements, root.Statements[i+1:]); unused {
+			a.addImportError(imp, ErrUnusedImport, fmt.Sprintf("import %q is unused: %s", path, reason))
+		}
+	}
+}
+
+// importUnused decides whether the statements of an imported file are
+// shadowed entirely by the statements that follow the import.
+func importUnused(imported, after []Statement) (string, bool) {
+	keys := make(map[string]bool)
+	for _, stmt := range imported {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			keys[string(s.Name.Value)] = true
+		case *BlockStatement, *ImportStatement:
+			// 块会与后续同名块合并, 嵌套导入也可能提供配置, 均视为有效.
+			return "", false
+		}
+	}
+	if len(keys) == 0 {
+		return "the imported file defines no keys", true
+	}
+	for _, stmt := range after {
+		if s, ok := stmt.(*AssignStatement); ok {
+			delete(keys, string(s.Name.Value))
+		}
+	}
+	if len(keys) == 0 {
+		return "every key it defines is assigned again later in this file", true
+	}
+	return "", false
+}
+
+// checkImportOrder reports imports that follow other statements, are not
+// sorted by path, or repeat an earlier import. Formatting with SortImports
+// resolves all of them.
+func (a *astAnalyzer) checkImportOrder(root *RootNode) {
+	if !a.opts.config.enabled(ErrImportOrder) {
+		return
+	}
+	seen := make(map[string]Token)
+	var prev []byte
+	afterOther := false
+	for _, stmt := range root.Statements {
+		imp, ok := stmt.(*ImportStatement)
+		if !ok {
+			afterOther = true
+			continue
+		}
+		path := string(imp.Path.Value)
+		if first, ok := seen[importKey(path)]; ok {
+			a.addImportError(imp, ErrImportOrder, fmt.Sprintf("import %q is already imported at line %d", path, first.Line))
+			continue
+		}
+		seen[importKey(path)] = imp.Token
+		switch {
+		case afterOther:
+			a.addImportError(imp, ErrImportOrder, fmt.Sprintf("import %q should be placed before other statements", path))
+		case bytes.Compare(imp.Path.Value, prev) < 0:
+			a.addImportError(imp, ErrImportOrder, fmt.Sprintf("import %q is not sorted, it should come before %q", path, prev))
+		default:
+			prev = imp.Path.Value
+		}
+	}
+}
+
+// importKey 返回用于判断两个导入是否重复的规范化路径.
+func importKey(path string) string {
+	return filepath.Clean(path)
+}
+
+// sortImports moves the import statements to the front of stmts, sorted by
+// path and with duplicates removed. Like goimports it does not preserve the
+// original position of an import; the comments of a dropped duplicate are
+// dropped with it.
+func sortImports(stmts []Statement) []Statement {
+	var imports, rest []Statement
+	seen := make(map[string]bool)
+	for _, stmt := range stmts {
+		imp, ok := stmt.(*ImportStatement)
+		if !ok {
+			rest = append(rest, stmt)
+			continue
+		}
+		key := importKey(string(imp.Path.Value))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		imports = append(imports, imp)
+	}
+	sort.SliceStable(imports, func(i, j int) bool {
+		return bytes.Compare(imports[i].(*ImportStatement).Path.Value, imports[j].(*ImportStatement).Path.Value) < 0
+	})
+	return append(imports, rest...)
+}
+
+func (a *astAnalyzer) addImportError(imp *ImportStatement, typ ErrorType, msg string) {
+	endLine, endColumn := endOf(imp.Path.Token, len(imp.Path.Value)+2)
+	a.errors = append(a.errors, LintError{
+		Line:      imp.Path.Token.Line,
+		Column:    imp.Path.Token.Column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Message:   msg,
+		Level:     ErrorLevelLint,
+		Type:      typ,
+		Args:      []string{string(imp.Path.Value)},
+	})
+}
+
+// unwrapPathError drops the path from *os.PathError, which is already part of the message.
+func unwrapPathError(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
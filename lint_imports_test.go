@@ -0,0 +1,96 @@
+package wanf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint_Imports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"db.wanf":       "database {\n\thost = \"localhost\"\n}\n",
+		"vars.wanf":     "var unused = 1\n",
+		"defaults.wanf": "log_level = \"info\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := `import "db.wanf"
+import "vars.wanf"
+import "defaults.wanf"
+import "missing.wanf"
+
+log_level = "debug"
+`
+	_, errs := Lint([]byte(src), WithLintBasePath(dir))
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Rule+" "+e.Message)
+	}
+	want := []string{
+		`WANF012 import "vars.wanf" is unused: the imported file defines no keys`,
+		`WANF012 import "defaults.wanf" is unused: every key it defines is assigned again later in this file`,
+		`WANF011 cannot resolve import "missing.wanf"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("issue %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+
+	// Without a base path imports are not checked.
+	if _, errs := Lint([]byte(src)); len(errs) != 0 {
+		t.Errorf("expected no issues without a base path, got %v", errs)
+	}
+}
+
+func TestLint_ImportOrder(t *testing.T) {
+	src := `import "b.wanf"
+import "a.wanf"
+log_level = "debug"
+import "./a.wanf"
+import "c.wanf"
+`
+	cfg, err := ParseLintConfig([]byte(`rule "import-order" { enabled = true }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, errs := Lint([]byte(src), WithLintConfig(cfg))
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Rule+" "+e.Message)
+	}
+	want := []string{
+		`WANF016 import "a.wanf" is not sorted, it should come before "b.wanf"`,
+		`WANF016 import "./a.wanf" is already imported at line 2`,
+		`WANF016 import "c.wanf" should be placed before other statements`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	formatted := string(Format(program, FormatOptions{Style: StyleStreaming, SortImports: true}))
+	wantFmt := `import "a.wanf"
+import "b.wanf"
+import "c.wanf"
+
+log_level = "debug"`
+	if formatted != wantFmt {
+		t.Errorf("formatted output mismatch:\n--- want\n%s\n--- got\n%s", wantFmt, formatted)
+	}
+	if _, errs := Lint([]byte(formatted), WithLintConfig(cfg)); len(errs) != 0 {
+		t.Errorf("formatted output still has issues: %v", errs)
+	}
+
+	// The rule is opt-in.
+	if _, errs := Lint([]byte(src)); len(errs) != 0 {
+		t.Errorf("expected no issues without config, got %v", errs)
+	}
+}
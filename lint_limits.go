@@ -0,0 +1,109 @@
+package wanf
+
+import "fmt"
+
+// 结构限制规则的默认上限, 可通过规则选项 `max` 调整.
+const (
+	defaultMaxDepth = 5
+	defaultMaxLines = 1000
+	defaultMaxKeys  = 50
+)
+
+// limitChecker 遍历 AST, 检查块的嵌套深度和键数量.
+type limitChecker struct {
+	a          *astAnalyzer
+	checkDepth bool
+	maxDepth   int
+	checkKeys  bool
+	maxKeys    int
+}
+
+// checkLimits reports blocks nested deeper than max-depth, files longer than
+// max-lines and blocks containing more than max-keys entries.
+func (a *astAnalyzer) checkLimits(root *RootNode) {
+	cfg := a.opts.config
+	if cfg.enabled(ErrMaxLines) {
+		max := cfg.intOption(ErrMaxLines, "max", defaultMaxLines)
+		if a.lines > max {
+			a.errors = append(a.errors, LintError{
+				Line:      max + 1,
+				Column:    1,
+				EndLine:   a.lines,
+				EndColumn: 1,
+				Message:   fmt.Sprintf("file has %d lines, more than the maximum of %d", a.lines, max),
+				Level:     ErrorLevelLint,
+				Type:      ErrMaxLines,
+				Args:      []string{fmt.Sprint(a.lines), fmt.Sprint(max)},
+			})
+		}
+	}
+	lc := &limitChecker{
+		a:          a,
+		checkDepth: cfg.enabled(ErrMaxDepth),
+		maxDepth:   cfg.intOption(ErrMaxDepth, "max", defaultMaxDepth),
+		checkKeys:  cfg.enabled(ErrMaxKeys),
+		maxKeys:    cfg.intOption(ErrMaxKeys, "max", defaultMaxKeys),
+	}
+	if lc.checkDepth || lc.checkKeys {
+		lc.walk(root, 0)
+	}
+}
+
+func (lc *limitChecker) walk(node Node, depth int) {
+	switch n := node.(type) {
+	case *RootNode:
+		for _, stmt := range n.Statements {
+			lc.walk(stmt, depth)
+		}
+	case *BlockStatement:
+		lc.block(n.Token, string(n.Name.Value), n.Body, depth+1)
+	case *BlockLiteral:
+		lc.block(n.Token, "{...}", n.Body, depth+1)
+	case *AssignStatement:
+		lc.walk(n.Value, depth)
+	case *VarStatement:
+		lc.walk(n.Value, depth)
+	case *ListLiteral:
+		for _, el := range n.Elements {
+			lc.walk(el, depth)
+		}
+	}
+}
+
+func (lc *limitChecker) block(tok Token, name string, body *RootNode, depth int) {
+	if body == nil {
+		return
+	}
+	// 只报告第一层越界的块, 其内部更深的块不再重复报告.
+	if lc.checkDepth && depth == lc.maxDepth+1 {
+		lc.report(tok, ErrMaxDepth, fmt.Sprintf("block %s is nested %d levels deep, more than the maximum of %d", name, depth, lc.maxDepth),
+			name, fmt.Sprint(depth), fmt.Sprint(lc.maxDepth))
+	}
+	if lc.checkKeys {
+		keys := 0
+		for _, stmt := range body.Statements {
+			switch stmt.(type) {
+			case *AssignStatement, *BlockStatement:
+				keys++
+			}
+		}
+		if keys > lc.maxKeys {
+			lc.report(tok, ErrMaxKeys, fmt.Sprintf("block %s has %d keys, more than the maximum of %d", name, keys, lc.maxKeys),
+				name, fmt.Sprint(keys), fmt.Sprint(lc.maxKeys))
+		}
+	}
+	lc.walk(body, depth)
+}
+
+func (lc *limitChecker) report(tok Token, typ ErrorType, msg string, args ...string) {
+	lc.a.errors = append(lc.a.errors, LintError{
+		Line:      tok.Line,
+		Column:    tok.Column,
+		EndLine:   tok.Line,
+		EndColumn: tok.Column + len(tok.Literal),
+		Message:   msg,
+		Level:     ErrorLevelLint,
+		Type:      typ,
+		Args:      args,
+	})
+}
@@ -0,0 +1,67 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_Limits(t *testing.T) {
+	cfg, err := ParseLintConfig([]byte(`
+rule "max-depth" {
+	enabled = true
+	options = {[
+		max = 2,
+	]}
+}
+
+rule "max-lines" {
+	enabled = true
+	options = {[
+		max = 8,
+	]}
+}
+
+rule "max-keys" {
+	enabled = true
+	options = {[
+		max = 2,
+	]}
+}
+`))
+	if err != nil {
+		t.Fatalf("ParseLintConfig failed: %v", err)
+	}
+	src := `a {
+	b {
+		c {
+			d {
+				x = 1
+			}
+		}
+	}
+}
+big {
+	k1 = 1
+	k2 = 2
+	k3 = 3
+}
+`
+	_, errs := Lint([]byte(src), WithLintConfig(cfg))
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Rule+": "+e.Message)
+	}
+	want := []string{
+		"WANF014: file has 14 lines, more than the maximum of 8",
+		"WANF013: block c is nested 3 levels deep, more than the maximum of 2",
+		"WANF015: block big has 3 keys, more than the maximum of 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The structural rules are opt-in.
+	if _, errs := Lint([]byte(src)); len(errs) != 0 {
+		t.Errorf("expected no issues by default, got %v", errs)
+	}
+}
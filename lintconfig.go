@@ -0,0 +1,204 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// LintConfigFileName 是 wanflint 默认查找的 lint 配置文件名.
+const LintConfigFileName = ".wanflint.wanf"
+
+// LintConfig 描述一组 lint 规则的配置, 通常从 .wanflint.wanf 读取:
+//
+//	rule "unused-variable" {
+//		severity = "info"
+//		options = {[
+//			ignore_prefix = "_",
+//		]}
+//	}
+//
+//	rule "redundant-comma" {
+//		enabled = false
+//	}
+//
+// 未出现在配置中的规则保持启用, 并使用默认严重程度.
+type LintConfig struct {
+	Rules map[string]RuleConfig `wanf:"rule"`
+}
+
+// RuleConfig 是单条规则的配置.
+type RuleConfig struct {
+	Enabled  *bool                  `wanf:"enabled"`
+	Severity string                 `wanf:"severity"`
+	Options  map[string]interface{} `wanf:"options"`
+}
+
+// LoadLintConfig reads and validates a lint configuration file.
+func LoadLintConfig(path string) (*LintConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ParseLintConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseLintConfig parses and validates a lint configuration document.
+// The document is decoded in sandbox mode.
+func ParseLintConfig(data []byte) (*LintConfig, error) {
+	cfg := &LintConfig{}
+	dec, err := NewDecoder(bytes.NewReader(data), WithSandbox())
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *LintConfig) validate() error {
+	for name, rule := range c.Rules {
+		if _, ok := ParseErrorType(name); !ok {
+			return fmt.Errorf("unknown lint rule %q", name)
+		}
+		if rule.Severity != "" {
+			if _, err := ParseSeverity(rule.Severity); err != nil {
+				return fmt.Errorf("rule %q: %w", name, err)
+			}
+		}
+		if t, _ := ParseErrorType(name); t == ErrNamingConvention {
+			if style, ok := rule.Options["style"]; ok && !isNamingStyle(style) {
+				return fmt.Errorf("rule %q: unknown style %v, expected %s or %s", name, style, styleSnakeCase, styleCamelCase)
+			}
+		}
+	}
+	return nil
+}
+
+// WithLintConfig applies a rule configuration to Lint.
+func WithLintConfig(cfg *LintConfig) LintOption {
+	return func(o *lintOptions) {
+		o.config = cfg
+	}
+}
+
+func (c *LintConfig) rule(t ErrorType) (RuleConfig, bool) {
+	if c == nil {
+		return RuleConfig{}, false
+	}
+	rule, ok := c.Rules[t.String()]
+	return rule, ok
+}
+
+// optInRules 默认关闭, 需要在配置中显式启用.
+var optInRules = map[ErrorType]bool{
+	ErrNamingConvention: true,
+	ErrMaxDepth:         true,
+	ErrMaxLines:         true,
+	ErrMaxKeys:          true,
+	ErrImportOrder:      true,
+}
+
+// enabled reports whether the rule for t should be reported.
+func (c *LintConfig) enabled(t ErrorType) bool {
+	rule, ok := c.rule(t)
+	if !ok || rule.Enabled == nil {
+		return !optInRules[t]
+	}
+	return *rule.Enabled
+}
+
+// severity returns the configured severity for e, falling back to the default.
+func (c *LintConfig) severity(e LintError) Severity {
+	if rule, ok := c.rule(e.Type); ok && rule.Severity != "" {
+		if s, err := ParseSeverity(rule.Severity); err == nil {
+			return s
+		}
+	}
+	return defaultSeverity(e)
+}
+
+// withRuleEnabled returns a copy of c in which the rule for t is enabled,
+// keeping its other settings. A rule that is already enabled keeps c as is.
+func (c *LintConfig) withRuleEnabled(t ErrorType) *LintConfig {
+	if c.enabled(t) {
+		return c
+	}
+	out := &LintConfig{Rules: make(map[string]RuleConfig)}
+	if c != nil {
+		for name, rule := range c.Rules {
+			out.Rules[name] = rule
+		}
+	}
+	rule := out.Rules[t.String()]
+	enabled := true
+	rule.Enabled = &enabled
+	out.Rules[t.String()] = rule
+	return out
+}
+
+// stringOption returns a string option of the rule for t.
+func (c *LintConfig) stringOption(t ErrorType, key string) string {
+	rule, _ := c.rule(t)
+	s, _ := rule.Options[key].(string)
+	return s
+}
+
+// intOption returns an integer option of the rule for t, or def if it is not set.
+func (c *LintConfig) intOption(t ErrorType, key string, def int) int {
+	rule, _ := c.rule(t)
+	switch v := rule.Options[key].(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// listOption returns a list-of-strings option of the rule for t.
+func (c *LintConfig) listOption(t ErrorType, key string) []string {
+	rule, _ := c.rule(t)
+	items, _ := rule.Options[key].([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// defaultSeverity: 语法错误、重复定义、无法解析的导入与解码器不接受的 `#` 注释为 error,
+// 解码器能够接受的 BOM 与 CRLF 换行为 info, 其余问题为 warning.
+func defaultSeverity(e LintError) Severity {
+	switch e.Type {
+	case ErrUnexpectedToken, ErrExpectDiffToken, ErrDuplicateKey, ErrDuplicateBlock, ErrUnresolvedImport, ErrHashComment, ErrSchemaViolation, ErrSchemaUnknown:
+		return SeverityError
+	case ErrFileEncoding:
+		return SeverityInfo
+	}
+	return SeverityWarning
+}
+
+// apply drops issues of disabled rules and assigns severities.
+func (c *LintConfig) apply(errs []LintError) []LintError {
+	out := errs[:0]
+	for _, e := range errs {
+		if !c.enabled(e.Type) {
+			continue
+		}
+		e.Rule = e.Type.RuleID()
+		e.Severity = c.severity(e)
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLintConfig(t *testing.T) {
+	data := `
+rule "unused-variable" {
+	severity = "info"
+	options = {[
+		ignore_prefix = "_",
+	]}
+}
+
+rule "redundant-label" {
+	enabled = false
+}
+
+rule "unknown-profile" {
+	options = {[
+		known = ["production"],
+	]}
+}
+`
+	cfg, err := ParseLintConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseLintConfig failed: %v", err)
+	}
+
+	src := `
+var used = 1
+var unused = 2
+var _scratch = 3
+a = ${used}
+server "only" {
+	port = 1
+}
+profile "staging" {
+	a = 2
+}
+`
+	_, errs := Lint([]byte(src), WithLintConfig(cfg))
+	got := map[ErrorType][]LintError{}
+	for _, e := range errs {
+		got[e.Type] = append(got[e.Type], e)
+	}
+	if len(got[ErrRedundantLabel]) != 0 {
+		t.Errorf("redundant-label is disabled, got %v", got[ErrRedundantLabel])
+	}
+	if unused := got[ErrUnusedVariable]; len(unused) != 1 || unused[0].Args[0] != "unused" || unused[0].Severity != SeverityInfo {
+		t.Errorf("unexpected unused-variable issues: %+v", unused)
+	}
+	if profiles := got[ErrUnknownProfile]; len(profiles) != 1 || profiles[0].Severity != SeverityWarning {
+		t.Errorf("unexpected unknown-profile issues: %+v", profiles)
+	}
+}
+
+func TestParseLintConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{`rule "no-such-rule" { enabled = false }`, `unknown lint rule "no-such-rule"`},
+		{`rule "redundant-comma" { severity = "fatal" }`, `unknown severity "fatal"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseLintConfig([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseLintConfig(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
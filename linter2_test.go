@@ -168,6 +168,39 @@ sList = {[
 	item2 = {},
 ]}`,
 		},
+		{
+			name: "duplicate key",
+			input: `
+server {
+	port = 8080
+	host = "localhost"
+	port = 9090
+}`,
+			wantErrors: []string{`line 5:2: key "port" is already assigned at line 3:2`},
+		},
+		{
+			name: "duplicate key in map literal",
+			input: `
+headers = {[
+	accept = "json",
+	accept = "xml",
+]}`,
+			wantErrors: []string{`key "accept" is already assigned at line 3:2`},
+		},
+		{
+			name: "duplicate block label",
+			input: `
+server "api" {
+	port = 1
+}
+server "web" {
+	port = 2
+}
+server "api" {
+	port = 3
+}`,
+			wantErrors: []string{`line 8:1: block server "api" is already defined at line 2:1`},
+		},
 		{
 			name: "fmt with no sort",
 			input: `
@@ -232,3 +265,269 @@ b_kv = 2`,
 		})
 	}
 }
+
+func TestFormat_AlignAssignments(t *testing.T) {
+	input := `
+server {
+	host = "localhost"
+	port = 8080
+	max_connections = 100
+	tls {
+		enabled = true
+	}
+	headers = {[
+		accept = "json",
+		x_request_timeout = "5s",
+	]}
+}`
+	want := `server {
+	host            = "localhost"
+	max_connections = 100
+	port            = 8080
+	headers = {[
+		accept            = "json",
+		x_request_timeout = "5s",
+	]}
+	tls {
+		enabled = true
+	}
+}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, AlignAssignments: true})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+func TestFormat_AlignAssignmentsBlankLineGroups(t *testing.T) {
+	input := `
+server {
+	host = "localhost"
+	port = 8080
+
+	// timeouts
+	read_timeout = 5s
+	write_timeout = 5s
+	headers = {[
+		a = "1",
+		bb = "2",
+
+		long_key = "3",
+	]}
+}`
+	want := `server {
+	host = "localhost"
+	port = 8080
+
+	// timeouts
+	read_timeout  = 5s
+	write_timeout = 5s
+	headers = {[
+		a  = "1",
+		bb = "2",
+
+		long_key = "3",
+	]}
+}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleStreaming, AlignAssignments: true, PreserveBlankLines: true})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+func TestFormat_MaxWidth(t *testing.T) {
+	input := `
+ports = [80, 443]
+hosts = ["alpha.example.com", "beta.example.com", "gamma.example.com"]
+server {
+	labels = {[ team = "infra", env = "prod" ]}
+	routes = [{}, { path = "/" }]
+}`
+	want := `hosts = [
+	"alpha.example.com",
+	"beta.example.com",
+	"gamma.example.com",
+]
+
+ports = [80, 443]
+
+server {
+	labels = {[env = "prod", team = "infra"]}
+	routes = [
+		{},
+		{
+			path = "/"
+		},
+	]
+}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleAllSorted, EmptyLines: true, MaxWidth: 50})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+func TestFormat_PreserveBlankLines(t *testing.T) {
+	input := `
+server {
+	host = "localhost"
+	port = 8080
+
+
+	// timeouts
+	read_timeout = 5s
+	write_timeout = 5s
+	headers = {[
+		a = "1",
+
+		b = "2",
+	]}
+}`
+	want := `server {
+	host = "localhost"
+	port = 8080
+
+	// timeouts
+	read_timeout = 5s
+	write_timeout = 5s
+	headers = {[
+		a = "1",
+
+		b = "2",
+	]}
+}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleStreaming, PreserveBlankLines: true})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+
+	stmts := program.Statements[0].(*BlockStatement).Body.Statements
+	if n := stmts[2].(*AssignStatement).BlankLinesBefore; n != 2 {
+		t.Errorf("BlankLinesBefore = %d, want 2", n)
+	}
+}
+
+func TestFormat_SortDirectives(t *testing.T) {
+	input := `
+server {
+	zone = "a"
+	// wanf:sort off
+	middleware_b = "auth"
+	middleware_a = "log"
+	// wanf:sort on
+	port = 80
+	host = "localhost"
+	// wanf:nosort
+	priorities = {[
+		high = 1,
+		low = 2,
+	]}
+}`
+	want := `server {
+	zone = "a"
+	// wanf:sort off
+	middleware_b = "auth"
+	middleware_a = "log"
+	// wanf:sort on
+	host = "localhost"
+	port = 80
+	// wanf:nosort
+	priorities = {[
+		high = 1,
+		low = 2,
+	]}
+}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+func TestFormatStable(t *testing.T) {
+	input := `
+var name = "svc"
+server "main" {
+	port = 8080,
+	host = "${name}"
+	tags = ["a", "b"]
+}`
+	opts := FormatOptions{Style: StyleBlockSorted, EmptyLines: true}
+	got, err := FormatStable([]byte(input), opts)
+	if err != nil {
+		t.Fatalf("FormatStable failed: %v", err)
+	}
+	program, _ := Lint([]byte(input))
+	if want := Format(program, opts); string(got) != string(want) {
+		t.Errorf("FormatStable output differs from Format:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+
+	if _, err := FormatStable([]byte("a = [1, 2"), opts); err == nil {
+		t.Error("expected an error for a document with syntax errors")
+	}
+}
+
+func TestFormat_CommentsInListsAndMaps(t *testing.T) {
+	input := `
+middleware = [
+	// runs first
+	"recover",
+	"auth", // requires session
+	// "trace",
+]
+limits = {[
+	// per client
+	rps = 10, // burst excluded
+	burst = 20
+	// end of limits
+]}`
+	want := `middleware = [
+	// runs first
+	"recover",
+	"auth", // requires session
+	// "trace",
+]
+
+limits = {[
+	burst = 20,
+	// per client
+	rps = 10, // burst excluded
+	// end of limits
+]}`
+	program, errs := Lint([]byte(input))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got := strings.TrimSpace(string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, MaxWidth: 80})))
+	if got != want {
+		t.Errorf("output mismatch:\n--- want\n%s\n--- got\n%s", want, got)
+	}
+
+	var cfg struct {
+		Middleware []string       `wanf:"middleware"`
+		Limits     map[string]int `wanf:"limits"`
+	}
+	if err := Decode([]byte(input), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(cfg.Middleware) != 2 || cfg.Limits["burst"] != 20 {
+		t.Errorf("unexpected decode result: %+v", cfg)
+	}
+}
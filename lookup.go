@@ -0,0 +1,288 @@
+package wanf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrKeyNotFound 表示 Lookup、Get 或 Editor.Unset 的路径指向不存在的键、
+// 标签或列表下标, 可以用 errors.Is 判断.
+var ErrKeyNotFound = errors.New("key not found")
+
+// pathSegment 是查询路径中的一段: 键 (标识符或带引号的标签) 或列表下标.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s pathSegment) String() string {
+	if s.isIndex {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return strconv.Quote(s.key)
+}
+
+// Lookup 在 program 中查找 path 指向的表达式, 无需解码到 Go 结构体.
+//
+// path 由 '.' 分隔的键组成, 键可以用双引号包围以包含 '.' 等字符,
+// 键之后可以跟若干 [n] 下标, 例如:
+//
+//	server."main".port
+//	servers[0].host
+//	matrix[1][0]
+//
+// 块的标签作为紧随块名之后的键, `server "main" {...}` 可以写作
+// server."main" 或 server.main. 指向块本身时返回一个 *BlockLiteral.
+// 同名的赋值以最后一次为准, 与解码行为一致. Lookup 不求值变量与 env().
+func Lookup(program *RootNode, path string) (Expression, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("wanf: invalid path %q: %w", path, err)
+	}
+	expr, err := lookupStatements(program.Statements, segs)
+	if err != nil {
+		return nil, fmt.Errorf("wanf: lookup %q: %w", path, err)
+	}
+	return expr, nil
+}
+
+// Get 与 Lookup 相同, 但将只含字面量的值转换为 Go 值: int64, float64,
+// string, bool, time.Duration, []interface{} 与 map[string]interface{}
+// (块与映射). 值中含有变量引用或 env() 时无法静态求值, 返回表达式本身;
+// 需要求值时使用 DecodePath.
+func Get(program *RootNode, path string) (interface{}, error) {
+	expr, err := Lookup(program, path)
+	if err != nil {
+		return nil, err
+	}
+	d := &internalDecoder{vars: make(map[string]interface{}), sandbox: true}
+	if val, err := d.evalExpression(expr); err == nil && !containsVarRef(expr) {
+		return val, nil
+	}
+	return expr, nil
+}
+
+// containsVarRef 报告 expr 中是否有字符串引用了变量. 静态求值时变量未定义,
+// 这样的字符串会保持原样, 因此不能当作最终的值.
+func containsVarRef(expr Expression) bool {
+	found := false
+	Walk(expr, func(n Node) bool {
+		if s, ok := n.(*StringLiteral); ok && varRegex.Match(s.Value) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// DecodePath 解码 data 中 path 指向的部分到 v, 路径语法同 Lookup. 文档先按
+// NewDecoder 的流程处理 (导入、profile 与变量). path 指向块或映射时 v 应为
+// 结构体指针, 否则可以是任意可接收该值的指针, 如 *int.
+func DecodePath(data []byte, path string, v interface{}, opts ...DecoderOption) error {
+	dec, err := NewDecoder(bytes.NewReader(data), opts...)
+	if err != nil {
+		return err
+	}
+	return dec.DecodePath(path, v)
+}
+
+// DecodePath 解码文档中 path 指向的部分到 v, 规则同包级函数 DecodePath.
+func (dec *Decoder) DecodePath(path string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("v must be a non-nil pointer")
+	}
+	expr, err := Lookup(dec.program, path)
+	if err != nil {
+		return err
+	}
+	target := rv.Elem()
+	if target.Kind() == reflect.Struct {
+		var body *RootNode
+		switch e := expr.(type) {
+		case *BlockLiteral:
+			body = e.Body
+		case *MapLiteral:
+			body = &RootNode{Statements: e.Elements}
+		default:
+			return fmt.Errorf("wanf: %q is a %s, not a block", path, expressionKind(expr))
+		}
+		if err := dec.d.decodeRoot(body, target); err != nil {
+			return err
+		}
+		return callValidate(target)
+	}
+	val, err := dec.d.evalExpression(expr)
+	if err != nil {
+		return err
+	}
+	if err := dec.d.setField(target, val); err != nil {
+		return fmt.Errorf("wanf: %q: %w", path, err)
+	}
+	return nil
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	i := 0
+	for {
+		if i >= len(path) {
+			return nil, fmt.Errorf("missing key at offset %d", i)
+		}
+		var key string
+		if path[i] == '"' {
+			end := strings.IndexByte(path[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			key = path[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' && path[i] != '"' {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("missing key at offset %d", i)
+			}
+			key = path[start:i]
+		}
+		segs = append(segs, pathSegment{key: key})
+		for i < len(path) && path[i] == '[' {
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' at offset %d", i)
+			}
+			n, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index %q at offset %d", path[i+1:i+end], i)
+			}
+			segs = append(segs, pathSegment{index: n, isIndex: true})
+			i += end + 1
+		}
+		if i == len(path) {
+			return segs, nil
+		}
+		if path[i] != '.' {
+			return nil, fmt.Errorf("unexpected %q at offset %d", path[i], i)
+		}
+		i++
+	}
+}
+
+// lookupStatements 在语句列表中解析 segs. 从后向前查找, 使后出现的赋值生效;
+// 重复的同名块依次尝试, 直到某一个能够解析剩余路径.
+func lookupStatements(stmts []Statement, segs []pathSegment) (Expression, error) {
+	seg := segs[0]
+	if seg.isIndex {
+		return nil, fmt.Errorf("cannot index %s into a block", seg)
+	}
+	var blockErr error
+	var labelMismatch bool
+	for i := len(stmts) - 1; i >= 0; i-- {
+		switch s := stmts[i].(type) {
+		case *AssignStatement:
+			if string(s.Name.Value) == seg.key {
+				return lookupExpression(s.Value, segs[1:])
+			}
+		case *BlockStatement:
+			if string(s.Name.Value) != seg.key {
+				continue
+			}
+			rest, ok := matchLabels(s.Labels(), segs[1:])
+			if !ok {
+				labelMismatch = true
+				continue
+			}
+			lit := &BlockLiteral{Token: s.Token, Body: s.Body, Rbrace: s.Rbrace}
+			if len(rest) == 0 {
+				return lit, nil
+			}
+			expr, err := lookupStatements(s.Body.Statements, rest)
+			if err == nil {
+				return expr, nil
+			}
+			if blockErr == nil {
+				blockErr = err
+			}
+		}
+	}
+	if blockErr != nil {
+		return nil, blockErr
+	}
+	if labelMismatch {
+		return nil, errorOf(ErrKeyNotFound, "no block %s with labels matching the path", seg)
+	}
+	return nil, errorOf(ErrKeyNotFound, "key %s not found", seg)
+}
+
+// matchLabels 检查 segs 是否以 labels 开头, 并返回其余部分.
+func matchLabels(labels []string, segs []pathSegment) ([]pathSegment, bool) {
+	if len(segs) < len(labels) {
+		return nil, false
+	}
+	for i, label := range labels {
+		if segs[i].isIndex || segs[i].key != label {
+			return nil, false
+		}
+	}
+	return segs[len(labels):], true
+}
+
+func lookupExpression(expr Expression, segs []pathSegment) (Expression, error) {
+	if len(segs) == 0 {
+		return expr, nil
+	}
+	seg := segs[0]
+	switch e := expr.(type) {
+	case *ListLiteral:
+		if !seg.isIndex {
+			return nil, fmt.Errorf("cannot look up key %s in a list", seg)
+		}
+		if seg.index >= len(e.Elements) {
+			return nil, errorOf(ErrKeyNotFound, "index %d out of range (list has %d elements)", seg.index, len(e.Elements))
+		}
+		return lookupExpression(e.Elements[seg.index], segs[1:])
+	case *MapLiteral:
+		if seg.isIndex {
+			return nil, fmt.Errorf("cannot index %s into a map", seg)
+		}
+		for i := len(e.Elements) - 1; i >= 0; i-- {
+			if assign, ok := e.Elements[i].(*AssignStatement); ok && string(assign.Name.Value) == seg.key {
+				return lookupExpression(assign.Value, segs[1:])
+			}
+		}
+		return nil, errorOf(ErrKeyNotFound, "key %s not found", seg)
+	case *BlockLiteral:
+		return lookupStatements(e.Body.Statements, segs)
+	}
+	return nil, fmt.Errorf("cannot look up %s in a value of type %s", seg, expressionKind(expr))
+}
+
+// expressionKind 返回表达式的简短类型名, 用于错误信息.
+func expressionKind(expr Expression) string {
+	switch expr.(type) {
+	case *StringLiteral:
+		return "string"
+	case *IntegerLiteral:
+		return "integer"
+	case *FloatLiteral:
+		return "float"
+	case *BoolLiteral:
+		return "bool"
+	case *DurationLiteral:
+		return "duration"
+	case *VarExpression:
+		return "variable"
+	case *EnvExpression:
+		return "env()"
+	case *SecretExpression:
+		return "secret()"
+	}
+	return fmt.Sprintf("%T", expr)
+}
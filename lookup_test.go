@@ -0,0 +1,166 @@
+package wanf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	src := `name = "first"
+name = "svc"
+server "main" {
+	port = 8080
+	tags = ["a", "b"]
+}
+server "admin" {
+	port = 9090
+}
+route "api" "v2" {
+	target = "backend"
+}
+database {
+	host = "localhost"
+}
+database {
+	user = "admin"
+}
+limits = {[
+	rate = 10,
+]}
+matrix = [[1, 2], [3, 4]]
+`
+	p := NewParser(NewLexer([]byte(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`name`, `"svc"`},
+		{`server."main".port`, `8080`},
+		{`server.admin.port`, `9090`},
+		{`server.main.tags[1]`, `"b"`},
+		{`route.api.v2.target`, `"backend"`},
+		{`database.host`, `"localhost"`},
+		{`database.user`, `"admin"`},
+		{`limits.rate`, `10`},
+		{`matrix[1][0]`, `3`},
+	}
+	for _, tt := range tests {
+		expr, err := Lookup(program, tt.path)
+		if err != nil {
+			t.Errorf("Lookup(%q) error: %v", tt.path, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if expr, err := Lookup(program, `server.main`); err != nil {
+		t.Errorf("Lookup of a block failed: %v", err)
+	} else if _, ok := expr.(*BlockLiteral); !ok {
+		t.Errorf("Lookup of a block returned %T, want *BlockLiteral", expr)
+	}
+
+	errTests := []struct {
+		path string
+		want string
+	}{
+		{`missing`, `key "missing" not found`},
+		{`server.other.port`, `no block "server" with labels matching the path`},
+		{`server.main.tags[5]`, `index 5 out of range (list has 2 elements)`},
+		{`server.main.port.x`, `cannot look up "x" in a value of type integer`},
+		{`matrix.x`, `cannot look up key "x" in a list`},
+		{`name[0]`, `cannot look up [0] in a value of type string`},
+		{`server.`, `missing key at offset 7`},
+		{`"server`, `unterminated quote`},
+		{`matrix[x]`, `invalid index "x"`},
+	}
+	for _, tt := range errTests {
+		_, err := Lookup(program, tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Lookup(%q) error = %v, want %q", tt.path, err, tt.want)
+		}
+		// 只有指向不存在的键、标签或下标的错误属于 ErrKeyNotFound.
+		if notFound := strings.Contains(tt.want, "not found") || strings.Contains(tt.want, "matching") || strings.Contains(tt.want, "out of range"); errors.Is(err, ErrKeyNotFound) != notFound {
+			t.Errorf("Lookup(%q): errors.Is(err, ErrKeyNotFound) = %v", tt.path, !notFound)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	program := parseForMerge(t, `
+var host = "db"
+database {
+	port = 5432
+	timeout = 5s
+	tags = ["a", "b"]
+	addr = "${host}:5432"
+	user = env("DB_USER", "root")
+}
+`)
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"database.port", int64(5432)},
+		{"database.timeout", 5 * time.Second},
+		{"database.tags", []interface{}{"a", "b"}},
+	}
+	for _, tt := range tests {
+		got, err := Get(program, tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %#v, %v; want %#v", tt.path, got, err, tt.want)
+		}
+	}
+	// 引用变量或 env() 的值无法静态求值, 返回表达式.
+	for _, path := range []string{"database.addr", "database.user", "database"} {
+		if got, err := Get(program, path); err != nil {
+			t.Errorf("Get(%q) failed: %v", path, err)
+		} else if _, ok := got.(Expression); !ok {
+			t.Errorf("Get(%q) = %#v, want an Expression", path, got)
+		}
+	}
+	if _, err := Get(program, "database.missing"); err == nil {
+		t.Error("Get(database.missing) succeeded")
+	}
+}
+
+func TestDecodePath(t *testing.T) {
+	data := []byte(`
+var base = 8000
+server "main" {
+	port = ${base}
+	host = "localhost"
+}
+limits = {[
+	rate = 10,
+]}
+`)
+	var srv struct {
+		Port int    `wanf:"port"`
+		Host string `wanf:"host"`
+	}
+	if err := DecodePath(data, `server.main`, &srv); err != nil || srv.Port != 8000 || srv.Host != "localhost" {
+		t.Errorf("DecodePath(server.main) = %+v, %v", srv, err)
+	}
+	var limits struct {
+		Rate int `wanf:"rate"`
+	}
+	if err := DecodePath(data, "limits", &limits); err != nil || limits.Rate != 10 {
+		t.Errorf("DecodePath(limits) = %+v, %v", limits, err)
+	}
+	var port int
+	if err := DecodePath(data, "server.main.port", &port); err != nil || port != 8000 {
+		t.Errorf("DecodePath(port) = %d, %v", port, err)
+	}
+	if err := DecodePath(data, "server.main.host", &srv); err == nil {
+		t.Error("DecodePath of a string into a struct succeeded")
+	}
+}
@@ -0,0 +1,167 @@
+package wanf
+
+import "bytes"
+
+// MergePolicy 控制 Merge 如何合并两侧都存在的列表.
+type MergePolicy int
+
+const (
+	// MergeReplaceLists 使用 src 的列表替换 dst 的列表. 这是默认策略.
+	MergeReplaceLists MergePolicy = iota
+
+	// MergeAppendLists 将 src 列表的元素追加到 dst 列表之后.
+	MergeAppendLists
+)
+
+// Merge 将 src 深度合并到 dst 中, 用于分层配置 (如 base + override):
+//   - 同名同标签的块递归合并, map 字面量与块字面量按键递归合并;
+//   - 两侧都是列表时按 policy 替换或追加;
+//   - 其余同名赋值与 var 由 src 覆盖, 类型不同时 (如块与赋值) 同样由 src 覆盖;
+//   - 只存在于 src 的语句与 import 追加到 dst 的末尾.
+//
+// dst 会被原地修改, 并可能引用 src 中的节点, 因此之后不应再修改 src.
+func Merge(dst, src *RootNode, policy MergePolicy) {
+	dst.Statements = mergeStatements(dst.Statements, src.Statements, policy)
+}
+
+func mergeStatements(dst, src []Statement, policy MergePolicy) []Statement {
+	for _, stmt := range src {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			i := findMergeTarget(dst, stmt)
+			if i < 0 {
+				dst = append(dst, s)
+				continue
+			}
+			if d, ok := dst[i].(*AssignStatement); ok {
+				d.Value = mergeExpressions(d.Value, s.Value, policy)
+				mergeComments(d, s)
+				continue
+			}
+			dst = append(removeStatement(dst, i), s)
+		case *BlockStatement:
+			i := findMergeTarget(dst, stmt)
+			if i < 0 {
+				dst = append(dst, s)
+				continue
+			}
+			if d, ok := dst[i].(*BlockStatement); ok {
+				d.Body.Statements = mergeStatements(d.Body.Statements, s.Body.Statements, policy)
+				if len(s.LeadingComments) > 0 {
+					d.LeadingComments = s.LeadingComments
+				}
+				continue
+			}
+			dst = append(removeStatement(dst, i), s)
+		case *VarStatement:
+			if i := findMergeTarget(dst, stmt); i >= 0 {
+				dst[i] = s
+				continue
+			}
+			dst = append(dst, s)
+		case *ImportStatement:
+			if findMergeTarget(dst, stmt) < 0 {
+				dst = append(dst, s)
+			}
+		default:
+			dst = append(dst, stmt)
+		}
+	}
+	return dst
+}
+
+// findMergeTarget 返回 dst 中与 stmt 对应的最后一条语句的下标, 没有时返回 -1.
+// 赋值与块只要名称相同即对应 (块还需标签相同), 以便检测类型冲突.
+func findMergeTarget(dst []Statement, stmt Statement) int {
+	for i := len(dst) - 1; i >= 0; i-- {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			switch d := dst[i].(type) {
+			case *AssignStatement:
+				if bytes.Equal(d.Name.Value, s.Name.Value) {
+					return i
+				}
+			case *BlockStatement:
+				if d.Label == nil && bytes.Equal(d.Name.Value, s.Name.Value) {
+					return i
+				}
+			}
+		case *BlockStatement:
+			switch d := dst[i].(type) {
+			case *BlockStatement:
+				if bytes.Equal(d.Name.Value, s.Name.Value) && equalLabels(d.Labels(), s.Labels()) {
+					return i
+				}
+			case *AssignStatement:
+				if s.Label == nil && bytes.Equal(d.Name.Value, s.Name.Value) {
+					return i
+				}
+			}
+		case *VarStatement:
+			if d, ok := dst[i].(*VarStatement); ok && bytes.Equal(d.Name.Value, s.Name.Value) {
+				return i
+			}
+		case *ImportStatement:
+			if d, ok := dst[i].(*ImportStatement); ok && bytes.Equal(d.Path.Value, s.Path.Value) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func mergeExpressions(dst, src Expression, policy MergePolicy) Expression {
+	switch d := dst.(type) {
+	case *ListLiteral:
+		if s, ok := src.(*ListLiteral); ok && policy == MergeAppendLists {
+			appendListElements(d, s)
+			return d
+		}
+	case *MapLiteral:
+		if s, ok := src.(*MapLiteral); ok {
+			d.Elements = mergeStatements(d.Elements, s.Elements, policy)
+			return d
+		}
+	case *BlockLiteral:
+		if s, ok := src.(*BlockLiteral); ok {
+			d.Body.Statements = mergeStatements(d.Body.Statements, s.Body.Statements, policy)
+			return d
+		}
+	}
+	return src
+}
+
+func appendListElements(dst, src *ListLiteral) {
+	offset := len(dst.Elements)
+	dst.Elements = append(dst.Elements, src.Elements...)
+	for i := range src.Elements {
+		dst.setComments(offset+i, src.elementComments(i))
+	}
+	dst.TrailingComments = append(dst.TrailingComments, src.TrailingComments...)
+}
+
+// mergeComments 在 src 带有注释时用其替换 dst 的注释.
+func mergeComments(dst, src *AssignStatement) {
+	if len(src.LeadingComments) > 0 {
+		dst.LeadingComments = src.LeadingComments
+	}
+	if src.LineComment != nil {
+		dst.LineComment = src.LineComment
+	}
+}
+
+func removeStatement(stmts []Statement, i int) []Statement {
+	return append(stmts[:i], stmts[i+1:]...)
+}
+
+func equalLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package wanf
+
+import "testing"
+
+func parseForMerge(t *testing.T, src string) *RootNode {
+	t.Helper()
+	p := NewParser(NewLexer([]byte(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestMerge(t *testing.T) {
+	base := `var env_name = "dev"
+name = "svc"
+hosts = ["a", "b"]
+labels = {[team = "core", tier = "2"]}
+
+server "main" {
+	port = 8080
+	tls {
+		enabled = false
+	}
+}
+
+server "admin" {
+	port = 9090
+}
+
+cache {
+	size = 10
+}
+`
+	override := `var env_name = "prod"
+hosts = ["c"]
+labels = {[tier = "1"]}
+
+server "main" {
+	tls {
+		enabled = true
+	}
+}
+
+cache = "disabled"
+debug = false
+`
+	tests := []struct {
+		policy MergePolicy
+		want   string
+	}{
+		{MergeReplaceLists, `var env_name = "prod"
+name = "svc"
+
+hosts = [
+	"c",
+]
+
+labels = {[
+	team = "core",
+	tier = "1",
+]}
+
+server "main" {
+	port = 8080
+	tls {
+		enabled = true
+	}
+}
+
+server "admin" {
+	port = 9090
+}
+
+cache = "disabled"
+debug = false`},
+		{MergeAppendLists, `var env_name = "prod"
+name = "svc"
+
+hosts = [
+	"a",
+	"b",
+	"c",
+]
+
+labels = {[
+	team = "core",
+	tier = "1",
+]}
+
+server "main" {
+	port = 8080
+	tls {
+		enabled = true
+	}
+}
+
+server "admin" {
+	port = 9090
+}
+
+cache = "disabled"
+debug = false`},
+	}
+	for _, tt := range tests {
+		dst := parseForMerge(t, base)
+		Merge(dst, parseForMerge(t, override), tt.policy)
+		got := string(Format(dst, FormatOptions{Style: StyleBlockSorted, EmptyLines: true}))
+		if got != tt.want {
+			t.Errorf("policy %d mismatch.\ngot:\n%s\nwant:\n%s", tt.policy, got, tt.want)
+		}
+	}
+}
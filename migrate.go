@@ -0,0 +1,211 @@
+package wanf
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// VersionKey 是声明文档格式版本的顶层键, 如 `wanf_version = 2`.
+// 未声明版本的文档视为版本 1.
+const VersionKey = "wanf_version"
+
+// MigrationFunc 将文档从一个版本升级到下一个版本, 直接修改 program.
+type MigrationFunc func(program *RootNode) error
+
+type migration struct {
+	to int
+	fn MigrationFunc
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = make(map[int]migration) // 以起始版本为键
+)
+
+// RegisterMigration 注册从版本 from 升级到版本 to 的迁移函数. Decoder 在解码前
+// 按版本依次执行已注册的迁移, 直到没有以当前版本为起点的迁移为止, 并将
+// wanf_version 更新为最终版本. 每个起始版本只能注册一个迁移; to 不大于 from
+// 或重复注册时 panic, 因此通常在 init 中调用.
+//
+// 迁移函数可以使用 RenameKey 与 RemoveKey 等函数修改 AST.
+func RegisterMigration(from, to int, fn MigrationFunc) {
+	if to <= from || fn == nil {
+		panic(fmt.Sprintf("wanf: invalid migration from version %d to %d", from, to))
+	}
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if _, dup := migrations[from]; dup {
+		panic(fmt.Sprintf("wanf: migration from version %d is already registered", from))
+	}
+	migrations[from] = migration{to: to, fn: fn}
+}
+
+// LatestVersion 返回已注册的迁移能够升级到的最高版本, 没有注册迁移时为 1.
+func LatestVersion() int {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	latest := 1
+	for _, m := range migrations {
+		latest = max(latest, m.to)
+	}
+	return latest
+}
+
+// DocumentVersion 返回 program 顶层 wanf_version 声明的版本, 未声明时为 1.
+func DocumentVersion(program *RootNode) (int, error) {
+	s := versionStatement(program)
+	if s == nil {
+		return 1, nil
+	}
+	lit, ok := s.Value.(*IntegerLiteral)
+	if !ok || lit.Value < 1 {
+		return 0, fmt.Errorf("wanf: line %d: %s must be a positive integer", s.Token.Line, VersionKey)
+	}
+	return int(lit.Value), nil
+}
+
+// Migrate 对 program 执行已注册的迁移, 返回迁移前后的版本. 文档版本高于
+// LatestVersion (且注册了迁移) 时报错, 因为它需要更新的程序才能读取.
+func Migrate(program *RootNode) (from, to int, err error) {
+	from, err = DocumentVersion(program)
+	if err != nil {
+		return 0, 0, err
+	}
+	if latest := LatestVersion(); latest > 1 && from > latest {
+		return from, from, fmt.Errorf("wanf: document version %d is newer than the latest supported version %d", from, latest)
+	}
+	to = from
+	for {
+		migrationsMu.RLock()
+		m, ok := migrations[to]
+		migrationsMu.RUnlock()
+		if !ok {
+			break
+		}
+		if err := m.fn(program); err != nil {
+			return from, to, fmt.Errorf("wanf: migration from version %d to %d: %w", to, m.to, err)
+		}
+		to = m.to
+	}
+	if to != from {
+		setDocumentVersion(program, to)
+	}
+	return from, to, nil
+}
+
+func versionStatement(program *RootNode) *AssignStatement {
+	for i := len(program.Statements) - 1; i >= 0; i-- {
+		if s, ok := program.Statements[i].(*AssignStatement); ok && string(s.Name.Value) == VersionKey {
+			return s
+		}
+	}
+	return nil
+}
+
+// setDocumentVersion 更新 wanf_version, 未声明时将其插入到文档开头.
+func setDocumentVersion(program *RootNode, version int) {
+	lit := []byte(strconv.Itoa(version))
+	value := &IntegerLiteral{Token: Token{Type: INT, Literal: lit}, Value: int64(version)}
+	if s := versionStatement(program); s != nil {
+		old := exprToken(s.Value)
+		value.Token.Line, value.Token.Column = old.Line, old.Column
+		s.Value = value
+		return
+	}
+	name := &Identifier{Token: Token{Type: IDENT, Literal: []byte(VersionKey)}, Value: []byte(VersionKey)}
+	stmt := &AssignStatement{Token: name.Token, Name: name, Value: value}
+	if len(program.Statements) > 0 && blankLinesBefore(program.Statements[0]) == 0 {
+		// 与原有的第一条语句 (及其注释) 之间空一行.
+		switch first := program.Statements[0].(type) {
+		case *AssignStatement:
+			first.BlankLinesBefore = 1
+		case *BlockStatement:
+			first.BlankLinesBefore = 1
+		case *VarStatement:
+			first.BlankLinesBefore = 1
+		case *ImportStatement:
+			first.BlankLinesBefore = 1
+		}
+	}
+	program.Statements = append([]Statement{stmt}, program.Statements...)
+}
+
+// RenameKey 将 path 指向的全部键与块改名为 name, 返回被修改的语句数. path 的
+// 语法与 Lookup 相同, 但不支持下标; 省略标签的块路径匹配该名称的全部块,
+// 例如 server.addr 匹配每个 server 块中的 addr.
+func RenameKey(program *RootNode, path, name string) (int, error) {
+	n := 0
+	err := walkKeyPath(program, path, func(stmts *[]Statement, i int) {
+		var ident *Identifier
+		switch s := (*stmts)[i].(type) {
+		case *AssignStatement:
+			ident = s.Name
+		case *BlockStatement:
+			ident = s.Name
+		}
+		ident.Value, ident.Token.Literal = []byte(name), []byte(name)
+		n++
+	})
+	return n, err
+}
+
+// RemoveKey 删除 path 指向的全部键与块 (连同其注释), 返回被删除的语句数.
+// path 的匹配规则同 RenameKey.
+func RemoveKey(program *RootNode, path string) (int, error) {
+	n := 0
+	err := walkKeyPath(program, path, func(stmts *[]Statement, i int) {
+		*stmts = append((*stmts)[:i], (*stmts)[i+1:]...)
+		n++
+	})
+	return n, err
+}
+
+// walkKeyPath 对 path 匹配的每条语句调用 fn. 语句从后向前访问, 因此 fn 可以删除它.
+func walkKeyPath(program *RootNode, path string, fn func(stmts *[]Statement, i int)) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return fmt.Errorf("wanf: invalid path %q: %w", path, err)
+	}
+	for _, seg := range segs {
+		if seg.isIndex {
+			return fmt.Errorf("wanf: path %q: indexes are not supported", path)
+		}
+	}
+	walkKeyStatements(&program.Statements, segs, fn)
+	return nil
+}
+
+func walkKeyStatements(stmts *[]Statement, segs []pathSegment, fn func(stmts *[]Statement, i int)) {
+	for i := len(*stmts) - 1; i >= 0; i-- {
+		switch s := (*stmts)[i].(type) {
+		case *AssignStatement:
+			if string(s.Name.Value) != segs[0].key {
+				continue
+			}
+			if len(segs) == 1 {
+				fn(stmts, i)
+				continue
+			}
+			switch v := s.Value.(type) {
+			case *BlockLiteral:
+				walkKeyStatements(&v.Body.Statements, segs[1:], fn)
+			case *MapLiteral:
+				walkKeyStatements(&v.Elements, segs[1:], fn)
+			}
+		case *BlockStatement:
+			if string(s.Name.Value) != segs[0].key {
+				continue
+			}
+			rest, ok := matchLabels(s.Labels(), segs[1:])
+			if !ok {
+				rest = segs[1:]
+			}
+			if len(rest) == 0 {
+				fn(stmts, i)
+				continue
+			}
+			walkKeyStatements(&s.Body.Statements, rest, fn)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package wanf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withTestMigrations 注册测试用的迁移, 并在测试结束时清除, 以免影响其他测试.
+func withTestMigrations(t *testing.T) {
+	t.Helper()
+	RegisterMigration(1, 2, func(program *RootNode) error {
+		if _, err := RenameKey(program, "server.addr", "listen"); err != nil {
+			return err
+		}
+		_, err := RemoveKey(program, "legacy")
+		return err
+	})
+	RegisterMigration(2, 3, func(program *RootNode) error {
+		_, err := RenameKey(program, "debug", "verbose")
+		return err
+	})
+	t.Cleanup(func() {
+		migrationsMu.Lock()
+		defer migrationsMu.Unlock()
+		migrations = make(map[int]migration)
+	})
+}
+
+type migrateTestConfig struct {
+	Version int  `wanf:"wanf_version"`
+	Verbose bool `wanf:"verbose"`
+	Server  map[string]struct {
+		Listen string `wanf:"listen"`
+	} `wanf:"server"`
+}
+
+func TestDecodeAppliesMigrations(t *testing.T) {
+	withTestMigrations(t)
+	data := `
+debug = true
+legacy = 1
+server "a" {
+	addr = ":80"
+}
+server "b" {
+	addr = ":81"
+}
+`
+	var cfg migrateTestConfig
+	if err := Decode([]byte(data), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Version != 3 || !cfg.Verbose {
+		t.Errorf("Version = %d, Verbose = %v", cfg.Version, cfg.Verbose)
+	}
+	if cfg.Server["a"].Listen != ":80" || cfg.Server["b"].Listen != ":81" {
+		t.Errorf("Server = %+v", cfg.Server)
+	}
+}
+
+func TestMigrateFromDeclaredVersion(t *testing.T) {
+	withTestMigrations(t)
+	program := parseForMerge(t, "wanf_version = 2\ndebug = true\nserver \"a\" {\n\taddr = \":80\"\n}\n")
+	from, to, err := Migrate(program)
+	if err != nil || from != 2 || to != 3 {
+		t.Fatalf("Migrate = %d, %d, %v; want 2, 3, nil", from, to, err)
+	}
+	out := string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true, NoSort: true}))
+	// 版本 2 已经完成了 addr 的改名, 因此只执行 2 -> 3.
+	for _, want := range []string{"wanf_version = 3", "verbose = true", "addr = \":80\""} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	withTestMigrations(t)
+	for _, data := range []string{"wanf_version = 4", `wanf_version = "2"`, "wanf_version = 0"} {
+		if _, _, err := Migrate(parseForMerge(t, data)); err == nil {
+			t.Errorf("Migrate(%q) succeeded, want an error", data)
+		}
+	}
+
+	errBroken := errors.New("broken")
+	RegisterMigration(3, 4, func(*RootNode) error { return errBroken })
+	_, _, err := Migrate(parseForMerge(t, "wanf_version = 3"))
+	if !errors.Is(err, errBroken) || !strings.Contains(err.Error(), "migration from version 3 to 4") {
+		t.Errorf("Migrate error = %v", err)
+	}
+}
+
+func TestRegisterMigrationPanics(t *testing.T) {
+	withTestMigrations(t)
+	for _, tt := range []struct{ from, to int }{{1, 5}, {5, 5}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterMigration(%d, %d) did not panic", tt.from, tt.to)
+				}
+			}()
+			RegisterMigration(tt.from, tt.to, func(*RootNode) error { return nil })
+		}()
+	}
+}
+
+func TestRenameAndRemoveKey(t *testing.T) {
+	program := parseForMerge(t, `
+a = 1
+server "x" {
+	port = 1
+}
+server "y" {
+	port = 2
+}
+db = {
+	port = 3
+}
+`)
+	if n, err := RenameKey(program, `server."x".port`, "listen"); err != nil || n != 1 {
+		t.Errorf("RenameKey with labels = %d, %v; want 1", n, err)
+	}
+	if n, err := RemoveKey(program, "server.port"); err != nil || n != 1 {
+		t.Errorf("RemoveKey = %d, %v; want 1", n, err)
+	}
+	if n, err := RenameKey(program, "db.port", "p"); err != nil || n != 1 {
+		t.Errorf("RenameKey in block literal = %d, %v; want 1", n, err)
+	}
+	if n, _ := RemoveKey(program, "missing"); n != 0 {
+		t.Errorf("RemoveKey(missing) = %d, want 0", n)
+	}
+	if _, err := RemoveKey(program, "a[0]"); err == nil {
+		t.Error("RemoveKey with an index succeeded, want an error")
+	}
+	out := string(Format(program, FormatOptions{Style: StyleBlockSorted, NoSort: true}))
+	if !strings.Contains(out, "listen = 1") || strings.Contains(out, "port = 2") || !strings.Contains(out, "p = 3") {
+		t.Errorf("unexpected result:\n%s", out)
+	}
+}
@@ -0,0 +1,53 @@
+package wanf
+
+// Minify 返回 data 去除所有注释后的单行 (StyleSingleLine) 表示, 便于嵌入环境变量
+// 或通过命令行传递. 语义保持不变; 包含换行的字符串无法压缩到一行, 其换行会被保留.
+func Minify(data []byte) ([]byte, error) {
+	p := NewParser(NewLexer(data))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, p.Errors()[0]
+	}
+	StripComments(program)
+	return Format(program, FormatOptions{Style: StyleSingleLine}), nil
+}
+
+// StripComments 原地删除 program 中的全部注释, 包括列表与 map 中的注释.
+func StripComments(program *RootNode) {
+	program.TrailingComments = nil
+	stripStatementComments(program.Statements)
+}
+
+func stripStatementComments(stmts []Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			s.LeadingComments, s.LineComment = nil, nil
+			stripExpressionComments(s.Value)
+		case *BlockStatement:
+			s.LeadingComments, s.Body.TrailingComments = nil, nil
+			stripStatementComments(s.Body.Statements)
+		case *VarStatement:
+			s.LeadingComments, s.LineComment = nil, nil
+			stripExpressionComments(s.Value)
+		case *ImportStatement:
+			s.LeadingComments, s.LineComment = nil, nil
+		}
+	}
+}
+
+func stripExpressionComments(expr Expression) {
+	switch e := expr.(type) {
+	case *ListLiteral:
+		e.Comments, e.TrailingComments = nil, nil
+		for _, el := range e.Elements {
+			stripExpressionComments(el)
+		}
+	case *MapLiteral:
+		e.TrailingComments = nil
+		stripStatementComments(e.Elements)
+	case *BlockLiteral:
+		e.Body.TrailingComments = nil
+		stripStatementComments(e.Body.Statements)
+	}
+}
@@ -0,0 +1,62 @@
+package wanf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	src := `// header
+var port = 80 // line
+
+/* block */
+server "main" {
+	// leading
+	port = ${port}
+	hosts = [
+		"a", // first
+		"b",
+		// trailing
+	]
+	limits = {[
+		// comment
+		rps = 10,
+	]}
+}
+`
+	got, err := Minify([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `var port = 80;server "main"{port = ${port};hosts = ["a","b"];limits = {[rps=10]}}`
+	if string(got) != want {
+		t.Errorf("Minify() =\n%s\nwant\n%s", got, want)
+	}
+	if bytes.Contains(got, []byte("\n")) {
+		t.Errorf("Minify() output spans several lines")
+	}
+
+	var full, minified struct {
+		Server map[string]struct {
+			Port   int            `wanf:"port"`
+			Hosts  []string       `wanf:"hosts"`
+			Limits map[string]int `wanf:"limits"`
+		} `wanf:"server"`
+	}
+	if err := Decode([]byte(src), &full); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode(got, &minified); err != nil {
+		t.Fatalf("minified output does not decode: %v", err)
+	}
+	if !reflect.DeepEqual(full, minified) {
+		t.Errorf("minified output decodes to %+v, want %+v", minified, full)
+	}
+}
+
+func TestMinify_SyntaxError(t *testing.T) {
+	if _, err := Minify([]byte("a = [1, 2")); err == nil {
+		t.Error("expected an error for invalid input")
+	}
+}
@@ -0,0 +1,70 @@
+package wanf
+
+import (
+	"os"
+	"reflect"
+)
+
+// WithMmap 使 DecodeFile 以只读方式将文件映射到内存并直接在映射上进行词法分析,
+// 不把整个文件复制到堆上, 适合数 MB 的生成配置. 解码完成后映射即被释放,
+// 解码结果中的字符串都是复制出来的, 不引用映射. 当前平台不支持 mmap、文件为空
+// 或目标类型含有 RawBlock (它会保留语法树) 时, DecodeFile 照常读取文件.
+// 对 NewDecoder 等不读取文件的函数没有作用.
+func WithMmap() DecoderOption {
+	return func(d *internalDecoder) {
+		d.mmap = true
+	}
+}
+
+// readDecodeFile 读取 DecodeFile 要解码的文件. 返回的 release 在解码结束后调用.
+func readDecodeFile(path string, typ reflect.Type, opts []DecoderOption) (data []byte, release func(), err error) {
+	var probe internalDecoder
+	for _, opt := range opts {
+		opt(&probe)
+	}
+	if probe.mmap && typ != nil && !retainsSyntax(typ, map[reflect.Type]bool{}) {
+		if data, unmap, err := mmapFile(path); err == nil {
+			return data, unmap, nil
+		}
+	}
+	data, err = probe.limits.readFile(path)
+	return data, func() {}, err
+}
+
+// mmapFile 只读映射 path 指向的文件.
+func mmapFile(path string) ([]byte, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, nil, errMmapUnsupported
+	}
+	return mmap(f, int(size))
+}
+
+// retainsSyntax 报告解码到 typ 时是否可能保留语法树 (即含有 RawBlock).
+func retainsSyntax(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Map || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ == rawBlockType {
+		return true
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return false
+	}
+	seen[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		if retainsSyntax(typ.Field(i).Type, seen) {
+			return true
+		}
+	}
+	return false
+}
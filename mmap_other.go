@@ -0,0 +1,14 @@
+//go:build !unix
+
+package wanf
+
+import (
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("memory-mapped files are not supported on this platform")
+
+func mmap(f *os.File, size int) ([]byte, func(), error) {
+	return nil, nil, errMmapUnsupported
+}
@@ -0,0 +1,78 @@
+package wanf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFileMmap(t *testing.T) {
+	type item struct {
+		Labels []string `wanf:",labels"`
+		Value  string   `wanf:"value"`
+	}
+	type config struct {
+		Name  string          `wanf:"name"`
+		Items map[string]item `wanf:"item"`
+		Any   interface{}     `wanf:"any"`
+	}
+	var b strings.Builder
+	b.WriteString("name = \"big\"\nany = { k = \"v\" }\n")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "item \"i%d\" {\n\tvalue = \"value-%d\"\n}\n", i, i)
+	}
+	path := filepath.Join(t.TempDir(), "big.wanf")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var want, got config
+	if err := DecodeFile(path, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeFile(path, &got, WithMmap()); err != nil {
+		t.Fatal(err)
+	}
+	// 映射已释放, 结果中的字符串必须仍然可读.
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("mmap decode differs from regular decode")
+	}
+	if got.Items["i1999"].Value != "value-1999" || got.Items["i7"].Labels[0] != "i7" {
+		t.Errorf("unexpected items: %+v", got.Items["i1999"])
+	}
+}
+
+func TestDecodeFileMmapFallback(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.wanf")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var c struct {
+		Name string `wanf:"name"`
+	}
+	if err := DecodeFile(empty, &c, WithMmap()); err != nil {
+		t.Errorf("empty file: %v", err)
+	}
+	if err := DecodeFile(filepath.Join(dir, "missing.wanf"), &c, WithMmap()); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v, want not-exist error", err)
+	}
+
+	// RawBlock 保留语法树, 不使用 mmap.
+	raw := filepath.Join(dir, "raw.wanf")
+	if err := os.WriteFile(raw, []byte("plugin {\n\tname = \"auth\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var r struct {
+		Plugin RawBlock `wanf:"plugin"`
+	}
+	if err := DecodeFile(raw, &r, WithMmap()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Plugin.Decode(&c); err != nil || c.Name != "auth" {
+		t.Errorf("raw block decode = %q, %v", c.Name, err)
+	}
+}
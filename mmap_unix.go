@@ -0,0 +1,19 @@
+//go:build unix
+
+package wanf
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var errMmapUnsupported = errors.New("file cannot be memory-mapped")
+
+func mmap(f *os.File, size int) ([]byte, func(), error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
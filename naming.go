@@ -0,0 +1,107 @@
+package wanf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// naming-convention 规则支持的命名风格.
+const (
+	styleSnakeCase = "snake_case"
+	styleCamelCase = "camelCase"
+)
+
+func isNamingStyle(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && (s == styleSnakeCase || s == styleCamelCase)
+}
+
+// checkNaming reports keys and block names that do not follow the configured
+// style. The suggested name is stored in Args[1] so that Fix can rename it.
+func (a *astAnalyzer) checkNaming(stmts []Statement) {
+	if !a.opts.config.enabled(ErrNamingConvention) {
+		return
+	}
+	style := a.opts.config.stringOption(ErrNamingConvention, "style")
+	if style == "" {
+		style = styleSnakeCase
+	}
+	for _, stmt := range stmts {
+		var tok Token
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			tok = s.Name.Token
+		case *BlockStatement:
+			tok = s.Name.Token
+		default:
+			continue
+		}
+		name := string(tok.Literal)
+		want := convertName(name, style)
+		if want == name {
+			continue
+		}
+		a.errors = append(a.errors, LintError{
+			Line:      tok.Line,
+			Column:    tok.Column,
+			EndLine:   tok.Line,
+			EndColumn: tok.Column + len(name),
+			Message:   fmt.Sprintf("%q should be %s: %q", name, style, want),
+			Level:     ErrorLevelFmt,
+			Type:      ErrNamingConvention,
+			Args:      []string{name, want},
+		})
+	}
+}
+
+// convertName converts an identifier to the given style. Leading underscores
+// are kept as they are.
+func convertName(name, style string) string {
+	trimmed := strings.TrimLeft(name, "_")
+	prefix := name[:len(name)-len(trimmed)]
+	words := splitWords(trimmed)
+	if len(words) == 0 {
+		return name
+	}
+	if style == styleCamelCase {
+		var b strings.Builder
+		b.WriteString(prefix)
+		b.WriteString(words[0])
+		for _, w := range words[1:] {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+		return b.String()
+	}
+	return prefix + strings.Join(words, "_")
+}
+
+// splitWords splits snake_case, camelCase and PascalCase identifiers into
+// lower-case words; "HTTPServer" becomes ["http", "server"].
+func splitWords(name string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(cur) > 0:
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
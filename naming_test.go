@@ -0,0 +1,69 @@
+package wanf
+
+import "testing"
+
+func TestConvertName(t *testing.T) {
+	tests := []struct {
+		in, snake, camel string
+	}{
+		{"listen_addr", "listen_addr", "listenAddr"},
+		{"listenAddr", "listen_addr", "listenAddr"},
+		{"HTTPServer", "http_server", "httpServer"},
+		{"maxConnsV2", "max_conns_v2", "maxConnsV2"},
+		{"_private_key", "_private_key", "_privateKey"},
+	}
+	for _, tt := range tests {
+		if got := convertName(tt.in, styleSnakeCase); got != tt.snake {
+			t.Errorf("convertName(%q, snake) = %q, want %q", tt.in, got, tt.snake)
+		}
+		if got := convertName(tt.in, styleCamelCase); got != tt.camel {
+			t.Errorf("convertName(%q, camel) = %q, want %q", tt.in, got, tt.camel)
+		}
+	}
+}
+
+func TestLint_NamingConvention(t *testing.T) {
+	src := "listenAddr = \":80\"\nserver_pool {\n\tmaxConns = 1\n}\nheaders = {[\n\tcontentType = \"json\",\n]}\n"
+
+	// The rule is opt-in.
+	if _, errs := Lint([]byte(src)); len(errs) != 0 {
+		t.Fatalf("expected no issues by default, got %v", errs)
+	}
+
+	cfg, err := ParseLintConfig([]byte(`rule "naming-convention" {
+	enabled = true
+}`))
+	if err != nil {
+		t.Fatalf("ParseLintConfig failed: %v", err)
+	}
+	_, errs := Lint([]byte(src), WithLintConfig(cfg))
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 issues (map literal keys are data), got %v", errs)
+	}
+
+	fixed, remaining, err := Fix([]byte(src), "naming-convention")
+	if err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	want := "listen_addr = \":80\"\nserver_pool {\n\tmax_conns = 1\n}\nheaders = {[\n\tcontentType = \"json\",\n]}\n"
+	if string(fixed) != want || len(remaining) != 0 {
+		t.Errorf("got %q (remaining %v), want %q", fixed, remaining, want)
+	}
+
+	camel, err := ParseLintConfig([]byte(`rule "naming-convention" {
+	enabled = true
+	options = {[
+		style = "camelCase",
+	]}
+}`))
+	if err != nil {
+		t.Fatalf("ParseLintConfig failed: %v", err)
+	}
+	fixed, _, err = FixWithConfig([]byte(src), camel)
+	if err != nil {
+		t.Fatalf("FixWithConfig failed: %v", err)
+	}
+	if want := "listenAddr = \":80\"\nserverPool {\n\tmaxConns = 1\n}\nheaders = {[\n\tcontentType = \"json\",\n]}\n"; string(fixed) != want {
+		t.Errorf("got %q, want %q", fixed, want)
+	}
+}
@@ -1,5 +1,10 @@
 package wanf
 
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
 // OutputStyle defines the different formatting styles for the output.
 type OutputStyle int
 
@@ -29,7 +34,63 @@ const (
 
 // FormatOptions provides options for controlling the formatter's output.
 type FormatOptions struct {
-	Style      OutputStyle
-	EmptyLines bool // If true, adds empty lines between blocks in supported styles.
-	NoSort     bool // If true, disables sorting within blocks.
+	Style              OutputStyle
+	EmptyLines         bool // If true, adds empty lines between blocks in supported styles.
+	NoSort             bool // If true, disables sorting within blocks.
+	AlignAssignments   bool // If true, pads keys so that '=' signs line up within a block.
+	MaxWidth           int  // If > 0, lists and maps that fit within this many columns stay on one line.
+	PreserveBlankLines bool // If true, keeps one blank line wherever the source separated statements with blank lines.
+	SortImports        bool // If true, moves top-level imports to the top of the file, sorted and deduplicated.
+	NormalizeComments  bool // If true, rewrites '#' comments as '//', adds a space after '//' and reflows long block comments.
+	RedactSecrets      bool // If true, the encoder writes fields tagged `wanf:",secret"` as "<redacted>".
+	NonFiniteAsString  bool // If true, the encoder writes NaN and ±Inf floats as the strings "NaN", "+Inf" and "-Inf" instead of failing.
+}
+
+// tabWidth is the number of columns a tab counts for when measuring line width.
+const tabWidth = 4
+
+// alignWidths returns the key width that each of n entries should be padded to
+// when aligning assignments. Consecutive entries for which key reports ok form
+// a run and share the width of their longest key; other entries break the run
+// and get 0. If split is not nil, an entry for which it reports true (such as
+// one preceded by a preserved blank line) starts a new run, as in gofmt.
+func alignWidths(n int, key func(i int) (name string, ok bool), split func(i int) bool) []int {
+	widths := make([]int, n)
+	start, max := 0, 0
+	flush := func(end int) {
+		for j := start; j < end; j++ {
+			widths[j] = max
+		}
+	}
+	for i := 0; i < n; i++ {
+		if split != nil && i > start && split(i) {
+			flush(i)
+			start, max = i, 0
+		}
+		name, ok := key(i)
+		if !ok {
+			flush(i)
+			start, max = i+1, 0
+			continue
+		}
+		if len(name) > max {
+			max = len(name)
+		}
+	}
+	flush(n)
+	return widths
+}
+
+// writePadding writes the spaces needed to pad a key of length n to width.
+func writePadding(w *bytes.Buffer, n, width int) {
+	for ; n < width; n++ {
+		w.WriteByte(' ')
+	}
+}
+
+// lineWidth returns the width in columns of the last line in b.
+func lineWidth(b []byte) int {
+	line := b[bytes.LastIndexByte(b, '\n')+1:]
+	tabs := bytes.Count(line, []byte("\t"))
+	return utf8.RuneCount(line) - tabs + tabs*tabWidth
 }
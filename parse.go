@@ -0,0 +1,119 @@
+package wanf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseError 是一个语法错误. File 为空表示错误来自 Parse 而非 ParseFile.
+type ParseError struct {
+	File      string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Message   string
+}
+
+func (e ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s: line %d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// syntaxError 返回标记 tok 处的语法错误.
+func syntaxError(tok Token, format string, args ...interface{}) error {
+	endLine, endColumn := endOf(tok, len(tok.Literal))
+	return &ParseError{
+		Line:      tok.Line,
+		Column:    tok.Column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Message:   fmt.Sprintf(format, args...),
+	}
+}
+
+// ParseErrorList 包含解析一个文档时发现的全部语法错误, 按出现顺序排列.
+type ParseErrorList struct {
+	Errors []ParseError
+}
+
+// Error 每行输出一个错误.
+func (l *ParseErrorList) Error() string {
+	msgs := make([]string, len(l.Errors))
+	for i, e := range l.Errors {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap 返回其中的每个错误, 因此 errors.As 可以取出第一个 *ParseError.
+func (l *ParseErrorList) Unwrap() []error {
+	errs := make([]error, len(l.Errors))
+	for i := range l.Errors {
+		errs[i] = &l.Errors[i]
+	}
+	return errs
+}
+
+// Parse 解析 data 并返回 AST, 不执行导入、变量替换、profile 与迁移.
+// 文档有语法错误时返回 *ParseErrorList, 此时仍返回已解析出的部分 AST.
+func Parse(data []byte) (*RootNode, error) {
+	return parse(data, "", 0)
+}
+
+// lenientMaxDepth 是 ParseLenient 的嵌套深度上限. 过深的嵌套会耗尽栈空间,
+// 这是 recover 无法处理的致命错误.
+const lenientMaxDepth = 1000
+
+// ParseLenient 解析任意字节序列, 返回尽可能完整的 AST 与全部语法错误, 供编辑器
+// 与语言服务器处理写到一半的文件. 它保证不会 panic (由 FuzzParseLenient 检验):
+// 有错误的语句被跳过, 未闭合的块、列表与映射保留已解析的部分并报告缺少的
+// 结尾括号, 嵌套超过 1000 层的部分被截断. 返回的 AST 不为 nil, 没有语法错误时
+// diags 为空.
+func ParseLenient(data []byte) (program *RootNode, diags []ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			program = &RootNode{Statements: []Statement{}}
+			diags = append(diags, ParseError{Line: 1, Column: 1, EndLine: 1, EndColumn: 1, Message: fmt.Sprintf("internal parser error: %v", r)})
+		}
+	}()
+	program, err := parse(data, "", lenientMaxDepth)
+	if list, ok := err.(*ParseErrorList); ok {
+		diags = list.Errors
+	}
+	return program, diags
+}
+
+// ParseFile 读取并解析 path 指向的文件, 错误中的 File 为 path.
+func ParseFile(path string) (*RootNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, path, 0)
+}
+
+// parse 解析 data, maxDepth 见 Parser.SetMaxDepth.
+func parse(data []byte, file string, maxDepth int) (*RootNode, error) {
+	p := NewParser(NewLexer(data))
+	p.SetMaxDepth(maxDepth)
+	program := p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		return program, nil
+	}
+	list := &ParseErrorList{Errors: make([]ParseError, len(p.Errors()))}
+	for i, e := range p.Errors() {
+		list.Errors[i] = ParseError{
+			File:      file,
+			Line:      e.Line,
+			Column:    e.Column,
+			EndLine:   e.EndLine,
+			EndColumn: e.EndColumn,
+			Message:   e.Message,
+		}
+	}
+	return program, list
+}
@@ -0,0 +1,182 @@
+package wanf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	program, err := Parse([]byte("a = 1\nserver {\n\tport = 80\n}\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(program.Statements) != 2 {
+		t.Errorf("got %d statements, want 2", len(program.Statements))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("a = 1\nb = )\n"))
+	var list *ParseErrorList
+	if !errors.As(err, &list) || len(list.Errors) == 0 {
+		t.Fatalf("Parse error = %v, want a *ParseErrorList", err)
+	}
+	e := list.Errors[0]
+	if e.File != "" || e.Line != 2 || e.EndColumn < e.Column || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("unexpected error %+v (%v)", e, err)
+	}
+}
+
+func TestParseUnclosedBlock(t *testing.T) {
+	tests := []struct {
+		src       string
+		line, col int
+	}{
+		{"server {\n", 1, 8},
+		{"a = 1\nserver \"main\" {\n\tport = 80\n", 2, 15},
+		{"s {\n\t// note\n", 1, 3},
+		{"a = {\n\tb = 1\n", 1, 5},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.src))
+		var list *ParseErrorList
+		if !errors.As(err, &list) || len(list.Errors) == 0 {
+			t.Errorf("Parse(%q) error = %v, want a *ParseErrorList", tt.src, err)
+			continue
+		}
+		e := list.Errors[len(list.Errors)-1]
+		if e.Line != tt.line || e.Column != tt.col || !strings.Contains(e.Message, "missing }") {
+			t.Errorf("Parse(%q) error = %v, want missing } at %d:%d", tt.src, err, tt.line, tt.col)
+		}
+	}
+}
+
+func TestParseHashComment(t *testing.T) {
+	for _, src := range []string{"# note\na = 1\n", "a = 1 # note\n", "s {\n\t# note\n\ta = 1\n}\n"} {
+		_, err := Parse([]byte(src))
+		if err == nil || !strings.Contains(err.Error(), "'#' comments are not supported, use '//' instead") {
+			t.Errorf("Parse(%q) error = %v", src, err)
+		}
+		var cfg struct {
+			A int `wanf:"a"`
+			S struct {
+				A int `wanf:"a"`
+			} `wanf:"s"`
+		}
+		dec, err := NewStreamDecoder(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.Decode(&cfg); err == nil || !strings.Contains(err.Error(), "use '//' instead") {
+			t.Errorf("StreamDecoder(%q) error = %v", src, err)
+		}
+		fixed, remaining, err := Fix([]byte(src))
+		if err != nil || len(remaining) != 0 || strings.Contains(string(fixed), "#") {
+			t.Errorf("Fix(%q) = %q, %v, %v", src, fixed, remaining, err)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.wanf")
+	if err := os.WriteFile(path, []byte("a = [1, 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ParseFile(path)
+	var list *ParseErrorList
+	if !errors.As(err, &list) || list.Errors[0].File != path || !strings.HasPrefix(err.Error(), path+": line ") {
+		t.Errorf("ParseFile error = %v", err)
+	}
+
+	// 解码器返回的错误同样可以取出语法错误列表.
+	err = DecodeFile(path, &struct{}{})
+	if !errors.As(err, &list) {
+		t.Errorf("DecodeFile error = %v, want it to wrap a *ParseErrorList", err)
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	src := "name = \"app\"\nserver \"main\" {\n\tport = 80\n\thost = \n"
+	program, diags := ParseLenient([]byte(src))
+	if len(diags) == 0 || diags[0].File != "" {
+		t.Errorf("diags = %v, want syntax errors", diags)
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("got %d statements, want 2: %s", len(program.Statements), program)
+	}
+	if v, ok := lookupStatement(program, `server."main".port`); !ok || v != "80" {
+		t.Errorf("server.\"main\".port = %q, %v", v, ok)
+	}
+
+	program, diags = ParseLenient([]byte("server {\n\tport = 80\n"))
+	if len(diags) != 1 || diags[0].Line != 1 || diags[0].Column != 8 || !strings.Contains(diags[0].Message, "missing }") {
+		t.Errorf("unclosed block diags = %v, want missing } at 1:8", diags)
+	}
+	if v, ok := lookupStatement(program, "server.port"); !ok || v != "80" {
+		t.Errorf("server.port = %q, %v", v, ok)
+	}
+
+	for _, src := range []string{"", "a = 1\n", "\nA\"\"", "s {", "A=[ ", "var", "import", strings.Repeat("a = [", 5000)} {
+		program, diags := ParseLenient([]byte(src))
+		if program == nil {
+			t.Errorf("ParseLenient(%.20q) returned a nil AST", src)
+		}
+		if _, err := Parse([]byte(src)); (err == nil) != (len(diags) == 0) {
+			t.Errorf("ParseLenient(%.20q) diags = %v, Parse error = %v", src, diags, err)
+		}
+	}
+}
+
+// lookupStatement 返回 path 处赋值的值的源码形式.
+func lookupStatement(program *RootNode, path string) (string, bool) {
+	var val string
+	found := false
+	Inspect(program, func(c *Cursor) bool {
+		if as, ok := c.Node.(*AssignStatement); ok && joinPath(c.Path, string(as.Name.Value)) == path && as.Value != nil {
+			val, found = as.Value.String(), true
+		}
+		return true
+	})
+	return val, found
+}
+
+// FuzzParseLenient 检验 ParseLenient 对任意输入都不会 panic, 且返回的 AST 可以
+// 安全地遍历与输出.
+func FuzzParseLenient(f *testing.F) {
+	files, _ := filepath.Glob("testfile/*.wanf")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, src := range []string{
+		"a = {[ b = 1, c = [1, 2] ]}\ns \"x\" \"y\" { v = ${name} }\n",
+		"var x = env(\"HOME\", \"/\")\nimport \"a.wanf\"\nk = secret(\"ref\")\n",
+		"a = [1, 2\nb = {\n# c\n",
+		"\xef\xbb\xbfa = \"x\r\n",
+	} {
+		f.Add([]byte(src))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		program, diags := ParseLenient(data)
+		if program == nil {
+			t.Fatal("nil AST")
+		}
+		for _, d := range diags {
+			if strings.HasPrefix(d.Message, "internal parser error") {
+				t.Fatal(d.Message)
+			}
+		}
+		Walk(program, func(n Node) bool {
+			n.Pos()
+			n.End()
+			return true
+		})
+		_ = program.String()
+	})
+}
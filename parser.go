@@ -43,8 +43,147 @@ const (
 	ErrUnusedVariable
 	ErrExpectDiffToken
 	ErrMissingComma
+	ErrUnknownProfile
+	ErrDuplicateKey
+	ErrDuplicateBlock
+	ErrNamingConvention
+	ErrUnresolvedImport
+	ErrUnusedImport
+	ErrMaxDepth
+	ErrMaxLines
+	ErrMaxKeys
+	ErrImportOrder
+	ErrHashComment
+	ErrSchemaViolation
+	ErrSchemaUnknown
+	ErrPlaintextSecret
+	ErrFileEncoding
 )
 
+// ruleNames 是每种 ErrorType 在 lint 配置文件中使用的规则名.
+var ruleNames = map[ErrorType]string{
+	ErrUnexpectedToken:  "unexpected-token",
+	ErrRedundantComma:   "redundant-comma",
+	ErrRedundantLabel:   "redundant-label",
+	ErrUnusedVariable:   "unused-variable",
+	ErrExpectDiffToken:  "expect-diff-token",
+	ErrMissingComma:     "missing-comma",
+	ErrUnknownProfile:   "unknown-profile",
+	ErrDuplicateKey:     "duplicate-key",
+	ErrDuplicateBlock:   "duplicate-block",
+	ErrNamingConvention: "naming-convention",
+	ErrUnresolvedImport: "unresolved-import",
+	ErrUnusedImport:     "unused-import",
+	ErrMaxDepth:         "max-depth",
+	ErrMaxLines:         "max-lines",
+	ErrMaxKeys:          "max-keys",
+	ErrImportOrder:      "import-order",
+	ErrHashComment:      "hash-comment",
+	ErrSchemaViolation:  "schema",
+	ErrSchemaUnknown:    "schema-unknown",
+	ErrPlaintextSecret:  "plaintext-secret",
+	ErrFileEncoding:     "file-encoding",
+}
+
+// ruleIDs 是每种 ErrorType 的稳定规则 ID, 用于输出和抑制注释. 已分配的 ID 不可更改.
+var ruleIDs = map[ErrorType]string{
+	ErrUnexpectedToken:  "WANF001",
+	ErrRedundantComma:   "WANF002",
+	ErrMissingComma:     "WANF003",
+	ErrRedundantLabel:   "WANF004",
+	ErrUnusedVariable:   "WANF005",
+	ErrExpectDiffToken:  "WANF006",
+	ErrUnknownProfile:   "WANF007",
+	ErrDuplicateKey:     "WANF008",
+	ErrDuplicateBlock:   "WANF009",
+	ErrNamingConvention: "WANF010",
+	ErrUnresolvedImport: "WANF011",
+	ErrUnusedImport:     "WANF012",
+	ErrMaxDepth:         "WANF013",
+	ErrMaxLines:         "WANF014",
+	ErrMaxKeys:          "WANF015",
+	ErrImportOrder:      "WANF016",
+	ErrHashComment:      "WANF017",
+	ErrSchemaViolation:  "WANF018",
+	ErrSchemaUnknown:    "WANF019",
+	ErrPlaintextSecret:  "WANF020",
+	ErrFileEncoding:     "WANF021",
+}
+
+// RuleID returns the stable rule ID of the error type, e.g. "WANF004".
+func (t ErrorType) RuleID() string {
+	if id, ok := ruleIDs[t]; ok {
+		return id
+	}
+	return "WANF000"
+}
+
+// String returns the rule name of the error type, e.g. "unused-variable".
+func (t ErrorType) String() string {
+	if name, ok := ruleNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseErrorType returns the ErrorType whose rule name or rule ID is name.
+func ParseErrorType(name string) (ErrorType, bool) {
+	for t, n := range ruleNames {
+		if n == name || ruleIDs[t] == name {
+			return t, true
+		}
+	}
+	return ErrUnknown, false
+}
+
+// Severity 表示 lint 问题的严重程度, 可通过 lint 配置文件按规则调整.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSeverity parses "error", "warning" or "info".
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "error":
+		return SeverityError, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "info":
+		return SeverityInfo, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected error, warning or info", s)
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
 type LintError struct {
 	Line      int        `json:"line"`
 	Column    int        `json:"column"`
@@ -53,6 +192,8 @@ type LintError struct {
 	Message   string     `json:"message"`
 	Level     ErrorLevel `json:"level"`
 	Type      ErrorType  `json:"type"`
+	Rule      string     `json:"rule"`
+	Severity  Severity   `json:"severity"`
 	Args      []string   `json:"args,omitempty"`
 }
 
@@ -68,6 +209,11 @@ type Parser struct {
 	prefixParseFns map[TokenType]prefixParseFn
 	LintMode       bool
 	lintErrors     []LintError
+	prevLine       int // 上一个标记结束的行号, 用于统计语句前的空行
+	arena          *nodeArena
+	maxDepth       int  // 块、列表与映射的最大嵌套深度, 见 SetMaxDepth
+	depth          int  // 当前的嵌套深度
+	stopped        bool // 超出 maxDepth 后停止解析
 }
 
 func NewParser(l lexer) *Parser {
@@ -75,6 +221,7 @@ func NewParser(l lexer) *Parser {
 		l:          l,
 		errors:     []LintError{},
 		lintErrors: []LintError{},
+		arena:      &nodeArena{},
 	}
 	p.prefixParseFns = make(map[TokenType]prefixParseFn)
 	p.registerPrefix(IDENT, p.parseIdentifier)
@@ -97,19 +244,32 @@ func (p *Parser) Errors() []LintError {
 func (p *Parser) SetLintMode(enabled bool) {
 	p.LintMode = enabled
 }
+
+// SetMaxDepth limits the nesting of blocks, lists and maps to n levels, see
+// WithMaxDepth. n <= 0 removes the limit.
+func (p *Parser) SetMaxDepth(n int) {
+	p.maxDepth = n
+}
 func (p *Parser) LintErrors() []LintError {
 	return p.lintErrors
 }
 func (p *Parser) nextToken() {
+	p.prevLine = p.curToken.endLine()
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
 }
 
 func (p *Parser) ParseProgram() *RootNode {
-	program := &RootNode{}
+	program := &RootNode{arena: p.arena}
 	program.Statements = []Statement{}
 	for !p.curTokenIs(EOF) {
-		stmt := p.parseStatement()
+		blankLines := p.curToken.Line - p.prevLine - 1
+		leading := p.parseLeadingComments()
+		if p.curTokenIs(EOF) {
+			program.TrailingComments = leading
+			break
+		}
+		stmt := p.parseCommentedStatement(leading, blankLines)
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -119,38 +279,86 @@ func (p *Parser) ParseProgram() *RootNode {
 
 func (p *Parser) parseLeadingComments() []*Comment {
 	var comments []*Comment
-	for p.curTokenIs(COMMENT) {
-		comment := &Comment{Token: p.curToken, Text: p.curToken.Literal}
-		comments = append(comments, comment)
+	for p.isComment(p.curToken) {
+		comments = append(comments, p.newComment())
 		p.nextToken()
 	}
 	return comments
 }
 
-func (p *Parser) parseStatement() Statement {
-	leadingComments := p.parseLeadingComments()
+// isComment reports whether tok is a comment. In lint mode `#` comments are
+// accepted too, so that they are kept and reported as hash-comment issues.
+func (p *Parser) isComment(tok Token) bool {
+	return tok.Type == COMMENT || (p.LintMode && tok.Type == ILLEGAL_COMMENT)
+}
+
+// hashCommentMessage is reported for `#` comments, the most common mistake
+// when coming from other configuration formats. Fix and `wanflint fmt
+// -normcomments` rewrite them as `//`.
+const hashCommentMessage = "'#' comments are not supported, use '//' instead"
 
+// newComment creates a comment node for the current token.
+func (p *Parser) newComment() *Comment {
+	if p.curTokenIs(ILLEGAL_COMMENT) {
+		endLine, endColumn := endOf(p.curToken, len(p.curToken.Literal))
+		p.lintErrors = append(p.lintErrors, LintError{
+			Line:      p.curToken.Line,
+			Column:    p.curToken.Column,
+			EndLine:   endLine,
+			EndColumn: endColumn,
+			Message:   hashCommentMessage,
+			Level:     ErrorLevelFmt,
+			Type:      ErrHashComment,
+		})
+	}
+	c := p.arena.comment()
+	*c = Comment{Token: p.curToken, Text: p.curToken.Literal}
+	return c
+}
+
+// parseCommentedStatement parses a statement whose leading comments have
+// already been consumed.
+func (p *Parser) parseCommentedStatement(leadingComments []*Comment, blankLines int) Statement {
 	if p.curTokenIs(EOF) {
 		return nil
 	}
 
+	// The statement parsers return typed nil pointers after reporting an
+	// error; they must not end up in the Statement interface.
 	var stmt Statement
+	failed := false
 	switch p.curToken.Type {
 	case SEMICOLON:
 		p.nextToken()
 		return nil
 	case VAR:
-		stmt = p.parseVarStatement(leadingComments)
+		if s := p.parseVarStatement(leadingComments); s != nil {
+			stmt = s
+		} else {
+			failed = true
+		}
 	case IMPORT:
-		stmt = p.parseImportStatement(leadingComments)
+		if s := p.parseImportStatement(leadingComments); s != nil {
+			stmt = s
+		} else {
+			failed = true
+		}
 	case IDENT:
 		if p.peekTokenIs(ASSIGN) {
 			stmt = p.parseAssignStatement(leadingComments)
 		} else if p.peekTokenIs(LBRACE) || p.peekTokenIs(STRING) {
-			stmt = p.parseBlockStatement(leadingComments)
+			if s := p.parseBlockStatement(leadingComments); s != nil {
+				stmt = s
+			} else {
+				failed = true
+			}
 		}
 	}
 
+	if failed {
+		p.nextToken()
+		return nil
+	}
 	if stmt == nil {
 		if p.LintMode {
 			message := fmt.Sprintf("unexpected token %s (%s)", p.curToken.Type, string(p.curToken.Literal))
@@ -161,16 +369,19 @@ func (p *Parser) parseStatement() Statement {
 			if p.curToken.Type != ILLEGAL {
 				args = []string{string(p.curToken.Type), string(p.curToken.Literal)}
 			}
+			endLine, endColumn := endOf(p.curToken, len(p.curToken.Literal))
 			p.lintErrors = append(p.lintErrors, LintError{
 				Line:      p.curToken.Line,
 				Column:    p.curToken.Column,
-				EndLine:   p.curToken.Line,
-				EndColumn: p.curToken.Column + len(p.curToken.Literal),
+				EndLine:   endLine,
+				EndColumn: endColumn,
 				Message:   message,
 				Level:     ErrorLevelLint,
 				Type:      ErrUnexpectedToken,
 				Args:      args,
 			})
+		} else if p.curTokenIs(ILLEGAL_COMMENT) {
+			p.appendError(hashCommentMessage)
 		} else {
 			p.appendError(fmt.Sprintf("unexpected token %s (%s)", p.curToken.Type, string(p.curToken.Literal)))
 		}
@@ -178,9 +389,22 @@ func (p *Parser) parseStatement() Statement {
 		return nil
 	}
 
-	if p.peekTokenIs(COMMENT) && p.peekToken.Line == p.curToken.Line {
+	if blankLines > 0 {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			s.BlankLinesBefore = blankLines
+		case *BlockStatement:
+			s.BlankLinesBefore = blankLines
+		case *VarStatement:
+			s.BlankLinesBefore = blankLines
+		case *ImportStatement:
+			s.BlankLinesBefore = blankLines
+		}
+	}
+
+	if p.isComment(p.peekToken) && p.peekToken.Line == p.curToken.Line {
 		p.nextToken()
-		lineComment := &Comment{Token: p.curToken, Text: p.curToken.Literal}
+		lineComment := p.newComment()
 		switch s := stmt.(type) {
 		case *AssignStatement:
 			s.LineComment = lineComment
@@ -196,8 +420,9 @@ func (p *Parser) parseStatement() Statement {
 }
 
 func (p *Parser) parseAssignStatement(leading []*Comment) *AssignStatement {
-	stmt := &AssignStatement{Token: p.curToken, LeadingComments: leading}
-	stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt := p.arena.assign()
+	*stmt = AssignStatement{Token: p.curToken, LeadingComments: leading}
+	stmt.Name = p.newIdentifier()
 	p.nextToken()
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
@@ -205,25 +430,44 @@ func (p *Parser) parseAssignStatement(leading []*Comment) *AssignStatement {
 }
 
 func (p *Parser) parseBlockStatement(leading []*Comment) *BlockStatement {
-	stmt := &BlockStatement{Token: p.curToken, LeadingComments: leading}
-	stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt := p.arena.block()
+	*stmt = BlockStatement{Token: p.curToken, LeadingComments: leading}
+	stmt.Name = p.newIdentifier()
 	if p.peekTokenIs(STRING) {
 		p.nextToken()
 		stmt.Label = p.parseStringLiteral().(*StringLiteral)
 	}
+	for p.peekTokenIs(STRING) {
+		p.nextToken()
+		stmt.ExtraLabels = append(stmt.ExtraLabels, p.parseStringLiteral().(*StringLiteral))
+	}
 	if !p.expectPeek(LBRACE) {
 		return nil
 	}
 	stmt.Body = p.parseBlockBody()
+	if p.curTokenIs(RBRACE) {
+		stmt.Rbrace = p.curToken
+	}
 	return stmt
 }
 
 func (p *Parser) parseBlockBody() *RootNode {
-	body := &RootNode{}
+	body := p.arena.root()
 	body.Statements = []Statement{}
+	if !p.enterNesting() {
+		return body
+	}
+	defer p.leaveNesting()
+	lbrace := p.curToken
 	p.nextToken()
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
-		stmt := p.parseStatement()
+		blankLines := p.curToken.Line - p.prevLine - 1
+		leading := p.parseLeadingComments()
+		if p.curTokenIs(RBRACE) || p.curTokenIs(EOF) {
+			body.TrailingComments = leading
+			break
+		}
+		stmt := p.parseCommentedStatement(leading, blankLines)
 		if stmt != nil {
 			body.Statements = append(body.Statements, stmt)
 		}
@@ -240,6 +484,9 @@ func (p *Parser) parseBlockBody() *RootNode {
 			p.nextToken()
 		}
 	}
+	if p.curTokenIs(EOF) {
+		p.appendErrorAt(lbrace, "missing } to close the block opened here")
+	}
 	return body
 }
 
@@ -248,7 +495,7 @@ func (p *Parser) parseVarStatement(leading []*Comment) *VarStatement {
 	if !p.expectPeek(IDENT) {
 		return nil
 	}
-	stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name = p.newIdentifier()
 	if !p.expectPeek(ASSIGN) {
 		return nil
 	}
@@ -276,17 +523,31 @@ func (p *Parser) parseExpression(precedence int) Expression {
 	return leftExp
 }
 
-var envLiteral = []byte("env")
+var (
+	envLiteral    = []byte("env")
+	secretLiteral = []byte("secret")
+)
 
 func (p *Parser) parseIdentifier() Expression {
 	if bytes.Equal(p.curToken.Literal, envLiteral) && p.peekTokenIs(LPAREN) {
 		return p.parseEnvExpression()
 	}
-	return &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if bytes.Equal(p.curToken.Literal, secretLiteral) && p.peekTokenIs(LPAREN) {
+		return p.parseSecretExpression()
+	}
+	return p.newIdentifier()
+}
+
+// newIdentifier creates an identifier node for the current token.
+func (p *Parser) newIdentifier() *Identifier {
+	id := p.arena.ident()
+	*id = Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return id
 }
 
 func (p *Parser) parseIntegerLiteral() Expression {
-	lit := &IntegerLiteral{Token: p.curToken}
+	lit := p.arena.intLit()
+	*lit = IntegerLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(BytesToString(p.curToken.Literal), 0, 64)
 	if err != nil {
 		p.appendError(fmt.Sprintf("could not parse %q as integer", p.curToken.Literal))
@@ -297,7 +558,8 @@ func (p *Parser) parseIntegerLiteral() Expression {
 }
 
 func (p *Parser) parseFloatLiteral() Expression {
-	lit := &FloatLiteral{Token: p.curToken}
+	lit := p.arena.floatLit()
+	*lit = FloatLiteral{Token: p.curToken}
 	value, err := strconv.ParseFloat(BytesToString(p.curToken.Literal), 64)
 	if err != nil {
 		p.appendError(fmt.Sprintf("could not parse %q as float", p.curToken.Literal))
@@ -308,11 +570,14 @@ func (p *Parser) parseFloatLiteral() Expression {
 }
 
 func (p *Parser) parseStringLiteral() Expression {
-	return &StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	lit := p.arena.stringLit()
+	*lit = StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	return lit
 }
 
 func (p *Parser) parseBooleanLiteral() Expression {
-	lit := &BoolLiteral{Token: p.curToken}
+	lit := p.arena.boolLit()
+	*lit = BoolLiteral{Token: p.curToken}
 	value, err := strconv.ParseBool(BytesToString(p.curToken.Literal))
 	if err != nil {
 		p.appendError(fmt.Sprintf("could not parse %q as boolean", p.curToken.Literal))
@@ -323,16 +588,75 @@ func (p *Parser) parseBooleanLiteral() Expression {
 }
 
 func (p *Parser) parseDurationLiteral() Expression {
-	return &DurationLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	lit := p.arena.durationLit()
+	*lit = DurationLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	return lit
 }
 
 func (p *Parser) parseListLiteral() Expression {
 	list := &ListLiteral{Token: p.curToken}
+	if !p.enterNesting() {
+		return list
+	}
+	defer p.leaveNesting()
 	p.nextToken()
-	list.Elements = p.parseExpressionList(RBRACK)
+	var pending []*Comment // 缺少逗号时, 已读取的下一个元素的前置注释
+	for {
+		leading := append(pending, p.parseLeadingComments()...)
+		pending = nil
+		if p.curTokenIs(RBRACK) {
+			list.TrailingComments = leading
+			break
+		}
+		comments := ElementComments{Leading: leading}
+		elem := p.parseExpression(LOWEST)
+		if elem == nil {
+			// 错误已经报告; 不在列表中留下 nil 元素.
+			break
+		}
+		list.Elements = append(list.Elements, elem)
+		comma := p.peekTokenIs(COMMA)
+		if comma {
+			p.nextToken()
+		}
+		if p.isComment(p.peekToken) && p.peekToken.Line == p.curToken.Line {
+			p.nextToken()
+			comments.Line = p.newComment()
+		}
+		list.setComments(len(list.Elements)-1, comments)
+		p.nextToken()
+		if !comma {
+			// 没有逗号时列表必须结束, 中间只允许出现注释. 后面紧跟着另一个
+			// 元素时报告缺少逗号并继续解析, 以便 Fix 一次补全所有逗号.
+			trailing := p.parseLeadingComments()
+			if !p.curTokenIs(RBRACK) && p.startsListElement() {
+				p.appendTypedErrorAt(p.curToken, ErrMissingComma, fmt.Sprintf("missing comma before %s in list", p.curToken.Type))
+				pending = trailing
+				continue
+			}
+			list.TrailingComments = trailing
+			if !p.curTokenIs(RBRACK) {
+				p.appendError(fmt.Sprintf("expected next token to be %s, got %s instead", RBRACK, p.curToken.Type))
+			}
+			break
+		}
+	}
+	if p.curTokenIs(RBRACK) {
+		list.Rbrack = p.curToken
+	}
 	return list
 }
 
+// startsListElement reports whether the current token can begin a list
+// element. An identifier only does as a function call such as env(...); one
+// followed by `=` or `{` starts the next statement of an unclosed list.
+func (p *Parser) startsListElement() bool {
+	if p.curTokenIs(IDENT) {
+		return p.peekTokenIs(LPAREN)
+	}
+	return p.prefixParseFns[p.curToken.Type] != nil
+}
+
 func (p *Parser) parseBlockOrMapLiteral() Expression {
 	if p.peekTokenIs(LBRACK) {
 		return p.parseMapLiteral()
@@ -342,46 +666,41 @@ func (p *Parser) parseBlockOrMapLiteral() Expression {
 
 func (p *Parser) parseMapLiteral() Expression {
 	mapLit := &MapLiteral{Token: p.curToken} // cur is {
-	p.nextToken()                            // consume {, cur is [
-	p.nextToken()                            // consume [, cur is first element
+	if !p.enterNesting() {
+		return nil
+	}
+	defer p.leaveNesting()
+	p.nextToken() // consume {, cur is [
+	p.nextToken() // consume [, cur is first element
 
-	mapLit.Elements = p.parseMapElementList()
-	if mapLit.Elements == nil {
+	elements, trailing, ok := p.parseMapElementList()
+	if !ok {
 		return nil
 	}
+	mapLit.Elements = elements
+	mapLit.TrailingComments = trailing
 
 	// after parseMapElementList, curToken is RBRACK
 	if !p.expectPeek(RBRACE) {
 		return nil
 	}
+	mapLit.Rbrace = p.curToken
 	return mapLit
 }
 
-func (p *Parser) parseMapElementList() []Statement {
+// parseMapElementList parses the entries of a map literal up to the closing
+// `]`, together with the comments that follow the last entry.
+func (p *Parser) parseMapElementList() ([]Statement, []*Comment, bool) {
 	var elements []Statement
-
-	if p.curTokenIs(RBRACK) {
-		return elements
-	}
-
+	needComma := false
 	for {
-		stmt := p.parseStatement()
-		if stmt == nil {
-			// A fatal error occurred in parseStatement, abort.
-			return nil
-		}
-		elements = append(elements, stmt)
-
+		blankLines := p.curToken.Line - p.prevLine - 1
+		leading := p.parseLeadingComments()
 		if p.curTokenIs(RBRACK) {
-			break // End of list
+			return elements, leading, true
 		}
 
-		if p.curTokenIs(COMMA) {
-			p.nextToken() // Consume comma
-			if p.curTokenIs(RBRACK) {
-				break // Trailing comma
-			}
-		} else {
+		if needComma {
 			// Error recovery: comma is missing.
 			// Log a warning and proceed as if a comma was there.
 			msg := fmt.Sprintf("missing comma, auto-inserted before %s", p.curToken.Type)
@@ -396,14 +715,33 @@ func (p *Parser) parseMapElementList() []Statement {
 				Args:      []string{string(p.curToken.Type)},
 			})
 		}
-	}
 
-	return elements
+		stmt := p.parseCommentedStatement(leading, blankLines)
+		if stmt == nil {
+			// A fatal error occurred in parseStatement, abort.
+			return nil, nil, false
+		}
+		elements = append(elements, stmt)
+
+		needComma = !p.curTokenIs(COMMA)
+		if !needComma {
+			commaLine := p.curToken.Line
+			p.nextToken() // Consume comma
+			// 逗号之后的同行注释属于该条目, 如 `a = 1, // note`.
+			if as, ok := stmt.(*AssignStatement); ok && as.LineComment == nil && p.isComment(p.curToken) && p.curToken.Line == commaLine {
+				as.LineComment = p.newComment()
+				p.nextToken()
+			}
+		}
+	}
 }
 
 func (p *Parser) parseBlockLiteral() Expression {
 	block := &BlockLiteral{Token: p.curToken}
 	block.Body = p.parseBlockBody()
+	if p.curTokenIs(RBRACE) {
+		block.Rbrace = p.curToken
+	}
 	return block
 }
 
@@ -416,6 +754,7 @@ func (p *Parser) parseVarExpression() Expression {
 	if !p.expectPeek(RBRACE) {
 		return nil
 	}
+	expr.Rbrace = p.curToken
 	return expr
 }
 
@@ -442,27 +781,55 @@ func (p *Parser) parseEnvExpression() Expression {
 	if !p.expectPeek(RPAREN) {
 		return nil
 	}
+	expr.Rparen = p.curToken
 	return expr
 }
 
-func (p *Parser) parseExpressionList(end TokenType) []Expression {
-	var list []Expression
-	if p.curTokenIs(end) {
-		return list
+func (p *Parser) parseSecretExpression() Expression {
+	expr := &SecretExpression{Token: p.curToken}
+	if !p.expectPeek(LPAREN) {
+		return nil
 	}
-	list = append(list, p.parseExpression(LOWEST))
-	for p.peekTokenIs(COMMA) {
-		p.nextToken()
-		p.nextToken()
-		if p.curTokenIs(end) {
-			break
-		}
-		list = append(list, p.parseExpression(LOWEST))
+	p.nextToken()
+	if !p.curTokenIs(STRING) {
+		p.appendError("expected string argument for secret()")
+		return nil
+	}
+	expr.Ref = p.parseStringLiteral().(*StringLiteral)
+	if !p.expectPeek(RPAREN) {
+		return nil
 	}
-	if !p.curTokenIs(end) {
-		p.expectPeek(end)
+	expr.Rparen = p.curToken
+	return expr
+}
+
+// enterNesting is called when a block, list or map is opened. Past maxDepth
+// it reports an error and stops parsing: the rest of the input is treated as
+// EOF, so that hostile input cannot make the parser recurse any deeper.
+func (p *Parser) enterNesting() bool {
+	if p.maxDepth > 0 && p.depth >= p.maxDepth {
+		p.appendError(fmt.Sprintf("nesting depth exceeds the maximum of %d", p.maxDepth))
+		eof := Token{Type: EOF, Line: p.curToken.Line, Column: p.curToken.Column}
+		p.l = eofLexer{eof}
+		p.curToken, p.peekToken = eof, eof
+		p.stopped = true
+		return false
 	}
-	return list
+	p.depth++
+	return true
+}
+
+func (p *Parser) leaveNesting() {
+	p.depth--
+}
+
+// eofLexer returns EOF forever; it replaces the lexer once parsing stops.
+type eofLexer struct {
+	eof Token
+}
+
+func (l eofLexer) NextToken() Token {
+	return l.eof
 }
 
 func (p *Parser) curTokenIs(t TokenType) bool {
@@ -484,6 +851,15 @@ func (p *Parser) peekError(t TokenType) {
 	p.appendErrorAt(p.peekToken, msg)
 }
 func (p *Parser) noPrefixParseFnError(t TokenType) {
+	if t == ILLEGAL && len(p.curToken.Literal) > 1 {
+		// 词法错误, 如 "unterminated string literal"
+		p.appendError(string(p.curToken.Literal))
+		return
+	}
+	if t == ILLEGAL_COMMENT {
+		p.appendError(hashCommentMessage)
+		return
+	}
 	p.appendError(fmt.Sprintf("no prefix parse function for %s found", t))
 }
 
@@ -492,14 +868,23 @@ func (p *Parser) appendError(msg string) {
 }
 
 func (p *Parser) appendErrorAt(tok Token, msg string) {
+	p.appendTypedErrorAt(tok, ErrUnexpectedToken, msg)
+}
+
+func (p *Parser) appendTypedErrorAt(tok Token, typ ErrorType, msg string) {
+	if p.stopped {
+		// 停止解析后的错误只是截断输入的结果.
+		return
+	}
+	endLine, endColumn := endOf(tok, len(tok.Literal))
 	p.errors = append(p.errors, LintError{
 		Line:      tok.Line,
 		Column:    tok.Column,
-		EndLine:   tok.Line,
-		EndColumn: tok.Column + len(tok.Literal),
+		EndLine:   endLine,
+		EndColumn: endColumn,
 		Message:   "parser error: " + msg,
 		Level:     ErrorLevelLint,
-		Type:      ErrUnexpectedToken,
+		Type:      typ,
 	})
 }
 
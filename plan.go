@@ -0,0 +1,243 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// Plan 是针对一个结构体类型预先编译的解码计划. 字段匹配 (包括大小写不敏感的
+// 匹配)、嵌套结构体、带标签块与 key= 字段的处理方式都在 CompileType 时确定,
+// 解码时按计划直接取字段, 不再逐个语句查找字段缓存或遍历字段列表.
+// Plan 不可变, 可以在多个 goroutine 中并发使用.
+type Plan struct {
+	typ  reflect.Type
+	root *structPlan
+}
+
+// structPlan 是一个结构体类型的字段表.
+type structPlan struct {
+	typ    reflect.Type
+	fields map[string]*fieldPlan // 按键精确匹配
+	fold   map[string]*fieldPlan // 小写键, 仅含键与字段名相同的字段
+	labels int                   // `wanf:",labels"` 字段的下标, 没有时为 -1
+	custom bool                  // RawBlock 或实现了 Unmarshaler, 交给常规解码
+}
+
+// fieldPlan 描述一个字段的解码方式.
+type fieldPlan struct {
+	index int
+	tag   wanfTag
+	block *structPlan // 结构体、结构体指针以及元素为结构体的 map 与切片字段
+}
+
+// CompileType 为结构体类型 typ (或指向结构体的指针类型) 编译解码计划.
+// 计划只需编译一次, 之后可反复用于 Plan.Decode. typ 不是结构体时,
+// 返回的计划在解码时报错.
+func CompileType(typ reflect.Type) *Plan {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	p := &Plan{typ: typ}
+	if typ.Kind() == reflect.Struct {
+		p.root = compileStruct(typ, map[reflect.Type]*structPlan{})
+	}
+	return p
+}
+
+// compileStruct 编译 typ 的字段表. seen 保存已编译的类型, 使递归类型共用同一个计划.
+func compileStruct(typ reflect.Type, seen map[reflect.Type]*structPlan) *structPlan {
+	if sp, ok := seen[typ]; ok {
+		return sp
+	}
+	sp := &structPlan{
+		typ:    typ,
+		fields: map[string]*fieldPlan{},
+		fold:   map[string]*fieldPlan{},
+		labels: -1,
+		custom: typ == rawBlockType || reflect.PointerTo(typ).Implements(unmarshalerType),
+	}
+	seen[typ] = sp
+
+	// 与 getOrCacheDecoderFields 相同: 后出现的同名键覆盖先出现的,
+	// 字段名只在没有 wanf 标签时作为补充的键.
+	byIndex := map[int]*fieldPlan{}
+	for key, f := range getOrCacheDecoderFields(typ) {
+		fp, ok := byIndex[f.Index]
+		if !ok {
+			fp = &fieldPlan{index: f.Index, tag: f.Tag, block: compileBlock(f.FieldTyp.Type, seen)}
+			byIndex[f.Index] = fp
+		}
+		sp.fields[key] = fp
+		if f.Tag.Labels && sp.labels < 0 {
+			sp.labels = f.Index
+		}
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		fp, ok := byIndex[i]
+		if !ok || fp.tag.Name != typ.Field(i).Name {
+			continue
+		}
+		lower := strings.ToLower(fp.tag.Name)
+		if _, ok := sp.fold[lower]; !ok {
+			sp.fold[lower] = fp
+		}
+	}
+	return sp
+}
+
+// compileBlock 返回 typ 作为块解码时其中结构体的计划, typ 不含结构体时返回 nil.
+func compileBlock(typ reflect.Type, seen map[reflect.Type]*structPlan) *structPlan {
+	for {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			typ = typ.Elem()
+			continue
+		case reflect.Struct:
+			return compileStruct(typ, seen)
+		}
+		return nil
+	}
+}
+
+func (sp *structPlan) lookup(name []byte) *fieldPlan {
+	if fp, ok := sp.fields[string(name)]; ok {
+		return fp
+	}
+	if len(sp.fold) == 0 {
+		return nil
+	}
+	return sp.fold[string(bytes.ToLower(name))]
+}
+
+// Decode 解析 data 并按计划解码到 v 中, 结果与 Decode 相同. v 必须是指向
+// 编译计划时所用结构体类型的指针.
+func (p *Plan) Decode(data []byte, v interface{}, opts ...DecoderOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("v must be a pointer to a struct")
+	}
+	if rv.Elem().Type() != p.typ {
+		return fmt.Errorf("plan compiled for %s cannot decode into %s", p.typ, rv.Elem().Type())
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	d := newInternalDecoder(opts)
+	program, err := d.parseFile(data, "")
+	if err != nil {
+		return fmt.Errorf("parser errors: %w", err)
+	}
+	dec, err := d.load(program)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	d.beginProvenance(dec.program)
+	err = d.fileError(d.decodePlan(p.root, dec.program, rv.Elem()))
+	d.endProvenance()
+	if err == nil {
+		err = callValidate(rv.Elem())
+	}
+	d.hooks.decoded(time.Since(start), err)
+	return err
+}
+
+// decodePlan 是按计划进行的 decodeRoot.
+func (d *internalDecoder) decodePlan(sp *structPlan, root *RootNode, rv reflect.Value) error {
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("can only decode root into a struct, got %s", rv.Kind())
+	}
+	if sp.custom {
+		return d.decodeRoot(root, rv)
+	}
+	if plan := getFlatPlan(sp.typ); plan != nil && rv.CanAddr() {
+		return d.decodeFlat(plan, root, rv)
+	}
+	for _, stmt := range root.Statements {
+		d.statementProvenance(root, stmt)
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			fp := sp.lookup(s.Name.Value)
+			if fp == nil {
+				if err := d.unknownField(s); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decodeAssignField(s, rv.Field(fp.index), fp.tag); err != nil {
+				return err
+			}
+		case *BlockStatement:
+			fp := sp.lookup(s.Name.Value)
+			if fp == nil {
+				if err := d.unknownField(s); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decodePlanBlock(fp, s, rv.Field(fp.index)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodePlanBlock 是按计划进行的 decodeBlockField. 不含结构体的字段 (如
+// map[string]string) 交给 decodeBlockField.
+func (d *internalDecoder) decodePlanBlock(fp *fieldPlan, stmt *BlockStatement, field reflect.Value) error {
+	if fp.block == nil {
+		return d.decodeBlockField(stmt, field)
+	}
+	return blockError(d.decodePlanBlockValue(fp.block, stmt, field), stmt)
+}
+
+// decodePlanBlockValue 即 decodePlanBlock, 错误尚未带有块的路径.
+func (d *internalDecoder) decodePlanBlockValue(sp *structPlan, stmt *BlockStatement, field reflect.Value) error {
+	if d.prov != nil {
+		defer d.leaveBlockProvenance(d.enterBlockProvenance(stmt))
+	}
+	decodeBody := func(v reflect.Value) error {
+		if err := d.decodePlan(sp, stmt.Body, v); err != nil {
+			return err
+		}
+		return validateBlock(v, stmt.Name.Token)
+	}
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return decodeBody(field.Elem())
+	case reflect.Struct:
+		sp.setLabels(field, stmt.Labels())
+		return decodeBody(field)
+	case reflect.Map:
+		if stmt.Label == nil {
+			return errorOf(ErrTypeMismatch, "block %q is for a map, but is missing a label", string(stmt.Name.Value))
+		}
+		return decodeLabeledBlock(field, string(stmt.Name.Value), stmt.Labels(), decodeBody)
+	case reflect.Slice:
+		return decodeLabeledBlock(field, string(stmt.Name.Value), stmt.Labels(), decodeBody)
+	}
+	return nil
+}
+
+// setLabels 是按计划进行的 setBlockLabels.
+func (sp *structPlan) setLabels(structVal reflect.Value, labels []string) {
+	if sp.labels < 0 || len(labels) == 0 {
+		return
+	}
+	field := structVal.Field(sp.labels)
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+		field.Set(reflect.ValueOf(labels).Convert(field.Type()))
+	}
+}
@@ -0,0 +1,181 @@
+package wanf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type planRoute struct {
+	Labels []string `wanf:",labels"`
+	Target string   `wanf:"target"`
+	Weight int      `wanf:"weight,min=1"`
+}
+
+type planNode struct {
+	Name     string      `wanf:"name"`
+	Children []*planNode `wanf:"child"`
+}
+
+type planUser struct {
+	ID   string `wanf:"id"`
+	Role string `wanf:"role"`
+}
+
+type planConfig struct {
+	Name    string
+	Timeout time.Duration        `wanf:"timeout"`
+	Server  *unmarshalServer     `wanf:"server"`
+	Routes  map[string]planRoute `wanf:"route"`
+	Rules   []planRoute          `wanf:"rule"`
+	Headers map[string]string    `wanf:"headers"`
+	Users   map[string]planUser  `wanf:"users,key=id"`
+	Tree    planNode             `wanf:"tree"`
+	Gen     unmarshalServer      `wanf:"gen"`
+	Extra   RawBlock             `wanf:"extra"`
+}
+
+func TestPlanDecode(t *testing.T) {
+	src := `var region = "eu"
+name = "svc-${region}"
+timeout = 5s
+server {
+	host = "localhost"
+	port = 8080
+}
+route "api" {
+	target = "http://api"
+	weight = 2
+}
+rule "a" {
+	target = "x"
+	weight = 1
+}
+rule "b" {
+	target = "y"
+	weight = 3
+}
+headers {
+	accept = "json"
+}
+users = [
+	{ id = "alice", role = "admin" },
+	{ id = "bob", role = "dev" },
+]
+tree {
+	name = "root"
+	child {
+		name = "leaf"
+		child {
+			name = "deep"
+		}
+	}
+}
+gen {
+	debug = true
+	tags = ["a"]
+}
+extra {
+	anything = 1
+}
+`
+	var want planConfig
+	if err := decodeString(src, &want); err != nil {
+		t.Fatal(err)
+	}
+	plan := CompileType(reflect.TypeOf(&planConfig{}))
+	var got planConfig
+	if err := plan.Decode([]byte(src), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "svc-eu" || got.Tree.Children[0].Children[0].Name != "deep" || got.Rules[1].Labels[0] != "b" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+	// RawBlock 保存解码器, 单独比较其内容.
+	var wantExtra, gotExtra struct {
+		Anything int `wanf:"anything"`
+	}
+	if err := want.Extra.Decode(&wantExtra); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Extra.Decode(&gotExtra); err != nil {
+		t.Fatal(err)
+	}
+	if gotExtra.Anything != 1 || gotExtra != wantExtra {
+		t.Errorf("extra = %v, want %v", gotExtra, wantExtra)
+	}
+	want.Extra, got.Extra = RawBlock{}, RawBlock{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan decode = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestPlanDecodeErrors(t *testing.T) {
+	plan := CompileType(reflect.TypeOf(planConfig{}))
+	var c planConfig
+	err := plan.Decode([]byte("route \"api\" {\n\tweight = 0\n}\n"), &c)
+	if err == nil || !strings.Contains(err.Error(), "weight") {
+		t.Errorf("got %v, want weight validation error", err)
+	}
+	err = plan.Decode([]byte("route {\n\tweight = 1\n}\n"), &c)
+	if err == nil || !strings.Contains(err.Error(), "missing a label") {
+		t.Errorf("got %v, want missing label error", err)
+	}
+	var other flatConfig
+	if err := plan.Decode([]byte("name = \"x\"\n"), &other); err == nil {
+		t.Error("decoding into a different type should fail")
+	}
+}
+
+func TestPlanDecodeFoldCase(t *testing.T) {
+	plan := CompileType(reflect.TypeOf(flatConfig{}))
+	var c flatConfig
+	if err := plan.Decode([]byte("MODE = \"fast\"\nport = 80\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mode != "fast" || c.Port != 80 {
+		t.Errorf("got %+v", c)
+	}
+	plan = CompileType(reflect.TypeOf(planConfig{}))
+	var p planConfig
+	if err := plan.Decode([]byte("NAME = \"svc\"\n"), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "svc" {
+		t.Errorf("Name = %q, want svc", p.Name)
+	}
+}
+
+func TestPlanDecodePointerMapElems(t *testing.T) {
+	type flatMap struct {
+		MP map[string]*flatConfig `wanf:"mp"`
+	}
+	type labeledMap struct {
+		MP map[string]*planRoute `wanf:"mp"`
+	}
+	type nestedMap struct {
+		MP map[string]map[string]*planRoute `wanf:"mp"`
+	}
+	tests := []struct {
+		name string
+		v    interface{}
+		src  string
+	}{
+		{"flat", &flatMap{}, "mp \"d\" {\n\tport = 4\n}\n"},
+		{"labels", &labeledMap{}, "mp \"d\" {\n\tweight = 4\n}\n"},
+		{"nested", &nestedMap{}, "mp \"a\" \"b\" {\n\tweight = 4\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := Decode([]byte(tt.src), reflect.New(reflect.TypeOf(tt.v).Elem()).Interface())
+			if want == nil {
+				t.Fatal("Decode accepted pointer map elements, test needs updating")
+			}
+			err := CompileType(reflect.TypeOf(tt.v)).Decode([]byte(tt.src), tt.v)
+			if err == nil || err.Error() != want.Error() {
+				t.Errorf("plan decode error = %v, want %v", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package wanf
+
+// 各节点的 Pos 与 End. 手工构造的节点可能缺少结尾标记, 此时 End 返回
+// 无效的 Position.
+
+func (c *Comment) Pos() Position { return c.Token.Pos() }
+func (c *Comment) End() Position { return c.Token.End() }
+
+func (p *RootNode) Pos() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[0].Pos()
+}
+
+func (p *RootNode) End() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[len(p.Statements)-1].End()
+}
+
+func (as *AssignStatement) Pos() Position { return as.Token.Pos() }
+func (as *AssignStatement) End() Position {
+	if as.Value == nil {
+		return as.Name.End()
+	}
+	return as.Value.End()
+}
+
+func (bs *BlockStatement) Pos() Position { return bs.Token.Pos() }
+func (bs *BlockStatement) End() Position { return bs.Rbrace.End() }
+
+func (vs *VarStatement) Pos() Position { return vs.Token.Pos() }
+func (vs *VarStatement) End() Position {
+	if vs.Value == nil {
+		return vs.Name.End()
+	}
+	return vs.Value.End()
+}
+
+func (is *ImportStatement) Pos() Position { return is.Token.Pos() }
+func (is *ImportStatement) End() Position {
+	if is.Path == nil {
+		return is.Token.End()
+	}
+	return is.Path.End()
+}
+
+func (i *Identifier) Pos() Position { return i.Token.Pos() }
+func (i *Identifier) End() Position { return i.Token.End() }
+
+func (sl *StringLiteral) Pos() Position { return sl.Token.Pos() }
+func (sl *StringLiteral) End() Position { return sl.Token.End() }
+
+func (il *IntegerLiteral) Pos() Position { return il.Token.Pos() }
+func (il *IntegerLiteral) End() Position { return il.Token.End() }
+
+func (fl *FloatLiteral) Pos() Position { return fl.Token.Pos() }
+func (fl *FloatLiteral) End() Position { return fl.Token.End() }
+
+func (bl *BoolLiteral) Pos() Position { return bl.Token.Pos() }
+func (bl *BoolLiteral) End() Position { return bl.Token.End() }
+
+func (dl *DurationLiteral) Pos() Position { return dl.Token.Pos() }
+func (dl *DurationLiteral) End() Position { return dl.Token.End() }
+
+func (ll *ListLiteral) Pos() Position { return ll.Token.Pos() }
+func (ll *ListLiteral) End() Position { return ll.Rbrack.End() }
+
+func (bl *BlockLiteral) Pos() Position { return bl.Token.Pos() }
+func (bl *BlockLiteral) End() Position { return bl.Rbrace.End() }
+
+func (ml *MapLiteral) Pos() Position { return ml.Token.Pos() }
+func (ml *MapLiteral) End() Position { return ml.Rbrace.End() }
+
+func (ve *VarExpression) Pos() Position { return ve.Token.Pos() }
+func (ve *VarExpression) End() Position { return ve.Rbrace.End() }
+
+func (ee *EnvExpression) Pos() Position { return ee.Token.Pos() }
+func (ee *EnvExpression) End() Position { return ee.Rparen.End() }
+
+func (se *SecretExpression) Pos() Position { return se.Token.Pos() }
+func (se *SecretExpression) End() Position { return se.Rparen.End() }
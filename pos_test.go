@@ -0,0 +1,69 @@
+package wanf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestNodePositions(t *testing.T) {
+	src := `// 注释不计入范围
+name = "app" // 行尾注释
+server "main" {
+	hosts = [
+		"a",
+		${host},
+	]
+	env_value = env("HOST", "b")
+	opts = {[
+		debug = true,
+	]}
+}
+`
+	program, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	Walk(program, func(n Node) bool {
+		switch n.(type) {
+		case *AssignStatement, *BlockStatement, *ListLiteral, *MapLiteral, *VarExpression, *EnvExpression:
+			got = append(got, src[n.Pos().Offset:n.End().Offset])
+		}
+		return true
+	})
+	want := []string{
+		`name = "app"`,
+		src[bytes.Index([]byte(src), []byte("server")) : len(src)-1],
+		"hosts = [\n\t\t\"a\",\n\t\t${host},\n\t]",
+		"[\n\t\t\"a\",\n\t\t${host},\n\t]",
+		"${host}",
+		`env_value = env("HOST", "b")`,
+		`env("HOST", "b")`,
+		"opts = {[\n\t\tdebug = true,\n\t]}",
+		"{[\n\t\tdebug = true,\n\t]}",
+		"debug = true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	block := program.Statements[1].(*BlockStatement)
+	if pos, end := block.Pos(), block.End(); pos.Line != 3 || pos.Column != 1 || end.Line != 12 || end.Column != 2 {
+		t.Errorf("block range = %v-%v, want 3:1-12:2", pos, end)
+	}
+}
+
+func TestStreamLexerPositions(t *testing.T) {
+	src := []byte("a = \"x\"\n/* 多行\n注释 */\nb = [1, 2s]\n")
+	l, sl := NewLexer(src), newStreamLexer(bytes.NewReader(src))
+	for {
+		want, got := l.NextToken(), sl.NextToken()
+		if got.Pos() != want.Pos() || got.End() != want.End() {
+			t.Errorf("%s: stream lexer range %v-%v, want %v-%v", want.Literal, got.Pos(), got.End(), want.Pos(), want.End())
+		}
+		if want.Type == EOF {
+			break
+		}
+	}
+}
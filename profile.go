@@ -0,0 +1,84 @@
+package wanf
+
+import "fmt"
+
+// profileBlockName 是配置剖面 (profile) 块的保留名称.
+// 顶层的 `profile "name" { ... }` 块只在通过 WithProfile 选中时生效.
+const profileBlockName = "profile"
+
+// WithProfile selects the profile whose top-level `profile "name" { ... }`
+// section is applied on top of the base document. Statements inside the
+// selected section override the ones outside of it; all other profile
+// sections are skipped.
+func WithProfile(name string) DecoderOption {
+	return func(d *internalDecoder) {
+		d.profile = name
+	}
+}
+
+// isProfileBlock reports whether a top-level statement is a profile section.
+func isProfileBlock(stmt Statement) (*BlockStatement, bool) {
+	bs, ok := stmt.(*BlockStatement)
+	if !ok || bs.Label == nil || string(bs.Name.Value) != profileBlockName {
+		return nil, false
+	}
+	return bs, true
+}
+
+// applyProfile removes every profile section from stmts and appends the body
+// of the selected one(s) after the base statements, so that they take precedence.
+func applyProfile(stmts []Statement, profile string) []Statement {
+	var base, overrides []Statement
+	for _, stmt := range stmts {
+		bs, ok := isProfileBlock(stmt)
+		if !ok {
+			base = append(base, stmt)
+			continue
+		}
+		if profile != "" && string(bs.Label.Value) == profile {
+			overrides = append(overrides, bs.Body.Statements...)
+		}
+	}
+	return append(base, overrides...)
+}
+
+// checkProfiles reports profile sections that are missing a label or whose
+// name is not one of the known profiles.
+func (a *astAnalyzer) checkProfiles(root *RootNode) {
+	known := make(map[string]bool, len(a.opts.knownProfiles))
+	for _, name := range a.opts.knownProfiles {
+		known[name] = true
+	}
+	for _, stmt := range root.Statements {
+		bs, ok := stmt.(*BlockStatement)
+		if !ok || string(bs.Name.Value) != profileBlockName {
+			continue
+		}
+		if bs.Label == nil {
+			a.errors = append(a.errors, LintError{
+				Line:      bs.Token.Line,
+				Column:    bs.Token.Column,
+				EndLine:   bs.Token.Line,
+				EndColumn: bs.Token.Column + len(bs.Name.Value),
+				Message:   "profile block requires a label, e.g. profile \"production\" { ... }",
+				Level:     ErrorLevelLint,
+				Type:      ErrUnknownProfile,
+			})
+			continue
+		}
+		name := string(bs.Label.Value)
+		if len(known) > 0 && !known[name] {
+			endLine, endColumn := endOf(bs.Label.Token, len(bs.Label.Value)+2)
+			a.errors = append(a.errors, LintError{
+				Line:      bs.Label.Token.Line,
+				Column:    bs.Label.Token.Column,
+				EndLine:   endLine,
+				EndColumn: endColumn,
+				Message:   fmt.Sprintf("unknown profile %q", name),
+				Level:     ErrorLevelLint,
+				Type:      ErrUnknownProfile,
+				Args:      []string{name},
+			})
+		}
+	}
+}
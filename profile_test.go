@@ -0,0 +1,93 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+const profileTestData = `
+log_level = "info"
+server {
+	port = 8080
+	workers = 2
+}
+
+profile "production" {
+	log_level = "warn"
+	server {
+		workers = 16
+	}
+}
+
+profile "staging" {
+	log_level = "debug"
+}
+`
+
+type profileTestConfig struct {
+	LogLevel string `wanf:"log_level"`
+	Server   struct {
+		Port    int `wanf:"port"`
+		Workers int `wanf:"workers"`
+	} `wanf:"server"`
+}
+
+func TestDecode_Profiles(t *testing.T) {
+	tests := []struct {
+		profile     string
+		wantLevel   string
+		wantWorkers int
+	}{
+		{"", "info", 2},
+		{"production", "warn", 16},
+		{"staging", "debug", 2},
+		{"missing", "info", 2},
+	}
+	for _, tt := range tests {
+		t.Run("profile="+tt.profile, func(t *testing.T) {
+			dec, err := NewDecoder(strings.NewReader(profileTestData), WithProfile(tt.profile))
+			if err != nil {
+				t.Fatalf("NewDecoder failed: %v", err)
+			}
+			var cfg profileTestConfig
+			if err := dec.Decode(&cfg); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if cfg.LogLevel != tt.wantLevel || cfg.Server.Workers != tt.wantWorkers || cfg.Server.Port != 8080 {
+				t.Errorf("got %+v, want log_level=%q workers=%d", cfg, tt.wantLevel, tt.wantWorkers)
+			}
+		})
+	}
+}
+
+func TestStreamDecode_Profiles(t *testing.T) {
+	dec, err := NewStreamDecoder(strings.NewReader(profileTestData), WithProfile("production"))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder failed: %v", err)
+	}
+	var cfg profileTestConfig
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.Server.Workers != 16 || cfg.Server.Port != 8080 {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestLint_Profiles(t *testing.T) {
+	_, errs := Lint([]byte(profileTestData), WithKnownProfiles("production", "development"))
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Type != ErrUnknownProfile || !strings.Contains(errs[0].Message, `unknown profile "staging"`) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+
+	// A lone profile section keeps its label.
+	_, errs = Lint([]byte(`profile "production" {
+	a = 1
+}`))
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
@@ -0,0 +1,236 @@
+package wanf
+
+// SourceKind 描述一个值是如何提供的.
+type SourceKind string
+
+const (
+	SourceLiteral SourceKind = "literal" // 直接写在文档中的值
+	SourceVar     SourceKind = "var"     // ${name} 引用的变量, 包括 WithVariables 注入的值
+	SourceEnv     SourceKind = "env"     // env() 读取的环境变量
+	SourceDefault SourceKind = "default" // env() 的环境变量未设置, 使用了其默认值
+	SourceSecret  SourceKind = "secret"  // secret() 解析的机密
+	SourceProfile SourceKind = "profile" // WithProfile 选中的 profile 覆盖的字面量
+)
+
+// Provenance 记录一个结构体字段的值来自哪里.
+type Provenance struct {
+	File   string // 所在文件: 主文档为 WithProvenance 的参数, 导入的文件为其绝对路径
+	Line   int    // 值在文件中的位置
+	Column int
+	Kind   SourceKind
+	// Name 是 var 的变量名、env() 的环境变量名或 secret() 的引用, 字面量时为空.
+	Name string
+	// Profile 非空时表示该值由 WithProfile 选中的同名 profile 覆盖. 其中的字面量
+	// 记为 SourceProfile, env()、var 等仍记为相应的 Kind.
+	Profile string
+}
+
+// WithProvenance 使解码器记录每个被写入的结构体字段的来源, 解码后通过
+// Decoder.Provenance 取得. file 是主文档的名字, 用于 Provenance.File.
+//
+//	dec, err := wanf.NewDecoder(f, wanf.WithProvenance("app.wanf"))
+//	...
+//	p := dec.Provenance()["database.password"]
+//	// app.wanf:12:16 env DB_PASSWORD
+func WithProvenance(file string) DecoderOption {
+	return func(d *internalDecoder) {
+		d.prov = newProvenanceTracker(file)
+		d.prov.record = true
+	}
+}
+
+// Provenance 返回上一次 Decode 写入的字段的来源. 键是值的路径, 语法与 Lookup
+// 相同, 如 server."main".port. 列表、字面量映射与自定义解码的类型 (RawBlock 与
+// Unmarshaler) 作为整体记录. 未使用 WithProvenance 时返回 nil.
+func (dec *Decoder) Provenance() map[string]Provenance {
+	if dec.d.prov == nil || !dec.d.prov.record {
+		return nil
+	}
+	return dec.d.prov.result
+}
+
+// provenanceTracker 保存 WithProvenance 与 OnFieldSet 所需的状态. 解码器在
+// 写入字段的位置调用 recordField 与 recordOpaque, 来源与实际写入的值一致.
+type provenanceTracker struct {
+	file     string               // WithProvenance 的参数, 未使用时为空
+	record   bool                 // 使用了 WithProvenance, 结果保存在 result 中
+	profiles map[Statement]string // 来自选中 profile 的语句 -> profile 名
+	envFound map[string]bool      // env() 引用的环境变量在最近一次求值时是否已设置
+	shadowed map[string]bool      // 选中的 profile 写入的路径, 基础文档中的同名写入不调用 OnFieldSet
+	result   map[string]Provenance
+
+	// 以下是 Decode 过程中的状态.
+	active      bool
+	root        *RootNode       // 正在解码的文档
+	path        string          // 正在解码的块的路径
+	block       *BlockStatement // 正在解码的块
+	stmtFile    string          // 正在解码的顶层语句所在的文件
+	stmtProfile string          // 正在解码的顶层语句所属的 profile
+	opaque      int             // 大于 0 时正在解码作为整体记录的 Unmarshaler, 不记录其中的字段
+}
+
+func newProvenanceTracker(file string) *provenanceTracker {
+	return &provenanceTracker{
+		file:     file,
+		profiles: make(map[Statement]string),
+		envFound: make(map[string]bool),
+		shadowed: make(map[string]bool),
+	}
+}
+
+// provFrame 保存进入一个块之前的状态, 见 enterBlockProvenance.
+type provFrame struct {
+	path  string
+	block *BlockStatement
+}
+
+// shadow 记下 profile 中的语句 stmts 将写入的路径, path 是它们所在块的路径.
+// 块本身也记下, 以覆盖作为整体记录的 RawBlock 与 Unmarshaler.
+func (p *provenanceTracker) shadow(path string, stmts []Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			p.shadowed[joinPath(path, string(s.Name.Value))] = true
+		case *BlockStatement:
+			sub := joinPath(path, blockPath(string(s.Name.Value), s.Labels()))
+			p.shadowed[sub] = true
+			p.shadow(sub, s.Body.Statements)
+		}
+	}
+}
+
+// beginProvenance 开始记录 root 的解码.
+func (d *internalDecoder) beginProvenance(root *RootNode) {
+	p := d.prov
+	if p == nil {
+		return
+	}
+	p.active, p.root, p.path, p.block = true, root, "", nil
+	if p.record {
+		p.result = make(map[string]Provenance)
+	}
+}
+
+// endProvenance 结束记录, 此后 (如 RawBlock.Decode) 写入的字段不再记录.
+func (d *internalDecoder) endProvenance() {
+	if d.prov != nil {
+		d.prov.active = false
+	}
+}
+
+// statementProvenance 在解码 root 中的语句 stmt 之前调用. stmt 是顶层语句时,
+// 记下它所在的文件与 profile, 其中的值都来自这里.
+func (d *internalDecoder) statementProvenance(root *RootNode, stmt Statement) {
+	p := d.prov
+	if p == nil || root != p.root {
+		return
+	}
+	p.stmtFile = p.file
+	if file, ok := d.files[stmt]; ok {
+		p.stmtFile = file
+	}
+	p.stmtProfile = p.profiles[stmt]
+}
+
+// enterBlockProvenance 在解码块 stmt 之前调用, 返回值交给 leaveBlockProvenance.
+func (d *internalDecoder) enterBlockProvenance(stmt *BlockStatement) provFrame {
+	p := d.prov
+	if p == nil {
+		return provFrame{}
+	}
+	frame := provFrame{path: p.path, block: p.block}
+	p.path = joinPath(p.path, blockPath(string(stmt.Name.Value), stmt.Labels()))
+	p.block = stmt
+	return frame
+}
+
+func (d *internalDecoder) leaveBlockProvenance(frame provFrame) {
+	if p := d.prov; p != nil {
+		p.path, p.block = frame.path, frame.block
+	}
+}
+
+// tracking 报告当前写入的字段是否需要记录.
+func (p *provenanceTracker) tracking() bool {
+	return p != nil && p.active && p.opaque == 0
+}
+
+// recordField 记录当前块中的键 name 已由 expr 的值写入.
+func (d *internalDecoder) recordField(name []byte, expr Expression) {
+	if !d.prov.tracking() {
+		return
+	}
+	d.emitProvenance(joinPath(d.prov.path, string(name)), d.provenanceOf(expr))
+}
+
+// recordOpaque 记录当前块作为整体写入了 RawBlock 或 Unmarshaler. root 是
+// 块的内容; 解码的是整个文档时不记录.
+func (d *internalDecoder) recordOpaque(root *RootNode) {
+	p := d.prov
+	if !p.tracking() || root == p.root || p.block == nil {
+		return
+	}
+	pos := p.block.Pos()
+	d.emitProvenance(p.path, Provenance{File: p.stmtFile, Line: pos.Line, Column: pos.Column, Kind: p.literalKind(), Profile: p.stmtProfile})
+}
+
+// unmarshalBody 调用 u.UnmarshalWANF 解码 root. u 作为整体记录, 它通过
+// BodyDecoder 写入的字段不单独记录.
+func (d *internalDecoder) unmarshalBody(u Unmarshaler, root *RootNode) error {
+	if d.prov == nil {
+		return u.UnmarshalWANF(&BodyDecoder{d: d, stmts: root.Statements})
+	}
+	d.prov.opaque++
+	err := u.UnmarshalWANF(&BodyDecoder{d: d, stmts: root.Statements})
+	d.prov.opaque--
+	if err == nil {
+		d.recordOpaque(root)
+	}
+	return err
+}
+
+func (d *internalDecoder) emitProvenance(path string, source Provenance) {
+	if d.prov.record {
+		d.prov.result[path] = source
+	}
+	// 被 profile 覆盖的值不是最终写入的值, 只为 profile 中的写入调用一次.
+	if source.Profile == "" && d.prov.shadowed[path] {
+		return
+	}
+	d.hooks.fieldSet(path, source)
+}
+
+// literalKind 返回当前语句中字面量的 Kind.
+func (p *provenanceTracker) literalKind() SourceKind {
+	if p.stmtProfile != "" {
+		return SourceProfile
+	}
+	return SourceLiteral
+}
+
+// provenanceOf 返回当前语句中 expr 的来源. 引用了变量的字符串 (如 "${base}/app")
+// 记为 var, Name 为其中第一个变量.
+func (d *internalDecoder) provenanceOf(expr Expression) Provenance {
+	p := d.prov
+	pos := expr.Pos()
+	src := Provenance{File: p.stmtFile, Line: pos.Line, Column: pos.Column, Kind: p.literalKind(), Profile: p.stmtProfile}
+	switch e := expr.(type) {
+	case *VarExpression:
+		src.Kind, src.Name = SourceVar, string(e.Name)
+	case *EnvExpression:
+		src.Kind, src.Name = SourceEnv, string(e.Name.Value)
+		if !p.envFound[src.Name] && e.DefaultValue != nil {
+			src.Kind = SourceDefault
+		}
+	case *SecretExpression:
+		src.Kind, src.Name = SourceSecret, string(e.Ref.Value)
+	case *StringLiteral:
+		for _, m := range varRegex.FindAllStringSubmatch(string(e.Value), -1) {
+			if d.hasVar(m[1]) {
+				src.Kind, src.Name = SourceVar, m[1]
+				break
+			}
+		}
+	}
+	return src
+}
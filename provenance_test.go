@@ -0,0 +1,172 @@
+package wanf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoderProvenance(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.wanf")
+	if err := os.WriteFile(common, []byte("timeout = 5s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PROV_HOST", "db.internal")
+	src := `import "common.wanf"
+var base = "/srv"
+name = "app"
+root = "${base}/data"
+database {
+	host = env("PROV_HOST")
+	port = env("PROV_UNSET_PORT", "5432")
+}
+server "main" {
+	port = 8080
+}
+labels {
+	team = "infra"
+}
+profile "production" {
+	name = "app-prod"
+}
+`
+	type config struct {
+		Name     string        `wanf:"name"`
+		Root     string        `wanf:"root"`
+		Timeout  time.Duration `wanf:"timeout"`
+		Database struct {
+			Host string `wanf:"host"`
+			Port int    `wanf:"port"`
+		} `wanf:"database"`
+		Server map[string]struct {
+			Port int `wanf:"port"`
+		} `wanf:"server"`
+		Labels map[string]string `wanf:"labels"`
+	}
+	dec, err := NewDecoder(strings.NewReader(src), WithBasePath(dir), WithProfile("production"), WithProvenance("app.wanf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	absCommon, _ := filepath.Abs(common)
+	want := map[string]Provenance{
+		"name":               {File: "app.wanf", Line: 16, Column: 9, Kind: SourceProfile, Profile: "production"},
+		"root":               {File: "app.wanf", Line: 4, Column: 8, Kind: SourceVar, Name: "base"},
+		"timeout":            {File: absCommon, Line: 1, Column: 11, Kind: SourceLiteral},
+		"database.host":      {File: "app.wanf", Line: 6, Column: 9, Kind: SourceEnv, Name: "PROV_HOST"},
+		"database.port":      {File: "app.wanf", Line: 7, Column: 9, Kind: SourceDefault, Name: "PROV_UNSET_PORT"},
+		`server."main".port`: {File: "app.wanf", Line: 10, Column: 9, Kind: SourceLiteral},
+		"labels.team":        {File: "app.wanf", Line: 13, Column: 9, Kind: SourceLiteral},
+	}
+	got := dec.Provenance()
+	if len(got) != len(want) {
+		t.Errorf("got %d entries, want %d: %v", len(got), len(want), got)
+	}
+	for key, w := range want {
+		if got[key] != w {
+			t.Errorf("%s = %+v, want %+v", key, got[key], w)
+		}
+	}
+}
+
+func TestDecoderProvenanceDisabled(t *testing.T) {
+	dec, err := NewDecoder(strings.NewReader(`name = "app"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Name string `wanf:"name"`
+	}
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if p := dec.Provenance(); p != nil {
+		t.Errorf("Provenance() = %v, want nil", p)
+	}
+}
+
+func TestDecoderProvenanceSetSites(t *testing.T) {
+	t.Setenv("PROV_SET_PORT", "9000")
+	flat, err := NewDecoder(strings.NewReader("name = \"x\"\nport = env(\"PROV_SET_PORT\", \"80\")\nmissing = 1\n"), WithProvenance("flat.wanf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc flatConfig
+	if err := flat.Decode(&fc); err != nil {
+		t.Fatal(err)
+	}
+	wantFlat := map[string]Provenance{
+		"name": {File: "flat.wanf", Line: 1, Column: 8, Kind: SourceLiteral},
+		"port": {File: "flat.wanf", Line: 2, Column: 8, Kind: SourceEnv, Name: "PROV_SET_PORT"},
+	}
+	if got := flat.Provenance(); !reflect.DeepEqual(got, wantFlat) {
+		t.Errorf("flat provenance = %v, want %v", got, wantFlat)
+	}
+
+	src := `server {
+	host = "localhost"
+}
+extra {
+	anything = 1
+}
+rule "a" {
+	target = "x"
+	weight = 1
+}
+name = "svc"
+`
+	type config struct {
+		Name   string           `wanf:"name"`
+		Server *unmarshalServer `wanf:"server"`
+		Extra  RawBlock         `wanf:"extra"`
+		Rules  []planRoute      `wanf:"rule"`
+	}
+	var events []string
+	hooks := &Hooks{OnFieldSet: func(path string, source Provenance) {
+		events = append(events, fmt.Sprintf("%s@%d:%d", path, source.Line, source.Column))
+	}}
+	dec, err := NewDecoder(strings.NewReader(src), WithProvenance("app.wanf"), WithHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"server@1:1", "extra@4:1", `rule."a".target@8:11`, `rule."a".weight@9:11`, "name@11:8"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("OnFieldSet events = %q, want %q", events, want)
+	}
+	if got := len(dec.Provenance()); got != len(want) {
+		t.Errorf("Provenance has %d entries, want %d: %v", got, len(want), dec.Provenance())
+	}
+
+	// Fields decoded from a RawBlock after Decode returns are not recorded.
+	var extra struct {
+		Anything int `wanf:"anything"`
+	}
+	if err := cfg.Extra.Decode(&extra); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(dec.Provenance()); got != len(want) || len(events) != len(want) {
+		t.Errorf("RawBlock.Decode recorded fields: %v, %q", dec.Provenance(), events)
+	}
+
+	// Plan.Decode reports the same fields.
+	events = nil
+	var planned config
+	if err := CompileType(reflect.TypeOf(planned)).Decode([]byte(src), &planned, WithHooks(hooks)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Plan OnFieldSet events = %q, want %q", events, want)
+	}
+}
@@ -0,0 +1,86 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RawBlock 是延迟解码的块. 类型为 RawBlock 或 *RawBlock 的字段只保存块的语法树,
+// 不解码其内容, 之后按需调用 Decode. 大型配置中只用到少数部分时可以省去其余
+// 部分的解码开销:
+//
+//	type Config struct {
+//		Server  Server                   `wanf:"server"`
+//		Plugins map[string]wanf.RawBlock `wanf:"plugin"`
+//	}
+//
+// 块与 `key = { ... }` 块字面量都可以保存为 RawBlock. 同名的块依次合并, 与常规
+// 解码一致. StreamDecoder 不构建语法树, 因此不支持 RawBlock.
+type RawBlock struct {
+	// Labels 是块的标签, 块字面量没有标签.
+	Labels []string `wanf:",labels"`
+
+	body *RootNode
+	d    *internalDecoder
+}
+
+var rawBlockType = reflect.TypeOf(RawBlock{})
+
+// Decode 将块解码到 v 指向的结构体, 规则与 Decoder.Decode 相同, 使用原文档的
+// 变量、环境变量与选项. 配置中没有该块时 Decode 不修改 v 并返回 nil.
+func (r RawBlock) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("v must be a pointer to a struct")
+	}
+	if r.body == nil {
+		return nil
+	}
+	if err := r.d.decodeRoot(r.body, rv.Elem()); err != nil {
+		return err
+	}
+	return callValidate(rv.Elem())
+}
+
+// Root 返回块体的语法树, 配置中没有该块时返回 nil. 不要修改返回的节点.
+func (r RawBlock) Root() *RootNode {
+	return r.body
+}
+
+// capture 保存块体, 重复出现的块追加到已有的块体之后.
+func (r *RawBlock) capture(d *internalDecoder, body *RootNode) {
+	if r.body != nil {
+		stmts := make([]Statement, 0, len(r.body.Statements)+len(body.Statements))
+		stmts = append(append(stmts, r.body.Statements...), body.Statements...)
+		body = &RootNode{Statements: stmts}
+	}
+	r.body, r.d = body, d
+}
+
+// captureRawBlock 在 field 是 RawBlock 或 *RawBlock 且 expr 是块字面量时保存它,
+// 报告是否已处理.
+func (d *internalDecoder) captureRawBlock(field reflect.Value, expr Expression) bool {
+	bl, ok := expr.(*BlockLiteral)
+	if !ok {
+		return false
+	}
+	if field.Kind() == reflect.Ptr && field.Type().Elem() == rawBlockType {
+		if field.IsNil() {
+			field.Set(reflect.New(rawBlockType))
+		}
+		field = field.Elem()
+	}
+	if field.Type() != rawBlockType || !field.CanAddr() {
+		return false
+	}
+	field.Addr().Interface().(*RawBlock).capture(d, bl.Body)
+	return true
+}
+
+// containsRawBlock 报告类型为 t 的字段是否会把块保存为 RawBlock.
+func containsRawBlock(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Map || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == rawBlockType
+}
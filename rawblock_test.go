@@ -0,0 +1,93 @@
+package wanf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type rawPlugin struct {
+	Path    string `wanf:"path"`
+	Enabled bool   `wanf:"enabled"`
+}
+
+type rawConfig struct {
+	Name    string              `wanf:"name"`
+	Server  RawBlock            `wanf:"server"`
+	Cache   *RawBlock           `wanf:"cache"`
+	Extra   RawBlock            `wanf:"extra"`
+	Plugins map[string]RawBlock `wanf:"plugin"`
+	Missing RawBlock            `wanf:"missing"`
+}
+
+func TestRawBlock(t *testing.T) {
+	src := `var dir = "/opt"
+name = "svc"
+server {
+	path = "${dir}/server"
+}
+server {
+	enabled = true
+}
+cache {
+	path = env("WANF_RAW_CACHE", "/tmp")
+}
+extra = {
+	path = "x"
+}
+plugin "auth" {
+	path = "auth.so"
+	enabled = "not a bool"
+}
+`
+	var c rawConfig
+	if err := decodeString(src, &c); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	var server rawPlugin
+	if err := c.Server.Decode(&server); err != nil {
+		t.Fatalf("Server.Decode: %v", err)
+	}
+	if want := (rawPlugin{Path: "/opt/server", Enabled: true}); server != want {
+		t.Errorf("server = %+v, want %+v", server, want)
+	}
+
+	var cache, extra rawPlugin
+	if err := c.Cache.Decode(&cache); err != nil || cache.Path != "/tmp" {
+		t.Errorf("Cache.Decode = %+v, %v", cache, err)
+	}
+	if err := c.Extra.Decode(&extra); err != nil || extra.Path != "x" {
+		t.Errorf("Extra.Decode = %+v, %v", extra, err)
+	}
+
+	auth := c.Plugins["auth"]
+	if !reflect.DeepEqual(auth.Labels, []string{"auth"}) {
+		t.Errorf("plugin labels = %v, want [auth]", auth.Labels)
+	}
+	if n := len(auth.Root().Statements); n != 2 {
+		t.Errorf("plugin body has %d statements, want 2", n)
+	}
+	var plugin rawPlugin
+	if err := auth.Decode(&plugin); err == nil {
+		t.Error("decoding an invalid plugin block succeeded, want an error")
+	}
+
+	if c.Missing.Root() != nil {
+		t.Error("Missing.Root() != nil for an absent block")
+	}
+	if err := c.Missing.Decode(&plugin); err != nil {
+		t.Errorf("Missing.Decode: %v", err)
+	}
+}
+
+func TestRawBlockStream(t *testing.T) {
+	dec, err := NewStreamDecoder(strings.NewReader("server {\n\tpath = \"x\"\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c rawConfig
+	if err := dec.Decode(&c); err == nil || !strings.Contains(err.Error(), "RawBlock") {
+		t.Errorf("stream Decode error = %v, want RawBlock not supported", err)
+	}
+}
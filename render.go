@@ -0,0 +1,167 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Render 解析 data, 展开 import 并应用剖面, 然后求值所有 var, `${...}` 与
+// env(), 返回一个不含 var 与 import 的格式化 WANF 文档, 即解码时实际看到的配置.
+// 块及其标签, 字面量的类型 (如 duration) 以及注释保持不变. opts 与 NewDecoder 相同.
+func Render(data []byte, opts ...DecoderOption) ([]byte, error) {
+	dec, err := NewDecoder(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := dec.d.renderStatements(dec.program.Statements)
+	if err != nil {
+		return nil, err
+	}
+	return Format(&RootNode{Statements: stmts}, FormatOptions{Style: StyleBlockSorted, EmptyLines: true}), nil
+}
+
+func (d *internalDecoder) renderStatements(stmts []Statement) ([]Statement, error) {
+	out := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			val, err := d.renderExpression(s.Value)
+			if err != nil {
+				return nil, err
+			}
+			rendered := *s
+			rendered.Value = val
+			out = append(out, &rendered)
+		case *BlockStatement:
+			body, err := d.renderStatements(s.Body.Statements)
+			if err != nil {
+				return nil, err
+			}
+			rendered := *s
+			rendered.Body = &RootNode{Statements: body}
+			out = append(out, &rendered)
+		case *VarStatement, *ImportStatement:
+			// 已被求值或展开.
+		default:
+			out = append(out, stmt)
+		}
+	}
+	return out, nil
+}
+
+// renderExpression 返回 expr 求值后的表达式. 变量引用被替换为变量声明的
+// 表达式 (同样经过求值), 因此 duration 等类型得以保留.
+func (d *internalDecoder) renderExpression(expr Expression) (Expression, error) {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		s, err := d.interpolate(string(e.Value))
+		if err != nil {
+			return nil, err
+		}
+		return newStringLiteral(s), nil
+	case *EnvExpression:
+		val, err := d.evalExpression(e)
+		if err != nil {
+			return nil, err
+		}
+		return newStringLiteral(val.(string)), nil
+	case *VarExpression:
+		name := string(e.Name)
+		if stmt, ok := d.varDecls[name]; ok {
+			return d.renderExpression(stmt.Value)
+		}
+		val, err := d.lookupVar(name)
+		if err != nil {
+			return nil, err
+		}
+		return valueToExpression(val)
+	case *ListLiteral:
+		rendered := *e
+		rendered.Elements = make([]Expression, len(e.Elements))
+		for i, el := range e.Elements {
+			val, err := d.renderExpression(el)
+			if err != nil {
+				return nil, err
+			}
+			rendered.Elements[i] = val
+		}
+		return &rendered, nil
+	case *MapLiteral:
+		elements, err := d.renderStatements(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		rendered := *e
+		rendered.Elements = elements
+		return &rendered, nil
+	case *BlockLiteral:
+		body, err := d.renderStatements(e.Body.Statements)
+		if err != nil {
+			return nil, err
+		}
+		return &BlockLiteral{Token: e.Token, Body: &RootNode{Statements: body}}, nil
+	}
+	return expr, nil
+}
+
+// valueToExpression 将 WithVariables 注入的 Go 值转换为表达式.
+func valueToExpression(v interface{}) (Expression, error) {
+	switch val := v.(type) {
+	case string:
+		return newStringLiteral(val), nil
+	case bool:
+		lit := []byte(strconv.FormatBool(val))
+		return &BoolLiteral{Token: Token{Type: BOOL, Literal: lit}, Value: val}, nil
+	case int:
+		return newIntegerLiteral(int64(val)), nil
+	case int64:
+		return newIntegerLiteral(val), nil
+	case float64:
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		if !bytes.ContainsRune([]byte(s), '.') {
+			s += ".0"
+		}
+		return &FloatLiteral{Token: Token{Type: FLOAT, Literal: []byte(s)}, Value: val}, nil
+	case time.Duration:
+		lit := []byte(durationLiteral(val))
+		return &DurationLiteral{Token: Token{Type: DUR, Literal: lit}, Value: lit}, nil
+	case []interface{}:
+		list := &ListLiteral{Token: Token{Type: LBRACK, Literal: []byte("[")}}
+		for _, el := range val {
+			expr, err := valueToExpression(el)
+			if err != nil {
+				return nil, err
+			}
+			list.Elements = append(list.Elements, expr)
+		}
+		return list, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		m := &MapLiteral{Token: Token{Type: LBRACE, Literal: []byte("{")}}
+		for _, k := range keys {
+			expr, err := valueToExpression(val[k])
+			if err != nil {
+				return nil, err
+			}
+			name := &Identifier{Token: Token{Type: IDENT, Literal: []byte(k)}, Value: []byte(k)}
+			m.Elements = append(m.Elements, &AssignStatement{Token: name.Token, Name: name, Value: expr})
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot render variable value of type %T", v)
+}
+
+func newStringLiteral(s string) *StringLiteral {
+	return &StringLiteral{Token: Token{Type: STRING, Literal: []byte(s)}, Value: []byte(s)}
+}
+
+func newIntegerLiteral(i int64) *IntegerLiteral {
+	return &IntegerLiteral{Token: Token{Type: INT, Literal: []byte(strconv.FormatInt(i, 10))}, Value: i}
+}
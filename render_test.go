@@ -0,0 +1,58 @@
+package wanf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db.wanf"), []byte("database {\n\thost = \"${host}\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WANF_RENDER_TEST", "from-env")
+	src := `import "db.wanf"
+
+var host = "db.internal"
+var timeout = 30s
+var ports = [80, 443]
+
+name = "svc-${region}"
+token = env("WANF_RENDER_TEST")
+fallback = env("WANF_RENDER_MISSING", "default")
+
+server "main" {
+	// 超时
+	timeout = ${timeout}
+	ports = ${ports}
+}
+`
+	got, err := Render([]byte(src), WithBasePath(dir), WithVariables(map[string]interface{}{"region": "eu"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `database {
+	host = "db.internal"
+}
+
+name = "svc-eu"
+token = "from-env"
+fallback = "default"
+
+server "main" {
+	// 超时
+	timeout = 30s
+	ports = [
+		80,
+		443,
+	]
+}`
+	if string(got) != want {
+		t.Errorf("Render mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := Render([]byte(`a = ${missing}`)); err == nil {
+		t.Error("expected an error for an undefined variable")
+	}
+}
@@ -0,0 +1,171 @@
+package wanf
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// LintReport 是单个文件的 lint 结果.
+type LintReport struct {
+	Path   string
+	Errors []LintError
+}
+
+// ruleDescriptions 是每条规则的简短说明, 用于 SARIF 等机器可读输出.
+var ruleDescriptions = map[ErrorType]string{
+	ErrUnexpectedToken:  "Unexpected token",
+	ErrRedundantComma:   "Redundant comma inside a block",
+	ErrMissingComma:     "Missing comma between list or map elements",
+	ErrRedundantLabel:   "Label on a block that is defined only once",
+	ErrUnusedVariable:   "Variable is declared but never used",
+	ErrExpectDiffToken:  "Expected a different token",
+	ErrUnknownProfile:   "Unknown or unlabeled profile section",
+	ErrDuplicateKey:     "Key assigned more than once in the same scope",
+	ErrDuplicateBlock:   "Block with the same name and labels defined more than once",
+	ErrNamingConvention: "Key does not follow the naming convention",
+	ErrUnresolvedImport: "Imported file cannot be read or parsed",
+	ErrUnusedImport:     "Import has no effect on the document",
+	ErrMaxDepth:         "Blocks nested too deeply",
+	ErrMaxLines:         "File is too long",
+	ErrMaxKeys:          "Block has too many keys",
+	ErrSchemaViolation:  "Document does not match its schema",
+	ErrSchemaUnknown:    "Key or block is not declared in the schema",
+	ErrPlaintextSecret:  "Secret field holds a plaintext value",
+	ErrFileEncoding:     "File has a byte order mark or CRLF line endings",
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name"`
+	ShortDescription     sarifMessage  `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConf `json:"defaultConfiguration"`
+}
+
+type sarifRuleConf struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitzero"`
+	EndLine     int `json:"endLine,omitzero"`
+	EndColumn   int `json:"endColumn,omitzero"`
+}
+
+// sarifLevel maps a Severity to a SARIF result level.
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// WriteSARIF writes the lint reports as a SARIF 2.1.0 log, suitable for
+// GitHub code scanning and other SARIF consumers.
+func WriteSARIF(w io.Writer, reports []LintReport) error {
+	types := make([]ErrorType, 0, len(ruleIDs))
+	for t := range ruleIDs {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].RuleID() < types[j].RuleID() })
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "wanflint",
+			InformationURI: "https://github.com/WJQSERVER/wanf",
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := make(map[ErrorType]int, len(types))
+	for i, t := range types {
+		ruleIndex[t] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   t.RuleID(),
+			Name:                 t.String(),
+			ShortDescription:     sarifMessage{Text: ruleDescriptions[t]},
+			DefaultConfiguration: sarifRuleConf{Level: sarifLevel(defaultSeverity(LintError{Type: t}))},
+		})
+	}
+
+	for _, report := range reports {
+		uri := filepath.ToSlash(report.Path)
+		for _, e := range report.Errors {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    e.Type.RuleID(),
+				RuleIndex: ruleIndex[e.Type],
+				Level:     sarifLevel(e.Severity),
+				Message:   sarifMessage{Text: e.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region: sarifRegion{
+						StartLine:   e.Line,
+						StartColumn: e.Column,
+						EndLine:     e.EndLine,
+						EndColumn:   e.EndColumn,
+					},
+				}}},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	return json.MarshalWrite(w, log, jsontext.Multiline(true), jsontext.WithIndent("  "))
+}
@@ -0,0 +1,36 @@
+package wanf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-json-experiment/json"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	_, errs := Lint([]byte("var unused = 1\n"))
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, []LintReport{{Path: "conf/app.wanf", Errors: errs}}); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(run.Results))
+	}
+	res := run.Results[0]
+	if res.RuleID != "WANF005" || res.Level != "warning" || run.Tool.Driver.Rules[res.RuleIndex].ID != "WANF005" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	loc := res.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "conf/app.wanf" || loc.Region.StartLine != 1 || loc.Region.StartColumn != 1 {
+		t.Errorf("unexpected location: %+v", loc)
+	}
+}
@@ -0,0 +1,83 @@
+package wanf
+
+import (
+	"fmt"
+	"io"
+)
+
+// Scanner 将 WANF 源码切分为标记, 供语法高亮、外部解析器等工具使用.
+// 注释作为 COMMENT 标记返回; 词法错误以 ILLEGAL 或 ILLEGAL_COMMENT 标记返回,
+// 并记录到 Errors 中, 扫描会继续进行.
+type Scanner struct {
+	l    lexer
+	tok  Token
+	done bool
+	errs []ParseError
+
+	stream *streamLexer // NewStreamScanner 的词法分析器, 用于报告读取错误
+}
+
+// NewScanner 返回扫描 data 的 Scanner. 标记的 Literal 引用 data, 不要修改 data.
+func NewScanner(data []byte) *Scanner {
+	return &Scanner{l: NewLexer(data)}
+}
+
+// NewStreamScanner 返回从 r 读取并扫描的 Scanner.
+func NewStreamScanner(r io.Reader) *Scanner {
+	l := newStreamLexer(r)
+	return &Scanner{l: l, stream: l}
+}
+
+// Next 返回下一个标记. 到达末尾后总是返回 EOF 标记.
+func (s *Scanner) Next() Token {
+	if s.done {
+		return s.tok
+	}
+	tok := s.l.NextToken()
+	switch tok.Type {
+	case EOF:
+		s.done = true
+	case ILLEGAL:
+		msg := fmt.Sprintf("unexpected character %q", tok.Literal)
+		if len(tok.Literal) > 1 {
+			msg = string(tok.Literal) // 如 "unclosed block comment"
+		}
+		s.error(tok, msg)
+	case ILLEGAL_COMMENT:
+		s.error(tok, hashCommentMessage)
+	}
+	s.tok = tok
+	return tok
+}
+
+// Pos 返回最近一次 Next 返回的标记的起始位置.
+func (s *Scanner) Pos() Position {
+	return s.tok.Pos()
+}
+
+// Errors 返回目前为止遇到的词法错误.
+func (s *Scanner) Errors() []ParseError {
+	return s.errs
+}
+
+// Err 在遇到过词法错误时返回 *ParseErrorList, 否则返回 nil. 从 io.Reader
+// 读取失败时扫描在该处以 EOF 结束, Err 返回包装了读取错误的 error.
+func (s *Scanner) Err() error {
+	if s.stream != nil && s.stream.Err() != nil {
+		return s.stream.Err()
+	}
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return &ParseErrorList{Errors: s.errs}
+}
+
+func (s *Scanner) error(tok Token, msg string) {
+	s.errs = append(s.errs, ParseError{
+		Line:      tok.Line,
+		Column:    tok.Column,
+		EndLine:   tok.EndLine,
+		EndColumn: tok.EndColumn,
+		Message:   msg,
+	})
+}
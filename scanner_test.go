@@ -0,0 +1,94 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanner(t *testing.T) {
+	src := "a = 1 // note\n# old\nb = @\n"
+	for _, s := range []*Scanner{NewScanner([]byte(src)), NewStreamScanner(strings.NewReader(src))} {
+		var types []string
+		for {
+			tok := s.Next()
+			types = append(types, string(tok.Type))
+			if tok.Type == EOF {
+				break
+			}
+		}
+		want := "IDENT = INT COMMENT ILLEGAL_COMMENT IDENT = ILLEGAL EOF"
+		if got := strings.Join(types, " "); got != want {
+			t.Errorf("tokens = %s, want %s", got, want)
+		}
+		if tok := s.Next(); tok.Type != EOF || s.Pos().Line != 4 {
+			t.Errorf("after EOF Next() = %v at %v", tok, s.Pos())
+		}
+
+		errs := s.Errors()
+		if len(errs) != 2 || errs[0].Line != 2 || errs[1].Message != `unexpected character "@"` || errs[1].Column != 5 {
+			t.Errorf("Errors() = %+v", errs)
+		}
+		var list *ParseErrorList
+		if !errors.As(s.Err(), &list) {
+			t.Errorf("Err() = %v, want a *ParseErrorList", s.Err())
+		}
+	}
+}
+
+func TestScannerUnclosedComment(t *testing.T) {
+	s := NewScanner([]byte("a = 1 /* never closed"))
+	for s.Next().Type != EOF {
+	}
+	if errs := s.Errors(); len(errs) != 1 || errs[0].Message != "unclosed block comment" {
+		t.Errorf("Errors() = %+v", errs)
+	}
+}
+
+func TestStreamScannerLiterals(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&b, "key_%d = \"value %d\" // c%d\ntimeout_%d = %dms\n", i, i, i, i, i)
+	}
+	// 比字面量块更长的字符串.
+	fmt.Fprintf(&b, "long = %q\n", strings.Repeat("x", 3*literalChunkSize))
+	src := b.String()
+
+	// 先读取全部标记再比较, 确认之前返回的 Literal 没有被之后的标记覆盖.
+	var want, got []Token
+	for s := NewScanner([]byte(src)); ; {
+		tok := s.Next()
+		want = append(want, tok)
+		if tok.Type == EOF {
+			break
+		}
+	}
+	for s := NewStreamScanner(strings.NewReader(src)); ; {
+		tok := s.Next()
+		got = append(got, tok)
+		if tok.Type == EOF {
+			break
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || string(got[i].Literal) != string(want[i].Literal) {
+			t.Fatalf("token %d = %s %q, want %s %q", i, got[i].Type, got[i].Literal, want[i].Type, want[i].Literal)
+		}
+	}
+}
+
+func TestStreamScannerReadError(t *testing.T) {
+	timeout := errors.New("i/o timeout")
+	s := NewStreamScanner(io.MultiReader(strings.NewReader("a = 1"), iotest.ErrReader(timeout)))
+	for s.Next().Type != EOF {
+	}
+	if err := s.Err(); !errors.Is(err, timeout) {
+		t.Errorf("Err() = %v, want the read error", err)
+	}
+}
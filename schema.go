@@ -0,0 +1,608 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schema 描述 WANF 文档 (或块体) 允许出现的键与块. Schema 通常由 ParseSchema
+// 从 WANF 格式的 schema 文件读取, 例如:
+//
+//	field "name" {
+//		type = "string"
+//		required = true
+//	}
+//
+//	block "server" {
+//		min = 1
+//		labels = 1
+//
+//		field "port" {
+//			type = "int"
+//			min = 1
+//			max = 65535
+//		}
+//		field "mode" {
+//			enum = ["dev", "prod"]
+//		}
+//		field "host" {
+//			pattern = "^[a-z.]+$"
+//		}
+//		field "password" {
+//			secret = true
+//		}
+//	}
+//
+// 未在 schema 中声明的键与块会被报告, 除非设置了 `allow_unknown = true`.
+type Schema struct {
+	Fields       map[string]*FieldSchema
+	Blocks       map[string]*BlockSchema
+	AllowUnknown bool
+}
+
+// BlockSchema 描述一种块: 其出现次数, 标签数量以及块体的结构.
+type BlockSchema struct {
+	Schema
+	Min    int // 最少出现次数, `required = true` 等同于 min = 1
+	Max    int // 最多出现次数, 0 表示不限
+	Labels int // 标签数量, -1 表示不限
+}
+
+// FieldSchema 描述一个键的值.
+type FieldSchema struct {
+	// Type 为 string, int, float, number (int 或 float), bool, duration,
+	// list, map 或 block, 为空时不检查类型.
+	Type     string
+	Required bool
+	// Enum 为允许的取值, 元素为 string, int64, float64, bool 或 time.Duration.
+	Enum []interface{}
+	// Min 与 Max 限制数值与 duration (以纳秒计) 的大小, 或字符串, 列表与 map 的长度.
+	Min, Max *float64
+	// Items 为列表元素的类型, 取值同 Type.
+	Items string
+	// Pattern 为字符串值必须匹配的正则表达式.
+	Pattern *regexp.Regexp
+	// Secret 表示值是机密, 应通过 secret() 或 env() 提供; 直接写出的字符串
+	// 以 ErrPlaintextSecret 报告. JSON Schema 中对应 writeOnly.
+	Secret bool
+}
+
+// schemaTypes 是 FieldSchema.Type 的合法取值.
+var schemaTypes = map[string]bool{
+	"string": true, "int": true, "float": true, "number": true, "bool": true,
+	"duration": true, "list": true, "map": true, "block": true,
+}
+
+// LoadSchema 读取并解析 schema 文件.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ParseSchema 解析 WANF 格式的 schema 文档, 语法见 Schema.
+func ParseSchema(data []byte) (*Schema, error) {
+	p := NewParser(NewLexer(data))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, p.Errors()[0]
+	}
+	s := &Schema{}
+	if err := parseSchemaBody(program.Statements, s, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseSchemaBody 读取 field 与 block 声明. 块体中的其他键由 blockKey 处理.
+func parseSchemaBody(stmts []Statement, s *Schema, blockKey func(*AssignStatement) (bool, error)) error {
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *BlockStatement:
+			name := string(st.Name.Value)
+			if name != "field" && name != "block" {
+				return schemaError(st.Token, "unknown schema block %q, expected field or block", name)
+			}
+			labels := st.Labels()
+			if len(labels) != 1 {
+				return schemaError(st.Token, "%s needs exactly one label, the key it describes", name)
+			}
+			key := labels[0]
+			if name == "field" {
+				if _, ok := s.Fields[key]; ok {
+					return schemaError(st.Token, "field %q is declared more than once", key)
+				}
+				fs, err := parseFieldSchema(st.Body.Statements)
+				if err != nil {
+					return err
+				}
+				if s.Fields == nil {
+					s.Fields = make(map[string]*FieldSchema)
+				}
+				s.Fields[key] = fs
+				continue
+			}
+			if _, ok := s.Blocks[key]; ok {
+				return schemaError(st.Token, "block %q is declared more than once", key)
+			}
+			bs, err := parseBlockSchema(st.Body.Statements)
+			if err != nil {
+				return err
+			}
+			if s.Blocks == nil {
+				s.Blocks = make(map[string]*BlockSchema)
+			}
+			s.Blocks[key] = bs
+		case *AssignStatement:
+			if string(st.Name.Value) == "allow_unknown" {
+				b, err := schemaBool(st)
+				if err != nil {
+					return err
+				}
+				s.AllowUnknown = b
+				continue
+			}
+			if blockKey != nil {
+				ok, err := blockKey(st)
+				if err != nil {
+					return err
+				}
+				if ok {
+					continue
+				}
+			}
+			return schemaError(st.Token, "unknown schema key %q", st.Name.Value)
+		default:
+			return schemaError(Token{}, "unexpected %s in schema", stmt.TokenLiteral())
+		}
+	}
+	return nil
+}
+
+func parseBlockSchema(stmts []Statement) (*BlockSchema, error) {
+	bs := &BlockSchema{Labels: -1}
+	err := parseSchemaBody(stmts, &bs.Schema, func(st *AssignStatement) (bool, error) {
+		var err error
+		switch string(st.Name.Value) {
+		case "required":
+			var b bool
+			if b, err = schemaBool(st); b && bs.Min == 0 {
+				bs.Min = 1
+			}
+		case "min":
+			bs.Min, err = schemaInt(st)
+		case "max":
+			bs.Max, err = schemaInt(st)
+		case "labels":
+			bs.Labels, err = schemaInt(st)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+func parseFieldSchema(stmts []Statement) (*FieldSchema, error) {
+	fs := &FieldSchema{}
+	for _, stmt := range stmts {
+		st, ok := stmt.(*AssignStatement)
+		if !ok {
+			return nil, schemaError(Token{}, "unexpected %s in field", stmt.TokenLiteral())
+		}
+		var err error
+		switch string(st.Name.Value) {
+		case "type":
+			fs.Type, err = schemaType(st)
+		case "items":
+			fs.Items, err = schemaType(st)
+		case "required":
+			fs.Required, err = schemaBool(st)
+		case "secret":
+			fs.Secret, err = schemaBool(st)
+		case "enum":
+			list, ok := st.Value.(*ListLiteral)
+			if !ok {
+				return nil, schemaError(st.Token, "enum must be a list")
+			}
+			for _, el := range list.Elements {
+				v, ok := literalValue(el)
+				if !ok {
+					return nil, schemaError(exprToken(el), "enum values must be literals")
+				}
+				fs.Enum = append(fs.Enum, v)
+			}
+		case "pattern":
+			str, ok := st.Value.(*StringLiteral)
+			if !ok {
+				return nil, schemaError(st.Token, "pattern must be a string")
+			}
+			if fs.Pattern, err = regexp.Compile(string(str.Value)); err != nil {
+				return nil, schemaError(st.Token, "invalid pattern: %v", err)
+			}
+		case "min":
+			fs.Min, err = schemaBound(st)
+		case "max":
+			fs.Max, err = schemaBound(st)
+		default:
+			return nil, schemaError(st.Token, "unknown field key %q", st.Name.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+func schemaError(tok Token, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if tok.Line == 0 {
+		return fmt.Errorf("schema: %s", msg)
+	}
+	return fmt.Errorf("schema: line %d:%d: %s", tok.Line, tok.Column, msg)
+}
+
+func schemaBool(st *AssignStatement) (bool, error) {
+	b, ok := st.Value.(*BoolLiteral)
+	if !ok {
+		return false, schemaError(st.Token, "%s must be true or false", st.Name.Value)
+	}
+	return b.Value, nil
+}
+
+func schemaInt(st *AssignStatement) (int, error) {
+	i, ok := st.Value.(*IntegerLiteral)
+	if !ok {
+		return 0, schemaError(st.Token, "%s must be an integer", st.Name.Value)
+	}
+	return int(i.Value), nil
+}
+
+func schemaType(st *AssignStatement) (string, error) {
+	s, ok := st.Value.(*StringLiteral)
+	if !ok || !schemaTypes[string(s.Value)] {
+		return "", schemaError(st.Token, "%s must be one of string, int, float, number, bool, duration, list, map or block", st.Name.Value)
+	}
+	return string(s.Value), nil
+}
+
+func schemaBound(st *AssignStatement) (*float64, error) {
+	v, ok := literalValue(st.Value)
+	if ok {
+		if f, ok := numericValue(v); ok {
+			return &f, nil
+		}
+	}
+	return nil, schemaError(st.Token, "%s must be a number or a duration", st.Name.Value)
+}
+
+// literalValue 返回标量字面量的值.
+func literalValue(expr Expression) (interface{}, bool) {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return string(e.Value), true
+	case *IntegerLiteral:
+		return e.Value, true
+	case *FloatLiteral:
+		return e.Value, true
+	case *BoolLiteral:
+		return e.Value, true
+	case *DurationLiteral:
+		d, err := time.ParseDuration(string(e.Value))
+		if err != nil {
+			return nil, false
+		}
+		return d, true
+	}
+	return nil, false
+}
+
+// numericValue 将 int64, float64 与 time.Duration 转换为 float64.
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case time.Duration:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// ValidateAgainstSchema 检查 doc 是否符合 schema, 并以 LintError 报告每一处不符:
+// 未声明的键与块为 ErrSchemaUnknown, secret 字段中的明文字符串为 ErrPlaintextSecret,
+// 其余问题为 ErrSchemaViolation. 无法静态确定
+// 的值 (如 `${var}`) 不做检查; import 不会被展开, 需要时可先使用 Render 得到完整文档.
+func ValidateAgainstSchema(doc *RootNode, schema *Schema) []LintError {
+	v := &schemaValidator{}
+	v.body(doc.Statements, schema, "", Token{Line: 1, Column: 1})
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		if v.errors[i].Line != v.errors[j].Line {
+			return v.errors[i].Line < v.errors[j].Line
+		}
+		return v.errors[i].Column < v.errors[j].Column
+	})
+	return v.errors
+}
+
+type schemaValidator struct {
+	errors []LintError
+}
+
+func (v *schemaValidator) report(tok Token, path, format string, args ...interface{}) {
+	v.reportAs(ErrSchemaViolation, tok, path, format, args...)
+}
+
+// reportAs 与 report 相同, 但使用错误类型 t.
+func (v *schemaValidator) reportAs(t ErrorType, tok Token, path, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	width := len(tok.Literal)
+	if width == 0 {
+		width = 1
+	}
+	endLine, endColumn := endOf(tok, width)
+	v.errors = append(v.errors, LintError{
+		Line:      tok.Line,
+		Column:    tok.Column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Message:   msg,
+		Level:     ErrorLevelLint,
+		Type:      t,
+		Rule:      t.RuleID(),
+		Severity:  defaultSeverity(LintError{Type: t}),
+		Args:      []string{path},
+	})
+}
+
+// body 检查一个块体. at 为缺少必填项时报告的位置 (所在块的名称).
+func (v *schemaValidator) body(stmts []Statement, s *Schema, prefix string, at Token) {
+	seen := make(map[string]bool)
+	blockCounts := make(map[string]int)
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *AssignStatement:
+			name := string(st.Name.Value)
+			path := joinPath(prefix, name)
+			seen[name] = true
+			if fs, ok := s.Fields[name]; ok {
+				v.value(st.Value, fs, path)
+				continue
+			}
+			if bs, ok := s.Blocks[name]; ok {
+				blockCounts[name]++
+				if bl, ok := st.Value.(*BlockLiteral); ok {
+					v.body(bl.Body.Statements, &bs.Schema, path, st.Name.Token)
+				} else {
+					v.report(exprToken(st.Value), path, "expected a block, got %s", schemaKind(st.Value))
+				}
+				continue
+			}
+			if !s.AllowUnknown && !(prefix == "" && name == VersionKey) {
+				v.reportAs(ErrSchemaUnknown, st.Name.Token, path, "unknown key %q", name)
+			}
+		case *BlockStatement:
+			name := string(st.Name.Value)
+			path := joinPath(prefix, name)
+			for _, l := range st.Labels() {
+				path += "." + strconv.Quote(l)
+			}
+			if bs, ok := s.Blocks[name]; ok {
+				blockCounts[name]++
+				if bs.Labels >= 0 && len(st.Labels()) != bs.Labels {
+					v.report(st.Name.Token, path, "block %q takes %d label(s), got %d", name, bs.Labels, len(st.Labels()))
+				}
+				v.body(st.Body.Statements, &bs.Schema, path, st.Name.Token)
+				continue
+			}
+			if fs, ok := s.Fields[name]; ok && (fs.Type == "map" || fs.Type == "block") {
+				// 无标签块也可以写出 map 或块字面量的值.
+				seen[name] = true
+				continue
+			}
+			if !s.AllowUnknown {
+				v.reportAs(ErrSchemaUnknown, st.Name.Token, path, "unknown block %q", name)
+			}
+		}
+	}
+
+	fields, blocks := s.names()
+	for _, name := range fields {
+		if s.Fields[name].Required && !seen[name] {
+			v.report(at, prefix, "missing required key %q", name)
+		}
+	}
+	for _, name := range blocks {
+		bs, n := s.Blocks[name], blockCounts[name]
+		if n < bs.Min {
+			v.report(at, prefix, "block %q must appear at least %d time(s), found %d", name, bs.Min, n)
+		}
+		if bs.Max > 0 && n > bs.Max {
+			v.report(at, prefix, "block %q may appear at most %d time(s), found %d", name, bs.Max, n)
+		}
+	}
+}
+
+// value 检查一个值的类型, 枚举与范围.
+func (v *schemaValidator) value(expr Expression, fs *FieldSchema, path string) {
+	if _, ok := expr.(*VarExpression); ok {
+		return
+	}
+	tok := exprToken(expr)
+	if id, ok := expr.(*Identifier); ok {
+		tok = id.Token
+	}
+	if fs.Type != "" && !schemaTypeMatches(fs.Type, expr) {
+		v.report(tok, path, "expected %s, got %s", fs.Type, schemaKind(expr))
+		return
+	}
+	if str, ok := expr.(*StringLiteral); ok && fs.Secret && len(str.Value) > 0 && !varRegex.Match(str.Value) {
+		v.reportAs(ErrPlaintextSecret, tok, path, "secret value is written in plain text; use secret() or env()")
+	}
+	if fs.Items != "" {
+		if list, ok := expr.(*ListLiteral); ok {
+			for i, el := range list.Elements {
+				if _, ok := el.(*VarExpression); !ok && !schemaTypeMatches(fs.Items, el) {
+					v.report(exprToken(el), fmt.Sprintf("%s[%d]", path, i), "expected %s, got %s", fs.Items, schemaKind(el))
+				}
+			}
+		}
+	}
+
+	val, isLiteral := literalValue(expr)
+	if len(fs.Enum) > 0 && isLiteral {
+		allowed := false
+		for _, e := range fs.Enum {
+			if schemaValuesEqual(e, val) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			v.report(tok, path, "value %s is not one of %s", formatSchemaValue(val), formatSchemaValues(fs.Enum))
+		}
+	}
+
+	if str, ok := val.(string); ok && fs.Pattern != nil && !fs.Pattern.MatchString(str) {
+		v.report(tok, path, "value %q does not match pattern %s", str, fs.Pattern)
+	}
+
+	if fs.Min == nil && fs.Max == nil {
+		return
+	}
+	var size float64
+	what := "value"
+	switch e := expr.(type) {
+	case *StringLiteral:
+		size, what = float64(len([]rune(string(e.Value)))), "length"
+	case *ListLiteral:
+		size, what = float64(len(e.Elements)), "length"
+	case *MapLiteral:
+		size, what = float64(len(e.Elements)), "length"
+	default:
+		n, ok := numericValue(val)
+		if !isLiteral || !ok {
+			return
+		}
+		size = n
+	}
+	_, isDuration := val.(time.Duration)
+	bound := func(f float64) string {
+		if isDuration {
+			return time.Duration(f).String()
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if fs.Min != nil && size < *fs.Min {
+		v.report(tok, path, "%s %s is less than the minimum %s", what, bound(size), bound(*fs.Min))
+	}
+	if fs.Max != nil && size > *fs.Max {
+		v.report(tok, path, "%s %s is greater than the maximum %s", what, bound(size), bound(*fs.Max))
+	}
+}
+
+func schemaTypeMatches(typ string, expr Expression) bool {
+	kind := schemaKind(expr)
+	switch typ {
+	case "number":
+		return kind == "int" || kind == "float"
+	case "float":
+		return kind == "int" || kind == "float"
+	}
+	return kind == typ
+}
+
+// schemaKind 返回表达式的 schema 类型名.
+func schemaKind(expr Expression) string {
+	switch expr.(type) {
+	case *StringLiteral, *EnvExpression, *SecretExpression:
+		return "string"
+	case *IntegerLiteral:
+		return "int"
+	case *FloatLiteral:
+		return "float"
+	case *BoolLiteral:
+		return "bool"
+	case *DurationLiteral:
+		return "duration"
+	case *ListLiteral:
+		return "list"
+	case *MapLiteral:
+		return "map"
+	case *BlockLiteral:
+		return "block"
+	case *Identifier:
+		return "identifier"
+	}
+	return expressionKind(expr)
+}
+
+func schemaValuesEqual(a, b interface{}) bool {
+	if x, ok := numericValue(a); ok {
+		if _, isDur := a.(time.Duration); isDur != isDurationValue(b) {
+			return false
+		}
+		y, ok := numericValue(b)
+		return ok && x == y
+	}
+	return a == b
+}
+
+func isDurationValue(v interface{}) bool {
+	_, ok := v.(time.Duration)
+	return ok
+}
+
+func formatSchemaValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case time.Duration:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func formatSchemaValues(vs []interface{}) string {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, v := range vs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(formatSchemaValue(v))
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// names 返回 schema 中声明的键与块的名称, 按字母排序.
+func (s *Schema) names() (fields, blocks []string) {
+	for name := range s.Fields {
+		fields = append(fields, name)
+	}
+	for name := range s.Blocks {
+		blocks = append(blocks, name)
+	}
+	sort.Strings(fields)
+	sort.Strings(blocks)
+	return fields, blocks
+}
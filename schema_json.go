@@ -0,0 +1,301 @@
+package wanf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// LoadJSONSchema 读取 JSON Schema 文件, 见 ParseJSONSchema.
+func LoadJSONSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseJSONSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ParseJSONSchema 将 JSON Schema 转换为 Schema, 以便用 ValidateAgainstSchema
+// 校验 WANF 文档. 文档按 ToJSON 的数据模型对应到 JSON:
+//   - 带 properties 的对象对应无标签块, additionalProperties 为此类对象的
+//     对象对应带一个标签的块, 元素为此类对象的数组对应可重复的块;
+//   - 其他对象对应 map, 数组对应列表, format 为 duration 的字符串对应 duration.
+//
+// 支持 type, enum, const, minimum, maximum, minLength, maxLength, minItems,
+// maxItems, pattern, required, properties, additionalProperties, items 以及
+// 指向 #/$defs 或 #/definitions 的 $ref. 其他关键字被忽略.
+func ParseJSONSchema(data []byte) (*Schema, error) {
+	dec := jsontext.NewDecoder(bytes.NewReader(data))
+	v, err := readJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("wanf: invalid JSON schema: %w", err)
+	}
+	if _, err := dec.ReadToken(); err != io.EOF {
+		return nil, fmt.Errorf("wanf: invalid JSON schema: unexpected data after top-level value")
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("wanf: JSON schema must be an object")
+	}
+	c := &jsonSchemaConverter{root: root, resolving: make(map[string]bool)}
+	node, err := c.resolve(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.properties()) == 0 && jsonSchemaType(node) != "object" {
+		return nil, fmt.Errorf("wanf: JSON schema must describe an object")
+	}
+	s := &Schema{}
+	if err := c.object(node, s, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// jsonSchemaNode 是 JSON Schema 中的一个 schema 对象.
+type jsonSchemaNode map[string]interface{}
+
+func (n jsonSchemaNode) properties() map[string]interface{} {
+	props, _ := n["properties"].(map[string]interface{})
+	return props
+}
+
+type jsonSchemaConverter struct {
+	root      map[string]interface{}
+	resolving map[string]bool // 正在展开的 $ref, 用于处理递归定义
+}
+
+// resolve 展开 $ref, 返回被引用的 schema.
+func (c *jsonSchemaConverter) resolve(v interface{}) (jsonSchemaNode, error) {
+	var n map[string]interface{}
+	switch m := v.(type) {
+	case map[string]interface{}:
+		n = m
+	case jsonSchemaNode:
+		n = m
+	default:
+		// true 等布尔 schema 不做约束.
+		return jsonSchemaNode{}, nil
+	}
+	ref, ok := n["$ref"].(string)
+	if !ok {
+		return n, nil
+	}
+	var name string
+	switch {
+	case strings.HasPrefix(ref, "#/$defs/"):
+		name = strings.TrimPrefix(ref, "#/$defs/")
+		n, _ = c.defs("$defs")[name].(map[string]interface{})
+	case strings.HasPrefix(ref, "#/definitions/"):
+		name = strings.TrimPrefix(ref, "#/definitions/")
+		n, _ = c.defs("definitions")[name].(map[string]interface{})
+	default:
+		return nil, fmt.Errorf("wanf: JSON schema: unsupported $ref %q", ref)
+	}
+	if n == nil {
+		return nil, fmt.Errorf("wanf: JSON schema: $ref %q not found", ref)
+	}
+	return c.resolve(n)
+}
+
+func (c *jsonSchemaConverter) defs(key string) map[string]interface{} {
+	defs, _ := c.root[key].(map[string]interface{})
+	return defs
+}
+
+// object 将对象 schema 的 properties 转换为 s 中的键与块.
+func (c *jsonSchemaConverter) object(n jsonSchemaNode, s *Schema, path string) error {
+	if ref, ok := n["$ref"].(string); ok {
+		if c.resolving[ref] {
+			// 递归定义只展开一层, 更深的层级不做检查.
+			s.AllowUnknown = true
+			return nil
+		}
+		c.resolving[ref] = true
+		defer delete(c.resolving, ref)
+		resolved, err := c.resolve(n)
+		if err != nil {
+			return err
+		}
+		n = resolved
+	}
+	required := make(map[string]bool)
+	if list, ok := n["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	s.AllowUnknown = n["additionalProperties"] != false
+	for name, raw := range n.properties() {
+		prop, err := c.resolve(raw)
+		if err != nil {
+			return err
+		}
+		propPath := path + "/properties/" + name
+		if bs, err := c.block(raw, prop, required[name], propPath); err != nil {
+			return err
+		} else if bs != nil {
+			if s.Blocks == nil {
+				s.Blocks = make(map[string]*BlockSchema)
+			}
+			s.Blocks[name] = bs
+			continue
+		}
+		fs, err := c.field(prop, propPath)
+		if err != nil {
+			return err
+		}
+		fs.Required = required[name]
+		if s.Fields == nil {
+			s.Fields = make(map[string]*FieldSchema)
+		}
+		s.Fields[name] = fs
+	}
+	return nil
+}
+
+// block 在 prop 对应 WANF 块时返回其 BlockSchema, 否则返回 nil.
+func (c *jsonSchemaConverter) block(raw interface{}, prop jsonSchemaNode, required bool, path string) (*BlockSchema, error) {
+	var body interface{}
+	bs := &BlockSchema{}
+	switch jsonSchemaType(prop) {
+	case "object":
+		if len(prop.properties()) > 0 {
+			body, bs.Max, bs.Labels = raw, 1, 0
+			break
+		}
+		values, err := c.resolve(prop["additionalProperties"])
+		if err != nil {
+			return nil, err
+		}
+		if len(values.properties()) == 0 {
+			return nil, nil
+		}
+		body, bs.Labels = prop["additionalProperties"], 1
+	case "array":
+		items, err := c.resolve(prop["items"])
+		if err != nil {
+			return nil, err
+		}
+		if len(items.properties()) == 0 {
+			return nil, nil
+		}
+		body, bs.Labels = prop["items"], -1
+	default:
+		return nil, nil
+	}
+	if required {
+		bs.Min = 1
+	}
+	node, _ := body.(map[string]interface{})
+	if err := c.object(node, &bs.Schema, path); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// field 将值的 schema 转换为 FieldSchema.
+func (c *jsonSchemaConverter) field(n jsonSchemaNode, path string) (*FieldSchema, error) {
+	fs := &FieldSchema{Type: wanfSchemaType(n)}
+	if fs.Type == "list" {
+		items, err := c.resolve(n["items"])
+		if err != nil {
+			return nil, err
+		}
+		fs.Items = wanfSchemaType(items)
+	}
+	if enum, ok := n["enum"].([]interface{}); ok {
+		for _, v := range enum {
+			fs.Enum = append(fs.Enum, jsonEnumValue(v, fs.Type))
+		}
+	}
+	if v, ok := n["const"]; ok {
+		fs.Enum = []interface{}{jsonEnumValue(v, fs.Type)}
+	}
+	for _, kw := range []string{"minimum", "minLength", "minItems", "minProperties"} {
+		if f, ok := numericValue(n[kw]); ok {
+			fs.Min = &f
+		}
+	}
+	for _, kw := range []string{"maximum", "maxLength", "maxItems", "maxProperties"} {
+		if f, ok := numericValue(n[kw]); ok {
+			fs.Max = &f
+		}
+	}
+	if p, ok := n["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("wanf: JSON schema %s: invalid pattern: %w", path, err)
+		}
+		fs.Pattern = re
+	}
+	fs.Secret, _ = n["writeOnly"].(bool)
+	return fs, nil
+}
+
+// jsonSchemaType 返回 schema 的 type, 忽略 "null". 有多个类型时返回空串.
+func jsonSchemaType(n jsonSchemaNode) string {
+	switch t := n["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		var found string
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				if found != "" {
+					return ""
+				}
+				found = s
+			}
+		}
+		return found
+	}
+	if _, ok := n["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// wanfSchemaType 将 JSON Schema 的类型映射为 FieldSchema.Type.
+func wanfSchemaType(n jsonSchemaNode) string {
+	switch jsonSchemaType(n) {
+	case "string":
+		if n["format"] == "duration" {
+			return "duration"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "number"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "list"
+	case "object":
+		return "map"
+	}
+	return ""
+}
+
+// jsonEnumValue 将 JSON 中的枚举值转换为 WANF 字面量的值, duration 字符串被解析.
+func jsonEnumValue(v interface{}, typ string) interface{} {
+	if s, ok := v.(string); ok && typ == "duration" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return v
+}
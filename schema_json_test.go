@@ -0,0 +1,153 @@
+package wanf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func validateForTest(t *testing.T, schema *Schema, doc string) string {
+	t.Helper()
+	p := NewParser(NewLexer([]byte(doc)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	var got []string
+	for _, e := range ValidateAgainstSchema(program, schema) {
+		got = append(got, fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message))
+	}
+	return strings.Join(got, "\n")
+}
+
+func TestParseJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name", "server"],
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "replicas": {"type": ["integer", "null"], "minimum": 1, "maximum": 5},
+    "mode": {"enum": ["dev", "prod"]},
+    "version": {"const": 2},
+    "hosts": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "timeout": {"type": "string", "format": "duration"},
+    "server": {"$ref": "#/definitions/server"},
+    "route": {"type": "object", "additionalProperties": {"$ref": "#/definitions/route"}}
+  },
+  "definitions": {
+    "server": {
+      "type": "object",
+      "properties": {"port": {"type": "integer", "maximum": 65535}}
+    },
+    "route": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {"path": {"type": "string", "minLength": 1}}
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `name = "svc"
+replicas = 3
+mode = "dev"
+version = 2
+hosts = ["a", "b"]
+labels = {[ team = "core" ]}
+timeout = 30s
+server {
+	port = 8080
+	extra = true
+}
+route "api" {
+	path = "/api"
+}
+`
+	if got := validateForTest(t, schema, valid); got != "" {
+		t.Errorf("valid document reported:\n%s", got)
+	}
+
+	invalid := `name = "Svc"
+replicas = 9
+mode = "qa"
+version = 3
+hosts = ["a", "b", "c"]
+timeout = "30s"
+server {
+	port = 70000
+}
+route {
+	path = ""
+	method = "GET"
+}
+other = 1
+`
+	want := strings.Join([]string{
+		`1:8: name: value "Svc" does not match pattern ^[a-z]+$`,
+		`2:12: replicas: value 9 is greater than the maximum 5`,
+		`3:8: mode: value "qa" is not one of ["dev", "prod"]`,
+		`4:11: version: value 3 is not one of [2]`,
+		`5:9: hosts: length 3 is greater than the maximum 2`,
+		`6:11: timeout: expected duration, got string`,
+		`8:9: server.port: value 70000 is greater than the maximum 65535`,
+		`10:1: route: block "route" takes 1 label(s), got 0`,
+		`11:9: route.path: length 0 is less than the minimum 1`,
+		`12:2: route.method: unknown key "method"`,
+		`14:1: other: unknown key "other"`,
+	}, "\n")
+	if got := validateForTest(t, schema, invalid); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseJSONSchema_FromSchemaOf(t *testing.T) {
+	data, err := SchemaOf(schemaTestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ParseJSONSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := `port = 0
+level = "trace"
+timeout = 1m
+route "a" {
+	colour = "red"
+	child "b" {
+		name = "leaf"
+	}
+}
+`
+	want := strings.Join([]string{
+		`1:8: port: value 0 is less than the minimum 1`,
+		`2:9: level: value "trace" is not one of ["debug", "info"]`,
+		`5:2: route."a".colour: unknown key "colour"`,
+	}, "\n")
+	if got := validateForTest(t, schema, doc); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseJSONSchema_Invalid(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`[1]`, "must be an object"},
+		{`{"type": "string"}`, "must describe an object"},
+		{`{"properties": {"a": {"$ref": "other.json#/x"}}}`, "unsupported $ref"},
+		{`{"properties": {"a": {"$ref": "#/$defs/missing"}}}`, "not found"},
+		{`{"properties": {"a": {"type": "string", "pattern": "("}}}`, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		_, err := ParseJSONSchema([]byte(tt.schema))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseJSONSchema(%s) error = %v, want containing %q", tt.schema, err, tt.want)
+		}
+	}
+}
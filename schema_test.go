@@ -0,0 +1,215 @@
+package wanf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+field "name" {
+	type = "string"
+	required = true
+	min = 1
+}
+field "mode" {
+	enum = ["dev", "prod"]
+}
+field "tags" {
+	type = "list"
+	items = "string"
+	max = 2
+}
+
+block "server" {
+	min = 1
+	max = 2
+	labels = 1
+
+	field "port" {
+		type = "int"
+		min = 1
+		max = 65535
+	}
+	field "timeout" {
+		type = "duration"
+		max = 1m
+	}
+}
+
+block "log" {
+	allow_unknown = true
+	field "level" {
+		enum = ["debug", "info"]
+	}
+}
+`
+
+func TestValidateAgainstSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		doc  string
+		want []string // 每个错误的 "line:col: message"
+	}{
+		{
+			name: "valid",
+			doc: `name = "svc"
+mode = "prod"
+tags = ["a", "b"]
+server "a" {
+	port = 8080
+	timeout = 30s
+}
+log {
+	level = "info"
+	format = "json"
+}
+`,
+		},
+		{
+			name: "values",
+			doc: `name = ""
+mode = "staging"
+tags = ["a", 1, "c"]
+server "a" {
+	port = 70000
+	timeout = 2m
+}
+`,
+			want: []string{
+				`1:8: name: length 0 is less than the minimum 1`,
+				`2:8: mode: value "staging" is not one of ["dev", "prod"]`,
+				`3:8: tags: length 3 is greater than the maximum 2`,
+				`3:14: tags[1]: expected string, got int`,
+				`5:9: server."a".port: value 70000 is greater than the maximum 65535`,
+				`6:12: server."a".timeout: value 2m0s is greater than the maximum 1m0s`,
+			},
+		},
+		{
+			name: "structure",
+			doc: `mode = "dev"
+extra = 1
+server {
+	port = "80"
+}
+cache {}
+`,
+			want: []string{
+				`1:1: missing required key "name"`,
+				`2:1: extra: unknown key "extra"`,
+				`3:1: server: block "server" takes 1 label(s), got 0`,
+				`4:9: server.port: expected int, got string`,
+				`6:1: cache: unknown block "cache"`,
+			},
+		},
+		{
+			name: "cardinality",
+			doc: `name = "svc"
+server "a" {}
+server "b" {}
+server "c" {}
+`,
+			want: []string{
+				`1:1: block "server" may appear at most 2 time(s), found 3`,
+			},
+		},
+		{
+			name: "missing block",
+			doc:  `name = "svc"`,
+			want: []string{
+				`1:1: block "server" must appear at least 1 time(s), found 0`,
+			},
+		},
+		{
+			name: "variables are not checked",
+			doc: `var port = "x"
+name = "svc"
+server "a" {
+	port = ${port}
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(NewLexer([]byte(tt.doc)))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatal(p.Errors())
+			}
+			var got []string
+			for _, e := range ValidateAgainstSchema(program, schema) {
+				wantType := ErrSchemaViolation
+				if strings.Contains(e.Message, ": unknown ") || strings.HasPrefix(e.Message, "unknown ") {
+					wantType = ErrSchemaUnknown
+				}
+				if e.Type != wantType || e.Severity != SeverityError {
+					t.Errorf("unexpected error type %v / severity %v", e.Type, e.Severity)
+				}
+				got = append(got, fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseSchema_Invalid(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`field "a" { type = "text" }`, "type must be one of"},
+		{`field "a" { color = "red" }`, `unknown field key "color"`},
+		{`field {}`, "needs exactly one label"},
+		{`rule "a" {}`, `unknown schema block "rule"`},
+		{"field \"a\" {}\nfield \"a\" {}", `field "a" is declared more than once`},
+		{`block "a" { min = "1" }`, "min must be an integer"},
+		{`field "a" { enum = "x" }`, "enum must be a list"},
+		{`field "a" { pattern = "(" }`, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSchema([]byte(tt.schema))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseSchema(%q) error = %v, want containing %q", tt.schema, err, tt.want)
+		}
+	}
+}
+
+func TestLintWithSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(`
+field "name" {
+	type = "string"
+	required = true
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("name = 1\nextra = true\n")
+	_, errs := Lint(data, WithSchema(schema))
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Rule+" "+e.Severity.String())
+	}
+	want := "WANF018 error,WANF019 error"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	cfg, err := ParseLintConfig([]byte(`rule "schema-unknown" {
+	enabled = false
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs = Lint(data, WithSchema(schema), WithLintConfig(cfg))
+	if len(errs) != 1 || errs[0].Type != ErrSchemaViolation {
+		t.Errorf("with schema-unknown disabled got %v", errs)
+	}
+}
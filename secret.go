@@ -0,0 +1,50 @@
+package wanf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretResolver 返回 secret() 中引用的机密, 例如对 "vault:db/prod#password"
+// 查询 Vault. 引用的格式由解析器自行约定.
+type SecretResolver func(ref string) (string, error)
+
+// WithSecretResolver 设置解码时用于求值 `secret("...")` 的解析器. 未设置时
+// 文档中出现 secret() 会返回错误. 与 env() 一样, secret() 在沙箱模式下不可用.
+func WithSecretResolver(r SecretResolver) DecoderOption {
+	return func(d *internalDecoder) {
+		d.secrets = r
+	}
+}
+
+// SecretSchemes 返回按引用前缀分派的解析器: "vault:db#password" 交给
+// resolvers["vault"], 参数为去掉前缀的 "db#password". 没有前缀或前缀未注册时
+// 返回错误.
+func SecretSchemes(resolvers map[string]SecretResolver) SecretResolver {
+	return func(ref string) (string, error) {
+		scheme, rest, ok := strings.Cut(ref, ":")
+		if !ok {
+			return "", fmt.Errorf("missing scheme, expected scheme:reference")
+		}
+		r, ok := resolvers[scheme]
+		if !ok {
+			return "", fmt.Errorf("no resolver for scheme %q", scheme)
+		}
+		return r(rest)
+	}
+}
+
+// resolveSecret 求值 secret(ref). 错误的位置由调用方的 *DecodeError 给出.
+func (d *internalDecoder) resolveSecret(ref string) (string, error) {
+	if d.sandbox {
+		return "", errorOf(ErrSandbox, "secret(%q) is not allowed in sandbox mode", ref)
+	}
+	if d.secrets == nil {
+		return "", fmt.Errorf("secret(%q) requires a resolver, see WithSecretResolver", ref)
+	}
+	val, err := d.secrets(ref)
+	if err != nil {
+		return "", fmt.Errorf("secret(%q): %w", ref, err)
+	}
+	return val, nil
+}
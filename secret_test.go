@@ -0,0 +1,129 @@
+package wanf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type secretConfig struct {
+	User     string `wanf:"user"`
+	Password string `wanf:"password,secret"`
+}
+
+func TestSecretExpression(t *testing.T) {
+	src := []byte("user = \"app\"\npassword = secret(\"vault:db/prod#password\")\n")
+	root, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Format(root, FormatOptions{Style: StyleBlockSorted, EmptyLines: true}); !bytes.Equal(got, bytes.TrimSuffix(src, []byte("\n"))) {
+		t.Errorf("Format = %q", got)
+	}
+
+	var refs []string
+	resolver := SecretSchemes(map[string]SecretResolver{
+		"vault": func(ref string) (string, error) {
+			refs = append(refs, ref)
+			return "s3cr3t", nil
+		},
+	})
+	var cfg secretConfig
+	if err := decodeWith(src, &cfg, WithSecretResolver(resolver)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "s3cr3t" || len(refs) != 1 || refs[0] != "db/prod#password" {
+		t.Errorf("password = %q, refs = %v", cfg.Password, refs)
+	}
+
+	dec, err := NewStreamDecoder(bytes.NewReader(src), WithSecretResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = secretConfig{}
+	if err := dec.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "s3cr3t" {
+		t.Errorf("stream password = %q", cfg.Password)
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	src := []byte("password = secret(\"vault:db#password\")\n")
+	tests := []struct {
+		name string
+		opts []DecoderOption
+		want string
+	}{
+		{"no resolver", nil, `line 1:1: password: secret("vault:db#password") requires a resolver, see WithSecretResolver`},
+		{"sandbox", []DecoderOption{WithSandbox(), WithSecretResolver(func(string) (string, error) { return "x", nil })}, `line 1:1: password: secret("vault:db#password") is not allowed in sandbox mode`},
+		{"resolver error", []DecoderOption{WithSecretResolver(func(string) (string, error) { return "", errors.New("denied") })}, `line 1:1: password: secret("vault:db#password"): denied`},
+		{"unknown scheme", []DecoderOption{WithSecretResolver(SecretSchemes(nil))}, `line 1:1: password: secret("vault:db#password"): no resolver for scheme "vault"`},
+	}
+	for _, tt := range tests {
+		var cfg secretConfig
+		if err := decodeWith(src, &cfg, tt.opts...); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+		dec, err := NewStreamDecoder(bytes.NewReader(src), tt.opts...)
+		if err == nil {
+			err = dec.Decode(&cfg)
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: StreamDecoder error = %v, want %q", tt.name, err, tt.want)
+		}
+		if _, err := ToValue(src, tt.opts...); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: ToValue error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLintPlaintextSecret(t *testing.T) {
+	schema, err := SchemaFromStruct(secretConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schema.Fields["password"].Secret {
+		t.Fatal("password field is not marked secret")
+	}
+	data := []byte(`var p = "app"
+user = ${p}
+password = "hunter2"
+`)
+	_, errs := Lint(data, WithSchema(schema))
+	if len(errs) != 1 || errs[0].Type != ErrPlaintextSecret || errs[0].Rule != "WANF020" || errs[0].Line != 3 {
+		t.Fatalf("got %v, want one plaintext-secret issue on line 3", errs)
+	}
+	for _, ok := range []string{`secret("vault:db#password")`, `env("DB_PASSWORD")`, `"${p}"`, `""`} {
+		data := []byte("var p = \"app\"\nuser = ${p}\npassword = " + ok + "\n")
+		if _, errs := Lint(data, WithSchema(schema)); len(errs) != 0 {
+			t.Errorf("password = %s: unexpected issues %v", ok, errs)
+		}
+	}
+
+	fieldSchema, err := ParseSchema([]byte("field \"token\" {\n\tsecret = true\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateAgainstSchema(mustParse(t, "token = \"abc\"\n"), fieldSchema); len(errs) != 1 || errs[0].Severity != SeverityWarning {
+		t.Errorf("ValidateAgainstSchema = %v, want one warning", errs)
+	}
+}
+
+func mustParse(t *testing.T, src string) *RootNode {
+	t.Helper()
+	root, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func decodeWith(data []byte, v interface{}, opts ...DecoderOption) error {
+	dec, err := NewDecoder(bytes.NewReader(data), opts...)
+	if err != nil {
+		return err
+	}
+	return dec.Decode(v)
+}
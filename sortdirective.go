@@ -0,0 +1,115 @@
+package wanf
+
+import (
+	"sort"
+	"strings"
+)
+
+// 排序指令 (sort directives) 用于在启用排序的样式下保留手工排列的顺序:
+//
+//	// wanf:sort off   从此语句起保持原有顺序, 直到 wanf:sort on 或所在块结束
+//	// wanf:sort on    恢复排序
+//	// wanf:nosort     紧随其后的块 (或映射) 内部保持原有顺序, 块本身仍参与排序
+//
+// 指令需写在语句的前置注释中. sort off 区域内的语句保持原位, 其内部的块和映射也不排序.
+const (
+	sortOffDirective = "wanf:sort off"
+	sortOnDirective  = "wanf:sort on"
+	noSortDirective  = "wanf:nosort"
+)
+
+// commentDirective returns the directive text of a comment, e.g. "wanf:sort off",
+// or "" if the comment is not a wanf: directive.
+func commentDirective(c *Comment) string {
+	text := string(c.Text)
+	text = strings.TrimPrefix(text, "//")
+	text = strings.TrimPrefix(text, "/*")
+	text = strings.TrimSuffix(text, "*/")
+	text = strings.Join(strings.Fields(text), " ")
+	if !strings.HasPrefix(text, suppressPrefix) {
+		return ""
+	}
+	return text
+}
+
+// sortRegions reports for each statement whether it lies in a `wanf:sort off`
+// region. It returns nil if stmts contain no such region.
+func sortRegions(stmts []Statement) []bool {
+	var frozen []bool
+	off := false
+	for i, stmt := range stmts {
+		for _, c := range stmt.GetLeadingComments() {
+			switch commentDirective(c) {
+			case sortOffDirective:
+				off = true
+			case sortOnDirective:
+				off = false
+			}
+		}
+		if off {
+			if frozen == nil {
+				frozen = make([]bool, len(stmts))
+			}
+			frozen[i] = true
+		}
+	}
+	return frozen
+}
+
+// hasNoSortDirective reports whether s is preceded by a `wanf:nosort` comment.
+func hasNoSortDirective(s Statement) bool {
+	for _, c := range s.GetLeadingComments() {
+		if commentDirective(c) == noSortDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRuns stably sorts each run of consecutive statements that are not
+// frozen, leaving frozen statements where they are.
+func sortRuns(stmts []Statement, frozen []bool, less func(a, b Statement) bool) {
+	start := 0
+	for i := 0; i <= len(stmts); i++ {
+		if i < len(stmts) && (frozen == nil || !frozen[i]) {
+			continue
+		}
+		run := stmts[start:i]
+		if len(run) > 1 {
+			// `wanf:sort on` 及其之前的注释属于区域边界, 不随语句移动.
+			head := detachSortOn(run[0])
+			sort.SliceStable(run, func(a, b int) bool { return less(run[a], run[b]) })
+			if head != nil {
+				setLeadingComments(run[0], append(head, run[0].GetLeadingComments()...))
+			}
+		}
+		start = i + 1
+	}
+}
+
+// detachSortOn removes the leading comments of s up to and including the last
+// `wanf:sort on` directive and returns them.
+func detachSortOn(s Statement) []*Comment {
+	comments := s.GetLeadingComments()
+	for i := len(comments) - 1; i >= 0; i-- {
+		if commentDirective(comments[i]) == sortOnDirective {
+			head := comments[: i+1 : i+1]
+			setLeadingComments(s, comments[i+1:])
+			return head
+		}
+	}
+	return nil
+}
+
+func setLeadingComments(s Statement, comments []*Comment) {
+	switch s := s.(type) {
+	case *AssignStatement:
+		s.LeadingComments = comments
+	case *BlockStatement:
+		s.LeadingComments = comments
+	case *VarStatement:
+		s.LeadingComments = comments
+	case *ImportStatement:
+		s.LeadingComments = comments
+	}
+}
@@ -1,30 +1,69 @@
 package wanf
 
 import (
+	"errors"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
-// TestStreamDecoder_VarError tests that the decoder correctly returns an error
-// if a var statement is used in stream mode.
-func TestStreamDecoder_VarError(t *testing.T) {
-	wanfData := `var a = 1`
-	var cfg struct{}
+// TestStreamDecoder_Var tests var declarations in stream mode: they are
+// evaluated in a single pass and must appear before their first use.
+func TestStreamDecoder_Var(t *testing.T) {
+	type config struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port"`
+		URL  string `wanf:"url"`
+		Srv  struct {
+			Port int `wanf:"port"`
+		} `wanf:"server"`
+	}
+	tests := []struct {
+		src     string
+		profile string
+		want    config
+		err     string
+	}{
+		{src: "var host = \"db\"\nvar port = 5432\nname = ${host}\nport = ${port}\nurl = \"${host}:${port}\"\n", want: config{Name: "db", Port: 5432, URL: "db:5432"}},
+		{src: "var p = 1\nport = ${p}\nvar p = 2\nurl = \"${p}\"\n", want: config{Port: 1, URL: "2"}},
+		{src: "var p = 1\nprofile \"prod\" {\n\tvar p = 2\n}\nport = ${p}\n", profile: "prod", want: config{Port: 2}},
+		{src: "url = \"${later}\"\nvar later = 1\n", want: config{URL: "${later}"}},
+		{src: "port = ${later}\nvar later = 1\n", err: `variable "later" is not defined`},
+		{src: "server {\n\tvar a = 1\n}\n", err: "line 2:2: var declarations are only allowed at the top level"},
+		{src: "var = 1\n", err: "expected identifier after var"},
+	}
+	for _, tt := range tests {
+		var cfg config
+		decoder, err := NewStreamDecoder(strings.NewReader(tt.src), WithProfile(tt.profile))
+		if err != nil {
+			t.Fatalf("NewStreamDecoder failed: %v", err)
+		}
+		err = decoder.Decode(&cfg)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Decode(%q) error = %v, want %q", tt.src, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || cfg != tt.want {
+			t.Errorf("Decode(%q) = %+v, %v, want %+v", tt.src, cfg, err, tt.want)
+		}
+	}
 
-	r := strings.NewReader(wanfData)
-	decoder, err := NewStreamDecoder(r)
+	// 变量只在所在的文档中有效.
+	decoder, err := NewStreamDecoder(strings.NewReader("var a = 1\nport = ${a}\n---\nport = ${a}\n"))
 	if err != nil {
-		t.Fatalf("NewStreamDecoder failed: %v", err)
+		t.Fatal(err)
 	}
-
-	err = decoder.Decode(&cfg)
-	if err == nil {
-		t.Fatal("Expected an error for var statement, but got nil")
+	var first, second config
+	if err := decoder.Decode(&first); err != nil || first.Port != 1 {
+		t.Fatalf("first document = %+v, %v", first, err)
 	}
-
-	expectedError := "var statements are not supported in stream decoding mode"
-	if !strings.Contains(err.Error(), expectedError) {
-		t.Errorf("Expected error to contain %q, but got: %v", expectedError, err)
+	if err := decoder.Decode(&second); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("second document error = %v, want ErrUndefinedVariable", err)
 	}
 }
 
@@ -50,3 +89,179 @@ func TestStreamDecoder_ImportError(t *testing.T) {
 		t.Errorf("Expected error to contain %q, but got: %v", expectedError, err)
 	}
 }
+
+// TestStreamDecoder_MultipleDocuments tests that documents separated by ---
+// lines are decoded one per call, followed by io.EOF.
+func TestStreamDecoder_MultipleDocuments(t *testing.T) {
+	wanfData := `---
+name = "a"
+server {
+	port = 1
+}
+---
+// second snapshot
+name = "b"
+---  
+name = "c-d"
+---
+`
+	type config struct {
+		Name   string `wanf:"name"`
+		Server struct {
+			Port int `wanf:"port"`
+		} `wanf:"server"`
+	}
+
+	decoder, err := NewStreamDecoder(strings.NewReader(wanfData))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder failed: %v", err)
+	}
+	var names []string
+	for {
+		var cfg config
+		err := decoder.Decode(&cfg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		names = append(names, cfg.Name)
+		if cfg.Name == "a" && cfg.Server.Port != 1 {
+			t.Errorf("first document: port = %d, want 1", cfg.Server.Port)
+		}
+	}
+	if got := strings.Join(names, " "); got != "a b c-d" {
+		t.Errorf("decoded %q, want %q", got, "a b c-d")
+	}
+
+	decoder, _ = NewStreamDecoder(strings.NewReader("server {\n---\n}\n"))
+	var cfg config
+	if err := decoder.Decode(&cfg); err == nil || !strings.Contains(err.Error(), "document separator inside a block") {
+		t.Errorf("separator inside block: err = %v", err)
+	}
+}
+
+// TestStreamDecoder_Groups tests documents written as top-level { } groups,
+// which may follow each other without a separator.
+func TestStreamDecoder_Groups(t *testing.T) {
+	type config struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port"`
+	}
+	src := "{ name = \"a\"; port = 1 } { name = \"b\" }\n// next\n{\n\tvar p = 3\n\tport = ${p}\n}\n---\nname = \"d\"\n"
+	decoder, err := NewStreamDecoder(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []config
+	for {
+		var cfg config
+		if err := decoder.Decode(&cfg); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		got = append(got, cfg)
+	}
+	want := []config{{"a", 1}, {"b", 0}, {"", 3}, {"d", 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+
+	for src, want := range map[string]string{
+		"{ name = \"a\"\n":         "expected '}' to close the document",
+		"{ name = \"a\"\n---\n}\n": "expected '}' to close the document",
+		"name = \"a\"\n}\n":        "unexpected token } at top level",
+	} {
+		decoder, _ := NewStreamDecoder(strings.NewReader(src))
+		var cfg config
+		if err := decoder.Decode(&cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Decode(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
+
+// TestStreamDecoder_Feed tests that Decode returns as soon as a document ends,
+// without waiting for the next one to arrive.
+func TestStreamDecoder_Feed(t *testing.T) {
+	type config struct {
+		Name string `wanf:"name"`
+	}
+	r, w := io.Pipe()
+	next := make(chan string)
+	go func() {
+		for doc := range next {
+			io.WriteString(w, doc)
+		}
+		w.Close()
+	}()
+	defer close(next)
+
+	next <- "name = \"a\"\n---\n"
+	decoder, err := NewStreamDecoder(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func() (string, error) {
+		done := make(chan error, 1)
+		var cfg config
+		go func() { done <- decoder.Decode(&cfg) }()
+		select {
+		case err := <-done:
+			return cfg.Name, err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Decode is waiting for the next document")
+			return "", nil
+		}
+	}
+	if name, err := decode(); err != nil || name != "a" {
+		t.Fatalf("first document = %q, %v", name, err)
+	}
+	next <- "{ name = \"b\" }"
+	if name, err := decode(); err != nil || name != "b" {
+		t.Fatalf("second document = %q, %v", name, err)
+	}
+	next <- "\n{ name = \"c\" }"
+	if name, err := decode(); err != nil || name != "c" {
+		t.Fatalf("third document = %q, %v", name, err)
+	}
+}
+
+// TestStreamDecoder_ReadError tests that a failing reader is reported instead
+// of being mistaken for the end of a valid document.
+func TestStreamDecoder_ReadError(t *testing.T) {
+	type config struct {
+		Name string `wanf:"name"`
+		Port int    `wanf:"port"`
+	}
+	timeout := errors.New("i/o timeout")
+	failing := func(src string) io.Reader {
+		return io.MultiReader(strings.NewReader(src), iotest.ErrReader(timeout))
+	}
+
+	decoder, _ := NewStreamDecoder(failing("name = \"a\"\nport = 80"))
+	var cfg config
+	err := decoder.Decode(&cfg)
+	if !errors.Is(err, timeout) || !strings.Contains(err.Error(), "line 2:10: reading input") {
+		t.Errorf("Decode error = %v, want the read error at 2:10", err)
+	}
+
+	// 已完整读取的文档正常返回, 下一次 Decode 报告读取错误而不是 io.EOF.
+	decoder, _ = NewStreamDecoder(failing("name = \"a\"\n---\n"))
+	if err := decoder.Decode(&cfg); err != nil || cfg.Name != "a" {
+		t.Fatalf("first document = %+v, %v", cfg, err)
+	}
+	if err := decoder.Decode(&cfg); !errors.Is(err, timeout) {
+		t.Errorf("second Decode error = %v, want the read error", err)
+	}
+
+	var rec blockRecord
+	decoder, _ = NewStreamDecoder(failing("record {\n\tid = 1\n}\nrecord {\n\tid = 2\n"))
+	it := decoder.Blocks("record", &rec)
+	for it.Next() {
+	}
+	if !errors.Is(it.Err(), timeout) {
+		t.Errorf("Blocks error = %v, want the read error", it.Err())
+	}
+}
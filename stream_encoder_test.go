@@ -2,6 +2,7 @@ package wanf
 
 import (
 	"bytes"
+	"math"
 	"strings"
 	"testing"
 )
@@ -103,3 +104,90 @@ nested {
 		})
 	}
 }
+
+func TestEncoder_AlignAssignments(t *testing.T) {
+	type tls struct {
+		Enabled bool `wanf:"enabled"`
+	}
+	type server struct {
+		Host           string            `wanf:"host"`
+		Port           int               `wanf:"port"`
+		MaxConnections int               `wanf:"max_connections"`
+		Labels         map[string]string `wanf:"labels"`
+		TLS            tls               `wanf:"tls"`
+	}
+	cfg := struct {
+		Server server `wanf:"server"`
+	}{Server: server{Host: "localhost", Port: 8080, MaxConnections: 100, Labels: map[string]string{"a": "1", "team": "infra"}, TLS: tls{Enabled: true}}}
+
+	// Multi-line values such as maps break the alignment run.
+	want := `server {
+	host = "localhost"
+	labels = {[
+		a    = "1",
+		team = "infra",
+	]}
+	max_connections = 100
+	port            = 8080
+	tls {
+		enabled = true
+	}
+}
+`
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, WithAlignAssignments()).Encode(cfg); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("Encoder output mismatch:\n--- want\n%s\n--- got\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := NewStreamEncoder(&buf).Encode(cfg, WithAlignAssignments()); err != nil {
+		t.Fatalf("StreamEncoder failed: %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("StreamEncoder output mismatch:\n--- want\n%s\n--- got\n%s", want, buf.String())
+	}
+}
+
+func TestEncoder_NonFiniteFloats(t *testing.T) {
+	type config struct {
+		Ratio   float64   `wanf:"ratio"`
+		Limits  []float64 `wanf:"limits"`
+		Scale32 float32   `wanf:"scale"`
+	}
+	in := config{Ratio: math.NaN(), Limits: []float64{math.Inf(-1), 1.5}, Scale32: float32(math.Inf(1))}
+
+	if _, err := Marshal(&in); err == nil || !strings.Contains(err.Error(), "unsupported float value NaN") {
+		t.Errorf("Marshal error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewStreamEncoder(&buf).Encode(&in); err == nil || !strings.Contains(err.Error(), "unsupported float value") {
+		t.Errorf("StreamEncoder error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := NewEncoder(&out, WithNonFiniteFloatsAsStrings()).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := NewStreamEncoder(&buf).Encode(&in, WithNonFiniteFloatsAsStrings()); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{out.String(), buf.String()} {
+		for _, want := range []string{`ratio = "NaN"`, `"-Inf",`, `scale = "+Inf"`} {
+			if !strings.Contains(s, want) {
+				t.Errorf("output does not contain %s:\n%s", want, s)
+			}
+		}
+	}
+
+	var got config
+	if err := Decode(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(got.Ratio) || !math.IsInf(got.Limits[0], -1) || got.Limits[1] != 1.5 || !math.IsInf(float64(got.Scale32), 1) {
+		t.Errorf("round trip = %+v", got)
+	}
+}
@@ -0,0 +1,146 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// BlockIterator 逐个解码流中同名的顶层块, 由 StreamDecoder.Blocks 创建.
+// 用法与 bufio.Scanner 相同:
+//
+//	var rec Record
+//	it := dec.Blocks("record", &rec)
+//	for it.Next() {
+//		// 处理 rec
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type BlockIterator struct {
+	dec   *StreamDecoder
+	name  string
+	rv    reflect.Value // v 指向的结构体
+	err   error
+	inDoc bool // 正在读取一个文档
+	done  bool
+}
+
+// Sentinels used by BlockIterator to stop readBody between statements.
+var (
+	errBlockFound     = errors.New("wanf: block found")
+	errProfileEntered = errors.New("wanf: profile entered")
+)
+
+// Blocks 返回遍历流中所有名为 name 的顶层块 (包括选中的 profile 中的) 的
+// 迭代器. 每次 Next 先把 v 重置为零值, 再把下一个块解码到 v 中, 因此无论
+// 有多少个块, 内存占用都不变. 块的标签写入 v 的 labels 字段.
+//
+// 其他顶层赋值与块被读取后丢弃, var 声明照常生效. 迭代跨越 `---` 分隔的
+// 所有文档. 使用 Blocks 后不应再调用同一解码器的 Decode.
+func (dec *StreamDecoder) Blocks(name string, v interface{}) *BlockIterator {
+	it := &BlockIterator{dec: dec, name: name}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		it.err = fmt.Errorf("v must be a pointer to a struct")
+		return it
+	}
+	it.rv = rv.Elem()
+	return it
+}
+
+// Next 把下一个块解码到 Blocks 的 v 中. 没有更多的块或发生错误时返回 false,
+// 此时由 Err 报告错误.
+func (it *BlockIterator) Next() bool {
+	if it.err != nil || it.done {
+		return false
+	}
+	dec := it.dec
+	skipAssign := func() error { return dec.assignEvent(&Events{}) }
+	for {
+		if !it.inDoc {
+			if dec.beginDocument() == io.EOF {
+				it.done = true
+				return false
+			}
+			it.inDoc = true
+		}
+
+		err := dec.readBody(skipAssign, it.block)
+		switch {
+		case err == errBlockFound:
+			dec.p.nextToken() // consume '}'
+			return true
+		case err == errProfileEntered:
+			continue
+		case err == nil && dec.profile && dec.p.curTokenIs(RBRACE):
+			// The selected profile stays open across calls and ends here.
+			it.leaveProfile()
+			dec.p.nextToken()
+			continue
+		}
+		if dec.profile {
+			it.leaveProfile()
+		}
+		if err := dec.endDocument(err); err != nil {
+			it.err = err
+			return false
+		}
+		it.inDoc = false
+	}
+}
+
+// Err 返回迭代中发生的第一个错误, 正常读完流时为 nil.
+func (it *BlockIterator) Err() error {
+	return it.err
+}
+
+func (it *BlockIterator) leaveProfile() {
+	it.dec.profile = false
+	it.dec.depth--
+}
+
+// block handles a top-level block: it opens the selected profile, decodes a
+// matching block into it.rv and skips everything else.
+func (it *BlockIterator) block() error {
+	dec := it.dec
+	topLevel := dec.depth == 0
+	if err := dec.enterNesting(); err != nil {
+		return err
+	}
+	defer func() { dec.depth-- }()
+
+	nameTok, labels, err := dec.readBlockHeader()
+	if err != nil {
+		return err
+	}
+	name := string(nameTok.Literal)
+	if topLevel && len(labels) > 0 && name == profileBlockName {
+		if labels[0] != dec.d.profile {
+			return dec.skipBlock()
+		}
+		// Hold the profile's depth until Next reaches its '}', so that var
+		// declarations and nested blocks are checked as in decodeProfile.
+		dec.profile = true
+		dec.depth++
+		return errProfileEntered
+	}
+	if name != it.name {
+		return dec.skipBlock()
+	}
+
+	it.rv.Set(reflect.Zero(it.rv.Type()))
+	setBlockLabels(it.rv, labels)
+	if err := dec.decodeBody(it.rv); err != nil && err != io.EOF {
+		prefixDecodeError(err, blockPath(name, labels), nil)
+		return err
+	}
+	if !dec.p.curTokenIs(RBRACE) {
+		return syntaxError(dec.p.curToken, "expected '}' to close block %q", name)
+	}
+	if err := validateBlock(it.rv, nameTok); err != nil {
+		return err
+	}
+	return errBlockFound
+}
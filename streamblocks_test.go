@@ -0,0 +1,121 @@
+package wanf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type blockRecord struct {
+	Labels []string `wanf:",labels"`
+	ID     int      `wanf:"id"`
+	Tags   []string `wanf:"tags"`
+	Owner  struct {
+		Name string `wanf:"name"`
+	} `wanf:"owner"`
+}
+
+func TestStreamDecoder_Blocks(t *testing.T) {
+	src := `var team = "infra"
+version = 2
+record "a" {
+	id = 1
+	tags = ["x"]
+	owner {
+		name = ${team}
+	}
+}
+other {
+	record {
+		id = 99
+	}
+}
+record "b" {
+	id = 2
+}
+profile "dev" {
+	record {
+		id = 98
+	}
+}
+profile "prod" {
+	var team = "ops"
+	record "c" {
+		id = 3
+		owner {
+			name = ${team}
+		}
+	}
+}
+---
+record {
+	id = 4
+}
+`
+	dec, err := NewStreamDecoder(strings.NewReader(src), WithProfile("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec blockRecord
+	var got []blockRecord
+	it := dec.Blocks("record", &rec)
+	for it.Next() {
+		got = append(got, rec)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	want := make([]blockRecord, 4)
+	want[0].Labels, want[0].ID, want[0].Tags, want[0].Owner.Name = []string{"a"}, 1, []string{"x"}, "infra"
+	want[1].Labels, want[1].ID = []string{"b"}, 2
+	want[2].Labels, want[2].ID, want[2].Owner.Name = []string{"c"}, 3, "ops"
+	want[3].ID = 4
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks = %+v, want %+v", got, want)
+	}
+	if it.Next() {
+		t.Error("Next() after the end = true")
+	}
+}
+
+func TestStreamDecoder_BlocksErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"record {\n\tid = \"x\"\n}\n", "record.id"},
+		{"record {\n\tid = 1\n", "expected '}' to close block \"record\""},
+		{"record {\n\tid = 1\n}\n}\n", "unexpected token } at top level"},
+		{"name = ${missing}\n", `variable "missing" is not defined`},
+	}
+	for _, tt := range tests {
+		dec, _ := NewStreamDecoder(strings.NewReader(tt.src))
+		var rec blockRecord
+		it := dec.Blocks("record", &rec)
+		for it.Next() {
+		}
+		if err := it.Err(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Blocks(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+
+	dec, _ := NewStreamDecoder(strings.NewReader("record {}\n"))
+	it := dec.Blocks("record", blockRecord{})
+	if it.Next() || it.Err() == nil {
+		t.Error("Blocks with a non-pointer value did not fail")
+	}
+
+	// 超出大小限制时报告 ErrInputTooLarge 而不是语法错误.
+	dec, _ = NewStreamDecoder(strings.NewReader("record {\n\tid = 1\n}\nrecord {\n\tid = 2\n}\n"), WithMaxSize(20))
+	var rec blockRecord
+	it = dec.Blocks("record", &rec)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 1 || !errors.Is(it.Err(), ErrInputTooLarge) {
+		t.Errorf("Blocks with WithMaxSize read %d blocks, error = %v", n, it.Err())
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"reflect"
 	"strconv"
 	"time"
@@ -12,10 +11,36 @@ import (
 
 // StreamDecoder 从输入流中读取并解码WANF格式的数据.
 // 这是一个真正的流式解码器, 它边解析边解码, 不会为整个文件构建AST.
-// 为了性能和低内存占用, 此解码器不支持 `var` 和 `import` 语句.
+// 为了性能和低内存占用, 此解码器不支持 `import` 语句.
+//
+// 输入只读取一遍, 因此 `var` 声明必须出现在引用之前: 声明之前的 `${name}`
+// 视为未定义的变量 (字符串中的引用原样保留), 同名的再次声明只影响其后的引用.
+// 变量只在所在的文档中有效.
+//
+// 一个流可以包含多个以只有 `---` 的一行分隔的文档, 或者多个前后相接、以
+// `{` `}` 包围的组, 每次调用 Decode 解码其中一个, 没有更多文档时返回 io.EOF.
+// Decode 在文档结束时立即返回, 不等待下一个文档到达, 因此适合读取长期连接上
+// 推送的配置:
+//
+//	for {
+//		var snap Snapshot
+//		if err := dec.Decode(&snap); err == io.EOF {
+//			break
+//		} else if err != nil {
+//			return err
+//		}
+//		...
+//	}
 type StreamDecoder struct {
-	d *internalDecoder
-	p *Parser
+	d       *internalDecoder
+	p       *Parser
+	lex     *streamLexer
+	depth   int              // current block nesting depth
+	started bool             // Decode 已被调用过, 用于跳过流开头的 ---
+	size    *sizeLimitReader // 见 WithMaxSize, 未设置时为 nil
+	profile bool             // 正在解码选中的 profile 块, 其中的 var 属于顶层
+	group   bool             // 当前文档是以 { } 包围的一组语句
+	between bool             // 当前标记结束了上一个文档, 词法分析器在其后暂停
 }
 
 // NewStreamDecoder 返回一个从 io.Reader 中读取数据的新解码器.
@@ -25,33 +50,132 @@ func NewStreamDecoder(r io.Reader, opts ...DecoderOption) (*StreamDecoder, error
 		opt(d)
 	}
 
+	var size *sizeLimitReader
+	if d.limits.maxSize > 0 {
+		size = &sizeLimitReader{r: r, n: d.limits.maxSize}
+		r = size
+	}
 	l := newStreamLexer(r)
+	l.pauseAtDocEnd()
 	p := NewParser(l)
 
 	dec := &StreamDecoder{
-		d: d,
-		p: p,
+		d:    d,
+		p:    p,
+		lex:  l,
+		size: size,
 	}
 
 	return dec, nil
 }
 
-// Decode reads and decodes the WANF stream into the value pointed to by v.
+// Decode reads the next document of the WANF stream and decodes it into the
+// value pointed to by v. It returns io.EOF when the stream has no more documents.
 func (dec *StreamDecoder) Decode(v interface{}) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("v must be a pointer to a struct")
 	}
 
-	err := dec.decodeBody(rv.Elem())
+	if err := dec.beginDocument(); err != nil {
+		return err
+	}
+	if err := dec.endDocument(dec.decodeBody(rv.Elem())); err != nil {
+		return err
+	}
+	return callValidate(rv.Elem())
+}
+
+// beginDocument 移到下一个文档的第一条语句, 没有更多文档时返回 io.EOF.
+// 以 { 开始的文档在对应的 } 处结束, 其中的语句与顶层的相同.
+func (dec *StreamDecoder) beginDocument() error {
+	if !dec.started {
+		dec.started = true
+		dec.skipComments()
+		dec.between = dec.p.curTokenIs(DOC_SEPARATOR)
+	}
+	if dec.between {
+		dec.between = false
+		afterGroup := dec.p.curTokenIs(RBRACE)
+		dec.resume()
+		dec.skipComments()
+		if afterGroup && dec.p.curTokenIs(DOC_SEPARATOR) {
+			// 组之后的 --- 只分隔文档, 不开始一个空文档.
+			dec.resume()
+		}
+	}
+	dec.skipComments()
+	if dec.p.curTokenIs(EOF) {
+		if err := dec.readError(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	dec.d.vars = make(map[string]interface{})
+	if dec.p.curTokenIs(LBRACE) {
+		dec.group = true
+		dec.p.nextToken()
+	}
+	return nil
+}
+
+// readError 报告输入是否因超出 WithMaxSize 的限制或读取错误而被截断.
+func (dec *StreamDecoder) readError() error {
+	if dec.size != nil && dec.size.exceeded {
+		return dec.d.limits.sizeError()
+	}
+	return dec.lex.Err()
+}
+
+// resume 越过结束上一个文档的标记. 词法分析器在该标记之后暂停, 直到这里
+// 才读取下一个文档.
+func (dec *StreamDecoder) resume() {
+	dec.lex.resume()
+	dec.p.peekToken = dec.lex.NextToken()
+	dec.p.nextToken()
+}
+
+// endDocument 处理文档主体返回的 err 并检查文档的结束标记.
+func (dec *StreamDecoder) endDocument(err error) error {
+	// 截断的输入可能已导致语法错误或看起来完整, 报告真正的原因.
+	if err := dec.readError(); err != nil {
+		return err
+	}
 	if err != nil && err != io.EOF {
 		return err
 	}
+	group := dec.group
+	dec.group = false
+	switch {
+	case group && !dec.p.curTokenIs(RBRACE):
+		return syntaxError(dec.p.curToken, "expected '}' to close the document")
+	case group || dec.p.curTokenIs(DOC_SEPARATOR):
+		dec.between = true
+	case dec.p.curTokenIs(RBRACE):
+		return syntaxError(dec.p.curToken, "unexpected token %s at top level", RBRACE)
+	}
 	return nil
 }
 
+// skipComments 跳过文档之间的注释与分号.
+func (dec *StreamDecoder) skipComments() {
+	for dec.p.curTokenIs(COMMENT) || dec.p.curTokenIs(SEMICOLON) {
+		dec.p.nextToken()
+	}
+}
+
 // decodeBody consumes tokens and decodes them into the reflect.Value.
 func (dec *StreamDecoder) decodeBody(rv reflect.Value) error {
+	return dec.readBody(
+		func() error { return dec.decodeAssignStatement(rv) },
+		func() error { return dec.decodeBlockStatement(rv) },
+	)
+}
+
+// readBody consumes the statements of a document or block body up to the
+// closing '}', calling assign and block with the current token on the key of
+// an assignment or the name of a block. It returns io.EOF at the end of input.
+func (dec *StreamDecoder) readBody(assign, block func() error) error {
 	for {
 		if dec.p.curTokenIs(EOF) {
 			return io.EOF
@@ -62,25 +186,34 @@ func (dec *StreamDecoder) decodeBody(rv reflect.Value) error {
 			dec.p.nextToken()
 			continue
 		case VAR:
-			return fmt.Errorf("wanf: var statements are not supported in stream decoding mode (line %d)", dec.p.curToken.Line)
+			if err := dec.decodeVarStatement(); err != nil {
+				return err
+			}
 		case IMPORT:
 			return fmt.Errorf("wanf: import statements are not supported in stream decoding mode (line %d)", dec.p.curToken.Line)
 		case IDENT:
 			if dec.p.peekTokenIs(ASSIGN) {
-				if err := dec.decodeAssignStatement(rv); err != nil {
+				if err := assign(); err != nil {
 					return err
 				}
 			} else if dec.p.peekTokenIs(LBRACE) || dec.p.peekTokenIs(STRING) {
-				if err := dec.decodeBlockStatement(rv); err != nil {
+				if err := block(); err != nil {
 					return err
 				}
 			} else {
-				return fmt.Errorf("wanf: unexpected token %s after identifier %q on line %d", dec.p.peekToken.Type, dec.p.curToken.Literal, dec.p.curToken.Line)
+				return syntaxError(dec.p.peekToken, "unexpected token %s after identifier %q", dec.p.peekToken.Type, dec.p.curToken.Literal)
 			}
 		case RBRACE:
 			return nil
+		case DOC_SEPARATOR:
+			if dec.depth > 0 {
+				return syntaxError(dec.p.curToken, "unexpected document separator inside a block")
+			}
+			return nil
+		case ILLEGAL_COMMENT:
+			return syntaxError(dec.p.curToken, hashCommentMessage)
 		default:
-			return fmt.Errorf("wanf: unexpected token %s at top level on line %d", dec.p.curToken.Type, dec.p.curToken.Line)
+			return syntaxError(dec.p.curToken, "unexpected token %s at top level", dec.p.curToken.Type)
 		}
 
 		dec.p.nextToken()
@@ -92,72 +225,165 @@ func (dec *StreamDecoder) decodeAssignStatement(rv reflect.Value) error {
 	ident := dec.p.curToken
 
 	if !dec.p.expectPeek(ASSIGN) {
-		return fmt.Errorf("wanf: expected '=' after identifier %q", ident.Literal)
+		return syntaxError(dec.p.peekToken, "expected '=' after identifier %q", ident.Literal)
 	}
 	dec.p.nextToken()
 
 	val, err := dec.evalExpressionOnTheFly()
 	if err != nil {
-		return err
+		return fieldError(err, nil, ident)
 	}
 
 	field, tag, ok := findFieldAndTag(rv, ident.Literal)
 	if !ok {
-		return nil
+		return dec.unknownField(string(ident.Literal), ident)
 	}
 
 	if tag.KeyField != "" {
-		return dec.d.setMapFromList(field, val, tag.KeyField)
+		err = dec.d.setMapFromList(field, val, tag.KeyField)
+	} else {
+		err = dec.d.setField(field, val)
+	}
+	if err != nil {
+		return fieldError(positionValidationError(err, ident), nil, ident)
 	}
-	return dec.d.setField(field, val)
+	return checkField(field, tag, ident)
 }
 
-// decodeBlockStatement decodes a block statement on the fly.
-func (dec *StreamDecoder) decodeBlockStatement(rv reflect.Value) error {
-	blockName := dec.p.curToken.Literal
+// decodeVarStatement evaluates a `var` declaration and stores its value for the
+// references that follow it. Like the buffered decoder, only top-level
+// declarations (including those in the selected profile) are variables.
+func (dec *StreamDecoder) decodeVarStatement() error {
+	if dec.depth > 0 && !(dec.profile && dec.depth == 1) {
+		return syntaxError(dec.p.curToken, "var declarations are only allowed at the top level")
+	}
+	if !dec.p.expectPeek(IDENT) {
+		return syntaxError(dec.p.peekToken, "expected identifier after var")
+	}
+	ident := dec.p.curToken
+	name := string(ident.Literal)
+	if !dec.p.expectPeek(ASSIGN) {
+		return syntaxError(dec.p.peekToken, "expected '=' after variable %q", name)
+	}
 	dec.p.nextToken()
+	val, err := dec.evalExpressionOnTheFly()
+	if err != nil {
+		return fieldError(err, nil, ident)
+	}
+	dec.d.vars[name] = val
+	return nil
+}
 
-	var label string
-	if dec.p.curTokenIs(STRING) {
-		label = BytesToString(dec.p.curToken.Literal)
-		dec.p.nextToken()
+// decodeBlockStatement decodes a block statement on the fly.
+func (dec *StreamDecoder) decodeBlockStatement(rv reflect.Value) error {
+	topLevel := dec.depth == 0
+	if err := dec.enterNesting(); err != nil {
+		return err
 	}
+	defer func() { dec.depth-- }()
 
-	if !dec.p.curTokenIs(LBRACE) {
-		return fmt.Errorf("wanf: expected '{' after block identifier on line %d", dec.p.curToken.Line)
+	nameTok, labels, err := dec.readBlockHeader()
+	if err != nil {
+		return err
+	}
+	blockName := nameTok.Literal
+	if topLevel && len(labels) > 0 && string(blockName) == profileBlockName {
+		return dec.decodeProfile(labels[0], func() error { return dec.decodeBody(rv) })
 	}
-	dec.p.nextToken()
 
 	field, _, ok := findFieldAndTag(rv, blockName)
 	if !ok {
+		if err := dec.unknownField(blockPath(string(blockName), labels), nameTok); err != nil {
+			return err
+		}
 		return dec.skipBlock()
 	}
+	if containsRawBlock(field.Type()) {
+		return fmt.Errorf("wanf: block %q: RawBlock is not supported in stream decoding mode", blockName)
+	}
 
+	path := blockPath(string(blockName), labels)
 	switch field.Kind() {
 	case reflect.Struct:
+		setBlockLabels(field, labels)
 		if err := dec.decodeBody(field); err != nil {
+			prefixDecodeError(err, path, nil)
 			return err
 		}
-	case reflect.Map:
-		if field.IsNil() {
-			field.Set(reflect.MakeMap(field.Type()))
-		}
-		mapElemType := field.Type().Elem()
-		newElem := reflect.New(mapElemType).Elem()
-		if err := dec.decodeBody(newElem); err != nil {
+		if err := validateBlock(field, nameTok); err != nil {
 			return err
 		}
-		if label == "" {
-			return fmt.Errorf("wanf: map block %q requires a label", blockName)
+	case reflect.Map, reflect.Slice:
+		if field.Kind() == reflect.Map && len(labels) == 0 {
+			return wrapDecodeError(errorOf(ErrTypeMismatch, "map block %q requires a label", blockName), path, nil, nameTok)
+		}
+		var bodyErr error // 块内容的错误, 其中的语法错误已带有行号
+		decodeBody := func(v reflect.Value) error {
+			if bodyErr = dec.decodeBody(v); bodyErr != nil {
+				return bodyErr
+			}
+			return validateBlock(v, nameTok)
+		}
+		if err := decodeLabeledBlock(field, string(blockName), labels, decodeBody); err != nil {
+			if prefixDecodeError(err, path, nil) {
+				return err
+			}
+			if err == bodyErr {
+				return fmt.Errorf("wanf: %w", err)
+			}
+			return wrapDecodeError(err, path, nil, nameTok)
 		}
-		field.SetMapIndex(reflect.ValueOf(label), newElem)
 
 	default:
-		return fmt.Errorf("wanf: block %q cannot be decoded into field of type %s", blockName, field.Type())
+		return wrapDecodeError(errorOf(ErrTypeMismatch, "block %q cannot be decoded into field of type %s", blockName, field.Type()), path, nil, nameTok)
 	}
 
 	if !dec.p.curTokenIs(RBRACE) {
-		return fmt.Errorf("wanf: expected '}' to close block %q on line %d", blockName, dec.p.curToken.Line)
+		return syntaxError(dec.p.curToken, "expected '}' to close block %q", blockName)
+	}
+	return nil
+}
+
+// unknownField handles a key or block without a matching field, like
+// internalDecoder.unknownField: it is ignored unless unknown fields are
+// disallowed.
+func (dec *StreamDecoder) unknownField(path string, tok Token) error {
+	if !dec.d.disallowUnknown {
+		return nil
+	}
+	return wrapDecodeError(errorOf(ErrUnknownField, "unknown field"), path, nil, tok)
+}
+
+// readBlockHeader consumes a block's name, labels and opening '{'.
+func (dec *StreamDecoder) readBlockHeader() (name Token, labels []string, err error) {
+	name = dec.p.curToken
+	dec.p.nextToken()
+	for dec.p.curTokenIs(STRING) {
+		labels = append(labels, string(dec.p.curToken.Literal))
+		dec.p.nextToken()
+	}
+	if !dec.p.curTokenIs(LBRACE) {
+		return name, nil, syntaxError(dec.p.curToken, "expected '{' after block identifier")
+	}
+	dec.p.nextToken()
+	return name, labels, nil
+}
+
+// decodeProfile handles a top-level profile block whose header has been read.
+// Profile sections are applied in place, so in stream mode they only override
+// statements that appear before them: the selected profile's body is read
+// with body, the others are skipped.
+func (dec *StreamDecoder) decodeProfile(label string, body func() error) error {
+	if label != dec.d.profile {
+		return dec.skipBlock()
+	}
+	dec.profile = true
+	defer func() { dec.profile = false }()
+	if err := body(); err != nil {
+		return err
+	}
+	if !dec.p.curTokenIs(RBRACE) {
+		return syntaxError(dec.p.curToken, "expected '}' to close profile %q", label)
 	}
 	return nil
 }
@@ -171,16 +397,21 @@ func (dec *StreamDecoder) evalExpressionOnTheFly() (interface{}, error) {
 	case FLOAT:
 		return strconv.ParseFloat(BytesToString(dec.p.curToken.Literal), 64)
 	case STRING:
-		return BytesToString(dec.p.curToken.Literal), nil
+		return dec.d.interpolate(string(dec.p.curToken.Literal))
 	case BOOL:
 		return strconv.ParseBool(BytesToString(dec.p.curToken.Literal))
 	case DUR:
 		return time.ParseDuration(BytesToString(dec.p.curToken.Literal))
 	case IDENT:
-		// This can only be an `env()` call in this context.
-		if bytes.Equal(dec.p.curToken.Literal, []byte("env")) {
+		// This can only be an `env()` or `secret()` call in this context.
+		if bytes.Equal(dec.p.curToken.Literal, envLiteral) {
 			return dec.evalEnvExpressionOnTheFly()
 		}
+		if bytes.Equal(dec.p.curToken.Literal, secretLiteral) {
+			return dec.evalSecretExpressionOnTheFly()
+		}
+	case DOLLAR_LBRACE:
+		return dec.evalVarExpressionOnTheFly()
 	case LBRACK:
 		return dec.decodeListLiteralOnTheFly()
 	case LBRACE:
@@ -190,10 +421,20 @@ func (dec *StreamDecoder) evalExpressionOnTheFly() (interface{}, error) {
 		}
 		return dec.decodeBlockLiteralOnTheFly()
 	}
-	return nil, fmt.Errorf("wanf: unexpected token %s in expression", dec.p.curToken.Type)
+	if dec.p.curTokenIs(ILLEGAL_COMMENT) {
+		return nil, syntaxError(dec.p.curToken, hashCommentMessage)
+	}
+	if dec.p.curToken.Type == ILLEGAL && len(dec.p.curToken.Literal) > 1 {
+		return nil, syntaxError(dec.p.curToken, "%s", dec.p.curToken.Literal)
+	}
+	return nil, syntaxError(dec.p.curToken, "unexpected token %s in expression", dec.p.curToken.Type)
 }
 
 func (dec *StreamDecoder) decodeListLiteralOnTheFly() (interface{}, error) {
+	if err := dec.enterNesting(); err != nil {
+		return nil, err
+	}
+	defer func() { dec.depth-- }()
 	var list []interface{}
 	dec.p.nextToken() // consume '['
 
@@ -208,13 +449,17 @@ func (dec *StreamDecoder) decodeListLiteralOnTheFly() (interface{}, error) {
 		if dec.p.curTokenIs(COMMA) {
 			dec.p.nextToken() // consume comma
 		} else if !dec.p.curTokenIs(RBRACK) {
-			return nil, fmt.Errorf("wanf: expected comma or ']' in list literal")
+			return nil, syntaxError(dec.p.curToken, "expected comma or ']' in list literal")
 		}
 	}
 	return list, nil
 }
 
 func (dec *StreamDecoder) decodeBlockLiteralOnTheFly() (interface{}, error) {
+	if err := dec.enterNesting(); err != nil {
+		return nil, err
+	}
+	defer func() { dec.depth-- }()
 	m := make(map[string]interface{})
 	dec.p.nextToken() // consume '{'
 
@@ -224,12 +469,12 @@ func (dec *StreamDecoder) decodeBlockLiteralOnTheFly() (interface{}, error) {
 			continue
 		}
 		if !dec.p.curTokenIs(IDENT) {
-			return nil, fmt.Errorf("wanf: expected identifier as key in block literal")
+			return nil, syntaxError(dec.p.curToken, "expected identifier as key in block literal")
 		}
-		key := BytesToString(dec.p.curToken.Literal)
+		key := string(dec.p.curToken.Literal)
 
 		if !dec.p.expectPeek(ASSIGN) {
-			return nil, fmt.Errorf("wanf: expected '=' after key in block literal")
+			return nil, syntaxError(dec.p.peekToken, "expected '=' after key in block literal")
 		}
 		dec.p.nextToken() // consume value token
 
@@ -244,17 +489,21 @@ func (dec *StreamDecoder) decodeBlockLiteralOnTheFly() (interface{}, error) {
 }
 
 func (dec *StreamDecoder) decodeMapLiteralOnTheFly() (interface{}, error) {
+	if err := dec.enterNesting(); err != nil {
+		return nil, err
+	}
+	defer func() { dec.depth-- }()
 	m := make(map[string]interface{})
 	dec.p.nextToken() // consume '{'
 	dec.p.nextToken() // consume '['
 
 	for !dec.p.curTokenIs(RBRACK) && !dec.p.curTokenIs(EOF) {
 		if !dec.p.curTokenIs(IDENT) {
-			return nil, fmt.Errorf("wanf: expected identifier as key in map literal")
+			return nil, syntaxError(dec.p.curToken, "expected identifier as key in map literal")
 		}
-		key := BytesToString(dec.p.curToken.Literal)
+		key := string(dec.p.curToken.Literal)
 		if !dec.p.expectPeek(ASSIGN) {
-			return nil, fmt.Errorf("wanf: expected '=' after key in map literal")
+			return nil, syntaxError(dec.p.peekToken, "expected '=' after key in map literal")
 		}
 		dec.p.nextToken() // consume value token
 		val, err := dec.evalExpressionOnTheFly()
@@ -267,52 +516,92 @@ func (dec *StreamDecoder) decodeMapLiteralOnTheFly() (interface{}, error) {
 		if dec.p.curTokenIs(COMMA) {
 			dec.p.nextToken()
 		} else if !dec.p.curTokenIs(RBRACK) {
-			return nil, fmt.Errorf("wanf: expected comma or ']' in map literal")
+			return nil, syntaxError(dec.p.curToken, "expected comma or ']' in map literal")
 		}
 	}
 	dec.p.nextToken() // consume ']'
 	if !dec.p.curTokenIs(RBRACE) {
-		return nil, fmt.Errorf("wanf: expected '}' to close map literal")
+		return nil, syntaxError(dec.p.curToken, "expected '}' to close map literal")
 	}
 	return m, nil
 }
 
+// evalVarExpressionOnTheFly resolves a `${name}` reference. Only variables
+// injected with WithVariables are visible in stream decoding mode.
+func (dec *StreamDecoder) evalVarExpressionOnTheFly() (interface{}, error) {
+	if !dec.p.expectPeek(IDENT) {
+		return nil, syntaxError(dec.p.peekToken, "expected identifier after '${'")
+	}
+	name := string(dec.p.curToken.Literal)
+	if !dec.p.expectPeek(RBRACE) {
+		return nil, syntaxError(dec.p.peekToken, "expected '}' to close variable reference")
+	}
+	return dec.d.lookupVar(name)
+}
+
+func (dec *StreamDecoder) evalSecretExpressionOnTheFly() (interface{}, error) {
+	if !dec.p.expectPeek(LPAREN) {
+		return nil, syntaxError(dec.p.peekToken, "expected '(' after secret")
+	}
+	dec.p.nextToken() // consume '('
+	if !dec.p.curTokenIs(STRING) {
+		return nil, syntaxError(dec.p.curToken, "expected string argument for secret()")
+	}
+	ref := string(dec.p.curToken.Literal)
+	if !dec.p.expectPeek(RPAREN) {
+		return nil, syntaxError(dec.p.peekToken, "expected ')' after secret() argument")
+	}
+	return dec.d.resolveSecret(ref)
+}
+
 func (dec *StreamDecoder) evalEnvExpressionOnTheFly() (interface{}, error) {
 	if !dec.p.expectPeek(LPAREN) {
-		return nil, fmt.Errorf("wanf: expected '(' after env")
+		return nil, syntaxError(dec.p.peekToken, "expected '(' after env")
 	}
 	dec.p.nextToken() // consume '('
 
 	if !dec.p.curTokenIs(STRING) {
-		return nil, fmt.Errorf("wanf: expected string argument for env()")
+		return nil, syntaxError(dec.p.curToken, "expected string argument for env()")
+	}
+	envVarName := string(dec.p.curToken.Literal)
+	if dec.d.sandbox {
+		return nil, errorOf(ErrSandbox, "env(%q) is not allowed in sandbox mode", envVarName)
 	}
-	envVarName := BytesToString(dec.p.curToken.Literal)
 
 	// Check for default value
 	if dec.p.peekTokenIs(COMMA) {
 		dec.p.nextToken() // consume ','
 		dec.p.nextToken() // consume default value string token
 		if !dec.p.curTokenIs(STRING) {
-			return nil, fmt.Errorf("wanf: expected string for env() default value")
+			return nil, syntaxError(dec.p.curToken, "expected string for env() default value")
 		}
-		defaultValue := BytesToString(dec.p.curToken.Literal)
-		if val, found := os.LookupEnv(envVarName); found {
+		defaultValue := string(dec.p.curToken.Literal)
+		if val, found := dec.d.lookupEnv(envVarName); found {
 			return val, nil
 		}
 		return defaultValue, nil
 	}
 
 	// No default value
-	if val, found := os.LookupEnv(envVarName); found {
+	if val, found := dec.d.lookupEnv(envVarName); found {
 		return val, nil
 	}
 
 	if !dec.p.peekTokenIs(RPAREN) {
-		return nil, fmt.Errorf("wanf: expected ')' after env() call")
+		return nil, syntaxError(dec.p.peekToken, "expected ')' after env() call")
 	}
 	dec.p.nextToken() // consume ')'
 
-	return nil, fmt.Errorf("wanf: environment variable %q not set and no default provided", envVarName)
+	return nil, errorOf(ErrEnvNotSet, "wanf: environment variable %q not set and no default provided", envVarName)
+}
+
+// enterNesting 进入一层块、列表或映射, 超出 WithMaxDepth 的限制时返回错误.
+func (dec *StreamDecoder) enterNesting() error {
+	if max := dec.d.limits.maxDepth; max > 0 && dec.depth >= max {
+		return syntaxError(dec.p.curToken, "nesting depth exceeds the maximum of %d", max)
+	}
+	dec.depth++
+	return nil
 }
 
 // skipBlock consumes tokens until the matching RBRACE is found.
@@ -320,7 +609,7 @@ func (dec *StreamDecoder) skipBlock() error {
 	openBraces := 1
 	for {
 		if dec.p.curTokenIs(EOF) {
-			return fmt.Errorf("wanf: unclosed block while skipping")
+			return syntaxError(dec.p.curToken, "unclosed block while skipping")
 		}
 		if dec.p.curTokenIs(LBRACE) {
 			openBraces++
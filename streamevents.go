@@ -0,0 +1,89 @@
+package wanf
+
+// Events 是 StreamDecoder.DecodeEvents 的回调, 用于逐条处理很大的 WANF 导出
+// 而不构建结构体或映射. 未设置的回调被忽略. 回调返回的错误会中止解码并原样
+// 由 DecodeEvents 返回.
+type Events struct {
+	// OnBlockStart 在读到块的 `{` 后调用, labels 为块的标签, 没有标签时为 nil.
+	OnBlockStart func(name string, labels []string) error
+	// OnAssign 在每条赋值语句求值后调用. value 的类型与 Get 相同: int64、
+	// float64、string、bool、time.Duration、[]interface{} 或
+	// map[string]interface{}.
+	OnAssign func(key string, value interface{}) error
+	// OnBlockEnd 在读到与 OnBlockStart 对应的 `}` 后调用.
+	OnBlockEnd func(name string) error
+}
+
+// DecodeEvents 读取流中的下一个文档, 按出现的顺序为其中的块与赋值调用 ev
+// 中的回调, 没有更多文档时返回 io.EOF. 与 Decode 一样, var 声明在读取时
+// 求值而不产生事件, 选中的 profile 块中的语句就地报告, 其他 profile 被跳过.
+func (dec *StreamDecoder) DecodeEvents(ev *Events) error {
+	if ev == nil {
+		ev = &Events{}
+	}
+	if err := dec.beginDocument(); err != nil {
+		return err
+	}
+	return dec.endDocument(dec.eventBody(ev))
+}
+
+// eventBody reports the statements of a document or block body to ev.
+func (dec *StreamDecoder) eventBody(ev *Events) error {
+	return dec.readBody(
+		func() error { return dec.assignEvent(ev) },
+		func() error { return dec.blockEvent(ev) },
+	)
+}
+
+// assignEvent evaluates an assignment and passes it to ev.OnAssign.
+func (dec *StreamDecoder) assignEvent(ev *Events) error {
+	ident := dec.p.curToken
+	if !dec.p.expectPeek(ASSIGN) {
+		return syntaxError(dec.p.peekToken, "expected '=' after identifier %q", ident.Literal)
+	}
+	dec.p.nextToken()
+
+	val, err := dec.evalExpressionOnTheFly()
+	if err != nil {
+		return fieldError(err, nil, ident)
+	}
+	if ev.OnAssign == nil {
+		return nil
+	}
+	return ev.OnAssign(string(ident.Literal), val)
+}
+
+// blockEvent reports a block and its body to ev.
+func (dec *StreamDecoder) blockEvent(ev *Events) error {
+	topLevel := dec.depth == 0
+	if err := dec.enterNesting(); err != nil {
+		return err
+	}
+	defer func() { dec.depth-- }()
+
+	nameTok, labels, err := dec.readBlockHeader()
+	if err != nil {
+		return err
+	}
+	name := string(nameTok.Literal)
+	if topLevel && len(labels) > 0 && name == profileBlockName {
+		return dec.decodeProfile(labels[0], func() error { return dec.eventBody(ev) })
+	}
+
+	if ev.OnBlockStart != nil {
+		if err := ev.OnBlockStart(name, labels); err != nil {
+			return err
+		}
+	}
+	if err := dec.eventBody(ev); err != nil {
+		prefixDecodeError(err, blockPath(name, labels), nil)
+		return err
+	}
+	if !dec.p.curTokenIs(RBRACE) {
+		return syntaxError(dec.p.curToken, "expected '}' to close block %q", name)
+	}
+	if ev.OnBlockEnd == nil {
+		return nil
+	}
+	return ev.OnBlockEnd(name)
+}
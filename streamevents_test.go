@@ -0,0 +1,118 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordEvents returns Events that append a line per event to out.
+func recordEvents(out *[]string) *Events {
+	return &Events{
+		OnBlockStart: func(name string, labels []string) error {
+			*out = append(*out, fmt.Sprintf("start %s %q", name, labels))
+			return nil
+		},
+		OnAssign: func(key string, value interface{}) error {
+			*out = append(*out, fmt.Sprintf("%s = %#v", key, value))
+			return nil
+		},
+		OnBlockEnd: func(name string) error {
+			*out = append(*out, "end "+name)
+			return nil
+		},
+	}
+}
+
+func TestStreamDecoder_DecodeEvents(t *testing.T) {
+	src := `var host = "db"
+name = "app" // comment
+timeout = 5s
+server "main" {
+	addr = "${host}:80"
+	tls {
+		enabled = true
+	}
+	ports = [80, 443]
+}
+profile "prod" {
+	name = "prod"
+}
+profile "dev" {
+	name = "dev"
+}
+---
+count = 2
+`
+	dec, err := NewStreamDecoder(strings.NewReader(src), WithProfile("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err := dec.DecodeEvents(recordEvents(&got)); err != nil {
+		t.Fatalf("DecodeEvents failed: %v", err)
+	}
+	want := []string{
+		`name = "app"`,
+		fmt.Sprintf("timeout = %#v", 5*time.Second),
+		`start server ["main"]`,
+		`addr = "db:80"`,
+		`start tls []`,
+		`enabled = true`,
+		`end tls`,
+		`ports = []interface {}{80, 443}`,
+		`end server`,
+		`name = "prod"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got = nil
+	if err := dec.DecodeEvents(recordEvents(&got)); err != nil || len(got) != 1 || got[0] != "count = 2" {
+		t.Errorf("second document = %q, %v", got, err)
+	}
+	if err := dec.DecodeEvents(recordEvents(&got)); err != io.EOF {
+		t.Errorf("DecodeEvents at the end = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamDecoder_DecodeEventsErrors(t *testing.T) {
+	// 回调的错误中止解码并原样返回.
+	stop := errors.New("stop")
+	dec, _ := NewStreamDecoder(strings.NewReader("a = 1\nb = 2\n"))
+	var keys []string
+	err := dec.DecodeEvents(&Events{OnAssign: func(key string, value interface{}) error {
+		keys = append(keys, key)
+		return stop
+	}})
+	if err != stop || len(keys) != 1 {
+		t.Errorf("DecodeEvents = %v after %q, want stop after one key", err, keys)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"server {\n\tport = ${missing}\n}\n", `line 2:2: server.port: variable "missing" is not defined`},
+		{"server {\n\tport = 1\n", ""},
+		{"server \"a\" port = 1\n", "expected '{' after block identifier"},
+	}
+	for _, tt := range tests {
+		dec, _ := NewStreamDecoder(strings.NewReader(tt.src))
+		err := dec.DecodeEvents(nil)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("DecodeEvents(%q) = %v", tt.src, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DecodeEvents(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -3,49 +3,143 @@ package wanf
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
-	"unicode"
 )
 
 // This file contains the stream-based lexer.
 
-var dot = []byte{'.'}
+// literalChunkSize 是 streamLexer 存放字面量的块的默认大小.
+const literalChunkSize = 1024
 
 // streamLexer 是一个从 io.Reader 读取数据的词法分析器.
-// 它使用 bufio.Reader 来实现高效的预读(peek)功能, 并使用两个交替的 bytes.Buffer 来实现零分配的词法单元字面量生成.
+// 它使用 bufio.Reader 来实现高效的预读(peek)功能. 字面量依次写入一个共享的块中,
+// 每个标记的 Literal 是块中的一段, 之后不会被覆盖, 因此调用方可以直接保留它,
+// 而分配只在块写满时发生一次, 不是每个标记一次.
 type streamLexer struct {
-	r       *bufio.Reader
-	ch      byte
-	line    int
-	column  int
-	bufA    bytes.Buffer
-	bufB    bytes.Buffer
-	useBufA bool
+	r      *bufio.Reader
+	ch     byte
+	line   int
+	column int
+	offset int   // ch 的字节偏移量
+	eof    bool  // 已读到输入末尾
+	err    error // 读取输入时发生的 io.EOF 以外的错误, 见 Err
+
+	chunk []byte // 当前的字面量块, 只追加
+	start int    // 正在读取的字面量在 chunk 中的起点
+
+	// 以下字段用于 StreamDecoder 按文档读取, 见 pauseAtDocEnd.
+	docs     bool // 在每个文档结束后暂停
+	paused   bool // 文档已结束, 在 resume 之前只返回 EOF 而不读取输入
+	unread   bool // 暂停时尚未读取结束标记之后的字符
+	depth    int  // 当前的花括号层数
+	group    bool // 当前文档是以 { 开始的一组语句
+	docStart bool // 当前文档还没有注释以外的标记
 }
 
 // newStreamLexer creates a new stream-based lexer.
 func newStreamLexer(r io.Reader) *streamLexer {
 	l := &streamLexer{
-		r:       bufio.NewReader(r),
-		line:    1,
-		useBufA: true,
+		r:      bufio.NewReader(r),
+		line:   1,
+		offset: -1,
+	}
+	b, err := l.r.Peek(len(utf8BOM))
+	l.fail(err)
+	if bytes.Equal(b, utf8BOM) {
+		l.r.Discard(len(utf8BOM))
+		l.offset += len(utf8BOM)
 	}
 	l.readChar()
 	return l
 }
 
+// pauseAtDocEnd 使词法分析器在每个文档结束后暂停, 直到调用 resume. 文档以
+// 顶层的 --- 或组的 } 结束; 暂停时不读取其后的输入, 因此解码长期连接上的
+// 文档时不必等待下一个文档到达.
+func (l *streamLexer) pauseAtDocEnd() {
+	l.docs = true
+	l.docStart = true
+}
+
+// resume 在暂停后继续读取下一个文档.
+func (l *streamLexer) resume() {
+	l.paused = false
+	if l.unread {
+		l.unread = false
+		l.readChar()
+	}
+}
+
+// trackDocument 根据刚返回的标记 tok 记录文档的边界, 在文档结束时暂停.
+func (l *streamLexer) trackDocument(tok Token) {
+	switch tok.Type {
+	case COMMENT, SEMICOLON:
+		return
+	case DOC_SEPARATOR:
+		if l.depth == 0 {
+			l.paused = true
+			l.docStart = true
+			return
+		}
+	case LBRACE, DOLLAR_LBRACE:
+		if tok.Type == LBRACE && l.depth == 0 && l.docStart {
+			l.group = true
+		}
+		l.depth++
+	case RBRACE:
+		if l.depth > 0 {
+			l.depth--
+		}
+		if l.group && l.depth == 0 {
+			l.group = false
+			l.paused = true
+			l.docStart = true
+			return
+		}
+	}
+	l.docStart = false
+}
+
 func (l *streamLexer) readChar() {
 	var err error
-	l.ch, err = l.r.ReadByte()
+	if l.err != nil {
+		err = l.err
+	} else {
+		l.ch, err = l.r.ReadByte()
+		l.fail(err)
+	}
 	if err != nil {
 		l.ch = 0
+		l.eof = true
 	}
 	l.column++
+	l.offset++
+}
+
+// fail 记录读取输入时的错误 err. 词法分析器把错误当作输入结束, 由调用方
+// 通过 Err 区分被中断的输入与完整的输入.
+func (l *streamLexer) fail(err error) {
+	if err == nil || err == io.EOF || err == bufio.ErrBufferFull || l.err != nil {
+		return
+	}
+	l.err = fmt.Errorf("wanf: line %d:%d: reading input: %w", l.line, l.column+1, err)
+}
+
+// Err 返回读取输入时发生的第一个 io.EOF 以外的错误, 没有时返回 nil.
+func (l *streamLexer) Err() error {
+	return l.err
+}
+
+// atEOF 报告 ch 是否已越过输入末尾, 以区分末尾与输入中的 NUL 字节.
+func (l *streamLexer) atEOF() bool {
+	return l.eof
 }
 
 func (l *streamLexer) peekChar() byte {
 	b, err := l.r.Peek(1)
 	if err != nil {
+		l.fail(err)
 		return 0
 	}
 	return b[0]
@@ -55,7 +149,32 @@ func (l *streamLexer) newToken(tokenType TokenType, ch byte, line, column int) T
 	return Token{Type: tokenType, Literal: singleCharByteSlices[ch], Line: line, Column: column}
 }
 
+// NextToken 返回下一个标记, 并记录其起止位置.
 func (l *streamLexer) NextToken() Token {
+	if l.paused {
+		return Token{Type: EOF, Literal: []byte{}, Line: l.line, Column: l.column, Offset: l.offset,
+			EndLine: l.line, EndColumn: l.column, EndOffset: l.offset}
+	}
+	l.skipWhitespace()
+	offset := l.offset
+	tok := l.nextToken()
+	tok.Offset = offset
+	if tok.Type == EOF {
+		tok.EndLine, tok.EndColumn, tok.EndOffset = tok.Line, tok.Column, offset
+		return tok
+	}
+	tok.EndLine, tok.EndColumn, tok.EndOffset = l.line, l.column, l.offset
+	if l.unread {
+		tok.EndColumn++
+		tok.EndOffset++
+	}
+	if l.docs {
+		l.trackDocument(tok)
+	}
+	return tok
+}
+
+func (l *streamLexer) nextToken() Token {
 	var tok Token
 	l.skipWhitespace()
 	line, col := l.line, l.column
@@ -70,6 +189,11 @@ func (l *streamLexer) NextToken() Token {
 		tok = l.newToken(LBRACE, l.ch, line, col)
 	case '}':
 		tok = l.newToken(RBRACE, l.ch, line, col)
+		if l.docs && l.group && l.depth == 1 {
+			// 不读取组之后的字符, 它可能属于尚未到达的下一个文档.
+			l.unread = true
+			return tok
+		}
 	case '[':
 		tok = l.newToken(LBRACK, l.ch, line, col)
 	case ']':
@@ -78,6 +202,14 @@ func (l *streamLexer) NextToken() Token {
 		tok = l.newToken(LPAREN, l.ch, line, col)
 	case ')':
 		tok = l.newToken(RPAREN, l.ch, line, col)
+	case '-':
+		if col == 1 && l.atDocSeparator() {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: DOC_SEPARATOR, Literal: []byte("---"), Line: line, Column: col}
+		} else {
+			tok = l.newToken(ILLEGAL, l.ch, line, col)
+		}
 	case '#':
 		tok.Type = ILLEGAL_COMMENT
 		tok.Literal = l.readUntilEndOfLine()
@@ -92,9 +224,14 @@ func (l *streamLexer) NextToken() Token {
 			tok = l.newToken(ILLEGAL, l.ch, line, col)
 		}
 	case '"', '\'', '`':
-		quote := l.ch
-		tok.Type = STRING
-		tok.Literal = l.readString(quote)
+		literal, ok := l.readString(l.ch)
+		if ok {
+			tok.Type = STRING
+			tok.Literal = literal
+		} else {
+			tok.Type = ILLEGAL
+			tok.Literal = []byte("unterminated string literal")
+		}
 		tok.Line = line
 		tok.Column = col
 		return tok
@@ -123,7 +260,8 @@ func (l *streamLexer) NextToken() Token {
 	case 0:
 		tok.Literal = []byte{}
 		tok.Type = EOF
-		l.readChar()
+		tok.Line = line
+		tok.Column = col
 		return tok
 	default:
 		if isIdentifierStart(l.ch) {
@@ -133,18 +271,18 @@ func (l *streamLexer) NextToken() Token {
 			tok.Line = line
 			tok.Column = col
 			return tok
-		} else if unicode.IsDigit(rune(l.ch)) {
-			literal := l.readNumber()
+		} else if isDigit(l.ch) {
+			isFloat := l.readNumber()
 			if l.ch == 's' || l.ch == 'm' || l.ch == 'h' || (l.ch == 'u' && l.peekChar() == 's') || (l.ch == 'n' && l.peekChar() == 's') || (l.ch == 'm' && l.peekChar() == 's') {
 				tok.Type = DUR
-				tok.Literal = l.readDurationSuffix(literal)
+				tok.Literal = l.readDurationSuffix()
 			} else {
-				if bytes.Contains(literal, dot) {
+				if isFloat {
 					tok.Type = FLOAT
 				} else {
 					tok.Type = INT
 				}
-				tok.Literal = literal
+				tok.Literal = l.literal()
 			}
 			tok.Line = line
 			tok.Column = col
@@ -157,33 +295,66 @@ func (l *streamLexer) NextToken() Token {
 	return tok
 }
 
-const defaultBufferSize = 64
+// atDocSeparator 报告从当前的 '-' 开始的一行是否为文档分隔符 ---,
+// 其后只允许空白.
+func (l *streamLexer) atDocSeparator() bool {
+	for i := 0; ; i++ {
+		b, err := l.r.Peek(i + 1)
+		l.fail(err)
+		if len(b) <= i {
+			return i >= 2 && err != bufio.ErrBufferFull
+		}
+		switch c := b[i]; {
+		case i < 2:
+			if c != '-' {
+				return false
+			}
+		case c == '\n' || c == '\r':
+			return true
+		case c != ' ' && c != '\t':
+			return false
+		}
+	}
+}
+
+// beginLiteral 开始一个新的字面量.
+func (l *streamLexer) beginLiteral() {
+	l.start = len(l.chunk)
+}
 
-func (l *streamLexer) activeBuffer() *bytes.Buffer {
-	var buf *bytes.Buffer
-	if l.useBufA {
-		buf = &l.bufA
-	} else {
-		buf = &l.bufB
+// writeLiteral 向当前字面量追加 c. 块写满时, 已读取的部分移入新块, 旧块中
+// 此前返回的字面量保持不变.
+func (l *streamLexer) writeLiteral(c byte) {
+	if len(l.chunk) == cap(l.chunk) {
+		n := len(l.chunk) - l.start
+		size := literalChunkSize
+		if 2*n > size {
+			size = 2 * n
+		}
+		chunk := make([]byte, n, size)
+		copy(chunk, l.chunk[l.start:])
+		l.chunk, l.start = chunk, 0
 	}
-	l.useBufA = !l.useBufA
-	buf.Reset()
-	buf.Grow(defaultBufferSize)
-	return buf
+	l.chunk = append(l.chunk, c)
 }
 
-func (l *streamLexer) readDurationSuffix(prefix []byte) []byte {
-	buf := l.activeBuffer()
-	buf.Write(prefix)
+// literal 返回当前字面量. 容量被截断, 调用方追加数据不会改写块中之后的字面量.
+func (l *streamLexer) literal() []byte {
+	end := len(l.chunk)
+	return l.chunk[l.start:end:end]
+}
+
+// readDurationSuffix 将单位追加到刚读取的数字之后, 返回完整的 duration 字面量.
+func (l *streamLexer) readDurationSuffix() []byte {
 	if l.ch == 'm' || l.ch == 'u' || l.ch == 'n' {
 		if l.peekChar() == 's' {
-			buf.WriteByte(l.ch)
+			l.writeLiteral(l.ch)
 			l.readChar()
 		}
 	}
-	buf.WriteByte(l.ch)
+	l.writeLiteral(l.ch)
 	l.readChar()
-	return buf.Bytes()
+	return l.literal()
 }
 
 func (l *streamLexer) skipWhitespace() {
@@ -197,30 +368,30 @@ func (l *streamLexer) skipWhitespace() {
 }
 
 func (l *streamLexer) readSingleLineComment() []byte {
-	buf := l.activeBuffer()
-	for l.ch != '\n' && l.ch != 0 {
-		buf.WriteByte(l.ch)
+	l.beginLiteral()
+	for l.ch != '\n' && l.ch != 0 && (l.ch != '\r' || l.peekChar() != '\n') {
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
-	return buf.Bytes()
+	return l.literal()
 }
 
 func (l *streamLexer) readMultiLineComment() ([]byte, bool) {
-	buf := l.activeBuffer()
+	l.beginLiteral()
 	startLine, startCol := l.line, l.column
-	buf.WriteByte(l.ch)
+	l.writeLiteral(l.ch)
 	l.readChar()
-	buf.WriteByte(l.ch)
+	l.writeLiteral(l.ch)
 	l.readChar()
 	for {
 		if l.ch == 0 {
 			l.line, l.column = startLine, startCol
-			return buf.Bytes(), false
+			return l.literal(), false
 		}
 		if l.ch == '*' && l.peekChar() == '/' {
-			buf.WriteByte(l.ch)
+			l.writeLiteral(l.ch)
 			l.readChar()
-			buf.WriteByte(l.ch)
+			l.writeLiteral(l.ch)
 			l.readChar()
 			break
 		}
@@ -228,53 +399,61 @@ func (l *streamLexer) readMultiLineComment() ([]byte, bool) {
 			l.line++
 			l.column = 0
 		}
-		buf.WriteByte(l.ch)
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
-	return buf.Bytes(), true
+	return l.literal(), true
 }
 
 func (l *streamLexer) readIdentifier() []byte {
-	buf := l.activeBuffer()
-	for isIdentifierStart(l.ch) || unicode.IsDigit(rune(l.ch)) {
-		buf.WriteByte(l.ch)
+	l.beginLiteral()
+	for isIdentifierChar(l.ch) {
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
-	return buf.Bytes()
+	return l.literal()
 }
 
-func (l *streamLexer) readNumber() []byte {
-	buf := l.activeBuffer()
+// readNumber 读取数字并报告其中是否有小数点. 数字由 literal 取得, 或由
+// readDurationSuffix 补上单位.
+func (l *streamLexer) readNumber() bool {
+	l.beginLiteral()
 	isFloat := false
-	for unicode.IsDigit(rune(l.ch)) || (l.ch == '.' && !isFloat) {
+	for isDigit(l.ch) || (l.ch == '.' && !isFloat) {
 		if l.ch == '.' {
 			isFloat = true
 		}
-		buf.WriteByte(l.ch)
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
-	return buf.Bytes()
+	return isFloat
 }
 
-func (l *streamLexer) readString(quote byte) []byte {
-	buf := l.activeBuffer()
+// readString 读取以 quote 为引号的字符串, 返回不含引号的内容. 到达输入
+// 末尾仍未遇到结尾的引号时 ok 为 false.
+func (l *streamLexer) readString(quote byte) (literal []byte, ok bool) {
+	l.beginLiteral()
 	l.readChar()
-	for {
-		if l.ch == quote || l.ch == 0 {
-			break
+	for l.ch != quote {
+		if l.atEOF() {
+			return nil, false
+		}
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
 		}
-		buf.WriteByte(l.ch)
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
 	l.readChar()
-	return buf.Bytes()
+	return l.literal(), true
 }
 
 func (l *streamLexer) readUntilEndOfLine() []byte {
-	buf := l.activeBuffer()
+	l.beginLiteral()
 	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
-		buf.WriteByte(l.ch)
+		l.writeLiteral(l.ch)
 		l.readChar()
 	}
-	return buf.Bytes()
+	return l.literal()
 }
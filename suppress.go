@@ -0,0 +1,139 @@
+package wanf
+
+import (
+	"bytes"
+	"strings"
+)
+
+// 抑制注释 (suppression comments) 用于在源文件中忽略特定的 lint 问题:
+//
+//	// wanf:disable WANF004            从此行起禁用规则, 直到 wanf:enable
+//	// wanf:enable WANF004             重新启用规则
+//	// wanf:disable-next-line WANF005  仅忽略下一行
+//	a = 1 // wanf:disable-line         仅忽略当前行
+//
+// 规则可以用 ID 或名称指定, 多个规则以逗号或空格分隔; 不指定规则时作用于所有规则.
+// 语法错误无法被抑制.
+const suppressPrefix = "wanf:"
+
+type suppressKind int
+
+const (
+	suppressDisable suppressKind = iota
+	suppressEnable
+	suppressNextLine
+	suppressLine
+)
+
+type suppressDirective struct {
+	line  int
+	kind  suppressKind
+	rules map[ErrorType]bool // nil 表示所有规则
+}
+
+func (d suppressDirective) matches(t ErrorType) bool {
+	return d.rules == nil || d.rules[t]
+}
+
+type suppressions []suppressDirective
+
+// parseSuppressions collects suppression directives from the comments in data.
+func parseSuppressions(data []byte) suppressions {
+	if !bytes.Contains(data, []byte(suppressPrefix)) {
+		return nil
+	}
+	var dirs suppressions
+	l := NewLexer(data)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		if tok.Type != COMMENT {
+			continue
+		}
+		if d, ok := parseSuppressDirective(tok); ok {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+func parseSuppressDirective(tok Token) (suppressDirective, bool) {
+	text := string(tok.Literal)
+	text = strings.TrimPrefix(text, "//")
+	text = strings.TrimPrefix(text, "/*")
+	text = strings.TrimSuffix(text, "*/")
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, suppressPrefix) {
+		return suppressDirective{}, false
+	}
+	fields := strings.FieldsFunc(text[len(suppressPrefix):], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return suppressDirective{}, false
+	}
+	d := suppressDirective{line: tok.Line}
+	switch fields[0] {
+	case "disable":
+		d.kind = suppressDisable
+	case "enable":
+		d.kind = suppressEnable
+	case "disable-next-line":
+		d.kind = suppressNextLine
+	case "disable-line":
+		d.kind = suppressLine
+	default:
+		return suppressDirective{}, false
+	}
+	if len(fields) > 1 {
+		d.rules = make(map[ErrorType]bool, len(fields)-1)
+		for _, name := range fields[1:] {
+			if t, ok := ParseErrorType(name); ok {
+				d.rules[t] = true
+			}
+		}
+	}
+	return d, true
+}
+
+// suppressed reports whether e is silenced by a directive.
+func (s suppressions) suppressed(e LintError) bool {
+	disabled := false
+	for _, d := range s {
+		if d.line > e.Line {
+			break
+		}
+		switch d.kind {
+		case suppressDisable:
+			if d.matches(e.Type) {
+				disabled = true
+			}
+		case suppressEnable:
+			if d.matches(e.Type) {
+				disabled = false
+			}
+		case suppressNextLine:
+			if d.line == e.Line-1 && d.matches(e.Type) {
+				return true
+			}
+		case suppressLine:
+			if d.line == e.Line && d.matches(e.Type) {
+				return true
+			}
+		}
+	}
+	return disabled
+}
+
+// filter removes suppressed issues.
+func (s suppressions) filter(errs []LintError) []LintError {
+	if len(s) == 0 {
+		return errs
+	}
+	out := errs[:0]
+	for _, e := range errs {
+		if s.suppressed(e) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
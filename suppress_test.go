@@ -0,0 +1,80 @@
+package wanf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_RuleIDs(t *testing.T) {
+	_, errs := Lint([]byte("var unused = 1\nserver \"a\" {\n\tport = 1\n}\n"))
+	rules := map[string]bool{}
+	for _, e := range errs {
+		rules[e.Rule] = true
+	}
+	if !rules["WANF004"] || !rules["WANF005"] {
+		t.Errorf("expected WANF004 and WANF005, got %v", errs)
+	}
+	if typ, ok := ParseErrorType("WANF004"); !ok || typ != ErrRedundantLabel {
+		t.Errorf("ParseErrorType(WANF004) = %v, %v", typ, ok)
+	}
+}
+
+func TestLint_Suppressions(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // rule IDs still reported
+	}{
+		{
+			name: "disable-next-line",
+			src: `// wanf:disable-next-line WANF005
+var a = 1
+var b = 2`,
+			want: []string{"WANF005"},
+		},
+		{
+			name: "disable-line by name",
+			src:  `var a = 1 // wanf:disable-line unused-variable`,
+			want: nil,
+		},
+		{
+			name: "disable and enable",
+			src: `/* wanf:disable */
+var a = 1
+var b = 2
+// wanf:enable
+var c = 3`,
+			want: []string{"WANF005"},
+		},
+		{
+			name: "other rule untouched",
+			src: `// wanf:disable WANF004
+var a = 1`,
+			want: []string{"WANF005"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := Lint([]byte(tt.src))
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestLint_SuppressedRedundantLabelIsKept(t *testing.T) {
+	src := "// wanf:disable-next-line WANF004\nserver \"main\" {\n\tport = 1\n}\n"
+	program, errs := Lint([]byte(src))
+	if len(errs) != 0 {
+		t.Fatalf("expected no issues, got %v", errs)
+	}
+	out := string(Format(program, FormatOptions{Style: StyleBlockSorted, EmptyLines: true}))
+	if !strings.Contains(out, `server "main" {`) {
+		t.Errorf("suppressed label was removed:\n%s", out)
+	}
+}
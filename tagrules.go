@@ -0,0 +1,152 @@
+package wanf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError 表示解码后的值违反了 `wanf` 标签中声明的约束 (例如
+// `wanf:"port,min=1,max=65535"`), 或结构体的 Validate 方法返回了错误.
+// Line 与 Column 指向源文件中的键或块名.
+type ValidationError struct {
+	File    string // 值所在的文件, 由 DecodeFiles 与 DecodeDir 填写
+	Key     string
+	Line    int
+	Column  int
+	Message string
+	Err     error // Validate 返回的错误, 标签约束为 nil
+}
+
+func (e *ValidationError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: line %d:%d: %s: %s", e.File, e.Line, e.Column, e.Key, e.Message)
+	}
+	return fmt.Sprintf("line %d:%d: %s: %s", e.Line, e.Column, e.Key, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// tagRegexCache 缓存 regex= 选项编译后的正则表达式.
+var tagRegexCache sync.Map // map[string]*regexp.Regexp
+
+// checkField 在字段赋值后检查标签约束, 违反时返回指向 tok 的 *ValidationError.
+func checkField(field reflect.Value, tag wanfTag, tok Token) error {
+	if !tag.hasRules() {
+		return nil
+	}
+	if err := checkTagRules(field, tag); err != nil {
+		return &ValidationError{Key: string(tok.Literal), Line: tok.Line, Column: tok.Column, Message: err.Error()}
+	}
+	return nil
+}
+
+// checkTagRules 检查 v 是否满足标签中的 min, max, oneof 与 regex 约束.
+// min 与 max 对数值与 time.Duration 限制大小, 对字符串 (按字符计), 列表与 map 限制长度.
+func checkTagRules(v reflect.Value, tag wanfTag) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if tag.Min != "" {
+		if err := checkBound(v, "min", tag.Min); err != nil {
+			return err
+		}
+	}
+	if tag.Max != "" {
+		if err := checkBound(v, "max", tag.Max); err != nil {
+			return err
+		}
+	}
+	if len(tag.OneOf) > 0 {
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, allowed := range tag.OneOf {
+			if s == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value %q is not one of: %s", s, strings.Join(tag.OneOf, " "))
+		}
+	}
+	if tag.Regex != "" {
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("regex can only be used with strings, not %s", v.Type())
+		}
+		re, err := tagRegexp(tag.Regex)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(v.String()) {
+			return fmt.Errorf("value %q does not match regex %q", v.String(), tag.Regex)
+		}
+	}
+	return nil
+}
+
+// checkBound 检查 v 不小于 (rule 为 "min") 或不大于 (rule 为 "max") bound.
+func checkBound(v reflect.Value, rule, bound string) error {
+	var (
+		actual, limit float64
+		what          = "value"
+		shown         interface{}
+	)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a duration", rule, bound)
+		}
+		actual, limit, shown = float64(v.Int()), float64(d), time.Duration(v.Int())
+	} else {
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a number", rule, bound)
+		}
+		limit = f
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			actual, shown = float64(v.Int()), v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			actual, shown = float64(v.Uint()), v.Uint()
+		case reflect.Float32, reflect.Float64:
+			actual, shown = v.Float(), v.Float()
+		case reflect.String:
+			n := utf8.RuneCountInString(v.String())
+			actual, shown, what = float64(n), n, "length"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			actual, shown, what = float64(v.Len()), v.Len(), "length"
+		default:
+			return fmt.Errorf("%s can not be used with %s", rule, v.Type())
+		}
+	}
+	if rule == "min" && actual < limit {
+		return fmt.Errorf("%s %v is less than min %s", what, shown, bound)
+	}
+	if rule == "max" && actual > limit {
+		return fmt.Errorf("%s %v is greater than max %s", what, shown, bound)
+	}
+	return nil
+}
+
+func tagRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := tagRegexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	tagRegexCache.Store(pattern, re)
+	return re, nil
+}
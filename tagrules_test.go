@@ -0,0 +1,101 @@
+package wanf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tagRulesConfig struct {
+	Port    int           `wanf:"port,min=1,max=65535"`
+	Level   string        `wanf:"level,oneof=debug info warn error"`
+	Name    string        `wanf:"name,min=2,regex=^[a-z]{1,8}$"`
+	Timeout time.Duration `wanf:"timeout,min=1s,max=1m"`
+	Hosts   []string      `wanf:"hosts,max=2"`
+	Ratio   float64       `wanf:"ratio,max=1"`
+}
+
+func TestDecodeTagRules(t *testing.T) {
+	valid := `
+port = 8080
+level = "info"
+name = "api"
+timeout = 30s
+hosts = ["a", "b"]
+ratio = 0.5
+`
+	var cfg tagRulesConfig
+	if err := Decode([]byte(valid), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"min", "port = 0", "line 1:1: port: value 0 is less than min 1"},
+		{"max", "\n  port = 70000", "line 2:3: port: value 70000 is greater than max 65535"},
+		{"oneof", `level = "trace"`, `level: value "trace" is not one of: debug info warn error`},
+		{"regex", `name = "API"`, `name: value "API" does not match regex "^[a-z]{1,8}$"`},
+		{"string length", `name = "a"`, "name: length 1 is less than min 2"},
+		{"duration", "timeout = 2m", "timeout: value 2m0s is greater than max 1m"},
+		{"list length", `hosts = ["a", "b", "c"]`, "hosts: length 3 is greater than max 2"},
+		{"float", "ratio = 1.5", "ratio: value 1.5 is greater than max 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg tagRulesConfig
+			err := Decode([]byte(tt.data), &cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Decode error = %v, want it to contain %q", err, tt.want)
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("error %v is not a *ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestStreamDecodeTagRules(t *testing.T) {
+	var cfg tagRulesConfig
+	dec, err := NewStreamDecoder(strings.NewReader("level = \"info\"\nport = 0\n"))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder failed: %v", err)
+	}
+	err = dec.Decode(&cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Decode error = %v, want a *ValidationError", err)
+	}
+	if verr.Key != "port" || verr.Line != 2 || verr.Column != 1 {
+		t.Errorf("got %+v, want key port at 2:1", verr)
+	}
+}
+
+func TestParseWanfTagRules(t *testing.T) {
+	tag := parseWanfTag("code,omitempty,min=3,oneof=a b,regex=^[0-9]{2,4}$", "Code")
+	if tag.Name != "code" || !tag.Omitempty || tag.Min != "3" {
+		t.Errorf("unexpected tag: %+v", tag)
+	}
+	if strings.Join(tag.OneOf, "|") != "a|b" {
+		t.Errorf("OneOf = %v", tag.OneOf)
+	}
+	if tag.Regex != "^[0-9]{2,4}$" {
+		t.Errorf("Regex = %q, want the rest of the tag", tag.Regex)
+	}
+}
+
+func TestSchemaOfTagRules(t *testing.T) {
+	out, err := SchemaOf(tagRulesConfig{})
+	if err != nil {
+		t.Fatalf("SchemaOf failed: %v", err)
+	}
+	for _, want := range []string{`"maximum": 65535`, `"minLength": 2`, `"pattern": "^[a-z]{1,8}$"`, `"info"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("schema does not contain %s:\n%s", want, out)
+		}
+	}
+}
@@ -11,6 +11,55 @@ type Token struct {
 	Literal []byte // 使用 []byte 避免在词法分析阶段分配新字符串
 	Line    int
 	Column  int
+	Offset  int // 标记第一个字节的偏移量
+
+	// 标记之后第一个字节的位置. 字符串的 Literal 不含引号, 但范围包含引号.
+	EndLine   int
+	EndColumn int
+	EndOffset int
+}
+
+// Position 是源文本中的一个位置. Line 与 Column 从 1 开始, Column 以字节计;
+// Offset 是从 0 开始的字节偏移. Line 为 0 表示没有位置信息, 例如由程序
+// 创建而非解析得到的节点.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid 报告 p 是否带有位置信息.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Pos 返回标记的起始位置.
+func (t Token) Pos() Position {
+	return Position{Offset: t.Offset, Line: t.Line, Column: t.Column}
+}
+
+// End 返回标记之后第一个字节的位置.
+func (t Token) End() Position {
+	return Position{Offset: t.EndOffset, Line: t.EndLine, Column: t.EndColumn}
+}
+
+// endLine 返回标记结束所在的行号. 块注释与含换行的字符串可以跨越多行.
+func (t Token) endLine() int {
+	if t.EndLine > t.Line {
+		return t.EndLine
+	}
+	return t.Line
+}
+
+// endOf 返回诊断范围的结束行列. 词法分析器产生的标记记录了结束位置, 可以
+// 跨越多行; 手工构造的标记没有结束位置, 按 width 列推算.
+func endOf(tok Token, width int) (line, column int) {
+	if tok.EndLine > 0 {
+		return tok.EndLine, tok.EndColumn
+	}
+	return tok.Line, tok.Column + width
 }
 
 func (t Token) String() string {
@@ -40,6 +89,7 @@ const (
 	DOLLAR_LBRACE TokenType = "${"
 	COMMENT TokenType = "COMMENT"
 	ILLEGAL_COMMENT TokenType = "ILLEGAL_COMMENT"
+	DOC_SEPARATOR TokenType = "---" // 只有 --- 的一行, 分隔流中的文档, 见 StreamDecoder
 )
 
 // LookupIdentifier 检查 ident 是否是关键字.
@@ -0,0 +1,204 @@
+package wanf
+
+import (
+	"reflect"
+	"time"
+)
+
+// Unmarshaler 由能够不借助反射解码自身的结构体实现, 通常由 `wanflint gen-decoder`
+// 生成. 解码器在将块体解码到实现了 Unmarshaler 的结构体 (的指针) 时调用
+// UnmarshalWANF, 不再通过反射查找字段.
+type Unmarshaler interface {
+	UnmarshalWANF(d *BodyDecoder) error
+}
+
+// BodyDecoder 逐个访问块体中的赋值与块, 供 UnmarshalWANF 使用. 典型用法:
+//
+//	for d.Next() {
+//		switch d.Key() {
+//		case "port":
+//			err = d.Int(&c.Port)
+//		}
+//	}
+//
+// 各方法按与反射解码相同的规则转换值: 常见类型直接赋值, 其余情况 (如字符串
+// 转数字、var 引用、错误) 回退到反射解码, 结果与未实现 Unmarshaler 时一致.
+type BodyDecoder struct {
+	d     *internalDecoder
+	stmts []Statement
+	i     int
+	stmt  Statement
+}
+
+// Next 前进到下一个赋值或块, 没有更多时返回 false. var 与 import 语句被跳过.
+func (b *BodyDecoder) Next() bool {
+	for b.i < len(b.stmts) {
+		stmt := b.stmts[b.i]
+		b.i++
+		switch stmt.(type) {
+		case *AssignStatement, *BlockStatement:
+			b.stmt = stmt
+			return true
+		}
+	}
+	b.stmt = nil
+	return false
+}
+
+// Key 返回当前赋值或块的名称.
+func (b *BodyDecoder) Key() string {
+	switch s := b.stmt.(type) {
+	case *AssignStatement:
+		return string(s.Name.Value)
+	case *BlockStatement:
+		return string(s.Name.Value)
+	}
+	return ""
+}
+
+// String 将当前值解码到 p.
+func (b *BodyDecoder) String(p *string) error {
+	val, err := b.value()
+	if v, ok := val.(string); ok {
+		*p = v
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Bool 将当前值解码到 p.
+func (b *BodyDecoder) Bool(p *bool) error {
+	val, err := b.value()
+	if v, ok := val.(bool); ok {
+		*p = v
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Int 将当前值解码到 p.
+func (b *BodyDecoder) Int(p *int) error {
+	val, err := b.value()
+	if v, ok := val.(int64); ok {
+		*p = int(v)
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Int64 将当前值解码到 p.
+func (b *BodyDecoder) Int64(p *int64) error {
+	val, err := b.value()
+	if v, ok := val.(int64); ok {
+		*p = v
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Uint 将当前值解码到 p.
+func (b *BodyDecoder) Uint(p *uint) error {
+	val, err := b.value()
+	if v, ok := val.(int64); ok && (v >= 0 || !b.d.strictNumbers) {
+		*p = uint(v)
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Uint64 将当前值解码到 p.
+func (b *BodyDecoder) Uint64(p *uint64) error {
+	val, err := b.value()
+	if v, ok := val.(int64); ok && (v >= 0 || !b.d.strictNumbers) {
+		*p = uint64(v)
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Float64 将当前值解码到 p.
+func (b *BodyDecoder) Float64(p *float64) error {
+	val, err := b.value()
+	if v, ok := val.(float64); ok {
+		*p = v
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Duration 将当前值解码到 p.
+func (b *BodyDecoder) Duration(p *time.Duration) error {
+	val, err := b.value()
+	if v, ok := val.(time.Duration); ok {
+		*p = v
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Strings 将当前值解码到 p.
+func (b *BodyDecoder) Strings(p *[]string) error {
+	val, err := b.value()
+	if list, ok := val.([]interface{}); ok {
+		out := make([]string, len(list))
+		for i, el := range list {
+			s, ok := el.(string)
+			if !ok {
+				return b.set(p, val, err)
+			}
+			out[i] = s
+		}
+		*p = out
+		return nil
+	}
+	return b.set(p, val, err)
+}
+
+// Block 将当前块解码到 u, 并在 u 实现 Validator 时校验它.
+func (b *BodyDecoder) Block(u Unmarshaler) error {
+	bs, ok := b.stmt.(*BlockStatement)
+	if !ok {
+		return b.Decode(u, "")
+	}
+	rv := reflect.ValueOf(u)
+	setBlockLabels(rv.Elem(), bs.Labels())
+	if err := u.UnmarshalWANF(&BodyDecoder{d: b.d, stmts: bs.Body.Statements}); err != nil {
+		return err
+	}
+	return validateBlock(rv, bs.Name.Token)
+}
+
+// Decode 使用反射将当前键解码到 p 指向的字段, tag 是该字段的 wanf 标签.
+// 生成的代码用它处理没有专门方法的类型以及带有约束的字段.
+func (b *BodyDecoder) Decode(p interface{}, tag string) error {
+	field := reflect.ValueOf(p).Elem()
+	switch s := b.stmt.(type) {
+	case *AssignStatement:
+		return b.d.decodeAssignField(s, field, parseWanfTag(tag, ""))
+	case *BlockStatement:
+		return b.d.decodeBlockField(s, field)
+	}
+	return nil
+}
+
+// value 计算当前赋值的值, 当前是块时返回 nil.
+func (b *BodyDecoder) value() (interface{}, error) {
+	as, ok := b.stmt.(*AssignStatement)
+	if !ok {
+		return nil, nil
+	}
+	return b.d.evalExpression(as.Value)
+}
+
+// set 是各方法的慢路径, 按反射解码的规则处理 val.
+func (b *BodyDecoder) set(p interface{}, val interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	as, ok := b.stmt.(*AssignStatement)
+	if !ok {
+		return b.Decode(p, "")
+	}
+	err = positionValidationError(b.d.setField(reflect.ValueOf(p).Elem(), val), as.Name.Token)
+	return fieldError(err, as, as.Name.Token)
+}
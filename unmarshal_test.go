@@ -0,0 +1,127 @@
+package wanf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unmarshalServer 手写实现 Unmarshaler, 用于测试 BodyDecoder 与解码器对
+// Unmarshaler 的处理. wanflint gen-decoder 生成的代码在 wanflint/internal/gentest 中测试.
+type unmarshalServer struct {
+	Labels  []string      `wanf:",labels"`
+	Host    string        `wanf:"host"`
+	Port    int           `wanf:"port"`
+	Timeout time.Duration `wanf:"timeout"`
+	Level   int           `wanf:"level,min=1"`
+	Tags    []string      `wanf:"tags"`
+	Debug   bool          `wanf:"debug"`
+}
+
+func (v *unmarshalServer) UnmarshalWANF(d *BodyDecoder) error {
+	for d.Next() {
+		var err error
+		switch d.Key() {
+		case "host":
+			err = d.String(&v.Host)
+		case "port":
+			err = d.Int(&v.Port)
+		case "timeout":
+			err = d.Duration(&v.Timeout)
+		case "level":
+			err = d.Decode(&v.Level, "level,min=1")
+		case "tags":
+			err = d.Strings(&v.Tags)
+		case "debug":
+			err = d.Bool(&v.Debug)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *unmarshalServer) Validate() error {
+	if v.Host == "bad" {
+		return errors.New("bad host")
+	}
+	return nil
+}
+
+type unmarshalConfig struct {
+	Server  *unmarshalServer           `wanf:"server"`
+	Servers map[string]unmarshalServer `wanf:"servers"`
+}
+
+// reflectServer 与 unmarshalServer 字段相同但不实现 Unmarshaler.
+type reflectServer struct {
+	Labels  []string      `wanf:",labels"`
+	Host    string        `wanf:"host"`
+	Port    int           `wanf:"port"`
+	Timeout time.Duration `wanf:"timeout"`
+	Level   int           `wanf:"level,min=1"`
+	Tags    []string      `wanf:"tags"`
+	Debug   bool          `wanf:"debug"`
+}
+
+type reflectConfig struct {
+	Server  *reflectServer           `wanf:"server"`
+	Servers map[string]reflectServer `wanf:"servers"`
+}
+
+func TestUnmarshaler(t *testing.T) {
+	src := `var p = 9000
+server {
+	host = "localhost"
+	port = ${p}
+	timeout = 5s
+	level = 3
+	tags = ["a", "b"]
+	debug = "true"
+}
+servers "a" {
+	host = "a.local"
+	port = 1
+}
+`
+	var got unmarshalConfig
+	if err := decodeString(src, &got); err != nil {
+		t.Fatalf("Decode with Unmarshaler: %v", err)
+	}
+	var want reflectConfig
+	if err := decodeString(src, &want); err != nil {
+		t.Fatalf("Decode with reflection: %v", err)
+	}
+	if got.Server == nil || !reflect.DeepEqual(reflectServer(*got.Server), *want.Server) {
+		t.Errorf("server = %+v, want %+v", got.Server, want.Server)
+	}
+	if a := got.Servers["a"]; a.Host != "a.local" || !reflect.DeepEqual(a.Labels, []string{"a"}) {
+		t.Errorf("servers = %+v, want %+v", got.Servers, want.Servers)
+	}
+
+	errCases := []struct {
+		src, want string
+	}{
+		{"server {\n\tport = \"x\"\n}\n", "cannot set field of type int"},
+		{"server {\n\tlevel = 0\n}\n", "level"},
+		{"server {\n\thost = \"bad\"\n}\n", "bad host"},
+	}
+	for _, tc := range errCases {
+		var c unmarshalConfig
+		err := decodeString(tc.src, &c)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Decode(%q) error = %v, want it to contain %q", tc.src, err, tc.want)
+		}
+	}
+}
+
+func decodeString(src string, v interface{}) error {
+	dec, err := NewDecoder(strings.NewReader(src))
+	if err != nil {
+		return err
+	}
+	return dec.Decode(v)
+}
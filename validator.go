@@ -0,0 +1,58 @@
+package wanf
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Validator 由需要语义校验的配置结构体实现. 解码器在填充完一个结构体
+// (根结构体, 块, 带标签的块的每个实例以及列表与 map 中的结构体) 后调用其
+// Validate 方法; 嵌套结构体先于外层结构体校验.
+//
+// 块的 Validate 错误被包装为指向块名的 *ValidationError, 可以用 errors.Is
+// 与 errors.As 取得原始错误; 根结构体没有对应的位置, 其错误原样返回.
+type Validator interface {
+	Validate() error
+}
+
+// callValidate 在 v (结构体或结构体指针) 实现 Validator 时调用其 Validate.
+// 值与指针接收者的方法都会被找到.
+func callValidate(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if v.CanAddr() {
+		if val, ok := v.Addr().Interface().(Validator); ok {
+			return val.Validate()
+		}
+		return nil
+	}
+	if val, ok := v.Interface().(Validator); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+// validateBlock 校验由块 (名称标记为 tok) 解码得到的结构体, 错误指向块名.
+func validateBlock(v reflect.Value, tok Token) error {
+	if err := callValidate(v); err != nil {
+		return &ValidationError{Key: string(tok.Literal), Line: tok.Line, Column: tok.Column, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// positionValidationError 为尚无位置的 *ValidationError 补上键 tok 的位置.
+// 由 {...} 字面量解码的结构体在解码时不知道所在位置, 由赋值语句补充.
+func positionValidationError(err error, tok Token) error {
+	var verr *ValidationError
+	if errors.As(err, &verr) && verr.Line == 0 {
+		verr.Key, verr.Line, verr.Column = string(tok.Literal), tok.Line, tok.Column
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package wanf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var errValidatorTestPort = errors.New("port is required")
+
+type validatorTestServer struct {
+	Labels []string `wanf:",labels"`
+	Port   int      `wanf:"port"`
+}
+
+func (s validatorTestServer) Validate() error {
+	if s.Port == 0 {
+		return fmt.Errorf("server %s: %w", strings.Join(s.Labels, " "), errValidatorTestPort)
+	}
+	return nil
+}
+
+type validatorTestDB struct {
+	URL string `wanf:"url"`
+}
+
+func (db *validatorTestDB) Validate() error {
+	if !strings.HasPrefix(db.URL, "postgres://") {
+		return errors.New("url must use postgres://")
+	}
+	return nil
+}
+
+type validatorTestConfig struct {
+	Servers map[string]validatorTestServer `wanf:"server"`
+	DB      validatorTestDB                `wanf:"db"`
+	Backup  *validatorTestDB               `wanf:"backup"`
+	Mirrors []validatorTestServer          `wanf:"mirror"`
+	Primary bool                           `wanf:"primary"`
+}
+
+func (c *validatorTestConfig) Validate() error {
+	if !c.Primary && c.Backup == nil {
+		return errors.New("a backup database is required for replicas")
+	}
+	return nil
+}
+
+func TestDecodeCallsValidate(t *testing.T) {
+	valid := `
+primary = true
+server "a" {
+	port = 80
+}
+db {
+	url = "postgres://localhost"
+}
+`
+	var cfg validatorTestConfig
+	if err := Decode([]byte(valid), &cfg); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"labeled block", "primary = true\nserver \"a\" {\n}", "line 2:1: server: server a: port is required"},
+		{"block", "primary = true\n  db {\n url = \"mysql://\"\n}", "line 2:3: db: url must use postgres://"},
+		{"pointer block", "primary = true\nbackup {\n}", "line 2:1: backup: url must use postgres://"},
+		{"slice block", "primary = true\nmirror \"m\" {\n}", "line 2:1: mirror: server m: port is required"},
+		{"root", `db { url = "postgres://x" }`, "a backup database is required for replicas"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg validatorTestConfig
+			err := Decode([]byte(tt.data), &cfg)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("Decode error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeValidateUnwrap(t *testing.T) {
+	var cfg validatorTestConfig
+	err := Decode([]byte("primary = true\nserver \"a\" {}"), &cfg)
+	if !errors.Is(err, errValidatorTestPort) {
+		t.Errorf("errors.Is(%v, errValidatorTestPort) = false", err)
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Key != "server" {
+		t.Errorf("error %v is not a *ValidationError for server", err)
+	}
+}
+
+type validatorTestListConfig struct {
+	Targets []validatorTestDB `wanf:"targets"`
+}
+
+func TestDecodeValidateBlockLiteral(t *testing.T) {
+	var cfg validatorTestListConfig
+	err := Decode([]byte("\ntargets = [{ url = \"x\" }]"), &cfg)
+	if err == nil || err.Error() != "line 2:1: targets: url must use postgres://" {
+		t.Errorf("Decode error = %v", err)
+	}
+}
+
+func TestStreamDecodeCallsValidate(t *testing.T) {
+	var cfg validatorTestConfig
+	dec, err := NewStreamDecoder(strings.NewReader("primary = true\nserver \"a\" {\n}\n"))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder failed: %v", err)
+	}
+	err = dec.Decode(&cfg)
+	if !errors.Is(err, errValidatorTestPort) {
+		t.Errorf("Decode error = %v, want it to wrap errValidatorTestPort", err)
+	}
+}
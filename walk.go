@@ -0,0 +1,110 @@
+package wanf
+
+import "strconv"
+
+// Cursor 描述 Inspect 访问到的一个节点及其在 AST 中的位置.
+type Cursor struct {
+	Node Node
+	// Parents 是从根节点到直接父节点的路径, 访问根节点时为空.
+	// 它只在回调期间有效, 需要保留时请复制.
+	Parents []Node
+	// Path 是节点所属键的路径, 语法与 Lookup 相同, 如 server."main".port 或
+	// hosts[1]. 键的名称与值共用同一路径; 根节点以及顶层的 var 与 import
+	// 语句的路径为空.
+	Path string
+}
+
+// Parent 返回直接父节点, 根节点返回 nil.
+func (c *Cursor) Parent() Node {
+	if len(c.Parents) == 0 {
+		return nil
+	}
+	return c.Parents[len(c.Parents)-1]
+}
+
+// Walk 以深度优先的先序遍历 node 及其全部子节点. fn 返回 false 时不再访问
+// 该节点的子节点. 子节点按源码顺序访问: 语句的名称先于值, 块的名称与标签
+// 先于块体. 注释不作为节点访问, 可以通过 GetLeadingComments 等字段获取.
+func Walk(node Node, fn func(Node) bool) {
+	Inspect(node, func(c *Cursor) bool {
+		return fn(c.Node)
+	})
+}
+
+// Inspect 与 Walk 相同, 但回调同时得到父节点与路径信息.
+func Inspect(node Node, fn func(c *Cursor) bool) {
+	if node == nil {
+		return
+	}
+	c := &Cursor{}
+	inspect(c, node, "", fn)
+}
+
+func inspect(c *Cursor, node Node, path string, fn func(c *Cursor) bool) {
+	c.Node, c.Path = node, path
+	if !fn(c) {
+		return
+	}
+	c.Parents = append(c.Parents, node)
+	defer func() { c.Parents = c.Parents[:len(c.Parents)-1] }()
+
+	switch n := node.(type) {
+	case *RootNode:
+		for _, stmt := range n.Statements {
+			inspect(c, stmt, path, fn)
+		}
+	case *AssignStatement:
+		path = joinPath(path, string(n.Name.Value))
+		inspect(c, n.Name, path, fn)
+		if n.Value != nil {
+			inspect(c, n.Value, path, fn)
+		}
+	case *BlockStatement:
+		path = joinPath(path, string(n.Name.Value))
+		for _, label := range n.Labels() {
+			path += "." + strconv.Quote(label)
+		}
+		inspect(c, n.Name, path, fn)
+		if n.Label != nil {
+			inspect(c, n.Label, path, fn)
+		}
+		for _, label := range n.ExtraLabels {
+			inspect(c, label, path, fn)
+		}
+		if n.Body != nil {
+			inspect(c, n.Body, path, fn)
+		}
+	case *VarStatement:
+		inspect(c, n.Name, path, fn)
+		if n.Value != nil {
+			inspect(c, n.Value, path, fn)
+		}
+	case *ImportStatement:
+		if n.Path != nil {
+			inspect(c, n.Path, path, fn)
+		}
+	case *BlockLiteral:
+		if n.Body != nil {
+			inspect(c, n.Body, path, fn)
+		}
+	case *MapLiteral:
+		for _, el := range n.Elements {
+			inspect(c, el, path, fn)
+		}
+	case *ListLiteral:
+		for i, el := range n.Elements {
+			inspect(c, el, path+"["+strconv.Itoa(i)+"]", fn)
+		}
+	case *EnvExpression:
+		if n.Name != nil {
+			inspect(c, n.Name, path, fn)
+		}
+		if n.DefaultValue != nil {
+			inspect(c, n.DefaultValue, path, fn)
+		}
+	case *SecretExpression:
+		if n.Ref != nil {
+			inspect(c, n.Ref, path, fn)
+		}
+	}
+}
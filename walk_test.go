@@ -0,0 +1,76 @@
+package wanf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	program := parseForMerge(t, `
+var port = 80
+server "main" {
+	hosts = ["a", env("HOST", "b")]
+}
+`)
+	var kinds []string
+	Walk(program, func(n Node) bool {
+		kinds = append(kinds, reflect.TypeOf(n).Elem().Name())
+		// 不进入列表.
+		_, isList := n.(*ListLiteral)
+		return !isList
+	})
+	want := []string{
+		"RootNode",
+		"VarStatement", "Identifier", "IntegerLiteral",
+		"BlockStatement", "Identifier", "StringLiteral", "RootNode",
+		"AssignStatement", "Identifier", "ListLiteral",
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Walk visited %v, want %v", kinds, want)
+	}
+}
+
+func TestInspectPaths(t *testing.T) {
+	program := parseForMerge(t, `
+server "main" {
+	hosts = ["a", env("HOST")]
+	db = {
+		port = 5432
+	}
+}
+`)
+	paths := make(map[string]string)
+	var envParent Node
+	Inspect(program, func(c *Cursor) bool {
+		switch n := c.Node.(type) {
+		case *StringLiteral:
+			paths[string(n.Value)] = c.Path
+		case *IntegerLiteral:
+			paths[n.String()] = c.Path
+		case *EnvExpression:
+			envParent = c.Parent()
+			if len(c.Parents) != 5 {
+				t.Errorf("env() has %d parents, want 5", len(c.Parents))
+			}
+		}
+		return true
+	})
+	want := map[string]string{
+		"main": `server."main"`,
+		"a":    `server."main".hosts[0]`,
+		"HOST": `server."main".hosts[1]`,
+		"5432": `server."main".db.port`,
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if _, ok := envParent.(*ListLiteral); !ok {
+		t.Errorf("env() parent = %T, want *ListLiteral", envParent)
+	}
+	// 路径可以直接交给 Lookup.
+	for _, path := range want {
+		if _, err := Lookup(program, path); err != nil {
+			t.Errorf("Lookup(%q): %v", path, err)
+		}
+	}
+}
@@ -3,32 +3,94 @@ package wanf
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
 )
 
 var (
 	varRegex = regexp.MustCompile(`\$\{(\w+)\}`)
 )
 
-func Lint(data []byte) (*RootNode, []LintError) {
+// LintOption configures the checks performed by Lint.
+type LintOption func(*lintOptions)
+
+type lintOptions struct {
+	knownProfiles []string
+	config        *LintConfig
+	basePath      string
+	schema        *Schema
+}
+
+// WithKnownProfiles makes Lint report `profile` sections whose name is not in names.
+func WithKnownProfiles(names ...string) LintOption {
+	return func(o *lintOptions) {
+		o.knownProfiles = append(o.knownProfiles, names...)
+	}
+}
+
+// WithLintBasePath sets the directory that `import` paths are resolved against,
+// enabling the unresolved-import and unused-import checks.
+func WithLintBasePath(dir string) LintOption {
+	return func(o *lintOptions) {
+		o.basePath = dir
+	}
+}
+
+// WithSchema makes Lint also check the document against schema, reporting
+// problems under the schema and schema-unknown rules (see ValidateAgainstSchema).
+func WithSchema(schema *Schema) LintOption {
+	return func(o *lintOptions) {
+		o.schema = schema
+	}
+}
+
+func Lint(data []byte, opts ...LintOption) (*RootNode, []LintError) {
+	var options lintOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	l := NewLexer(data)
 	p := NewParser(l)
 	p.SetLintMode(true)
 	program := p.ParseProgram()
 	if len(p.Errors()) > 0 {
-		return program, p.Errors()
+		// 语法错误不受规则配置影响, 总是 error 级别 (包括列表中缺少的逗号).
+		errs := p.Errors()
+		for i := range errs {
+			errs[i].Rule = errs[i].Type.RuleID()
+			errs[i].Severity = SeverityError
+		}
+		return program, errs
 	}
-	allErrors := p.LintErrors()
+	options.knownProfiles = append(options.knownProfiles, options.config.listOption(ErrUnknownProfile, "known")...)
+	allErrors := append(p.LintErrors(), checkEncoding(data)...)
 	analyzer := &astAnalyzer{
+		opts:         options,
+		suppress:     parseSuppressions(data),
+		lines:        countLines(data),
 		errors:       allErrors,
 		blockCounts:  make(map[string]int),
 		declaredVars: make(map[string]*VarStatement),
 		usedVars:     make(map[string]bool),
 	}
 	newProgram := analyzer.Analyze(program)
-	return newProgram.(*RootNode), analyzer.errors
+	if options.schema != nil {
+		analyzer.errors = append(analyzer.errors, ValidateAgainstSchema(newProgram.(*RootNode), options.schema)...)
+	}
+	errs := options.config.apply(analyzer.errors)
+	return newProgram.(*RootNode), analyzer.suppress.filter(errs)
+}
+
+// countLines returns the number of lines in data; a trailing newline does not
+// start a new line.
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
 }
 
 func Format(program *RootNode, opts FormatOptions) []byte {
@@ -37,13 +99,61 @@ func Format(program *RootNode, opts FormatOptions) []byte {
 	return out.Bytes()
 }
 
-func DecodeFile(path string, v interface{}) error {
-	f, err := os.Open(path)
+// FormatStable formats data like Lint followed by Format, then formats the
+// result a second time and returns an error if the two outputs differ. A
+// formatter that is not idempotent would keep changing a file on every run,
+// so this surfaces formatter bugs. lintOpts are passed to Lint.
+func FormatStable(data []byte, opts FormatOptions, lintOpts ...LintOption) ([]byte, error) {
+	first, err := lintAndFormat(data, opts, lintOpts)
+	if err != nil {
+		return nil, err
+	}
+	second, err := lintAndFormat(first, opts, lintOpts)
+	if err != nil {
+		return first, fmt.Errorf("wanf: formatted output does not parse: %w", err)
+	}
+	if !bytes.Equal(first, second) {
+		return first, fmt.Errorf("wanf: formatting is not idempotent: output changes at line %d when formatted again", firstDiffLine(first, second))
+	}
+	return first, nil
+}
+
+func lintAndFormat(data []byte, opts FormatOptions, lintOpts []LintOption) ([]byte, error) {
+	p := NewParser(NewLexer(data))
+	p.SetLintMode(true)
+	p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, p.Errors()[0]
+	}
+	program, _ := Lint(data, lintOpts...)
+	return Format(program, opts), nil
+}
+
+// firstDiffLine returns the 1-based line of the first byte at which a and b differ.
+func firstDiffLine(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return bytes.Count(a[:n], []byte("\n")) + 1
+}
+
+// DecodeFile 读取并解码 path 指向的文件, 导入路径相对于文件所在目录.
+// opts 在此之后应用, 可以覆盖基准路径; 见 WithMmap.
+func DecodeFile(path string, v interface{}, opts ...DecoderOption) error {
+	opts = append([]DecoderOption{WithBasePath(filepath.Dir(path))}, opts...)
+	data, release, err := readDecodeFile(path, reflect.TypeOf(v), opts)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	dec, err := NewDecoder(f, WithBasePath(filepath.Dir(path)))
+	defer release()
+	d := newInternalDecoder(opts)
+	d.file = path
+	program, err := d.parseFile(data, path)
+	if err != nil {
+		return fmt.Errorf("parser errors: %w", err)
+	}
+	dec, err := d.load(program)
 	if err != nil {
 		return err
 	}
@@ -62,6 +172,9 @@ func Decode(data []byte, v interface{}) error {
 }
 
 type astAnalyzer struct {
+	opts         lintOptions
+	suppress     suppressions
+	lines        int // 源文件行数
 	errors       []LintError
 	blockCounts  map[string]int
 	declaredVars map[string]*VarStatement
@@ -74,9 +187,19 @@ func (a *astAnalyzer) Analyze(node Node) Node {
 
 	// Second pass: check for issues.
 	newNode := a.check(node)
+	if root, ok := newNode.(*RootNode); ok {
+		a.checkProfiles(root)
+		a.checkImports(root)
+		a.checkImportOrder(root)
+		a.checkLimits(root)
+	}
 
 	// Post-pass: check for unused variables.
+	ignorePrefix := a.opts.config.stringOption(ErrUnusedVariable, "ignore_prefix")
 	for name, stmt := range a.declaredVars {
+		if ignorePrefix != "" && strings.HasPrefix(name, ignorePrefix) {
+			continue
+		}
 		if _, ok := a.usedVars[name]; !ok {
 			err := LintError{
 				Line:      stmt.Token.Line,
@@ -95,48 +218,15 @@ func (a *astAnalyzer) Analyze(node Node) Node {
 }
 
 func (a *astAnalyzer) collect(root Node) {
-	if root == nil {
-		return
-	}
-	stack := []Node{root}
-	for len(stack) > 0 {
-		node := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-
-		if node == nil {
-			continue
-		}
-
+	Walk(root, func(node Node) bool {
 		switch n := node.(type) {
 		case *BlockStatement:
 			a.blockCounts[BytesToString(n.Name.Value)]++
 		case *VarStatement:
 			a.declaredVars[BytesToString(n.Name.Value)] = n
 		}
-
-		switch n := node.(type) {
-		case *RootNode:
-			for i := len(n.Statements) - 1; i >= 0; i-- {
-				stack = append(stack, n.Statements[i])
-			}
-		case *BlockStatement:
-			stack = append(stack, n.Body)
-		case *BlockLiteral:
-			stack = append(stack, n.Body)
-		case *AssignStatement:
-			stack = append(stack, n.Value)
-		case *ListLiteral:
-			for i := len(n.Elements) - 1; i >= 0; i-- {
-				stack = append(stack, n.Elements[i])
-			}
-		case *MapLiteral:
-			for i := len(n.Elements) - 1; i >= 0; i-- {
-				stack = append(stack, n.Elements[i])
-			}
-		case *VarStatement:
-			stack = append(stack, n.Value)
-		}
-	}
+		return true
+	})
 }
 
 func (a *astAnalyzer) check(node Node) Node {
@@ -146,6 +236,8 @@ func (a *astAnalyzer) check(node Node) Node {
 
 	switch n := node.(type) {
 	case *RootNode:
+		a.checkDuplicates(n.Statements)
+		a.checkNaming(n.Statements)
 		for i, stmt := range n.Statements {
 			n.Statements[i] = a.check(stmt).(Statement)
 		}
@@ -154,7 +246,9 @@ func (a *astAnalyzer) check(node Node) Node {
 		if n.Body != nil {
 			n.Body = a.check(n.Body).(*RootNode)
 		}
-		if n.Label != nil && a.blockCounts[BytesToString(n.Name.Value)] == 1 {
+		// Profile sections always carry a label, even when there is only one.
+		// The fix below rewrites the AST, so it must not run for a disabled or suppressed rule.
+		if a.opts.config.enabled(ErrRedundantLabel) && !a.suppress.suppressed(LintError{Line: n.Token.Line, Type: ErrRedundantLabel}) && n.Label != nil && len(n.ExtraLabels) == 0 && a.blockCounts[BytesToString(n.Name.Value)] == 1 && BytesToString(n.Name.Value) != profileBlockName {
 			err := LintError{
 				Line:      n.Token.Line,
 				Column:    n.Token.Column,
@@ -167,11 +261,13 @@ func (a *astAnalyzer) check(node Node) Node {
 			}
 			a.errors = append(a.errors, err)
 			return &BlockStatement{
-				Token:           n.Token,
-				Name:            n.Name,
-				Label:           nil,
-				Body:            n.Body,
-				LeadingComments: n.LeadingComments,
+				Token:            n.Token,
+				Name:             n.Name,
+				Label:            nil,
+				Body:             n.Body,
+				Rbrace:           n.Rbrace,
+				LeadingComments:  n.LeadingComments,
+				BlankLinesBefore: n.BlankLinesBefore,
 			}
 		}
 		return n
@@ -191,6 +287,7 @@ func (a *astAnalyzer) check(node Node) Node {
 		}
 		return n
 	case *MapLiteral:
+		a.checkDuplicates(n.Elements)
 		for i, st := range n.Elements {
 			n.Elements[i] = a.check(st).(Statement)
 		}
@@ -215,3 +312,53 @@ func (a *astAnalyzer) check(node Node) Node {
 		return node
 	}
 }
+
+// checkDuplicates reports keys assigned more than once and blocks with the
+// same name and labels within a single scope. Decoding either of them
+// silently keeps only the last value.
+func (a *astAnalyzer) checkDuplicates(stmts []Statement) {
+	keys := make(map[string]Token)
+	blocks := make(map[string]Token)
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *AssignStatement:
+			name := string(s.Name.Value)
+			if first, ok := keys[name]; ok {
+				a.errors = append(a.errors, LintError{
+					Line:      s.Token.Line,
+					Column:    s.Token.Column,
+					EndLine:   s.Token.Line,
+					EndColumn: s.Token.Column + len(s.Name.Value),
+					Message:   fmt.Sprintf("key %q is already assigned at line %d:%d", name, first.Line, first.Column),
+					Level:     ErrorLevelLint,
+					Type:      ErrDuplicateKey,
+					Args:      []string{name, fmt.Sprintf("%d:%d", first.Line, first.Column)},
+				})
+				continue
+			}
+			keys[name] = s.Token
+		case *BlockStatement:
+			labels := s.Labels()
+			if len(labels) == 0 {
+				continue
+			}
+			name := string(s.Name.Value)
+			key := name + "\x00" + strings.Join(labels, "\x00")
+			if first, ok := blocks[key]; ok {
+				label := strings.Join(labels, `" "`)
+				a.errors = append(a.errors, LintError{
+					Line:      s.Token.Line,
+					Column:    s.Token.Column,
+					EndLine:   s.Token.Line,
+					EndColumn: s.Token.Column + len(s.Name.Value),
+					Message:   fmt.Sprintf("block %s \"%s\" is already defined at line %d:%d", name, label, first.Line, first.Column),
+					Level:     ErrorLevelLint,
+					Type:      ErrDuplicateBlock,
+					Args:      append([]string{name, fmt.Sprintf("%d:%d", first.Line, first.Column)}, labels...),
+				})
+				continue
+			}
+			blocks[key] = s.Token
+		}
+	}
+}
@@ -2,8 +2,10 @@ package wanf
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -201,3 +203,96 @@ func BenchmarkStreamEncode(b *testing.B) {
 		_ = enc.Encode(&config) // Using default options for benchmark
 	}
 }
+
+// BenchmarkDecodeFlat measures decoding a parsed file into a struct of scalar fields.
+func BenchmarkDecodeFlat(b *testing.B) {
+	program, err := Parse([]byte("name = \"svc\"\nport = 8080\nratio = 1.5\ndebug = true\ntimeout = 3s\n"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var c flatConfig
+	rv := reflect.ValueOf(&c).Elem()
+	d := &internalDecoder{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.decodeRoot(program, rv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPlanDecode measures decoding the same file as BenchmarkDecode with a compiled plan.
+func BenchmarkPlanDecode(b *testing.B) {
+	if benchmarkWanfData == nil {
+		b.Skip("Cannot read benchmark data file")
+	}
+	plan := CompileType(reflect.TypeOf(benchmarkConfig{}))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var cfg benchmarkConfig
+		_ = plan.Decode(benchmarkWanfData, &cfg)
+	}
+}
+
+// BenchmarkStreamLexer measures tokenizing a wanf file from an io.Reader.
+func BenchmarkStreamLexer(b *testing.B) {
+	if benchmarkWanfData == nil {
+		b.Skip("Cannot read benchmark data file")
+	}
+	reader := bytes.NewReader(benchmarkWanfData)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Seek(0, io.SeekStart)
+		l := newStreamLexer(reader)
+		for {
+			tok := l.NextToken()
+			if tok.Type == EOF {
+				break
+			}
+		}
+	}
+}
+
+// benchmarkLargeData is a generated file dominated by identifiers and numbers.
+var benchmarkLargeData = func() []byte {
+	var buf bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&buf, "service_%d {\n\tport_number = %d\n\tratio_value = %d.%d\n\ttimeout = %dms\n}\n", i, 8000+i, i, i%100, i*10)
+	}
+	return buf.Bytes()
+}()
+
+// BenchmarkLexerLarge measures raw tokenization throughput on a large file.
+func BenchmarkLexerLarge(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkLargeData)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(benchmarkLargeData)
+		for {
+			tok := l.NextToken()
+			if tok.Type == EOF {
+				break
+			}
+		}
+	}
+}
+
+// BenchmarkStreamLexerLarge is BenchmarkLexerLarge for the stream lexer.
+func BenchmarkStreamLexerLarge(b *testing.B) {
+	reader := bytes.NewReader(benchmarkLargeData)
+	b.SetBytes(int64(len(benchmarkLargeData)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader.Seek(0, io.SeekStart)
+		l := newStreamLexer(reader)
+		for {
+			tok := l.NextToken()
+			if tok.Type == EOF {
+				break
+			}
+		}
+	}
+}
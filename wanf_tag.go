@@ -7,6 +7,14 @@ type wanfTag struct {
 	Name      string
 	KeyField  string
 	Omitempty bool
+	Labels    bool // 字段接收块的标签列表, 如 `wanf:",labels"`
+	Secret    bool // 字段保存机密, 如 `wanf:"password,secret"`, 文档中应使用 secret() 或 env()
+
+	// 解码后检查的约束, 见 checkTagRules.
+	Min   string   // min=1
+	Max   string   // max=65535
+	OneOf []string // oneof=debug info warn
+	Regex string   // regex=^[a-z]+$, 必须是最后一个选项, 其值可以包含逗号
 }
 
 // parseWanfTag parses a raw struct tag string into a wanfTag struct.
@@ -20,13 +28,32 @@ func parseWanfTag(tagStr, fieldName string) wanfTag {
 	if tag.Name == "" {
 		tag.Name = fieldName
 	}
-	for _, part := range parts[1:] {
+	for i, part := range parts[1:] {
 		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "regex=") {
+			tag.Regex = strings.TrimPrefix(strings.TrimSpace(strings.Join(parts[i+1:], ",")), "regex=")
+			break
+		}
 		if strings.HasPrefix(part, "key=") {
 			tag.KeyField = strings.TrimPrefix(part, "key=")
 		} else if part == "omitempty" {
 			tag.Omitempty = true
+		} else if part == "labels" {
+			tag.Labels = true
+		} else if part == "secret" {
+			tag.Secret = true
+		} else if strings.HasPrefix(part, "min=") {
+			tag.Min = strings.TrimPrefix(part, "min=")
+		} else if strings.HasPrefix(part, "max=") {
+			tag.Max = strings.TrimPrefix(part, "max=")
+		} else if strings.HasPrefix(part, "oneof=") {
+			tag.OneOf = strings.Fields(strings.TrimPrefix(part, "oneof="))
 		}
 	}
 	return tag
 }
+
+// hasRules 报告标签是否声明了需要在解码后检查的约束.
+func (t wanfTag) hasRules() bool {
+	return t.Min != "" || t.Max != "" || len(t.OneOf) > 0 || t.Regex != ""
+}
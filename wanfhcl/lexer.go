@@ -0,0 +1,294 @@
+package wanfhcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokComment
+	tokIdent
+	tokString // 已去除引号与转义的字符串, 包括 heredoc
+	tokNumber
+	tokPunct // = : { } [ ] , ( ) .
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int // 标记结束所在的行
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "newline"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lexer 将 HCL 源码切分为标记. 注释统一转为 WANF 的 `//` 或 `/* */` 形式.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+// tokenize 返回 src 的全部标记, 以 tokEOF 结尾.
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1}
+	var toks []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, fmt.Errorf("wanfhcl: line %d: %w", l.line, err)
+		}
+		toks = append(toks, tok)
+		if tok.kind == tokEOF {
+			return toks, nil
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t' || l.src[l.pos] == '\r') {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+	c := l.src[l.pos]
+	rest := l.src[l.pos:]
+	switch {
+	case c == '\n':
+		l.pos++
+		l.line++
+		return token{kind: tokNewline, text: "\n", line: l.line - 1}, nil
+	case c == '#' || strings.HasPrefix(rest, "//"):
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		text := strings.TrimPrefix(strings.TrimPrefix(rest[:end], "#"), "//")
+		l.pos += end
+		return token{kind: tokComment, text: "//" + strings.TrimRight(text, " \t\r"), line: l.line}, nil
+	case strings.HasPrefix(rest, "/*"):
+		end := strings.Index(rest, "*/")
+		if end < 0 {
+			return token{}, fmt.Errorf("unclosed block comment")
+		}
+		text := rest[:end+2]
+		l.pos += end + 2
+		l.line += strings.Count(text, "\n")
+		return token{kind: tokComment, text: text, line: l.line}, nil
+	case c == '"':
+		s, err := l.readString()
+		return token{kind: tokString, text: s, line: l.line}, err
+	case strings.HasPrefix(rest, "<<"):
+		s, err := l.readHeredoc()
+		return token{kind: tokString, text: s, line: l.line}, err
+	case isDigit(c) || (c == '-' || c == '+') && len(rest) > 1 && isDigit(rest[1]):
+		return token{kind: tokNumber, text: l.readNumber(), line: l.line}, nil
+	case isIdentStart(c):
+		start := l.pos
+		for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], line: l.line}, nil
+	case strings.IndexByte("=:{}[](),.", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), line: l.line}, nil
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return token{}, fmt.Errorf("unexpected character %q", r)
+}
+
+// readString 读取带引号的字符串并处理转义. 插值 ${...} 原样保留, 其中可以
+// 出现引号; $${ 是 HCL 中字面的 ${.
+func (l *lexer) readString() (string, error) {
+	var b strings.Builder
+	l.pos++ // 开头的引号
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return b.String(), nil
+		case strings.HasPrefix(l.src[l.pos:], "$${"):
+			b.WriteString("${")
+			l.pos += 3
+		case strings.HasPrefix(l.src[l.pos:], "${"):
+			end := matchBrace(l.src, l.pos+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated interpolation")
+			}
+			b.WriteString(l.src[l.pos : end+1])
+			l.pos = end + 1
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return "", fmt.Errorf("unterminated string")
+			}
+			e := l.src[l.pos+1]
+			l.pos += 2
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(e)
+			case 'u', 'U':
+				n := 4
+				if e == 'U' {
+					n = 8
+				}
+				if l.pos+n > len(l.src) {
+					return "", fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+n], 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("invalid unicode escape %q", l.src[l.pos-2:l.pos+n])
+				}
+				b.WriteRune(rune(code))
+				l.pos += n
+			default:
+				return "", fmt.Errorf("invalid escape sequence \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// matchBrace 返回与 src[open] 处的 `{` 配对的 `}` 的位置, 没有时返回 -1.
+func matchBrace(src string, open int) int {
+	depth := 0
+	for i := open; i < len(src) && src[i] != '\n'; i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// readHeredoc 读取 <<EOF 或 <<-EOF 形式的多行字符串. 结果以换行结尾,
+// <<- 形式去除各行共同的缩进.
+func (l *lexer) readHeredoc() (string, error) {
+	l.pos += 2
+	indented := false
+	if l.pos < len(l.src) && l.src[l.pos] == '-' {
+		indented = true
+		l.pos++
+	}
+	start := l.pos
+	for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+		l.pos++
+	}
+	marker := l.src[start:l.pos]
+	if marker == "" || l.pos >= len(l.src) || l.src[l.pos] != '\n' {
+		return "", fmt.Errorf("invalid heredoc marker")
+	}
+	l.pos++
+	l.line++
+	var lines []string
+	for {
+		if l.pos >= len(l.src) {
+			return "", fmt.Errorf("heredoc %s is not terminated", marker)
+		}
+		end := strings.IndexByte(l.src[l.pos:], '\n')
+		if end < 0 {
+			end = len(l.src) - l.pos
+		}
+		line := strings.TrimRight(l.src[l.pos:l.pos+end], "\r")
+		l.pos += end
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+		lines = append(lines, line)
+		if l.pos < len(l.src) {
+			l.pos++
+			l.line++
+		}
+	}
+	if indented {
+		lines = dedent(lines)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// dedent 去除各非空行共同的前导空白.
+func dedent(lines []string) []string {
+	prefix := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if prefix < 0 || n < prefix {
+			prefix = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= prefix && prefix > 0 {
+			line = line[prefix:]
+		}
+		out[i] = line
+	}
+	return out
+}
+
+func (l *lexer) readNumber() string {
+	start := l.pos
+	if l.src[l.pos] == '-' || l.src[l.pos] == '+' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos+1 < len(l.src) && l.src[l.pos] == '.' && isDigit(l.src[l.pos+1]) {
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	return l.src[start:l.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
@@ -0,0 +1,347 @@
+// Package wanfhcl 将 HCL 配置片段转换为 WANF, 便于与 Terraform 相关的团队复用
+// 已有的配置.
+//
+// 支持的 HCL 子集:
+//   - 属性 `name = value` 与块 `type "label" label2 { ... }`, 标识符形式的标签
+//     转为字符串标签;
+//   - 字符串 (含转义与 heredoc)、数字、布尔值、列表与对象;
+//   - `#`、`//` 与 `/* */` 注释, 转为 WANF 注释并保留位置.
+//
+// 属性中的对象转为映射字面量 `{[...]}`, 列表中的对象转为块字面量. 引用
+// (如 var.region)、函数调用、null 与负数没有对应的 WANF 写法, 遇到时返回
+// 错误; 字符串中的插值 ${...} 原样保留. 键必须是合法的 WANF 标识符.
+package wanfhcl
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// FromHCL 将 HCL 源码转换为格式化后的 WANF 源码, 保持原有顺序.
+func FromHCL(data []byte) ([]byte, error) {
+	root, err := ToAST(data)
+	if err != nil {
+		return nil, err
+	}
+	out := wanf.Format(root, wanf.FormatOptions{Style: wanf.StyleBlockSorted, EmptyLines: true, NoSort: true})
+	return append(out, '\n'), nil
+}
+
+// ToAST 解析 HCL 源码并返回对应的 WANF 语法树.
+func ToAST(data []byte) (*wanf.RootNode, error) {
+	toks, err := tokenize(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if err := p.body("", false); err != nil {
+		return nil, err
+	}
+	root, err := wanf.Parse(p.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("wanfhcl: generated invalid WANF: %w", err)
+	}
+	return root, nil
+}
+
+// parser 读取 HCL 标记并直接写出等价的 WANF 源码, 再交给 wanf.Parse 生成语法树.
+type parser struct {
+	toks []token
+	pos  int
+	buf  bytes.Buffer
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) advance() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isPunct(s string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == s
+}
+
+func (p *parser) expect(s string) (token, error) {
+	tok := p.advance()
+	if tok.kind != tokPunct || tok.text != s {
+		return tok, p.errorf(tok, "expected %q, got %s", s, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("wanfhcl: line %d: %s", tok.line, fmt.Sprintf(format, args...))
+}
+
+// skipNewlines 跳过换行, 期间遇到的注释写成独占一行的 WANF 注释.
+func (p *parser) skipNewlines(indent string) {
+	for {
+		switch tok := p.peek(); tok.kind {
+		case tokNewline:
+			p.advance()
+		case tokComment:
+			p.advance()
+			p.buf.WriteString(indent + tok.text + "\n")
+		default:
+			return
+		}
+	}
+}
+
+// lineComment 若下一个标记是与 line 同一行的注释, 将其写为行尾注释.
+func (p *parser) lineComment(line int) {
+	if tok := p.peek(); tok.kind == tokComment && tok.line == line {
+		p.advance()
+		p.buf.WriteString(" " + tok.text)
+	}
+}
+
+// body 读取属性与块直到文件结尾, nested 时直到 `}`.
+func (p *parser) body(indent string, nested bool) error {
+	for {
+		p.skipNewlines(indent)
+		tok := p.peek()
+		switch {
+		case tok.kind == tokEOF:
+			if nested {
+				return p.errorf(tok, "unexpected end of file, expected \"}\"")
+			}
+			return nil
+		case tok.kind == tokPunct && tok.text == "}" && nested:
+			return nil
+		case tok.kind != tokIdent:
+			return p.errorf(tok, "expected attribute or block, got %s", tok)
+		}
+		p.advance()
+		if err := checkKey(tok); err != nil {
+			return err
+		}
+		if p.isPunct("=") {
+			p.advance()
+			p.buf.WriteString(indent + tok.text + " = ")
+			last, err := p.expr(indent, false)
+			if err != nil {
+				return err
+			}
+			p.lineComment(last)
+			p.buf.WriteString("\n")
+		} else if err := p.block(tok, indent); err != nil {
+			return err
+		}
+		if next := p.peek(); next.kind != tokNewline && next.kind != tokEOF && !(nested && next.kind == tokPunct && next.text == "}") {
+			return p.errorf(next, "expected newline after %s, got %s", tok.text, next)
+		}
+	}
+}
+
+// block 读取名为 name 的块的标签与内容.
+func (p *parser) block(name token, indent string) error {
+	p.buf.WriteString(indent + name.text)
+	for {
+		tok := p.advance()
+		switch {
+		case tok.kind == tokString || tok.kind == tokIdent:
+			if strings.ContainsAny(tok.text, "\"\n") {
+				return p.errorf(tok, "block label %q cannot be represented in WANF", tok.text)
+			}
+			p.buf.WriteString(` "` + tok.text + `"`)
+			continue
+		case tok.kind == tokPunct && tok.text == "{":
+		default:
+			return p.errorf(tok, "expected \"=\", label or \"{\" after %s, got %s", name.text, tok)
+		}
+		p.buf.WriteString(" {")
+		p.lineComment(tok.line)
+		p.buf.WriteString("\n")
+		break
+	}
+	if err := p.body(indent+"\t", true); err != nil {
+		return err
+	}
+	rbrace, err := p.expect("}")
+	if err != nil {
+		return err
+	}
+	p.buf.WriteString(indent + "}")
+	p.lineComment(rbrace.line)
+	p.buf.WriteString("\n")
+	return nil
+}
+
+// expr 读取一个值并写出对应的 WANF 表达式, 返回其最后一个标记所在的行.
+// inList 为真时对象写为块字面量, 否则写为映射字面量.
+func (p *parser) expr(indent string, inList bool) (int, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokString:
+		return tok.line, p.str(tok)
+	case tokNumber:
+		return tok.line, p.number(tok)
+	case tokIdent:
+		if next := p.peek(); next.kind == tokPunct && (next.text == "." || next.text == "(" || next.text == "[") {
+			return 0, p.errorf(tok, "expression %s... needs evaluation and is not supported", tok.text)
+		}
+		switch tok.text {
+		case "true", "false":
+			p.buf.WriteString(tok.text)
+			return tok.line, nil
+		case "null":
+			return 0, p.errorf(tok, "null is not supported")
+		}
+		return 0, p.errorf(tok, "reference %s needs evaluation and is not supported", tok.text)
+	case tokPunct:
+		switch tok.text {
+		case "[":
+			return p.list(indent)
+		case "{":
+			return p.object(indent, inList)
+		}
+	}
+	return 0, p.errorf(tok, "expected a value, got %s", tok)
+}
+
+// list 读取 `[` 之后的列表元素.
+func (p *parser) list(indent string) (int, error) {
+	inner := indent + "\t"
+	p.buf.WriteString("[\n")
+	for {
+		p.skipNewlines(inner)
+		if p.isPunct("]") {
+			break
+		}
+		p.buf.WriteString(inner)
+		last, err := p.expr(inner, true)
+		if err != nil {
+			return 0, err
+		}
+		p.buf.WriteString(",")
+		comma := p.isPunct(",")
+		if comma {
+			last = p.advance().line
+		}
+		p.lineComment(last)
+		p.buf.WriteString("\n")
+		if tok := p.peek(); !comma && tok.kind != tokNewline && tok.kind != tokComment && !p.isPunct("]") {
+			return 0, p.errorf(tok, "expected \",\" or \"]\" in list, got %s", tok)
+		}
+	}
+	end := p.advance()
+	p.buf.WriteString(indent + "]")
+	return end.line, nil
+}
+
+// object 读取 `{` 之后的对象条目. 条目之间可以用逗号或换行分隔, 键可以是
+// 标识符或字符串, 键值之间可以用 `=` 或 `:`.
+func (p *parser) object(indent string, block bool) (int, error) {
+	inner := indent + "\t"
+	open, sep := "{[\n", ","
+	if block {
+		open, sep = "{\n", ""
+	}
+	p.buf.WriteString(open)
+	for {
+		p.skipNewlines(inner)
+		if p.isPunct("}") {
+			break
+		}
+		key := p.advance()
+		if key.kind != tokIdent && key.kind != tokString {
+			return 0, p.errorf(key, "expected object key, got %s", key)
+		}
+		if err := checkKey(key); err != nil {
+			return 0, err
+		}
+		if !p.isPunct("=") && !p.isPunct(":") {
+			return 0, p.errorf(p.peek(), "expected \"=\" or \":\" after key %s", key.text)
+		}
+		p.advance()
+		p.buf.WriteString(inner + key.text + " = ")
+		last, err := p.expr(inner, false)
+		if err != nil {
+			return 0, err
+		}
+		p.buf.WriteString(sep)
+		comma := p.isPunct(",")
+		if comma {
+			last = p.advance().line
+		}
+		p.lineComment(last)
+		p.buf.WriteString("\n")
+		if tok := p.peek(); !comma && tok.kind != tokNewline && tok.kind != tokComment && !p.isPunct("}") {
+			return 0, p.errorf(tok, "expected \",\" or \"}\" in object, got %s", tok)
+		}
+	}
+	end := p.advance()
+	if block {
+		p.buf.WriteString(indent + "}")
+	} else {
+		p.buf.WriteString(indent + "]}")
+	}
+	return end.line, nil
+}
+
+// str 写出字符串字面量. WANF 字符串没有转义: 多行字符串使用反引号,
+// 其余使用双引号, 内容中不能出现所用的引号.
+func (p *parser) str(tok token) error {
+	quote := byte('"')
+	if strings.Contains(tok.text, "\n") {
+		quote = '`'
+	}
+	if strings.IndexByte(tok.text, quote) >= 0 {
+		return p.errorf(tok, "string %q cannot be represented without escapes", tok.text)
+	}
+	p.buf.WriteByte(quote)
+	p.buf.WriteString(tok.text)
+	p.buf.WriteByte(quote)
+	return nil
+}
+
+// number 写出数字. 整数保持原样, 带指数的数字展开为小数.
+func (p *parser) number(tok token) error {
+	text := strings.TrimPrefix(tok.text, "+")
+	if strings.HasPrefix(text, "-") {
+		return p.errorf(tok, "negative number %s is not supported", text)
+	}
+	if !strings.ContainsAny(text, ".eE") {
+		if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+			return p.errorf(tok, "invalid number %s", text)
+		}
+		p.buf.WriteString(text)
+		return nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return p.errorf(tok, "invalid number %s", text)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	p.buf.WriteString(s)
+	return nil
+}
+
+// checkKey 检查 HCL 的属性名或对象键能否用作 WANF 标识符. HCL 允许 `-`, WANF 不允许.
+func checkKey(tok token) error {
+	s := tok.text
+	ok := s != "" && isIdentStart(s[0])
+	for i := 1; ok && i < len(s); i++ {
+		ok = isIdentStart(s[i]) || isDigit(s[i])
+	}
+	if !ok || wanf.LookupIdentifier([]byte(s)) != wanf.IDENT {
+		return fmt.Errorf("wanfhcl: line %d: key %q is not a valid WANF identifier", tok.line, s)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package wanfhcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+func TestFromHCL(t *testing.T) {
+	src := `# Instance settings
+region = "us-east-1" // primary
+count = 3
+ratio = 1.5e2
+tags = ["web", "prod"]
+labels = {
+  env = "prod"
+  "team": "core",
+}
+
+resource "aws_instance" web {
+  ami = "ami-123"
+  /* bootstrap */
+  script = <<-EOF
+    echo hi
+    echo ${name}
+  EOF
+  rules = [{ port = 80 }, { port = 443 }]
+}
+`
+	got, err := FromHCL([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Instance settings\n" +
+		"region = \"us-east-1\" // primary\n" +
+		"count = 3\n" +
+		"ratio = 150.0\n" +
+		"\n" +
+		"tags = [\n\t\"web\",\n\t\"prod\",\n]\n" +
+		"\n" +
+		"labels = {[\n\tenv = \"prod\",\n\tteam = \"core\",\n]}\n" +
+		"\n" +
+		"resource \"aws_instance\" \"web\" {\n" +
+		"\tami = \"ami-123\"\n" +
+		"\t/* bootstrap */\n" +
+		"\tscript = `echo hi\necho ${name}\n`\n" +
+		"\trules = [\n\t\t{\n\t\t\tport = 80\n\t\t},\n\t\t{\n\t\t\tport = 443\n\t\t},\n\t]\n" +
+		"}\n"
+	if string(got) != want {
+		t.Errorf("FromHCL mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFromHCLDecode(t *testing.T) {
+	src := `
+listener "tcp" {
+  address = "0.0.0.0:8200"
+  tls_disable = true
+}
+listener "unix" {
+  address = "/run/vault.sock"
+}
+storage {
+  path = "/var/lib/vault"
+  retries = 5
+}
+`
+	root, err := ToAST([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type listener struct {
+		Address    string `wanf:"address"`
+		TLSDisable bool   `wanf:"tls_disable"`
+	}
+	var cfg struct {
+		Listener map[string]listener `wanf:"listener"`
+		Storage  struct {
+			Path    string `wanf:"path"`
+			Retries int    `wanf:"retries"`
+		} `wanf:"storage"`
+	}
+	if err := wanf.Decode(wanf.Format(root, wanf.FormatOptions{}), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Listener["tcp"]; got.Address != "0.0.0.0:8200" || !got.TLSDisable {
+		t.Errorf("listener tcp = %+v", got)
+	}
+	if got := cfg.Listener["unix"].Address; got != "/run/vault.sock" {
+		t.Errorf("listener unix address = %q", got)
+	}
+	if cfg.Storage.Path != "/var/lib/vault" || cfg.Storage.Retries != 5 {
+		t.Errorf("storage = %+v", cfg.Storage)
+	}
+}
+
+func TestFromHCLErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"reference", "region = var.region\n", "not supported"},
+		{"function", "id = uuid()\n", "not supported"},
+		{"null", "x = null\n", "null is not supported"},
+		{"negative", "x = -1\n", "negative number"},
+		{"dashed key", "max-size = 1\n", "not a valid WANF identifier"},
+		{"keyword key", "import = 1\n", "not a valid WANF identifier"},
+		{"quote in string", "x = \"say \\\"hi\\\"\"\n", "without escapes"},
+		{"unclosed block", "a {\n  b = 1\n", "line 3"},
+		{"unterminated string", "a = \"x\n", "unterminated string"},
+		{"missing separator", "a = [1 2]\n", "expected \",\" or \"]\""},
+	}
+	for _, tt := range tests {
+		_, err := FromHCL([]byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package wanfhttp 提供展示服务当前生效配置的 HTTP 调试接口:
+//
+//	http.Handle("/debug/config", wanfhttp.Handler(func() interface{} {
+//		return currentConfig() // 热加载后返回新的配置
+//	}))
+//
+// 配置按 wanf 标签编码为 WANF, 请求带有 ?format=json 或 Accept: application/json
+// 时返回 JSON. 带有 `wanf:",secret"` 标记的字段显示为 "<redacted>".
+// 接口会暴露配置的内容, 应只挂载在内部可访问的地址上.
+package wanfhttp
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// Handler 返回一个展示 current 所返回配置的 http.Handler. current 在每次
+// 请求时调用, 应返回结构体或结构体指针. 只接受 GET 与 HEAD 请求.
+func Handler(current func() interface{}) http.Handler {
+	return handler(current)
+}
+
+type handler func() interface{}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	asJSON, ok := wantJSON(r)
+	if !ok {
+		http.Error(w, "unknown format, expected wanf or json", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := wanf.NewEncoder(&buf, wanf.WithRedactedSecrets()).Encode(h()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, contentType := buf.Bytes(), "text/plain; charset=utf-8"
+	if asJSON {
+		var err error
+		if data, err = wanf.ToJSON(data, wanf.WithSandbox()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+// wantJSON 按 format 参数, 其次按 Accept 头选择输出格式. format 的值无效时
+// 第二个返回值为 false.
+func wantJSON(r *http.Request) (asJSON, ok bool) {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return true, true
+	case "wanf":
+		return false, true
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), "application/json"), true
+	}
+	return false, false
+}
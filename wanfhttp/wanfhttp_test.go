@@ -0,0 +1,79 @@
+package wanfhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name     string        `wanf:"name"`
+	Timeout  time.Duration `wanf:"timeout"`
+	Database struct {
+		User     string `wanf:"user"`
+		Password string `wanf:"password,secret"`
+	} `wanf:"database"`
+}
+
+func serve(t *testing.T, method, target, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	cfg := &testConfig{Name: "app", Timeout: 5 * time.Second}
+	cfg.Database.User = "admin"
+	cfg.Database.Password = "hunter2"
+	req := httptest.NewRequest(method, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	Handler(func() interface{} { return cfg }).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerWANF(t *testing.T) {
+	rec := serve(t, http.MethodGet, "/debug/config", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "hunter2") || !strings.Contains(body, `password = "<redacted>"`) {
+		t.Errorf("secret not redacted:\n%s", body)
+	}
+	if !strings.Contains(body, `name = "app"`) || !strings.Contains(body, "timeout = 5s") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestHandlerJSON(t *testing.T) {
+	for _, tt := range []struct{ target, accept string }{
+		{"/debug/config?format=json", ""},
+		{"/debug/config", "application/json"},
+	} {
+		rec := serve(t, http.MethodGet, tt.target, tt.accept)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.target, rec.Code, rec.Body)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: invalid JSON: %v\n%s", tt.target, err, rec.Body)
+		}
+		db := got["database"].(map[string]interface{})
+		if db["password"] != "<redacted>" || db["user"] != "admin" || got["timeout"] != "5s" {
+			t.Errorf("%s: got %v", tt.target, got)
+		}
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	if rec := serve(t, http.MethodPost, "/debug/config", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d", rec.Code)
+	}
+	if rec := serve(t, http.MethodGet, "/debug/config?format=xml", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("format=xml: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// bundler inlines the import tree of an entry file into a single document.
+type bundler struct {
+	root     string          // directory of the entry file, provenance paths are relative to it
+	included map[string]bool // absolute paths of files already inlined
+}
+
+// bundleFile writes the entry file with every import recursively expanded in
+// place to output. Each inlined file is wrapped in begin/end comments naming
+// its source, and a file imported more than once is only included the first
+// time, which matches how the decoder processes imports.
+func bundleFile(path, output string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("could not get absolute path for %s: %w", path, err)
+	}
+	b := &bundler{root: filepath.Dir(absPath), included: map[string]bool{absPath: true}}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", path, err)
+	}
+	out, err := b.inline(data, absPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	fmt.Printf("Bundled %d imports for %s into %s\n", len(b.included)-1, path, output)
+	return nil
+}
+
+// inline replaces every import statement of the file at srcPath with the
+// bundled content of the imported file.
+func (b *bundler) inline(data []byte, srcPath string) ([]byte, error) {
+	p := wanf.NewParser(wanf.NewLexer(data))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("parser errors in %s: %s", srcPath, p.Errors()[0].Error())
+	}
+
+	var edits []wanf.TextEdit
+	for _, stmt := range program.Statements {
+		imp, ok := stmt.(*wanf.ImportStatement)
+		if !ok {
+			continue
+		}
+		start, end := imp.Token.Offset, imp.Path.Token.EndOffset
+
+		target, err := filepath.Abs(filepath.Join(filepath.Dir(srcPath), string(imp.Path.Value)))
+		if err != nil {
+			return nil, fmt.Errorf("could not get absolute path for import %q: %w", string(imp.Path.Value), err)
+		}
+		if b.included[target] {
+			edits = append(edits, wanf.TextEdit{Start: start, End: end, Text: fmt.Sprintf("// import %q: already included above", string(imp.Path.Value))})
+			continue
+		}
+		b.included[target] = true
+		imported, err := os.ReadFile(target)
+		if err != nil {
+			return nil, fmt.Errorf("could not read imported file %s: %w", target, err)
+		}
+		content, err := b.inline(imported, target)
+		if err != nil {
+			return nil, err
+		}
+
+		source := b.provenance(target)
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "// --- begin import %q (%s) ---\n", string(imp.Path.Value), source)
+		if content = bytes.TrimRight(content, "\r\n"); len(content) > 0 {
+			buf.Write(content)
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "// --- end import %q ---", string(imp.Path.Value))
+		edits = append(edits, wanf.TextEdit{Start: start, End: end, Text: buf.String()})
+	}
+	return wanf.ApplyEdits(data, edits), nil
+}
+
+// provenance returns the path of an inlined file as shown in its begin comment.
+func (b *bundler) provenance(path string) string {
+	if rel, err := filepath.Rel(b.root, path); err == nil && isLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Exit codes of wanflint. CI pipelines can tell findings in otherwise valid
+// files apart from files that could not be processed at all.
+const (
+	exitOK       = 0 // success, no findings
+	exitFindings = 1 // lint issues, or more warnings than -max-warnings allows
+	exitError    = 2 // usage, I/O or parse errors
+)
+
+// findingsError is returned by a command that ran to completion but found
+// problems; runCLI maps it to exitFindings instead of exitError.
+type findingsError struct {
+	msg string
+}
+
+func (e *findingsError) Error() string { return e.msg }
+
+// command is a wanflint subcommand. Commands are registered in commands() and
+// dispatched by runCLI, which also derives the help output and the shell
+// completion scripts from them.
+type command struct {
+	name    string
+	args    string // argument synopsis shown in help, e.g. "[path ...]"
+	short   string // one-line description
+	long    string // optional details printed by "wanflint help <name>"
+	minArgs int
+	maxArgs int // -1 for no limit
+	flags   *flag.FlagSet
+	run     func(args []string) error
+}
+
+// newCommand returns a command with an empty flag set whose usage prints the
+// command's help.
+func newCommand(name, args, short string, minArgs, maxArgs int) *command {
+	c := &command{name: name, args: args, short: short, minArgs: minArgs, maxArgs: maxArgs}
+	c.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	c.flags.Usage = func() { c.printHelp(c.flags.Output()) }
+	return c
+}
+
+func (c *command) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: wanflint %s", c.name)
+	if c.hasFlags() {
+		fmt.Fprint(w, " [flags]")
+	}
+	if c.args != "" {
+		fmt.Fprintf(w, " %s", c.args)
+	}
+	fmt.Fprintf(w, "\n\n%s\n", c.short)
+	if c.long != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.long))
+	}
+	if c.hasFlags() {
+		fmt.Fprint(w, "\nFlags:\n")
+		out := c.flags.Output()
+		c.flags.SetOutput(w)
+		c.flags.PrintDefaults()
+		c.flags.SetOutput(out)
+	}
+}
+
+func (c *command) hasFlags() bool {
+	n := 0
+	c.flags.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// flagNames returns the names of the command's flags, sorted.
+func (c *command) flagNames() []string {
+	var names []string
+	c.flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	return names
+}
+
+const usageHeader = `wanflint: a tool for linting and formatting WANF files.
+
+Usage:
+  wanflint [global flags] <command> [arguments]
+
+A path of "-" reads from standard input; fmt then writes the result to
+standard output. Directories and "dir/..." are searched recursively for
+*.wanf files (skipping hidden, vendor and wanf_vendor directories), and
+glob patterns such as "configs/**/*.wanf" are expanded.
+`
+
+func printUsage(w io.Writer, global *flag.FlagSet, cmds []*command) {
+	fmt.Fprint(w, usageHeader)
+	fmt.Fprint(w, "\nCommands:\n")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-11s %s\n", c.name, c.short)
+	}
+	fmt.Fprint(w, "\nGlobal flags:\n")
+	out := global.Output()
+	global.SetOutput(w)
+	global.PrintDefaults()
+	global.SetOutput(out)
+	fmt.Fprint(w, "\nRun \"wanflint help <command>\" or \"wanflint <command> -h\" for details.\n")
+}
+
+// runCLI parses the global flags and runs the selected command, returning
+// the process exit code.
+func runCLI(args []string) int {
+	global := flag.NewFlagSet("wanflint", flag.ContinueOnError)
+	chdir := global.String("C", "", "Change to this directory before running the command")
+	version := global.Bool("version", false, "Print the wanflint version and exit")
+
+	cmds := commands()
+	cmds = append(cmds, helpCommand(global, &cmds), completionCommand(global, &cmds))
+	global.Usage = func() { printUsage(global.Output(), global, cmds) }
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if *version {
+		fmt.Println("wanflint", buildVersion())
+		return exitOK
+	}
+	if global.NArg() == 0 {
+		printUsage(os.Stderr, global, cmds)
+		return exitError
+	}
+	if *chdir != "" {
+		if err := os.Chdir(*chdir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	name := global.Arg(0)
+	c := findCommand(cmds, name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %q\n", name)
+		printUsage(os.Stderr, global, cmds)
+		return exitError
+	}
+	rest, err := parseInterspersed(c.flags, global.Args()[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if len(rest) < c.minArgs || (c.maxArgs >= 0 && len(rest) > c.maxArgs) {
+		fmt.Fprintf(os.Stderr, "Error: wrong number of arguments for %s.\n\n", c.name)
+		c.printHelp(os.Stderr)
+		return exitError
+	}
+	if err := c.run(rest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var findings *findingsError
+		if errors.As(err, &findings) {
+			return exitFindings
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+func findCommand(cmds []*command, name string) *command {
+	for _, c := range cmds {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseInterspersed parses args with fs, allowing flags to follow positional
+// arguments as in "vendor main.wanf -o bundle.wanf", and returns the positional
+// arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// helpCommand and completionCommand take a pointer to the command list since
+// they are part of it themselves.
+func helpCommand(global *flag.FlagSet, list *[]*command) *command {
+	c := newCommand("help", "[command]", "Show help for wanflint or a command", 0, 1)
+	c.run = func(args []string) error {
+		cmds := *list
+		if len(args) == 0 {
+			printUsage(os.Stdout, global, cmds)
+			return nil
+		}
+		target := findCommand(cmds, args[0])
+		if target == nil {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		target.printHelp(os.Stdout)
+		return nil
+	}
+	return c
+}
+
+func completionCommand(global *flag.FlagSet, list *[]*command) *command {
+	c := newCommand("completion", "<bash|zsh|fish>", "Generate a shell completion script", 1, 1)
+	c.long = `
+Load completions in the current shell with, for example:
+
+  source <(wanflint completion bash)
+  wanflint completion fish | source
+
+For zsh, write the script to a file named _wanflint in a directory on $fpath.`
+	c.run = func(args []string) error {
+		cmds := *list
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(os.Stdout, global, cmds)
+		case "zsh":
+			writeZshCompletion(os.Stdout, global, cmds)
+		case "fish":
+			writeFishCompletion(os.Stdout, global, cmds)
+		default:
+			return fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", args[0])
+		}
+		return nil
+	}
+	return c
+}
+
+func commandNames(cmds []*command) []string {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func globalFlagNames(global *flag.FlagSet) []string {
+	var names []string
+	global.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	return names
+}
+
+func writeBashCompletion(w io.Writer, global *flag.FlagSet, cmds []*command) {
+	fmt.Fprint(w, "# bash completion for wanflint\n_wanflint() {\n")
+	fmt.Fprint(w, "\tlocal cur cmd i\n\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n\tcmd=\"\"\n")
+	fmt.Fprint(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprint(w, "\t\tcase \"${COMP_WORDS[i]}\" in\n\t\t-C) ((i++)) ;;\n\t\t-*) ;;\n\t\t*) cmd=\"${COMP_WORDS[i]}\"; break ;;\n\t\tesac\n\tdone\n")
+	fmt.Fprint(w, "\tif [[ -z \"$cmd\" ]]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(append(commandNames(cmds), globalFlagNames(global)...), " "))
+	fmt.Fprint(w, "\t\treturn\n\tfi\n\tcase \"$cmd\" in\n")
+	for _, c := range cmds {
+		words := ""
+		for _, name := range c.flagNames() {
+			words += " -" + name
+		}
+		switch c.name {
+		case "help":
+			words += " " + strings.Join(commandNames(cmds), " ")
+		case "completion":
+			words += " bash zsh fish"
+		}
+		fmt.Fprintf(w, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", c.name, strings.TrimSpace(words))
+		if c.name != "help" && c.name != "completion" {
+			fmt.Fprint(w, "\t\tCOMPREPLY+=($(compgen -f -- \"$cur\"))\n")
+		}
+		fmt.Fprint(w, "\t\t;;\n")
+	}
+	fmt.Fprint(w, "\tesac\n}\ncomplete -o filenames -F _wanflint wanflint\n")
+}
+
+func writeZshCompletion(w io.Writer, global *flag.FlagSet, cmds []*command) {
+	fmt.Fprint(w, "#compdef wanflint\n\n_wanflint() {\n\tlocal -a commands\n\tcommands=(\n")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(c.name+":"+c.short))
+	}
+	fmt.Fprint(w, "\t)\n\t_arguments -C \\\n")
+	global.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "\t\t%s \\\n", shellQuote(zshFlagSpec(f)))
+	})
+	fmt.Fprint(w, "\t\t'1: :->command' \\\n\t\t'*:: :->args'\n")
+	fmt.Fprint(w, "\tcase $state in\n\tcommand)\n\t\t_describe 'command' commands\n\t\t;;\n\targs)\n\t\tcase $words[1] in\n")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "\t\t%s)\n\t\t\t_arguments \\\n", c.name)
+		c.flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "\t\t\t\t%s \\\n", shellQuote(zshFlagSpec(f)))
+		})
+		switch c.name {
+		case "help":
+			fmt.Fprint(w, "\t\t\t\t'1: :->command'\n\t\t\t[[ $state == command ]] && _describe 'command' commands\n")
+		case "completion":
+			fmt.Fprint(w, "\t\t\t\t'1:shell:(bash zsh fish)'\n")
+		default:
+			fmt.Fprint(w, "\t\t\t\t'*:file:_files'\n")
+		}
+		fmt.Fprint(w, "\t\t\t;;\n")
+	}
+	fmt.Fprint(w, "\t\tesac\n\t\t;;\n\tesac\n}\n\n_wanflint \"$@\"\n")
+}
+
+func zshFlagSpec(f *flag.Flag) string {
+	desc := strings.NewReplacer("[", "(", "]", ")", ":", " ").Replace(f.Usage)
+	if isBoolFlag(f) {
+		return fmt.Sprintf("-%s[%s]", f.Name, desc)
+	}
+	return fmt.Sprintf("-%s[%s]:%s:", f.Name, desc, f.Name)
+}
+
+func writeFishCompletion(w io.Writer, global *flag.FlagSet, cmds []*command) {
+	fmt.Fprint(w, "# fish completion for wanflint\n")
+	global.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "complete -c wanflint -n __fish_use_subcommand -o %s%s -d %s\n", f.Name, fishArg(f), fishQuote(f.Usage))
+	})
+	for _, c := range cmds {
+		fmt.Fprintf(w, "complete -c wanflint -f -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.short))
+	}
+	for _, c := range cmds {
+		cond := fishQuote("__fish_seen_subcommand_from " + c.name)
+		c.flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "complete -c wanflint -n %s -o %s%s -d %s\n", cond, f.Name, fishArg(f), fishQuote(f.Usage))
+		})
+		switch c.name {
+		case "help":
+			fmt.Fprintf(w, "complete -c wanflint -f -n %s -a %s\n", cond, fishQuote(strings.Join(commandNames(cmds), " ")))
+		case "completion":
+			fmt.Fprintf(w, "complete -c wanflint -f -n %s -a 'bash zsh fish'\n", cond)
+		}
+	}
+}
+
+func fishArg(f *flag.Flag) string {
+	if isBoolFlag(f) {
+		return ""
+	}
+	return " -r"
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, which escapes quotes inside single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLIUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		code       int
+		wantStdout string
+		wantStderr string
+	}{
+		{"no command", nil, exitError, "", "Usage:\n  wanflint [global flags] <command> [arguments]"},
+		{"unknown command", []string{"nope"}, exitError, "", "Unknown command: \"nope\"\nwanflint: a tool for linting"},
+		{"unknown global flag", []string{"-nope", "lint"}, exitError, "", "flag provided but not defined: -nope"},
+		{"global help", []string{"-h"}, exitOK, "", "Commands:\n  lint        Lint files and report issues\n"},
+		{"help", []string{"help"}, exitOK, "Run \"wanflint help <command>\"", ""},
+		{"help command", []string{"help", "diff"}, exitOK, "Usage: wanflint diff <old> <new>\n\nShow added, removed and changed keys between two files\n\nFormatting, comments", ""},
+		{"help flags", []string{"help", "merge"}, exitOK, "Usage: wanflint merge [flags] <base> <override ...>", ""},
+		{"help unknown command", []string{"help", "nope"}, exitError, "", "Error: unknown command \"nope\""},
+		{"command help flag", []string{"stats", "-h"}, exitOK, "", "Usage: wanflint stats [flags] <path ...>"},
+		{"too few arguments", []string{"diff", "a.wanf"}, exitError, "", "Error: wrong number of arguments for diff.\n\nUsage: wanflint diff"},
+		{"too many arguments", []string{"init", "x"}, exitError, "", "wrong number of arguments for init"},
+		{"unknown command flag", []string{"lint", "-nope", "a.wanf"}, exitError, "", "flag provided but not defined: -nope"},
+		{"version", []string{"-version"}, exitOK, "wanflint ", ""},
+		{"unsupported shell", []string{"completion", "tcsh"}, exitError, "", `unsupported shell "tcsh"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			stdout, stderr := capture(t, "", func() { code = runCLI(tt.args) })
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.code, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) || (tt.wantStdout == "" && stdout != "") {
+				t.Errorf("stdout = %q, want it to contain %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunCLIFlagsAfterArguments(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{"a.wanf": "a = 1\n"})
+	stdout, _ := capture(t, "", func() {
+		if code := runCLI([]string{"stats", paths["a.wanf"], "-json"}); code != exitOK {
+			t.Errorf("exit code = %d, want %d", code, exitOK)
+		}
+	})
+	if !strings.HasPrefix(stdout, "{") {
+		t.Errorf("-json after the path was not applied: %q", stdout)
+	}
+}
+
+func TestRunCLIChdir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.wanf": "a = 1\n"})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if code := runCLI([]string{"-C", dir, "lint", "a.wanf"}); code != exitOK {
+		t.Errorf("exit code = %d, want %d", code, exitOK)
+	}
+	var code int
+	_, stderr := capture(t, "", func() { code = runCLI([]string{"-C", filepath.Join(dir, "missing"), "lint", "a.wanf"}) })
+	if code != exitError || !strings.Contains(stderr, "no such file or directory") {
+		t.Errorf("missing directory: exit code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var code int
+			stdout, stderr := capture(t, "", func() { code = runCLI([]string{"completion", shell}) })
+			if code != exitOK {
+				t.Fatalf("exit code = %d, stderr: %s", code, stderr)
+			}
+			checkGolden(t, "completion/wanflint."+shell+".golden", stdout)
+			// Check the syntax of the script when the shell is installed.
+			path, err := exec.LookPath(shell)
+			if err != nil {
+				return
+			}
+			script := filepath.Join(t.TempDir(), "wanflint."+shell)
+			if err := os.WriteFile(script, []byte(stdout), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(path, "-n", script).CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v\n%s", shell, err, out)
+			}
+		})
+	}
+}
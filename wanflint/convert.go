@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// convertFormats lists the formats accepted by -from and -to.
+var convertFormats = []string{"wanf", "json", "yaml", "toml", "wanfb"}
+
+// convertFile converts the document at path from one format to another and
+// writes the result to output, or to standard output if output is empty.
+// An empty from is inferred from the file extension.
+func convertFile(path, from, to, output string) error {
+	if from == "" {
+		from = formatFromExt(path)
+		if from == "" {
+			return fmt.Errorf("cannot infer input format of %s; use -from", displayPath(path))
+		}
+	}
+	if to == "" {
+		to = "wanf"
+		if from == "wanf" {
+			to = "json"
+		}
+	}
+	for _, f := range []string{from, to} {
+		if formatFromName(f) == "" {
+			return fmt.Errorf("unknown format %q (supported: %s)", f, strings.Join(convertFormats, ", "))
+		}
+	}
+	data, err := readInput(path)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(path), err)
+	}
+	basePath := "."
+	if path != stdinPath {
+		basePath = filepath.Dir(path)
+	}
+	out, err := convertData(data, formatFromName(from), formatFromName(to), basePath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
+
+// convertData converts data between two of the formats in convertFormats.
+// basePath resolves relative imports when the input is WANF.
+func convertData(data []byte, from, to, basePath string) ([]byte, error) {
+	if from == "json" && to == "wanf" {
+		return wanf.FromJSON(data)
+	}
+	if from == "wanf" && to == "json" {
+		return wanf.ToJSON(data, wanf.WithBasePath(basePath))
+	}
+
+	var v map[string]interface{}
+	var err error
+	switch from {
+	case "wanf":
+		if to == "wanfb" {
+			// Keep durations as durations rather than strings.
+			var root *wanf.RootNode
+			if root, err = wanf.Parse(data); err == nil {
+				v, err = wanf.Eval(root, wanf.WithBasePath(basePath))
+			}
+			break
+		}
+		v, err = wanf.ToValue(data, wanf.WithBasePath(basePath))
+	case "json":
+		// Go through WANF so that integers keep their precision.
+		var src []byte
+		if src, err = wanf.FromJSON(data); err == nil {
+			v, err = wanf.ToValue(src)
+		}
+	case "yaml":
+		err = yaml.Unmarshal(data, &v)
+	case "toml":
+		err = toml.Unmarshal(data, &v)
+	case "wanfb":
+		v, err = wanf.UnmarshalBinary(data)
+		if err == nil && to != "wanf" && to != "wanfb" {
+			// Go through WANF so that durations become strings.
+			var src []byte
+			if src, err = wanf.FromValue(v); err == nil {
+				v, err = wanf.ToValue(src)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch to {
+	case "wanf":
+		return wanf.FromValue(v)
+	case "json":
+		src, err := wanf.FromValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return wanf.ToJSON(src)
+	case "yaml":
+		return yaml.Marshal(v)
+	case "toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "wanfb":
+		return wanf.MarshalBinary(v)
+	}
+	return nil, fmt.Errorf("unknown format %q", to)
+}
+
+// formatFromName normalizes a format name, accepting common aliases such as "yml".
+func formatFromName(name string) string {
+	switch strings.ToLower(name) {
+	case "wanf":
+		return "wanf"
+	case "json":
+		return "json"
+	case "yaml", "yml":
+		return "yaml"
+	case "toml":
+		return "toml"
+	case "wanfb", "binary":
+		return "wanfb"
+	}
+	return ""
+}
+
+func formatFromExt(path string) string {
+	return formatFromName(strings.TrimPrefix(filepath.Ext(path), "."))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	src := `name = "svc"
+port = 8080
+ratio = 0.5
+debug = true
+tags = ["a", "b"]
+server {
+	host = "localhost"
+	limits = {[
+		cpu = 2,
+	]}
+}
+`
+	want, err := wanf.ToValue([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"wanf", "json", "yaml", "toml", "wanfb"} {
+		t.Run(format, func(t *testing.T) {
+			out, err := convertData([]byte(src), "wanf", format, ".")
+			if err != nil {
+				t.Fatalf("wanf -> %s: %v", format, err)
+			}
+			back, err := convertData(out, format, "wanf", ".")
+			if err != nil {
+				t.Fatalf("%s -> wanf: %v\n%s", format, err, out)
+			}
+			got, err := wanf.ToValue(back)
+			if err != nil {
+				t.Fatalf("result is not valid WANF: %v\n%s", err, back)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip through %s = %v, want %v", format, got, want)
+			}
+		})
+	}
+}
+
+func TestConvertBinaryKeepsDurations(t *testing.T) {
+	out, err := convertData([]byte("timeout = 5s\n"), "wanf", "wanfb", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := convertData(out, "wanfb", "wanf", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(back)); got != "timeout = 5s" {
+		t.Errorf("wanfb -> wanf = %q, want timeout = 5s", got)
+	}
+	js, err := convertData(out, "wanfb", "json", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"5s"`) {
+		t.Errorf("wanfb -> json = %s, want the duration as a string", js)
+	}
+}
+
+func TestConvertFile(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeFiles(t, dir, map[string]string{
+		"app.wanf":    "import \"common.wanf\"\nname = \"svc\"\n",
+		"common.wanf": "port = 1\n",
+		"app.yml":     "name: svc\n",
+		"app.conf":    "name = \"svc\"\n",
+	})
+	out := filepath.Join(dir, "out")
+	tests := []struct {
+		name, path, from, to, want, wantErr string
+	}{
+		{"wanf defaults to json with imports", paths["app.wanf"], "", "", `"port": 1`, ""},
+		{"yml extension", paths["app.yml"], "", "", `name = "svc"`, ""},
+		{"explicit from", paths["app.conf"], "wanf", "toml", `name = "svc"`, ""},
+		{"unknown extension", paths["app.conf"], "", "", "", "cannot infer input format"},
+		{"unknown format", paths["app.wanf"], "", "xml", "", `unknown format "xml"`},
+		{"invalid input", paths["app.yml"], "json", "wanf", "", "app.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := convertFile(tt.path, tt.from, tt.to, out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("convertFile error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("output = %s, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// diffFiles prints the semantic differences between two files, one per line.
+// Formatting, comments and key order are ignored.
+func diffFiles(oldPath, newPath string) error {
+	var programs [2]*wanf.RootNode
+	for i, path := range []string{oldPath, newPath} {
+		data, err := readInput(path)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", displayPath(path), err)
+		}
+		program, err := parseFile(data, path)
+		if err != nil {
+			return err
+		}
+		programs[i] = program
+	}
+	changes := wanf.Diff(programs[0], programs[1])
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		return &findingsError{fmt.Sprintf("found %d difference(s)", len(changes))}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{
+		"old.wanf": `name = "svc"
+port = 8080
+server {
+	host = "a"
+	tls = true
+}
+`,
+		"new.wanf": `// Reordered and reformatted.
+server {
+	tls  = true
+	host = "b"
+}
+name = "svc"
+debug = true
+`,
+		"bad.wanf":  "name = \n",
+		"same.wanf": "port = 8080\nname = \"svc\"\nserver {\n\ttls = true\n\thost = \"a\"\n}\n",
+	})
+	tests := []struct {
+		name       string
+		old, new   string
+		code       int
+		wantStdout string
+		wantStderr string
+	}{
+		{"added, removed and changed", paths["old.wanf"], paths["new.wanf"], exitFindings,
+			"- port = 8080\n~ server.host: \"a\" -> \"b\"\n+ debug = true\n", "found 3 difference(s)"},
+		{"equivalent", paths["old.wanf"], paths["same.wanf"], exitOK, "", ""},
+		{"syntax error", paths["old.wanf"], paths["bad.wanf"], exitError, "", "bad.wanf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			stdout, stderr := capture(t, "", func() { code = runCLI([]string{"diff", tt.old, tt.new}) })
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.code, stderr)
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("stdout =\n%s\nwant\n%s", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// docEntry documents one key of a configuration.
+type docEntry struct {
+	Key         string // full path, e.g. database.max_conns or route "<name>".path
+	Type        string
+	Default     string
+	Description string
+}
+
+// docFile writes reference documentation for the keys of a configuration.
+// When file is set, keys, values and comments are taken from that WANF file;
+// otherwise they come from the Go struct typ in package directory pkg.
+func docFile(file, pkg, typ, format, output string) error {
+	var title, intro string
+	var entries []docEntry
+	if file != "" {
+		data, err := readInput(file)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+		}
+		p := wanf.NewParser(wanf.NewLexer(data))
+		p.SetLintMode(true)
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) > 0 {
+			return fmt.Errorf("%s: %s", displayPath(file), errs[0].Error())
+		}
+		title = displayPath(file)
+		entries = wanfDocEntries(program.Statements, "")
+	} else {
+		st, err := loadGoStruct(pkg, typ)
+		if err != nil {
+			return err
+		}
+		title, intro = st.Name, st.Doc
+		entries = structDocEntries(st.Fields, "")
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "md", "markdown":
+		writeMarkdownDoc(&buf, title, intro, entries)
+	case "html":
+		writeHTMLDoc(&buf, title, intro, entries)
+	default:
+		return fmt.Errorf("unknown doc format %q (want md or html)", format)
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
+
+func structDocEntries(fields []*structField, prefix string) []docEntry {
+	var entries []docEntry
+	for _, f := range fields {
+		key := prefix + f.Key
+		switch f.Kind {
+		case kindBlock:
+			entries = append(entries, docEntry{Key: key, Type: "block", Description: f.Doc})
+			entries = append(entries, structDocEntries(f.Fields, key+".")...)
+			continue
+		case kindLabeledBlock:
+			label := "name"
+			if strings.HasPrefix(f.Type, "map[") {
+				label = "key"
+			}
+			entries = append(entries, docEntry{Key: key, Type: "labeled block", Description: f.Doc})
+			entries = append(entries, structDocEntries(f.Fields, fmt.Sprintf("%s \"<%s>\".", key, label))...)
+			continue
+		}
+		entry := docEntry{Key: key, Type: f.Scalar, Description: f.Doc}
+		if f.Default != "" {
+			entry.Default = placeholder(f)
+		}
+		switch f.Kind {
+		case kindList:
+			entry.Type = "list of " + f.Elem
+		case kindMap:
+			entry.Type = "map of " + f.Elem
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func wanfDocEntries(stmts []wanf.Statement, prefix string) []docEntry {
+	var entries []docEntry
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *wanf.AssignStatement:
+			comments := s.LeadingComments
+			if s.LineComment != nil {
+				comments = append(comments[:len(comments):len(comments)], s.LineComment)
+			}
+			key := prefix + string(s.Name.Value)
+			entries = append(entries, docEntry{
+				Key:         key,
+				Type:        docValueType(s.Value),
+				Default:     formatInline(s.Value),
+				Description: commentText(comments),
+			})
+			if bl, ok := s.Value.(*wanf.BlockLiteral); ok {
+				entries = append(entries, wanfDocEntries(bl.Body.Statements, key+".")...)
+			}
+		case *wanf.BlockStatement:
+			key := prefix + string(s.Name.Value)
+			typ := "block"
+			for _, l := range s.Labels() {
+				key += fmt.Sprintf(" %q", l)
+				typ = "labeled block"
+			}
+			entries = append(entries, docEntry{Key: key, Type: typ, Description: commentText(s.LeadingComments)})
+			entries = append(entries, wanfDocEntries(s.Body.Statements, key+".")...)
+		}
+	}
+	return entries
+}
+
+func docValueType(expr wanf.Expression) string {
+	switch expr.(type) {
+	case *wanf.StringLiteral, *wanf.EnvExpression, *wanf.SecretExpression:
+		return "string"
+	case *wanf.IntegerLiteral:
+		return "int"
+	case *wanf.FloatLiteral:
+		return "float"
+	case *wanf.BoolLiteral:
+		return "bool"
+	case *wanf.DurationLiteral:
+		return "duration"
+	case *wanf.ListLiteral:
+		return "list"
+	case *wanf.MapLiteral:
+		return "map"
+	case *wanf.BlockLiteral:
+		return "block"
+	}
+	return ""
+}
+
+// formatInline formats a scalar or small literal on one line; block literals
+// are documented key by key instead.
+func formatInline(expr wanf.Expression) string {
+	if _, ok := expr.(*wanf.BlockLiteral); ok {
+		return ""
+	}
+	var buf bytes.Buffer
+	expr.Format(&buf, "", wanf.FormatOptions{Style: wanf.StyleSingleLine})
+	return buf.String()
+}
+
+// commentText strips comment markers and joins the comments into one paragraph.
+func commentText(comments []*wanf.Comment) string {
+	var lines []string
+	for _, c := range comments {
+		text := strings.TrimSpace(string(c.Text))
+		switch {
+		case strings.HasPrefix(text, "//"):
+			text = text[2:]
+		case strings.HasPrefix(text, "#"):
+			text = text[1:]
+		case strings.HasPrefix(text, "/*"):
+			text = strings.TrimSuffix(text[2:], "*/")
+		}
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+func writeMarkdownDoc(buf *bytes.Buffer, title, intro string, entries []docEntry) {
+	fmt.Fprintf(buf, "# %s\n\n", title)
+	if intro != "" {
+		fmt.Fprintf(buf, "%s\n\n", intro)
+	}
+	buf.WriteString("| Key | Type | Default | Description |\n")
+	buf.WriteString("| --- | --- | --- | --- |\n")
+	for _, e := range entries {
+		fmt.Fprintf(buf, "| %s | %s | %s | %s |\n",
+			markdownCode(e.Key), markdownCell(e.Type), markdownCode(e.Default), markdownCell(e.Description))
+	}
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+func writeHTMLDoc(buf *bytes.Buffer, title, intro string, entries []docEntry) {
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(buf, "<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+	fmt.Fprintf(buf, "<h1>%s</h1>\n", html.EscapeString(title))
+	if intro != "" {
+		fmt.Fprintf(buf, "<p>%s</p>\n", html.EscapeString(intro))
+	}
+	buf.WriteString("<table>\n<thead>\n<tr><th>Key</th><th>Type</th><th>Default</th><th>Description</th></tr>\n</thead>\n<tbody>\n")
+	for _, e := range entries {
+		fmt.Fprintf(buf, "<tr><td><code>%s</code></td><td>%s</td><td><code>%s</code></td><td>%s</td></tr>\n",
+			html.EscapeString(e.Key), html.EscapeString(e.Type), html.EscapeString(e.Default), html.EscapeString(e.Description))
+	}
+	buf.WriteString("</tbody>\n</table>\n</body>\n</html>\n")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file name in testdata, or rewrites
+// the file when the tests run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s", path, got)
+	}
+}
+
+func TestDocFile(t *testing.T) {
+	tests := []struct {
+		name, file, format, golden string
+	}{
+		{"wanf markdown", filepath.Join("testdata", "doc", "config.wanf"), "md", "doc/wanf.md.golden"},
+		{"wanf html", filepath.Join("testdata", "doc", "config.wanf"), "html", "doc/wanf.html.golden"},
+		{"struct markdown", "", "markdown", "doc/struct.md.golden"},
+		{"struct html", "", "html", "doc/struct.html.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "doc")
+			if err := docFile(tt.file, filepath.Join("testdata", "doc", "pkg"), "Config", tt.format, out); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, string(got))
+		})
+	}
+}
+
+func TestDocFileErrors(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{"bad.wanf": "a = \n"})
+	tests := []struct {
+		name, file, format, want string
+	}{
+		{"format", filepath.Join("testdata", "doc", "config.wanf"), "pdf", `unknown doc format "pdf"`},
+		{"syntax error", paths["bad.wanf"], "md", "bad.wanf"},
+		{"missing type", "", "md", "type Missing not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := docFile(tt.file, filepath.Join("testdata", "doc", "pkg"), "Missing", tt.format, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("docFile error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// editFile applies a set (value != nil) or unset to the file and writes it
+// back in place. Standard input is edited to standard output instead.
+func editFile(file, path string, value *string) error {
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	var out []byte
+	if value != nil {
+		out, err = wanf.SetPath(data, path, *value)
+	} else {
+		out, err = wanf.UnsetPath(data, path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(file), err)
+	}
+	if file == stdinPath {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(file, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// exportFormats lists the formats accepted by export -format.
+var exportFormats = []string{"properties", "env"}
+
+// exportFile prints the resolved document at file as flat key=value lines:
+// dotted keys such as server.main.port for "properties", or upper-case
+// variable names such as SERVER_MAIN_PORT for "env", ready for container
+// environment files. Lines are sorted by key.
+func exportFile(file, format, profile, output string) error {
+	if format != "properties" && format != "env" {
+		return fmt.Errorf("unknown export format %q (supported: %s)", format, strings.Join(exportFormats, ", "))
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	opts := []wanf.DecoderOption{wanf.WithBasePath(".")}
+	if file != stdinPath {
+		opts[0] = wanf.WithBasePath(filepath.Dir(file))
+	}
+	if profile != "" {
+		opts = append(opts, wanf.WithProfile(profile))
+	}
+	v, err := wanf.ToValue(data, opts...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(file), err)
+	}
+	flat := wanf.FlattenValue(v)
+	lines := make(map[string]string, len(flat))
+	for key, val := range flat {
+		if format == "env" {
+			name := envName(key)
+			if _, dup := lines[name]; dup {
+				return fmt.Errorf("%s: more than one key maps to the variable %s", displayPath(file), name)
+			}
+			lines[name] = envValue(val)
+			continue
+		}
+		lines[key] = val
+	}
+	keys := make([]string, 0, len(lines))
+	for k := range lines {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(k + "=" + lines[k] + "\n")
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
+
+// envName turns a flattened key into an environment variable name: letters
+// are upper-cased, every other character that is not a letter or digit
+// becomes '_', and a leading digit gets a '_' prefix.
+func envName(key string) string {
+	var b strings.Builder
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		b.WriteByte('_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			b.WriteByte(c - 'a' + 'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// envValue quotes val when it contains characters that a .env reader would
+// otherwise split or strip, such as spaces, quotes, '#' or newlines.
+func envValue(val string) string {
+	if val == "" || !strings.ContainsAny(val, " \t\r\n\"'#$\\`=") {
+		return val
+	}
+	return strconv.Quote(val)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// genKind classifies an inferred Go type.
+type genKind int
+
+const (
+	genScalar genKind = iota
+	genList
+	genMap
+	genStruct
+)
+
+// genType is a Go type inferred from WANF values.
+type genType struct {
+	kind   genKind
+	scalar string   // Go type of a genScalar, e.g. "string" or "time.Duration"
+	elem   *genType // element of a genList or genMap
+	name   string   // type name of a genStruct
+	fields []*genField
+}
+
+// genField is a field of an inferred struct. labels is the number of block
+// labels, each of which adds a map level around the struct.
+type genField struct {
+	key    string
+	typ    *genType
+	labels int
+	doc    string
+}
+
+var anyType = &genType{kind: genScalar, scalar: "interface{}"}
+
+// genFile infers a Go struct from the WANF document in file and writes the
+// generated source to output or standard output.
+func genFile(file, lang, typeName, pkg, output string) error {
+	if lang != "go" {
+		return fmt.Errorf("unsupported language %q (only go is supported)", lang)
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	program, err := parseFile(data, file)
+	if err != nil {
+		return err
+	}
+
+	g := &goGen{vars: make(map[string]wanf.Expression), names: make(map[string]bool)}
+	for _, stmt := range program.Statements {
+		if v, ok := stmt.(*wanf.VarStatement); ok {
+			g.vars[string(v.Name.Value)] = v.Value
+		}
+	}
+	root := g.structType(typeName, program.Statements)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by wanflint gen from %s. Edit as needed.\n\n", displayPath(file))
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if g.usesTime {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	g.writeStructs(&buf, root)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not compile: %w", err)
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	if err := os.WriteFile(output, src, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
+
+type goGen struct {
+	vars     map[string]wanf.Expression
+	names    map[string]bool // struct type names already taken
+	structs  []*genType      // in order of first appearance
+	usesTime bool
+}
+
+// structType infers a struct from a block body. Blocks with the same name are
+// merged into one field, so fields seen in any of them are included.
+func (g *goGen) structType(name string, stmts []wanf.Statement) *genType {
+	t := &genType{kind: genStruct, name: g.typeName(name)}
+	g.structs = append(g.structs, t)
+	g.addFields(t, stmts)
+	return t
+}
+
+func (g *goGen) addFields(t *genType, stmts []wanf.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *wanf.AssignStatement:
+			key := string(s.Name.Value)
+			g.addField(t, &genField{key: key, typ: g.exprType(key, s.Value), doc: commentText(s.LeadingComments)})
+		case *wanf.BlockStatement:
+			key := string(s.Name.Value)
+			if f := t.field(key); f != nil && f.typ.kind == genStruct {
+				g.addFields(f.typ, s.Body.Statements)
+				continue
+			}
+			g.addField(t, &genField{
+				key:    key,
+				typ:    g.structType(key, s.Body.Statements),
+				labels: len(s.Labels()),
+				doc:    commentText(s.LeadingComments),
+			})
+		}
+	}
+}
+
+// addField adds f to t, or merges its type into an existing field of the same key.
+func (g *goGen) addField(t *genType, f *genField) {
+	if existing := t.field(f.key); existing != nil {
+		existing.typ = g.unify(existing.typ, f.typ)
+		return
+	}
+	t.fields = append(t.fields, f)
+}
+
+func (t *genType) field(key string) *genField {
+	for _, f := range t.fields {
+		if f.key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+func (g *goGen) exprType(key string, expr wanf.Expression) *genType {
+	switch e := expr.(type) {
+	case *wanf.StringLiteral, *wanf.EnvExpression, *wanf.SecretExpression:
+		return &genType{kind: genScalar, scalar: "string"}
+	case *wanf.IntegerLiteral:
+		return &genType{kind: genScalar, scalar: "int"}
+	case *wanf.FloatLiteral:
+		return &genType{kind: genScalar, scalar: "float64"}
+	case *wanf.BoolLiteral:
+		return &genType{kind: genScalar, scalar: "bool"}
+	case *wanf.DurationLiteral:
+		g.usesTime = true
+		return &genType{kind: genScalar, scalar: "time.Duration"}
+	case *wanf.VarExpression:
+		if v, ok := g.vars[string(e.Name)]; ok {
+			return g.exprType(key, v)
+		}
+		return &genType{kind: genScalar, scalar: "string"}
+	case *wanf.ListLiteral:
+		var elem *genType
+		for _, el := range e.Elements {
+			elem = g.unify(elem, g.exprType(key, el))
+		}
+		if elem == nil {
+			elem = anyType
+		}
+		return &genType{kind: genList, elem: elem}
+	case *wanf.MapLiteral:
+		var elem *genType
+		for _, st := range e.Elements {
+			if as, ok := st.(*wanf.AssignStatement); ok {
+				elem = g.unify(elem, g.exprType(key, as.Value))
+			}
+		}
+		if elem == nil {
+			elem = anyType
+		}
+		return &genType{kind: genMap, elem: elem}
+	case *wanf.BlockLiteral:
+		return g.structType(key, e.Body.Statements)
+	}
+	return anyType
+}
+
+// unify returns a type that can hold values of both a and b.
+func (g *goGen) unify(a, b *genType) *genType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.kind != b.kind:
+		return anyType
+	}
+	switch a.kind {
+	case genScalar:
+		if a.scalar == b.scalar {
+			return a
+		}
+		if (a.scalar == "int" && b.scalar == "float64") || (a.scalar == "float64" && b.scalar == "int") {
+			return &genType{kind: genScalar, scalar: "float64"}
+		}
+		return anyType
+	case genList, genMap:
+		return &genType{kind: a.kind, elem: g.unify(a.elem, b.elem)}
+	case genStruct:
+		for _, f := range b.fields {
+			g.addField(a, f)
+		}
+		g.dropStruct(b)
+		return a
+	}
+	return anyType
+}
+
+// dropStruct removes a struct that was merged into another one.
+func (g *goGen) dropStruct(t *genType) {
+	for i, s := range g.structs {
+		if s == t {
+			g.structs = append(g.structs[:i], g.structs[i+1:]...)
+			return
+		}
+	}
+}
+
+// typeName returns an exported, unused Go type name for a WANF key.
+func (g *goGen) typeName(key string) string {
+	base := goName(key)
+	name := base
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.names[name] = true
+	return name
+}
+
+func (t *genType) goString() string {
+	switch t.kind {
+	case genList:
+		return "[]" + t.elem.goString()
+	case genMap:
+		return "map[string]" + t.elem.goString()
+	case genStruct:
+		return t.name
+	}
+	return t.scalar
+}
+
+func (g *goGen) writeStructs(buf *bytes.Buffer, root *genType) {
+	structs := append([]*genType(nil), g.structs...)
+	// Keep the root first; the others follow in order of appearance.
+	sort.SliceStable(structs, func(i, j int) bool { return structs[i] == root && structs[j] != root })
+	for i, t := range structs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "type %s struct {\n", t.name)
+		for _, f := range t.fields {
+			if f.doc != "" {
+				for _, line := range strings.Split(f.doc, "\n") {
+					fmt.Fprintf(buf, "\t// %s\n", line)
+				}
+			}
+			typ := f.typ.goString()
+			for i := 0; i < f.labels; i++ {
+				typ = "map[string]" + typ
+			}
+			fmt.Fprintf(buf, "\t%s %s `wanf:%q`\n", goName(f.key), typ, f.key)
+		}
+		buf.WriteString("}\n")
+	}
+}
+
+// goInitialisms are written in upper case in Go names, as in golint.
+var goInitialisms = map[string]bool{
+	"api": true, "dns": true, "http": true, "https": true, "id": true, "ip": true, "json": true,
+	"sql": true, "tcp": true, "tls": true, "ttl": true, "udp": true, "uri": true, "url": true, "uuid": true,
+}
+
+// goName converts a WANF key such as max_conns or maxConns to an exported Go
+// name such as MaxConns.
+func goName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' }) {
+		if goInitialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if b.Len() == 0 {
+		return "Field"
+	}
+	return b.String()
+}
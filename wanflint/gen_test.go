@@ -0,0 +1,88 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenFile(t *testing.T) {
+	const header = "package config\n\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"scalars",
+			"// Service name.\nname = \"svc\"\nport = 8080\nratio = 0.5\ndebug = true\nmax_conns = 10\napi_url = env(\"API\")\n",
+			header + "type Config struct {\n\t// Service name.\n\tName     string  `wanf:\"name\"`\n\tPort     int     `wanf:\"port\"`\n\tRatio    float64 `wanf:\"ratio\"`\n\tDebug    bool    `wanf:\"debug\"`\n\tMaxConns int     `wanf:\"max_conns\"`\n\tAPIURL   string  `wanf:\"api_url\"`\n}\n",
+		},
+		{
+			"duration and vars",
+			"var t = 5s\ntimeout = ${t}\nretry = 1s\n",
+			header + "import \"time\"\n\ntype Config struct {\n\tTimeout time.Duration `wanf:\"timeout\"`\n\tRetry   time.Duration `wanf:\"retry\"`\n}\n",
+		},
+		{
+			"lists and maps",
+			"tags = [\"a\", \"b\"]\nweights = [1, 2.5]\nmixed = [1, \"a\"]\nempty = []\nlimits = {[\n\tcpu = 1,\n\tmem = 2,\n]}\n",
+			header + "type Config struct {\n\tTags    []string       `wanf:\"tags\"`\n\tWeights []float64      `wanf:\"weights\"`\n\tMixed   []interface{}  `wanf:\"mixed\"`\n\tEmpty   []interface{}  `wanf:\"empty\"`\n\tLimits  map[string]int `wanf:\"limits\"`\n}\n",
+		},
+		{
+			"blocks",
+			"server \"a\" {\n\thost = \"x\"\n}\nserver \"b\" {\n\tport = 1\n}\ndatabase {\n\thost = \"db\"\n}\n",
+			header + "type Config struct {\n\tServer   map[string]Server `wanf:\"server\"`\n\tDatabase Database          `wanf:\"database\"`\n}\n\ntype Server struct {\n\tHost string `wanf:\"host\"`\n\tPort int    `wanf:\"port\"`\n}\n\ntype Database struct {\n\tHost string `wanf:\"host\"`\n}\n",
+		},
+		{
+			"name collision",
+			"config {\n\tx = 1\n}\n",
+			header + "type Config struct {\n\tConfig Config2 `wanf:\"config\"`\n}\n\ntype Config2 struct {\n\tX int `wanf:\"x\"`\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			in := filepath.Join(dir, "in.wanf")
+			out := filepath.Join(dir, "config.go")
+			if err := os.WriteFile(in, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := genFile(in, "go", "Config", "config", out); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), out, got, 0); err != nil {
+				t.Errorf("generated code does not parse: %v\n%s", err, got)
+			}
+			want := "// Code generated by wanflint gen from " + in + ". Edit as needed.\n\n" + tt.want
+			if string(got) != want {
+				t.Errorf("genFile =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestGenFileErrors(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{"in.wanf": "a = 1\n", "bad.wanf": "a = \n"})
+	tests := []struct {
+		name, file, lang, want string
+	}{
+		{"language", paths["in.wanf"], "rust", `unsupported language "rust"`},
+		{"syntax error", paths["bad.wanf"], "go", "bad.wanf"},
+		{"missing file", filepath.Join(t.TempDir(), "missing.wanf"), "go", "could not read file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := genFile(tt.file, tt.lang, "Config", "config", filepath.Join(t.TempDir(), "out.go"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("genFile error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+)
+
+// fastDecoders maps Go types to the wanf.BodyDecoder method that decodes them
+// without reflection.
+var fastDecoders = map[string]string{
+	"string":        "String",
+	"bool":          "Bool",
+	"int":           "Int",
+	"int64":         "Int64",
+	"uint":          "Uint",
+	"uint64":        "Uint64",
+	"float64":       "Float64",
+	"time.Duration": "Duration",
+	"[]string":      "Strings",
+}
+
+// genDecoder writes UnmarshalWANF methods for the comma-separated struct
+// types in the Go package directory pkg, and for every struct type of the
+// package they contain, so nested blocks are decoded without reflection too.
+func genDecoder(pkg, types, output string) error {
+	l, err := newStructLoader(pkg)
+	if err != nil {
+		return err
+	}
+	var structs []*goStruct
+	seen := map[string]bool{}
+	queue := strings.Split(types, ",")
+	for len(queue) > 0 {
+		name := strings.TrimSpace(queue[0])
+		queue = queue[1:]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		st, err := l.load(name)
+		if err != nil {
+			return err
+		}
+		structs = append(structs, st)
+		for _, f := range st.Fields {
+			if nested := l.nestedStructName(f); nested != "" {
+				queue = append(queue, nested)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by wanflint gen-decoder. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", l.pkg)
+	imports := []string{`"github.com/WJQSERVER/wanf"`}
+	for _, st := range structs {
+		if len(foldFields(st.Fields)) > 0 {
+			imports = append([]string{`"strings"`, ""}, imports...)
+			break
+		}
+	}
+	fmt.Fprintf(&buf, "import (\n%s\n)\n", strings.Join(imports, "\n"))
+	for _, st := range structs {
+		writeUnmarshal(&buf, st, seen)
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not compile: %w", err)
+	}
+
+	if output == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
+
+// nestedStructName returns the name of the struct type declared in the
+// package that f is decoded into, or "" if there is none.
+func (l *structLoader) nestedStructName(f *structField) string {
+	var typ string
+	switch f.Kind {
+	case kindBlock:
+		typ = f.Type
+	case kindLabeledBlock:
+		typ = f.Elem
+	default:
+		return ""
+	}
+	typ = strings.TrimPrefix(typ, "*")
+	if ts, ok := l.types[typ]; ok && l.structOf(ts.Name) != nil {
+		return typ
+	}
+	return ""
+}
+
+// writeUnmarshal writes the UnmarshalWANF method of st. Keys are matched like
+// the reflection decoder does: exactly, and case-insensitively for fields
+// whose key is their Go name. generated holds the types that get a method.
+func writeUnmarshal(buf *bytes.Buffer, st *goStruct, generated map[string]bool) {
+	// As in the reflection decoder, a later field with the same key wins.
+	var keys []string
+	byKey := map[string]*structField{}
+	for _, f := range st.Fields {
+		if _, ok := byKey[f.Key]; !ok {
+			keys = append(keys, f.Key)
+		}
+		byKey[f.Key] = f
+	}
+	fold := foldFields(st.Fields)
+
+	fmt.Fprintf(buf, "\n// UnmarshalWANF implements wanf.Unmarshaler.\n")
+	fmt.Fprintf(buf, "func (v *%s) UnmarshalWANF(d *wanf.BodyDecoder) error {\n", st.Name)
+	buf.WriteString("for d.Next() {\nvar err error\n")
+	if len(fold) > 0 {
+		buf.WriteString("switch key := d.Key(); key {\n")
+	} else {
+		buf.WriteString("switch d.Key() {\n")
+	}
+	for _, key := range keys {
+		fmt.Fprintf(buf, "case %q:\n", key)
+		writeFieldDecode(buf, byKey[key], generated)
+	}
+	if len(fold) > 0 {
+		buf.WriteString("default:\nswitch strings.ToLower(key) {\n")
+		for _, f := range fold {
+			fmt.Fprintf(buf, "case %q:\n", strings.ToLower(f.Key))
+			writeFieldDecode(buf, f, generated)
+		}
+		buf.WriteString("}\n")
+	}
+	buf.WriteString("}\nif err != nil {\nreturn err\n}\n}\nreturn nil\n}\n")
+}
+
+// foldFields returns the fields that also match their key case-insensitively,
+// which the reflection decoder does for fields whose key is their Go name.
+func foldFields(fields []*structField) []*structField {
+	var fold []*structField
+	seen := map[string]bool{}
+	for _, f := range fields {
+		lower := strings.ToLower(f.Key)
+		if f.Key != f.GoName || seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		fold = append(fold, f)
+	}
+	return fold
+}
+
+// writeFieldDecode writes the statement decoding the current key into f. Fields
+// without a dedicated method, or with options such as min= or key=, fall back
+// to reflection through BodyDecoder.Decode.
+func writeFieldDecode(buf *bytes.Buffer, f *structField, generated map[string]bool) {
+	plain := true
+	for _, opt := range strings.Split(f.Tag, ",")[1:] {
+		if o := strings.TrimSpace(opt); o != "" && o != "omitempty" {
+			plain = false
+		}
+	}
+	if plain {
+		if method, ok := fastDecoders[f.Type]; ok {
+			fmt.Fprintf(buf, "err = d.%s(&v.%s)\n", method, f.GoName)
+			return
+		}
+		if f.Kind == kindBlock && generated[f.Type] {
+			fmt.Fprintf(buf, "err = d.Block(&v.%s)\n", f.GoName)
+			return
+		}
+		if f.Kind == kindBlock && strings.HasPrefix(f.Type, "*") && generated[f.Type[1:]] {
+			fmt.Fprintf(buf, "if v.%s == nil {\nv.%s = new(%s)\n}\n", f.GoName, f.GoName, f.Type[1:])
+			fmt.Fprintf(buf, "err = d.Block(v.%s)\n", f.GoName)
+			return
+		}
+	}
+	fmt.Fprintf(buf, "err = d.Decode(&v.%s, %q)\n", f.GoName, f.Tag)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenDecoder regenerates the decoders of internal/gentest and compares
+// them with the committed config_wanf.go, whose behaviour is tested there.
+// Run "go generate ./internal/gentest" after changing the generator.
+func TestGenDecoder(t *testing.T) {
+	dir := filepath.Join("internal", "gentest")
+	out := filepath.Join(t.TempDir(), "config_wanf.go")
+	if err := genDecoder(dir, "Config", out); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), out, got, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	if formatted, err := format.Source(got); err != nil || !bytes.Equal(formatted, got) {
+		t.Errorf("generated code is not gofmt-formatted (err = %v)", err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "config_wanf.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s:\n%s", filepath.Join(dir, "config_wanf.go"), got)
+	}
+}
+
+func TestGenDecoderErrors(t *testing.T) {
+	dir := filepath.Join("internal", "gentest")
+	for _, tc := range []struct{ types, want string }{
+		{"Missing", "type Missing not found in package gentest"},
+		{"Config,Missing", "type Missing not found in package gentest"},
+	} {
+		err := genDecoder(dir, tc.types, filepath.Join(t.TempDir(), "out.go"))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("genDecoder(%q) error = %v, want %q", tc.types, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// fieldKind describes how a Go struct field appears in a WANF file.
+type fieldKind int
+
+const (
+	kindValue        fieldKind = iota // key = value
+	kindList                          // key = [...]
+	kindMap                           // key = {[...]}
+	kindBlock                         // key { ... }
+	kindLabeledBlock                  // key "label" { ... }, from map[string]T or []T with T a struct
+)
+
+// structField is a field of a Go struct as seen through its wanf tag.
+type structField struct {
+	Key     string // name used in WANF files
+	GoName  string
+	Type    string // Go type as written in the source
+	Kind    fieldKind
+	Scalar  string // WANF type of a kindValue field: string, int, float, bool, duration or the Go type
+	Elem    string // element type of lists, maps and labeled blocks
+	Doc     string
+	Default string // value of the `default` struct tag, if any
+	Tag     string // the wanf struct tag as written
+	Fields  []*structField
+}
+
+// goStruct is a struct type loaded from Go source.
+type goStruct struct {
+	Name   string
+	Doc    string
+	Fields []*structField
+}
+
+// structLoader resolves struct types declared in a single Go package directory.
+// It works on the syntax tree only, so the package does not need to build.
+type structLoader struct {
+	pkg     string // package name
+	types   map[string]*ast.TypeSpec
+	docs    map[string]string
+	loading map[string]bool // types being resolved, to stop on recursive types
+}
+
+// loadGoStruct parses the Go files in dir and returns the struct type named name.
+func loadGoStruct(dir, name string) (*goStruct, error) {
+	l, err := newStructLoader(dir)
+	if err != nil {
+		return nil, err
+	}
+	return l.load(name)
+}
+
+// newStructLoader parses the Go files in dir, skipping tests.
+func newStructLoader(dir string) (*structLoader, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	l := &structLoader{types: map[string]*ast.TypeSpec{}, docs: map[string]string{}, loading: map[string]bool{}}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		l.pkg = f.Name.Name
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				l.types[ts.Name.Name] = ts
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				l.docs[ts.Name.Name] = strings.TrimSpace(doc.Text())
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return l, nil
+}
+
+// load returns the struct type named name.
+func (l *structLoader) load(name string) (*goStruct, error) {
+	ts, ok := l.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", name, l.pkg)
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+	l.loading[name] = true
+	defer delete(l.loading, name)
+	return &goStruct{Name: name, Doc: l.docs[name], Fields: l.fields(st)}, nil
+}
+
+func (l *structLoader) fields(st *ast.StructType) []*structField {
+	var fields []*structField
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+		names := f.Names
+		if len(names) == 0 {
+			// Embedded fields are named after their type, as in reflect.
+			names = []*ast.Ident{ast.NewIdent(typeName(f.Type))}
+		}
+		doc := strings.TrimSpace(f.Doc.Text())
+		if doc == "" {
+			doc = strings.TrimSpace(f.Comment.Text())
+		}
+		for _, n := range names {
+			if !n.IsExported() {
+				continue
+			}
+			wanfTag := tag.Get("wanf")
+			parts := strings.Split(wanfTag, ",")
+			if hasTagOption(parts[1:], "labels") {
+				continue // filled from block labels, not written in the body
+			}
+			key := parts[0]
+			if key == "" {
+				key = n.Name
+			}
+			field := &structField{
+				Key:     key,
+				GoName:  n.Name,
+				Type:    exprString(f.Type),
+				Doc:     doc,
+				Default: tag.Get("default"),
+				Tag:     wanfTag,
+			}
+			l.classify(field, f.Type)
+			if field.Kind == kindValue {
+				field.Scalar = l.wanfType(field.Type)
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// classify sets the kind of field from its Go type, loading nested structs.
+func (l *structLoader) classify(field *structField, expr ast.Expr) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		field.Elem = exprString(t.Elt)
+		if st := l.structOf(t.Elt); st != nil {
+			field.Kind = kindLabeledBlock
+			field.Fields = l.nested(t.Elt, st)
+			return
+		}
+		field.Kind = kindList
+	case *ast.MapType:
+		field.Elem = exprString(t.Value)
+		if st := l.structOf(t.Value); st != nil {
+			field.Kind = kindLabeledBlock
+			field.Fields = l.nested(t.Value, st)
+			return
+		}
+		field.Kind = kindMap
+	case *ast.StructType:
+		field.Kind = kindBlock
+		field.Fields = l.fields(t)
+	case *ast.Ident:
+		if st := l.structOf(t); st != nil {
+			field.Kind = kindBlock
+			field.Fields = l.nested(t, st)
+		}
+	}
+}
+
+// structOf returns the struct type expr refers to, if it is declared in the package.
+func (l *structLoader) structOf(expr ast.Expr) *ast.StructType {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return t
+	case *ast.Ident:
+		if ts, ok := l.types[t.Name]; ok {
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+func (l *structLoader) nested(expr ast.Expr, st *ast.StructType) []*structField {
+	name := typeName(expr)
+	if l.loading[name] {
+		return nil
+	}
+	l.loading[name] = true
+	defer delete(l.loading, name)
+	return l.fields(st)
+}
+
+// underlying returns the basic type a named type in the package is declared
+// with, so that `type Level string` is treated as a string.
+func (l *structLoader) underlying(typ string) string {
+	for i := 0; i < 10; i++ {
+		ts, ok := l.types[typ]
+		if !ok {
+			break
+		}
+		typ = exprString(ts.Type)
+	}
+	return typ
+}
+
+func hasTagOption(options []string, name string) bool {
+	for _, o := range options {
+		if strings.TrimSpace(o) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "[...]" + exprString(t.Elt)
+		}
+		return "[]" + exprString(t.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	case *ast.StructType:
+		return "struct{...}"
+	case *ast.InterfaceType:
+		return "interface{}"
+	}
+	return "?"
+}
+
+// wanfType returns the WANF type name of a Go type: string, int, float,
+// bool, duration, or the Go type itself when there is no direct equivalent.
+func (l *structLoader) wanfType(goType string) string {
+	goType = strings.TrimPrefix(l.underlying(strings.TrimPrefix(goType, "*")), "*")
+	switch goType {
+	case "string":
+		return "string"
+	case "bool":
+		return "bool"
+	case "time.Duration":
+		return "duration"
+	case "float32", "float64":
+		return "float"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "int"
+	}
+	return goType
+}
+
+// placeholder returns the value written for a field in a skeleton: its
+// default if it has one, otherwise the zero value of its type.
+func placeholder(f *structField) string {
+	switch f.Kind {
+	case kindList:
+		if f.Default != "" {
+			return f.Default
+		}
+		return "[]"
+	case kindMap:
+		if f.Default != "" {
+			return f.Default
+		}
+		return "{[]}"
+	}
+	if f.Default != "" {
+		if f.Scalar == "string" && !strings.HasPrefix(f.Default, `"`) {
+			return strconv.Quote(f.Default)
+		}
+		return f.Default
+	}
+	switch f.Scalar {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	case "duration":
+		return "0s"
+	case "float":
+		return "0.0"
+	case "int":
+		return "0"
+	}
+	return `""`
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitConfig(t *testing.T) {
+	const pkg = "package config\n\nimport \"time\"\n\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"scalars and defaults",
+			`// Config is the service configuration.
+type Config struct {
+	// Name of the service.
+	Name    string        ` + "`wanf:\"name\" default:\"svc\"`" + `
+	Port    int           ` + "`wanf:\"port\" default:\"8080\"`" + `
+	Ratio   float64       ` + "`wanf:\"ratio\"`" + `
+	Debug   bool
+	Timeout time.Duration ` + "`wanf:\"timeout\" default:\"5s\"`" + `
+	Level   Level         ` + "`wanf:\"level\"`" + `
+	secret  string
+}
+
+type Level string
+`,
+			`//
+// Config is the service configuration.
+
+// Name of the service.
+name = "svc" // string
+port = 8080 // int
+ratio = 0.0 // float64
+Debug = false // bool
+timeout = 5s // time.Duration
+level = "" // Level
+`,
+		},
+		{
+			"nested structs",
+			`type Config struct {
+	Server Server ` + "`wanf:\"server\"`" + `
+	DB     *struct {
+		Host string ` + "`wanf:\"host\" default:\"localhost\"`" + `
+	} ` + "`wanf:\"db\"`" + `
+}
+
+type Server struct {
+	Labels []string ` + "`wanf:\",labels\"`" + `
+	Port   int      ` + "`wanf:\"port\"`" + `
+	Inner  Inner    ` + "`wanf:\"inner\"`" + `
+}
+
+type Inner struct {
+	On bool ` + "`wanf:\"on\"`" + `
+}
+`,
+			`
+server {
+	port = 0 // int
+
+	inner {
+		on = false // bool
+	}
+}
+
+db {
+	host = "localhost" // string
+}
+`,
+		},
+		{
+			"slices and maps",
+			`type Config struct {
+	Tags    []string            ` + "`wanf:\"tags\" default:\"[\\\"a\\\"]\"`" + `
+	Ports   []int               ` + "`wanf:\"ports\"`" + `
+	Labels  map[string]string   ` + "`wanf:\"labels\"`" + `
+	Routes  map[string]Route    ` + "`wanf:\"route\"`" + `
+	Rules   []Route             ` + "`wanf:\"rule\"`" + `
+}
+
+type Route struct {
+	Target string ` + "`wanf:\"target\"`" + `
+}
+`,
+			`
+tags = ["a"] // []string
+ports = [] // []int
+labels = {[]} // map[string]string
+
+// Repeat this block for each entry; the label is the map key.
+route "example" {
+	target = "" // string
+}
+
+// Repeat this block for each element of the list.
+rule "example" {
+	target = "" // string
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := writeFiles(t, dir, map[string]string{"config.go": pkg + tt.src})
+			out := filepath.Join(dir, "config.wanf")
+			stdout, _ := capture(t, "", func() {
+				if err := initConfig(dir, "Config", out); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if want := "Wrote " + out + "\n"; stdout != want {
+				t.Errorf("stdout = %q, want %q", stdout, want)
+			}
+			got, _ := capture(t, "", func() {
+				if err := initConfig(dir, "Config", ""); err != nil {
+					t.Fatal(err)
+				}
+			})
+			want := "// Generated by wanflint init from " + filepath.ToSlash(filepath.Dir(paths["config.go"])) + ".Config.\n" + tt.want
+			if got != want {
+				t.Errorf("skeleton =\n%s\nwant\n%s", got, want)
+			}
+			if _, err := parseFile([]byte(got), "skeleton"); err != nil {
+				t.Errorf("skeleton does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestInitConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeFiles(t, dir, map[string]string{
+		"config.go":   "package config\n\ntype Config struct {\n\tName string\n}\n\ntype Level string\n",
+		"config.wanf": "",
+	})
+	tests := []struct {
+		name, typ, output, want string
+	}{
+		{"missing type", "Missing", "", "type Missing not found in package config"},
+		{"not a struct", "Level", "", "type Level is not a struct"},
+		{"existing output", "Config", paths["config.wanf"], "already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := initConfig(dir, tt.typ, tt.output)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("initConfig error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+	if err := initConfig(t.TempDir(), "Config", ""); err == nil || !strings.Contains(err.Error(), "no Go files") {
+		t.Errorf("empty directory: error = %v, want no Go files", err)
+	}
+}
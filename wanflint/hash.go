@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// hashFiles prints the canonical content hash of each file in the style of
+// sha256sum, so that deployment tooling can tell whether a change is more
+// than formatting.
+func hashFiles(paths []string, profile string) error {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := readInput(file)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+		}
+		opts := []wanf.DecoderOption{wanf.WithBasePath(".")}
+		if file != stdinPath {
+			opts[0] = wanf.WithBasePath(filepath.Dir(file))
+		}
+		if profile != "" {
+			opts = append(opts, wanf.WithProfile(profile))
+		}
+		sum, err := wanf.Hash(data, opts...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayPath(file), err)
+		}
+		fmt.Printf("%s  %s\n", sum, displayPath(file))
+	}
+	return nil
+}
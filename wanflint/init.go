@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// initConfig writes a commented skeleton .wanf file for the struct type typ
+// declared in the Go package directory pkg.
+func initConfig(pkg, typ, output string) error {
+	st, err := loadGoStruct(pkg, typ)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Generated by wanflint init from %s.%s.\n", filepath.ToSlash(filepath.Clean(pkg)), st.Name)
+	if st.Doc != "" {
+		buf.WriteString("//\n")
+		writeComment(&buf, "", st.Doc)
+	}
+	buf.WriteString("\n")
+	writeSkeleton(&buf, st.Fields, "")
+
+	// The skeleton must be valid WANF, otherwise it is no use as a starting point.
+	if _, err := parseFile(buf.Bytes(), "skeleton for "+st.Name); err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists", output)
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	fmt.Printf("Wrote %s\n", output)
+	return nil
+}
+
+// writeSkeleton writes one statement per field, each preceded by the field's
+// documentation. Values are followed by their Go type; blocks and documented
+// fields are separated by blank lines.
+func writeSkeleton(buf *bytes.Buffer, fields []*structField, indent string) {
+	for i, f := range fields {
+		if i > 0 && (f.Doc != "" || f.Kind >= kindBlock || fields[i-1].Kind >= kindBlock) {
+			buf.WriteString("\n")
+		}
+		writeComment(buf, indent, f.Doc)
+		switch f.Kind {
+		case kindBlock:
+			fmt.Fprintf(buf, "%s%s {\n", indent, f.Key)
+			writeSkeleton(buf, f.Fields, indent+"\t")
+			fmt.Fprintf(buf, "%s}\n", indent)
+		case kindLabeledBlock:
+			if strings.HasPrefix(f.Type, "map[") {
+				fmt.Fprintf(buf, "%s// Repeat this block for each entry; the label is the map key.\n", indent)
+			} else {
+				fmt.Fprintf(buf, "%s// Repeat this block for each element of the list.\n", indent)
+			}
+			fmt.Fprintf(buf, "%s%s \"example\" {\n", indent, f.Key)
+			writeSkeleton(buf, f.Fields, indent+"\t")
+			fmt.Fprintf(buf, "%s}\n", indent)
+		default:
+			fmt.Fprintf(buf, "%s%s = %s // %s\n", indent, f.Key, placeholder(f), f.Type)
+		}
+	}
+}
+
+func writeComment(buf *bytes.Buffer, indent, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			fmt.Fprintf(buf, "%s//\n", indent)
+			continue
+		}
+		fmt.Fprintf(buf, "%s// %s\n", indent, line)
+	}
+}
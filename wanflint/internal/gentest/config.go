@@ -0,0 +1,38 @@
+// Package gentest holds the fixture types for wanflint gen-decoder. The
+// UnmarshalWANF methods in config_wanf.go are generated from them and double
+// as the generator's golden output.
+package gentest
+
+//go:generate go run ../.. gen-decoder -type Config -o config_wanf.go
+
+import (
+	"errors"
+	"time"
+)
+
+// Server is decoded through its generated UnmarshalWANF method.
+type Server struct {
+	Labels  []string      `wanf:",labels"`
+	Host    string        `wanf:"host"`
+	Port    int           `wanf:"port"`
+	Timeout time.Duration `wanf:"timeout"`
+	Level   int           `wanf:"level,min=1"`
+}
+
+func (v *Server) Validate() error {
+	if v.Host == "bad" {
+		return errors.New("bad host")
+	}
+	return nil
+}
+
+// Config covers every kind of field the generator handles: direct setters,
+// case-insensitive keys, pointer blocks and the reflection fallback.
+type Config struct {
+	Name    string
+	Debug   bool              `wanf:"debug"`
+	Ratio   float64           `wanf:"ratio"`
+	Tags    []string          `wanf:"tags"`
+	Server  *Server           `wanf:"server"`
+	Servers map[string]Server `wanf:"servers"`
+}
@@ -0,0 +1,80 @@
+package gentest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// reflectServer and reflectConfig have the fields of Server and Config but no
+// UnmarshalWANF methods, so they are decoded through reflection.
+type reflectServer struct {
+	Labels  []string      `wanf:",labels"`
+	Host    string        `wanf:"host"`
+	Port    int           `wanf:"port"`
+	Timeout time.Duration `wanf:"timeout"`
+	Level   int           `wanf:"level,min=1"`
+}
+
+type reflectConfig struct {
+	Name    string
+	Debug   bool                     `wanf:"debug"`
+	Ratio   float64                  `wanf:"ratio"`
+	Tags    []string                 `wanf:"tags"`
+	Server  *reflectServer           `wanf:"server"`
+	Servers map[string]reflectServer `wanf:"servers"`
+}
+
+func TestGeneratedDecoder(t *testing.T) {
+	src := `var p = 9000
+NAME = "svc"
+debug = "true"
+ratio = 2
+tags = ["a", "b"]
+server {
+	host = "localhost"
+	port = ${p}
+	timeout = 5s
+	level = 3
+}
+servers "a" {
+	host = "a.local"
+	port = 1
+}
+`
+	var got Config
+	if err := wanf.Decode([]byte(src), &got); err != nil {
+		t.Fatalf("Decode with generated decoder: %v", err)
+	}
+	var want reflectConfig
+	if err := wanf.Decode([]byte(src), &want); err != nil {
+		t.Fatalf("Decode with reflection: %v", err)
+	}
+	if got.Name != want.Name || got.Debug != want.Debug || got.Ratio != want.Ratio || !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("scalars = %+v, want %+v", got, want)
+	}
+	if got.Server == nil || !reflect.DeepEqual(reflectServer(*got.Server), *want.Server) {
+		t.Errorf("server = %+v, want %+v", got.Server, want.Server)
+	}
+	if a := got.Servers["a"]; a.Host != "a.local" || !reflect.DeepEqual(a.Labels, []string{"a"}) {
+		t.Errorf("servers = %+v, want %+v", got.Servers, want.Servers)
+	}
+
+	errCases := []struct {
+		src, want string
+	}{
+		{"server {\n\tport = \"x\"\n}\n", "cannot set field of type int"},
+		{"server {\n\tlevel = 0\n}\n", "level"},
+		{"server {\n\thost = \"bad\"\n}\n", "bad host"},
+	}
+	for _, tc := range errCases {
+		var c Config
+		err := wanf.Decode([]byte(tc.src), &c)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Decode(%q) error = %v, want it to contain %q", tc.src, err, tc.want)
+		}
+	}
+}
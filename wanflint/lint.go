@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// lintResult is the outcome of linting one file.
+type lintResult struct {
+	path        string
+	errs        []wanf.LintError
+	readErr     error
+	parseFailed bool // the file has syntax errors, reported in errs
+}
+
+// lintParallel lints paths with up to jobs workers. Results are returned
+// sorted by path, with the diagnostics of each file sorted by position, so the
+// output does not depend on scheduling.
+func lintParallel(paths []string, cfg *wanf.LintConfig, schema *wanf.Schema, jobs int) []lintResult {
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
+	results := make([]lintResult, len(paths))
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > len(paths) {
+		jobs = len(paths)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = lintOne(paths[i], cfg, schema)
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func lintOne(path string, cfg *wanf.LintConfig, schema *wanf.Schema) lintResult {
+	data, err := readInput(path)
+	if err != nil {
+		return lintResult{path: path, readErr: err}
+	}
+	// Parse once without lint mode, as Decode does: in lint mode syntax
+	// errors are only reported as lint findings.
+	p := wanf.NewParser(wanf.NewLexer(data))
+	p.ParseProgram()
+	parseFailed := len(p.Errors()) > 0
+
+	opts := []wanf.LintOption{wanf.WithLintConfig(cfg), wanf.WithLintBasePath(filepath.Dir(path))}
+	if schema != nil {
+		opts = append(opts, wanf.WithSchema(schema))
+	}
+	_, errs := wanf.Lint(data, opts...)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return lintResult{path: path, errs: errs, parseFailed: parseFailed}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeFiles creates the named files below dir and returns their paths.
+func writeFiles(t *testing.T, dir string, files map[string]string) map[string]string {
+	t.Helper()
+	paths := make(map[string]string, len(files))
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = p
+	}
+	return paths
+}
+
+// capture runs f with standard input reading stdin and returns what f wrote
+// to standard output and standard error.
+func capture(t *testing.T, stdin string, f func()) (stdout, stderr string) {
+	t.Helper()
+	dir := t.TempDir()
+	open := func(name string) *os.File {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	in, out, errOut := open("stdin"), open("stdout"), open("stderr")
+	defer in.Close()
+	defer out.Close()
+	defer errOut.Close()
+	if _, err := in.WriteString(stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = in, out, errOut
+	defer func() { os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr }()
+	f()
+	read := func(file *os.File) string {
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	return read(out), read(errOut)
+}
+
+func TestLintExitCodes(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{
+		"clean.wanf":    "a = 1\n",
+		"findings.wanf": "var x = 1\na = 1\n",
+		"syntax.wanf":   "syntax error here {\n",
+		"unclosed.wanf": "server {\n\tport = 80\n",
+	})
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"clean", []string{paths["clean.wanf"]}, exitOK},
+		{"findings", []string{paths["findings.wanf"]}, exitFindings},
+		{"syntax error", []string{paths["syntax.wanf"]}, exitError},
+		{"unclosed block", []string{paths["unclosed.wanf"]}, exitError},
+		{"syntax error wins over findings", []string{paths["findings.wanf"], paths["syntax.wanf"]}, exitError},
+		{"missing file", []string{filepath.Join(t.TempDir(), "missing.wanf")}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCLI(append([]string{"lint"}, tt.args...)); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintParallelDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 40; i++ {
+		var content string
+		switch i % 4 {
+		case 0:
+			content = "a = 1\n"
+		case 1:
+			content = "var x = 1\nvar y = 2\na = 1\n"
+		case 2:
+			content = "syntax error here {\n"
+		case 3:
+			content = "var z = 1\nb = [1, 2,]\n"
+		}
+		files[fmt.Sprintf("f%02d.wanf", i)] = content
+	}
+	var paths []string
+	for _, p := range writeFiles(t, dir, files) {
+		paths = append(paths, p)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.wanf"))
+
+	serial := lintParallel(paths, nil, nil, 1)
+	if !sort.SliceIsSorted(serial, func(i, j int) bool { return serial[i].path < serial[j].path }) {
+		t.Fatal("results are not sorted by path")
+	}
+	readErrs, parseFailures := 0, 0
+	for _, r := range serial {
+		if r.readErr != nil {
+			readErrs++
+		}
+		if r.parseFailed {
+			parseFailures++
+		}
+		if !sort.SliceIsSorted(r.errs, func(i, j int) bool {
+			if r.errs[i].Line != r.errs[j].Line {
+				return r.errs[i].Line < r.errs[j].Line
+			}
+			return r.errs[i].Column < r.errs[j].Column
+		}) {
+			t.Errorf("%s: diagnostics are not sorted by position", r.path)
+		}
+	}
+	if readErrs != 1 || parseFailures != 10 {
+		t.Errorf("got %d read errors and %d parse failures, want 1 and 10", readErrs, parseFailures)
+	}
+
+	for _, jobs := range []int{0, 2, 8, 64} {
+		for run := 0; run < 5; run++ {
+			if got := lintParallel(paths, nil, nil, jobs); !reflect.DeepEqual(got, serial) {
+				t.Fatalf("jobs=%d: results differ from serial run", jobs)
+			}
+		}
+	}
+}
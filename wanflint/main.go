@@ -3,9 +3,10 @@ package main
 import (
 	"bytes"
 	"errors"
-	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 
@@ -15,100 +16,343 @@ import (
 	"github.com/go-json-experiment/json/jsontext"
 )
 
-const usage = `wanflint: a tool for linting and formatting WANF files.
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-Usage:
-  wanflint <command> [arguments]
+// commands returns every wanflint command in the order shown by help.
+func commands() []*command {
+	lint := newCommand("lint", "<path ...>", "Lint files and report issues", 1, -1)
+	lint.long = "Exit status is 0 when clean, 1 when issues are found and 2 when a file cannot be read or parsed."
+	jsonOutput := lint.flags.Bool("json", false, "Output issues in JSON format (same as -format=json)")
+	outputFormat := lint.flags.String("format", "text", "Output format: text, json or sarif")
+	lintConfigPath := lint.flags.String("config", "", "Path to lint config (default: nearest "+wanf.LintConfigFileName+")")
+	lintJobs := lint.flags.Int("jobs", runtime.NumCPU(), "Number of files to lint in parallel")
+	maxWarnings := lint.flags.Int("max-warnings", 0, "Fail when there are more than this many warnings (-1 for no limit)")
+	errorsOnly := lint.flags.Bool("errors-only", false, "Report only issues with error severity")
+	lintSchema := lint.flags.String("schema", "", "Also check files against this WANF schema or JSON Schema (.json)")
+	lint.run = func(paths []string) error {
+		cfg, err := loadLintConfig(*lintConfigPath)
+		if err != nil {
+			return err
+		}
+		var schema *wanf.Schema
+		if *lintSchema != "" {
+			if schema, err = loadSchemaFile(*lintSchema); err != nil {
+				return err
+			}
+		}
+		if *jsonOutput {
+			*outputFormat = "json"
+		}
+		return lintFiles(paths, *outputFormat, cfg, schema, *lintJobs, *maxWarnings, *errorsOnly)
+	}
 
-Commands:
-  lint [path ...]   lint files and report issues
-  fmt [path ...]    format files
-`
+	format := newCommand("fmt", "<path ...>", "Format files", 1, -1)
+	displayOutput := format.flags.Bool("d", false, "Display formatted output instead of writing to file")
+	noSort := format.flags.Bool("nosort", false, "Do not sort fields within blocks")
+	align := format.flags.Bool("align", false, "Align '=' signs of consecutive assignments")
+	keepBlank := format.flags.Bool("keepblank", false, "Preserve blank lines between statements (collapsed to one)")
+	sortImports := format.flags.Bool("sortimports", false, "Move imports to the top of the file, sorted and deduplicated")
+	normComments := format.flags.Bool("normcomments", false, "Rewrite '#' comments as '//', add a space after '//' and reflow long block comments")
+	maxWidth := format.flags.Int("width", 0, "Keep lists and maps on one line if they fit within this many columns (0 always wraps)")
+	verify := format.flags.Bool("verify", false, "Check that formatting the output again does not change it")
+	fmtConfigPath := format.flags.String("config", "", "Path to lint config (default: nearest "+wanf.LintConfigFileName+")")
+	format.run = func(paths []string) error {
+		cfg, err := loadLintConfig(*fmtConfigPath)
+		if err != nil {
+			return err
+		}
+		// Use the default, opinionated style for the formatter.
+		opts := wanf.FormatOptions{
+			Style:              wanf.StyleBlockSorted,
+			EmptyLines:         true,
+			NoSort:             *noSort,
+			AlignAssignments:   *align,
+			MaxWidth:           *maxWidth,
+			PreserveBlankLines: *keepBlank,
+			SortImports:        *sortImports,
+			NormalizeComments:  *normComments,
+		}
+		return formatFiles(paths, *displayOutput, *verify, opts, cfg)
+	}
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+	vendor := newCommand("vendor", "<path ...>", "Copy imported files into wanf_vendor/ and rewrite import paths", 1, -1)
+	vendor.long = "With -o, all imports of a single entry file are instead inlined into one self-contained file."
+	vendorDir := vendor.flags.String("dir", defaultVendorDir, "Vendor directory, relative to each entry file")
+	vendorOutput := vendor.flags.String("o", "", "Inline all imports into this single file instead of copying them")
+	vendor.run = func(paths []string) error {
+		if *vendorOutput == "" {
+			return vendorFiles(paths, *vendorDir)
+		}
+		if len(paths) != 1 {
+			return errors.New("vendor -o takes exactly one entry file")
+		}
+		return bundleFile(paths[0], *vendorOutput)
+	}
+
+	convert := newCommand("convert", "<path>", "Convert a document between wanf, json, yaml, toml and binary wanfb", 1, 1)
+	convertFrom := convert.flags.String("from", "", "Input format: wanf, json, yaml, toml or wanfb (default: from the file extension)")
+	convertTo := convert.flags.String("to", "", "Output format: wanf, json, yaml, toml or wanfb (default: wanf, or json for wanf input)")
+	convertOutput := convert.flags.String("o", "", "Write the result to this file instead of standard output")
+	convert.run = func(args []string) error {
+		return convertFile(args[0], *convertFrom, *convertTo, *convertOutput)
+	}
+
+	query := newCommand("query", "<path> <file>", `Print the value at a path such as server."main".port`, 2, 2)
+	queryRaw := query.flags.Bool("raw", false, "Print string values without quotes")
+	query.run = func(args []string) error {
+		return queryFile(args[1], args[0], *queryRaw)
 	}
 
-	lintCmd := flag.NewFlagSet("lint", flag.ExitOnError)
-	jsonOutput := lintCmd.Bool("json", false, "Output issues in JSON format")
+	get := newCommand("get", "<file> <path>", "Print the value at a path, strings without quotes", 2, 2)
+	get.run = func(args []string) error {
+		return queryFile(args[0], args[1], true)
+	}
+
+	set := newCommand("set", "<file> <path> <value>", "Set the value at a path, keeping the rest of the file as is", 3, 3)
+	set.run = func(args []string) error {
+		return editFile(args[0], args[1], &args[2])
+	}
 
-	fmtCmd := flag.NewFlagSet("fmt", flag.ExitOnError)
-	displayOutput := fmtCmd.Bool("d", false, "Display formatted output instead of writing to file")
-	noSort := fmtCmd.Bool("nosort", false, "Do not sort fields within blocks")
+	unset := newCommand("unset", "<file> <path>", "Remove the value or block at a path", 2, 2)
+	unset.run = func(args []string) error {
+		return editFile(args[0], args[1], nil)
+	}
+
+	render := newCommand("render", "<path>", "Print the document with vars, env() and imports resolved", 1, 1)
+	renderJSON := render.flags.Bool("json", false, "Print the resolved document as JSON")
+	renderProfile := render.flags.String("profile", "", "Apply the named profile before resolving")
+	render.run = func(args []string) error {
+		return renderFile(args[0], *renderProfile, *renderJSON)
+	}
 
-	switch os.Args[1] {
-	case "lint":
-		lintCmd.Parse(os.Args[2:])
-		paths := lintCmd.Args()
-		if len(paths) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: missing file paths for lint command.")
-			os.Exit(1)
+	merge := newCommand("merge", "<base> <override ...>", "Deep-merge files, later files taking precedence", 2, -1)
+	mergeAppend := merge.flags.Bool("append", false, "Append list elements instead of replacing lists")
+	mergeOutput := merge.flags.String("o", "", "Write the result to this file instead of standard output")
+	merge.run = func(paths []string) error {
+		policy := wanf.MergeReplaceLists
+		if *mergeAppend {
+			policy = wanf.MergeAppendLists
 		}
-		if err := lintFiles(paths, *jsonOutput); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		return mergeFiles(paths, policy, *mergeOutput)
+	}
+
+	diff := newCommand("diff", "<old> <new>", "Show added, removed and changed keys between two files", 2, 2)
+	diff.long = "Formatting, comments and key order are ignored. Exit status is 0 when the files are equivalent, 1 when they differ and 2 on errors."
+	diff.run = func(args []string) error {
+		return diffFiles(args[0], args[1])
+	}
+
+	migrate := newCommand("migrate", "<path ...>", "Upgrade files to the latest wanf_version using a rules file", 1, -1)
+	migrate.long = "Each migration block in the rules file renames or removes keys to go from one version to the next."
+	migrateRules := migrate.flags.String("rules", "", "Path to the migration rules file (required)")
+	migrateDisplay := migrate.flags.Bool("d", false, "Display migrated output instead of writing to file")
+	migrate.run = func(paths []string) error {
+		return migrateFiles(paths, *migrateRules, *migrateDisplay)
+	}
+
+	initCmd := newCommand("init", "", "Generate a commented skeleton config from a Go struct", 0, 0)
+	initCmd.long = "Fields are read from the struct's wanf tags; a `default:\"...\"` tag sets the value written for a field."
+	initType := initCmd.flags.String("type", "Config", "Name of the struct type")
+	initPkg := initCmd.flags.String("pkg", ".", "Directory of the Go package declaring the type")
+	initOutput := initCmd.flags.String("o", "", "Write the skeleton to this file instead of standard output")
+	initCmd.run = func([]string) error {
+		return initConfig(*initPkg, *initType, *initOutput)
+	}
+
+	gen := newCommand("gen", "<path>", "Generate a tagged Go struct from a sample WANF document", 1, 1)
+	gen.long = "Types are inferred from the values: labeled blocks become maps of structs, lists become slices and durations time.Duration."
+	genLang := gen.flags.String("lang", "go", "Target language (only go is supported)")
+	genType := gen.flags.String("type", "Config", "Name of the generated root struct")
+	genPkg := gen.flags.String("pkg", "config", "Package name of the generated file")
+	genOutput := gen.flags.String("o", "", "Write the code to this file instead of standard output")
+	gen.run = func(args []string) error {
+		return genFile(args[0], *genLang, *genType, *genPkg, *genOutput)
+	}
+
+	genDec := newCommand("gen-decoder", "", "Generate reflection-free UnmarshalWANF methods for Go structs", 0, 0)
+	genDec.long = "Methods are also generated for the package's struct types nested in the given ones. Use it from a go:generate directive and regenerate when the structs change."
+	genDecType := genDec.flags.String("type", "Config", "Comma-separated names of the struct types")
+	genDecPkg := genDec.flags.String("pkg", ".", "Directory of the Go package declaring the types")
+	genDecOutput := genDec.flags.String("o", "", "Write the code to this file instead of standard output")
+	genDec.run = func([]string) error {
+		return genDecoder(*genDecPkg, *genDecType, *genDecOutput)
+	}
+
+	doc := newCommand("doc", "[path]", "Generate reference documentation for configuration keys", 0, 1)
+	doc.long = "Keys, types, values and comments are read from the given WANF file, or from a Go struct with -pkg and -type."
+	docType := doc.flags.String("type", "Config", "Name of the struct type, when no file is given")
+	docPkg := doc.flags.String("pkg", ".", "Directory of the Go package declaring the type, when no file is given")
+	docFormat := doc.flags.String("format", "md", "Output format: md or html")
+	docOutput := doc.flags.String("o", "", "Write the documentation to this file instead of standard output")
+	doc.run = func(args []string) error {
+		file := ""
+		if len(args) == 1 {
+			file = args[0]
 		}
-	case "fmt":
-		fmtCmd.Parse(os.Args[2:])
-		paths := fmtCmd.Args()
-		if len(paths) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: missing file paths for fmt command.")
-			os.Exit(1)
+		return docFile(file, *docPkg, *docType, *docFormat, *docOutput)
+	}
+
+	stats := newCommand("stats", "<path ...>", "Report keys, blocks, depth, vars, env() references and imports", 1, -1)
+	statsJSON := stats.flags.Bool("json", false, "Output statistics in JSON format, including per-file numbers")
+	stats.run = func(paths []string) error {
+		return statsFiles(paths, *statsJSON)
+	}
+
+	minify := newCommand("minify", "<path>", "Print the document on a single line without comments", 1, 1)
+	minifyOutput := minify.flags.String("o", "", "Write the result to this file instead of standard output")
+	minify.run = func(args []string) error {
+		return minifyFile(args[0], *minifyOutput)
+	}
+
+	export := newCommand("export", "<path>", "Print the resolved document as flat key=value lines", 1, 1)
+	export.long = "Keys are dotted paths such as server.main.port; with -format=env they become variable names such as SERVER_MAIN_PORT."
+	exportFormat := export.flags.String("format", "properties", "Output format: properties or env")
+	exportProfile := export.flags.String("profile", "", "Apply the named profile before resolving")
+	exportOutput := export.flags.String("o", "", "Write the result to this file instead of standard output")
+	export.run = func(args []string) error {
+		return exportFile(args[0], *exportFormat, *exportProfile, *exportOutput)
+	}
+
+	hash := newCommand("hash", "<path ...>", "Print a hash of each file's resolved content, independent of formatting", 1, -1)
+	hash.long = "Comments, whitespace, statement order and literal spelling do not change the hash; values, list order and types do."
+	hashProfile := hash.flags.String("profile", "", "Apply the named profile before hashing")
+	hash.run = func(paths []string) error {
+		return hashFiles(paths, *hashProfile)
+	}
+
+	validate := newCommand("validate", "<path ...>", "Check files against a WANF schema", 1, -1)
+	validate.long = "Exit status is 0 when all files match the schema, 1 on violations and 2 when a file cannot be read or parsed."
+	validateSchema := validate.flags.String("schema", "", "Path to the WANF schema, or a JSON Schema ending in .json (required)")
+	validateFormat := validate.flags.String("format", "text", "Output format: text, json or sarif")
+	validate.run = func(paths []string) error {
+		return validateFiles(paths, *validateSchema, *validateFormat)
+	}
+
+	return []*command{lint, validate, format, vendor, convert, query, get, set, unset, render, merge, diff, migrate, initCmd, gen, genDec, doc, stats, minify, export, hash}
+}
+
+// loadLintConfig loads the config at path, or the nearest .wanflint.wanf found
+// by walking up from the working directory. A missing config is not an error.
+func loadLintConfig(path string) (*wanf.LintConfig, error) {
+	if path != "" {
+		return wanf.LoadLintConfig(path)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		candidate := filepath.Join(dir, wanf.LintConfigFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return wanf.LoadLintConfig(candidate)
 		}
-		if err := formatFiles(paths, *displayOutput, *noSort); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
 		}
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %q\n", os.Args[1])
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+		dir = parent
 	}
 }
 
-func lintFiles(paths []string, jsonOutput bool) error {
-	var allErrors []wanf.LintError
-	hasParseErrors := false
+func lintFiles(paths []string, format string, cfg *wanf.LintConfig, schema *wanf.Schema, jobs, maxWarnings int, errorsOnly bool) error {
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	paths, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
 
-	for _, path := range paths {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
-			hasParseErrors = true
+	var reports []wanf.LintReport
+	failures := 0
+	numErrors, numWarnings := 0, 0
+
+	for _, r := range lintParallel(paths, cfg, schema, jobs) {
+		if r.readErr != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", displayPath(r.path), r.readErr)
+			failures++
 			continue
 		}
-		_, errs := wanf.Lint(data)
+		if r.parseFailed {
+			failures++
+		}
+		errs := r.errs
+		if errorsOnly {
+			errs = errs[:0:0]
+			for _, e := range r.errs {
+				if e.Severity == wanf.SeverityError {
+					errs = append(errs, e)
+				}
+			}
+		}
+		for _, e := range errs {
+			switch e.Severity {
+			case wanf.SeverityError:
+				numErrors++
+			case wanf.SeverityWarning:
+				numWarnings++
+			}
+		}
 		if len(errs) > 0 {
-			allErrors = append(allErrors, errs...)
+			reports = append(reports, wanf.LintReport{Path: displayPath(r.path), Errors: errs})
 		}
 	}
 
-	if jsonOutput {
-		err := json.MarshalWrite(os.Stdout, allErrors, jsontext.Multiline(true), jsontext.WithIndent("  "))
-		if err != nil {
-			return fmt.Errorf("could not marshal json: %w", err)
-		}
-		return nil
+	if err := writeReports(format, reports); err != nil {
+		return err
 	}
 
-	if len(allErrors) > 0 {
-		fmt.Fprintln(os.Stderr, "Linter found issues:")
-		for _, e := range allErrors {
-			fmt.Fprintf(os.Stderr, "  - [%s] %s:%d:%d: %s\n", e.Level, "file", e.Line, e.Column, e.Message)
-		}
-		return fmt.Errorf("linting found issues")
+	// Files that could not be read or parsed take precedence over findings.
+	if failures > 0 {
+		return fmt.Errorf("%d file(s) could not be read or parsed", failures)
 	}
-
-	if hasParseErrors {
-		return fmt.Errorf("errors encountered during linting")
+	if numErrors > 0 {
+		return &findingsError{fmt.Sprintf("linting found %d error(s) and %d warning(s)", numErrors, numWarnings)}
+	}
+	if maxWarnings >= 0 && numWarnings > maxWarnings {
+		return &findingsError{fmt.Sprintf("linting found %d warning(s), more than the maximum of %d", numWarnings, maxWarnings)}
 	}
+	return nil
+}
 
+// writeReports prints lint reports as text to standard error, or as JSON or
+// SARIF to standard output.
+func writeReports(format string, reports []wanf.LintReport) error {
+	switch format {
+	case "json":
+		var allErrors []wanf.LintError
+		for _, r := range reports {
+			allErrors = append(allErrors, r.Errors...)
+		}
+		if err := json.MarshalWrite(os.Stdout, allErrors, jsontext.Multiline(true), jsontext.WithIndent("  ")); err != nil {
+			return fmt.Errorf("could not marshal json: %w", err)
+		}
+	case "sarif":
+		if err := wanf.WriteSARIF(os.Stdout, reports); err != nil {
+			return fmt.Errorf("could not write sarif: %w", err)
+		}
+	default:
+		if len(reports) > 0 {
+			fmt.Fprintln(os.Stderr, "Linter found issues:")
+			for _, r := range reports {
+				for _, e := range r.Errors {
+					fmt.Fprintf(os.Stderr, "  - [%s] %s:%d:%d: %s: %s (%s)\n", e.Level, r.Path, e.Line, e.Column, e.Severity, e.Message, e.Rule)
+				}
+			}
+		}
+	}
 	return nil
 }
 
-func formatFiles(paths []string, displayOnly bool, noSort bool) error {
+func formatFiles(paths []string, displayOnly, verify bool, opts wanf.FormatOptions, cfg *wanf.LintConfig) error {
+	paths, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
 	var wg sync.WaitGroup
 	pathsChan := make(chan string, len(paths))
 	errChan := make(chan error, len(paths))
@@ -119,7 +363,7 @@ func formatFiles(paths []string, displayOnly bool, noSort bool) error {
 		go func() {
 			defer wg.Done()
 			for path := range pathsChan {
-				err := formatFile(path, displayOnly, noSort)
+				err := formatFile(path, displayOnly, verify, opts, cfg)
 				if err != nil {
 					errChan <- err
 				}
@@ -147,27 +391,37 @@ func formatFiles(paths []string, displayOnly bool, noSort bool) error {
 	return nil
 }
 
-func formatFile(path string, displayOnly bool, noSort bool) error {
-	data, err := os.ReadFile(path)
+func formatFile(path string, displayOnly, verify bool, opts wanf.FormatOptions, cfg *wanf.LintConfig) error {
+	data, err := readInput(path)
 	if err != nil {
-		return fmt.Errorf("could not read file %s: %w", path, err)
+		return fmt.Errorf("could not read file %s: %w", displayPath(path), err)
+	}
+	// Input from stdin can only be written back to stdout.
+	if path == stdinPath {
+		displayOnly = true
 	}
 
 	// Lint first to catch parsing errors and get the AST
-	program, errs := wanf.Lint(data)
+	program, errs := wanf.Lint(data, wanf.WithLintConfig(cfg))
+	// The formatted output does not refer to the AST, so its nodes can be
+	// reused for the next file.
+	defer program.Release()
 	if len(errs) > 0 {
 		// In format mode, we still format even if there are non-fatal errors,
 		// but we print the errors to stderr.
 		// Note: In concurrent mode, these prints might be interleaved, which is acceptable.
-		fmt.Fprintf(os.Stderr, "Warning: found %d issues in %s:\n", len(errs), path)
+		fmt.Fprintf(os.Stderr, "Warning: found %d issues in %s:\n", len(errs), displayPath(path))
 		for _, e := range errs {
 			fmt.Fprintf(os.Stderr, "  - %s\n", e.Error())
 		}
 	}
 
-	// Use the default, opinionated style for the formatter.
-	opts := wanf.FormatOptions{Style: wanf.StyleBlockSorted, EmptyLines: true, NoSort: noSort}
 	formatted := wanf.Format(program, opts)
+	if verify {
+		if _, err := wanf.FormatStable(data, opts, wanf.WithLintConfig(cfg)); err != nil {
+			return fmt.Errorf("%s: %w", displayPath(path), err)
+		}
+	}
 
 	if displayOnly {
 		os.Stdout.Write(formatted)
@@ -182,3 +436,22 @@ func formatFile(path string, displayOnly bool, noSort bool) error {
 	}
 	return nil
 }
+
+// stdinPath is the path argument that stands for standard input.
+const stdinPath = "-"
+
+// readInput reads the file at path, or standard input if path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == stdinPath {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// displayPath returns the name used for path in messages and reports.
+func displayPath(path string) string {
+	if path == stdinPath {
+		return "<stdin>"
+	}
+	return path
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// mergeFiles deep-merges the files from left to right, so later files override
+// earlier ones, and writes the combined document to output or standard output.
+func mergeFiles(paths []string, policy wanf.MergePolicy, output string) error {
+	var merged *wanf.RootNode
+	for _, path := range paths {
+		data, err := readInput(path)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", displayPath(path), err)
+		}
+		program, err := parseFile(data, path)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			merged = program
+			continue
+		}
+		wanf.Merge(merged, program, policy)
+	}
+	out := wanf.Format(merged, wanf.FormatOptions{Style: wanf.StyleBlockSorted, EmptyLines: true})
+	out = append(out, '\n')
+	if output == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
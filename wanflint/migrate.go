@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// migrationRules is the format of the file given to `migrate -rules`:
+//
+//	migration {
+//		from = 1
+//		to = 2
+//		rename = [["server.addr", "listen"]]
+//		remove = ["legacy"]
+//	}
+type migrationRules struct {
+	Steps []migrationStep `wanf:"migration"`
+}
+
+type migrationStep struct {
+	From   int           `wanf:"from"`
+	To     int           `wanf:"to"`
+	Rename []interface{} `wanf:"rename"` // [path, new name] pairs
+	Remove []string      `wanf:"remove"`
+}
+
+// loadMigrations reads a rules file and registers its steps as migrations.
+func loadMigrations(path string) error {
+	var rules migrationRules
+	if err := wanf.DecodeFile(path, &rules); err != nil {
+		return fmt.Errorf("could not read migration rules %s: %w", path, err)
+	}
+	if len(rules.Steps) == 0 {
+		return fmt.Errorf("%s: no migration blocks found", path)
+	}
+	seen := make(map[int]bool)
+	for _, step := range rules.Steps {
+		if step.To <= step.From || step.From < 1 {
+			return fmt.Errorf("%s: migration from %d to %d: to must be greater than from", path, step.From, step.To)
+		}
+		if seen[step.From] {
+			return fmt.Errorf("%s: more than one migration from version %d", path, step.From)
+		}
+		seen[step.From] = true
+		renames := make([][2]string, 0, len(step.Rename))
+		for _, r := range step.Rename {
+			pair, ok := r.([]interface{})
+			if !ok || len(pair) != 2 {
+				return fmt.Errorf("%s: migration from %d: rename entries must be [path, name] pairs", path, step.From)
+			}
+			from, ok1 := pair[0].(string)
+			to, ok2 := pair[1].(string)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("%s: migration from %d: rename entries must be [path, name] pairs", path, step.From)
+			}
+			renames = append(renames, [2]string{from, to})
+		}
+		remove := step.Remove
+		wanf.RegisterMigration(step.From, step.To, func(program *wanf.RootNode) error {
+			for _, r := range renames {
+				if _, err := wanf.RenameKey(program, r[0], r[1]); err != nil {
+					return err
+				}
+			}
+			for _, p := range remove {
+				if _, err := wanf.RemoveKey(program, p); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// migrateFiles upgrades each file to the latest version described by the
+// rules file, rewriting it in place unless displayOnly is set.
+func migrateFiles(paths []string, rulesPath string, displayOnly bool) error {
+	if rulesPath == "" {
+		return fmt.Errorf("missing -rules")
+	}
+	if err := loadMigrations(rulesPath); err != nil {
+		return err
+	}
+	paths, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, path := range paths {
+		if err := migrateFile(path, displayOnly); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("errors encountered while migrating")
+	}
+	return nil
+}
+
+func migrateFile(path string, displayOnly bool) error {
+	data, err := readInput(path)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(path), err)
+	}
+	if path == stdinPath {
+		displayOnly = true
+	}
+	program, err := parseFile(data, path)
+	if err != nil {
+		return err
+	}
+	from, to, err := wanf.Migrate(program)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+	// Keep the original order and spacing; only the migrated statements change.
+	out := wanf.Format(program, wanf.FormatOptions{
+		Style:              wanf.StyleBlockSorted,
+		EmptyLines:         true,
+		NoSort:             true,
+		PreserveBlankLines: true,
+	})
+	if displayOnly {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if from == to || bytes.Equal(data, out) {
+		return nil
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	fmt.Printf("Migrated %s from version %d to %d\n", displayPath(path), from, to)
+	return nil
+}
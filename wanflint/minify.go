@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// minifyFile prints file without comments on a single line, or writes it to output.
+func minifyFile(file, output string) error {
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	out, err := wanf.Minify(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(file), err)
+	}
+	out = append(out, '\n')
+	if output == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// skippedDirs are directory names that are never entered when walking a
+// directory argument, in addition to hidden directories.
+var skippedDirs = map[string]bool{
+	defaultVendorDir: true,
+	"vendor":         true,
+	"node_modules":   true,
+}
+
+// expandPaths turns the path arguments of lint and fmt into a list of files:
+//
+//   - a directory, or a path ending in "/...", is walked recursively for *.wanf files
+//   - a pattern containing "**" matches any number of directories, e.g. "configs/**/*.wanf"
+//   - any other pattern is expanded with filepath.Glob
+//   - "-" and plain file paths are kept as is
+//
+// Hidden directories and the directories in skippedDirs are not walked unless
+// they are named explicitly. Each file appears once, in argument order.
+func expandPaths(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			files = append(files, p)
+		}
+	}
+	for _, arg := range args {
+		switch {
+		case arg == stdinPath:
+			add(arg)
+		case strings.HasSuffix(arg, "/..."):
+			root := strings.TrimSuffix(arg, "/...")
+			if root == "" {
+				root = "."
+			}
+			if err := walkWanfFiles(root, nil, add); err != nil {
+				return nil, err
+			}
+		case strings.Contains(arg, "**"):
+			root, pattern := splitGlobRoot(filepath.ToSlash(arg))
+			if err := walkWanfFiles(root, func(rel string) bool { return matchDoubleStar(pattern, rel) }, add); err != nil {
+				return nil, err
+			}
+		case strings.ContainsAny(arg, "*?["):
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+			for _, m := range matches {
+				if info, err := os.Stat(m); err == nil && !info.IsDir() {
+					add(m)
+				}
+			}
+		default:
+			info, err := os.Stat(arg)
+			if err == nil && info.IsDir() {
+				if err := walkWanfFiles(arg, nil, add); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			// Missing files are reported by the command itself.
+			add(arg)
+		}
+	}
+	return files, nil
+}
+
+// walkWanfFiles calls add for every *.wanf file below root. If match is not
+// nil, it is given the slash-separated path relative to root and decides
+// whether the file is included.
+func walkWanfFiles(root string, match func(rel string) bool, add func(string)) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ".wanf" || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if match != nil {
+			rel, err := filepath.Rel(root, p)
+			if err != nil || !match(filepath.ToSlash(rel)) {
+				return nil
+			}
+		}
+		add(p)
+		return nil
+	})
+}
+
+// splitGlobRoot splits a slash-separated pattern into the directory before its
+// first wildcard segment and the remaining pattern.
+func splitGlobRoot(pattern string) (root, rest string) {
+	segs := strings.Split(pattern, "/")
+	i := 0
+	for i < len(segs)-1 && !strings.ContainsAny(segs[i], "*?[") {
+		i++
+	}
+	root = strings.Join(segs[:i], "/")
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+	return filepath.FromSlash(root), strings.Join(segs[i:], "/")
+}
+
+// matchDoubleStar reports whether the slash-separated name matches pattern,
+// where a "**" segment matches zero or more path segments.
+func matchDoubleStar(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.wanf":                   "",
+		"b.txt":                    "",
+		"conf/c.wanf":              "",
+		"conf/deep/d.wanf":         "",
+		"conf/.hidden/e.wanf":      "",
+		"conf/wanf_vendor/f.wanf":  "",
+		"conf/vendor/g.wanf":       "",
+		"conf/node_modules/h.wanf": "",
+		"conf/.i.wanf":             "",
+	})
+	j := func(names ...string) []string {
+		var out []string
+		for _, n := range names {
+			out = append(out, filepath.Join(dir, filepath.FromSlash(n)))
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{"file", j("a.wanf"), j("a.wanf"), ""},
+		{"stdin", []string{"-"}, []string{"-"}, ""},
+		{"directory", j("conf"), j("conf/c.wanf", "conf/deep/d.wanf"), ""},
+		{"dots", []string{filepath.Join(dir, "conf") + "/..."}, j("conf/c.wanf", "conf/deep/d.wanf"), ""},
+		{"explicit skipped dir", j("conf/.hidden"), j("conf/.hidden/e.wanf"), ""},
+		{"glob", j("*.wanf"), j("a.wanf"), ""},
+		{"double star", j("**/*.wanf"), j("a.wanf", "conf/c.wanf", "conf/deep/d.wanf"), ""},
+		{"double star subdir", j("conf/**/d.wanf"), j("conf/deep/d.wanf"), ""},
+		{"duplicates", j("a.wanf", "*.wanf", "a.wanf"), j("a.wanf"), ""},
+		{"missing file", j("missing.wanf"), j("missing.wanf"), ""},
+		{"glob without matches", j("*.json"), nil, "no files match"},
+		{"invalid glob", j("[.wanf"), nil, "invalid pattern"},
+		{"missing directory", []string{filepath.Join(dir, "missing") + "/..."}, nil, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandPaths(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandPaths(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandPaths(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchDoubleStar(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.wanf", "a.wanf", true},
+		{"**/*.wanf", "x/y/a.wanf", true},
+		{"x/**/a.wanf", "x/a.wanf", true},
+		{"x/**/a.wanf", "y/a.wanf", false},
+		{"**", "x/y", true},
+		{"*.wanf", "x/a.wanf", false},
+	}
+	for _, tt := range tests {
+		if got := matchDoubleStar(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchDoubleStar(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
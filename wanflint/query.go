@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// queryFile prints the expression at path in the file, formatted as WANF.
+// With raw set, string values are printed without quotes.
+func queryFile(file, path string, raw bool) error {
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	program, err := parseFile(data, file)
+	if err != nil {
+		return err
+	}
+	expr, err := wanf.Lookup(program, path)
+	if err != nil {
+		return err
+	}
+	if s, ok := expr.(*wanf.StringLiteral); ok && raw {
+		fmt.Println(string(s.Value))
+		return nil
+	}
+	var buf bytes.Buffer
+	expr.Format(&buf, "", wanf.FormatOptions{Style: wanf.StyleBlockSorted, EmptyLines: true})
+	buf.WriteByte('\n')
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
+
+// parseFile parses data and reports all parser errors for file as one error.
+func parseFile(data []byte, file string) (*wanf.RootNode, error) {
+	program, err := wanf.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayPath(file), err)
+	}
+	return program, nil
+}
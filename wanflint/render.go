@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// renderFile prints the fully resolved document: vars, env() and imports are
+// evaluated as they would be at decode time.
+func renderFile(file, profile string, asJSON bool) error {
+	data, err := readInput(file)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", displayPath(file), err)
+	}
+	opts := []wanf.DecoderOption{wanf.WithBasePath(".")}
+	if file != stdinPath {
+		opts[0] = wanf.WithBasePath(filepath.Dir(file))
+	}
+	if profile != "" {
+		opts = append(opts, wanf.WithProfile(profile))
+	}
+	var out []byte
+	if asJSON {
+		out, err = wanf.ToJSON(data, opts...)
+	} else {
+		out, err = wanf.Render(data, opts...)
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(file), err)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
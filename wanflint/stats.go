@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// fileStats holds the statistics of a single file.
+type fileStats struct {
+	Path       string   `json:"path"`
+	Keys       int      `json:"keys"`
+	Blocks     int      `json:"blocks"`
+	MaxDepth   int      `json:"max_depth"`
+	Vars       int      `json:"vars"`
+	UnusedVars []string `json:"unused_vars,omitempty"`
+	EnvRefs    []string `json:"env_refs,omitempty"`
+	Imports    int      `json:"imports"`
+}
+
+// corpusStats aggregates fileStats over all files.
+type corpusStats struct {
+	Files      int          `json:"files"`
+	Keys       int          `json:"keys"`
+	Blocks     int          `json:"blocks"`
+	MaxDepth   int          `json:"max_depth"`
+	Vars       int          `json:"vars"`
+	UnusedVars int          `json:"unused_vars"`
+	EnvRefs    int          `json:"env_refs"`
+	EnvNames   []string     `json:"env_names,omitempty"`
+	Imports    int          `json:"imports"`
+	MaxImports int          `json:"max_imports"`
+	PerFile    []*fileStats `json:"per_file"`
+}
+
+// statsFiles reports statistics about the files matched by paths.
+func statsFiles(paths []string, asJSON bool) error {
+	paths, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+	total := &corpusStats{}
+	envNames := make(map[string]bool)
+	failed := false
+	for _, path := range paths {
+		data, err := readInput(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", displayPath(path), err)
+			failed = true
+			continue
+		}
+		fs, err := collectFileStats(data, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		total.Files++
+		total.Keys += fs.Keys
+		total.Blocks += fs.Blocks
+		total.Vars += fs.Vars
+		total.UnusedVars += len(fs.UnusedVars)
+		total.EnvRefs += len(fs.EnvRefs)
+		total.Imports += fs.Imports
+		total.MaxDepth = max(total.MaxDepth, fs.MaxDepth)
+		total.MaxImports = max(total.MaxImports, fs.Imports)
+		for _, name := range fs.EnvRefs {
+			envNames[name] = true
+		}
+		total.PerFile = append(total.PerFile, fs)
+	}
+	for name := range envNames {
+		total.EnvNames = append(total.EnvNames, name)
+	}
+	sort.Strings(total.EnvNames)
+
+	if asJSON {
+		if err := json.MarshalWrite(os.Stdout, total, jsontext.Multiline(true), jsontext.WithIndent("  ")); err != nil {
+			return fmt.Errorf("could not marshal json: %w", err)
+		}
+		fmt.Println()
+	} else {
+		printStats(total)
+	}
+	if failed {
+		return fmt.Errorf("errors encountered while collecting stats")
+	}
+	return nil
+}
+
+func collectFileStats(data []byte, path string) (*fileStats, error) {
+	program, err := parseFile(data, path)
+	if err != nil {
+		return nil, err
+	}
+	fs := &fileStats{Path: displayPath(path)}
+	fs.walk(program.Statements, 0)
+	program.Release()
+
+	// Unused vars are found by the linter, which also knows about ${...} references.
+	_, errs := wanf.Lint(data, wanf.WithLintBasePath(filepath.Dir(path)))
+	for _, e := range errs {
+		if e.Type == wanf.ErrUnusedVariable && len(e.Args) > 0 {
+			fs.UnusedVars = append(fs.UnusedVars, e.Args[0])
+		}
+	}
+	sort.Strings(fs.UnusedVars)
+	return fs, nil
+}
+
+// walk counts the statements at the given nesting depth and below.
+func (fs *fileStats) walk(stmts []wanf.Statement, depth int) {
+	fs.MaxDepth = max(fs.MaxDepth, depth)
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *wanf.AssignStatement:
+			fs.Keys++
+			fs.walkExpression(s.Value, depth)
+		case *wanf.BlockStatement:
+			fs.Blocks++
+			fs.walk(s.Body.Statements, depth+1)
+		case *wanf.VarStatement:
+			fs.Vars++
+			fs.walkExpression(s.Value, depth)
+		case *wanf.ImportStatement:
+			fs.Imports++
+		}
+	}
+}
+
+func (fs *fileStats) walkExpression(expr wanf.Expression, depth int) {
+	switch e := expr.(type) {
+	case *wanf.EnvExpression:
+		fs.EnvRefs = append(fs.EnvRefs, string(e.Name.Value))
+	case *wanf.ListLiteral:
+		for _, el := range e.Elements {
+			fs.walkExpression(el, depth)
+		}
+	case *wanf.MapLiteral:
+		fs.walk(e.Elements, depth+1)
+	case *wanf.BlockLiteral:
+		fs.Blocks++
+		fs.walk(e.Body.Statements, depth+1)
+	}
+}
+
+func printStats(s *corpusStats) {
+	fmt.Printf("Files:       %d\n", s.Files)
+	fmt.Printf("Keys:        %d\n", s.Keys)
+	fmt.Printf("Blocks:      %d\n", s.Blocks)
+	fmt.Printf("Max depth:   %d%s\n", s.MaxDepth, statsWhere(s, func(fs *fileStats) bool { return fs.MaxDepth == s.MaxDepth && s.MaxDepth > 0 }))
+	fmt.Printf("Vars:        %d (%d unused)\n", s.Vars, s.UnusedVars)
+	fmt.Printf("env() refs:  %d", s.EnvRefs)
+	if len(s.EnvNames) > 0 {
+		fmt.Printf(" (%s)", strings.Join(s.EnvNames, ", "))
+	}
+	fmt.Println()
+	fmt.Printf("Imports:     %d (max fan-out %d%s)\n", s.Imports, s.MaxImports, statsWhere(s, func(fs *fileStats) bool { return fs.Imports == s.MaxImports && s.MaxImports > 0 }))
+	for _, fs := range s.PerFile {
+		for _, name := range fs.UnusedVars {
+			fmt.Printf("  %s: unused var %s\n", fs.Path, name)
+		}
+	}
+}
+
+// statsWhere names the first file matching pred, for pointing at the file
+// that sets a maximum.
+func statsWhere(s *corpusStats, pred func(*fileStats) bool) string {
+	if s.Files < 2 {
+		return ""
+	}
+	for _, fs := range s.PerFile {
+		if pred(fs) {
+			return " in " + fs.Path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectFileStats(t *testing.T) {
+	src := `import "a.wanf"
+import "b.wanf"
+var used = "x"
+var unused = 1
+name = "${used}"
+token = env("TOKEN")
+server {
+	host = env("HOST", "localhost")
+	tls {
+		cert = "c"
+	}
+}
+limits = {[
+	cpu = 1,
+]}
+peers = [env("PEER")]
+`
+	fs, err := collectFileStats([]byte(src), "app.wanf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &fileStats{
+		Path:       "app.wanf",
+		Keys:       7,
+		Blocks:     2,
+		MaxDepth:   2,
+		Vars:       2,
+		UnusedVars: []string{"unused"},
+		EnvRefs:    []string{"TOKEN", "HOST", "PEER"},
+		Imports:    2,
+	}
+	if !reflect.DeepEqual(fs, want) {
+		t.Errorf("collectFileStats = %+v, want %+v", fs, want)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStdinInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		code       int
+		wantStdout string
+		wantStderr string
+	}{
+		{"fmt", []string{"fmt", "-"}, "b=2\na =   1\n", exitOK, "b = 2\na = 1", ""},
+		{"fmt -d", []string{"fmt", "-d", "-"}, "a=1\n", exitOK, "a = 1", ""},
+		{"lint clean", []string{"lint", "-"}, "a = 1\n", exitOK, "", ""},
+		{"lint findings", []string{"lint", "-"}, "var x = 1\na = 1\n", exitFindings, "", "<stdin>:1:"},
+		{"lint syntax error", []string{"lint", "-"}, "a = \n", exitError, "", "could not be read or parsed"},
+		{"get", []string{"get", "-", "server.port"}, "server {\n\tport = 80\n}\n", exitOK, "80\n", ""},
+		{"set", []string{"set", "-", "port", "81"}, "port = 80 // keep\n", exitOK, "port = 81 // keep\n", ""},
+		{"minify", []string{"minify", "-"}, "// c\na = 1\nb = 2\n", exitOK, "a = 1;b = 2\n", ""},
+		{"convert", []string{"convert", "-from", "wanf", "-"}, "a = 1\n", exitOK, "\"a\": 1", ""},
+		{"convert without -from", []string{"convert", "-"}, "a = 1\n", exitError, "", "cannot infer input format of <stdin>"},
+		{"stats", []string{"stats", "-"}, "a = 1\n", exitOK, "Keys:        1\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			stdout, stderr := capture(t, tt.stdin, func() { code = runCLI(tt.args) })
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.code, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) || (tt.wantStdout == "" && stdout != "") {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package pkg
+
+import "time"
+
+// Config is the service configuration.
+type Config struct {
+	// Name of the service.
+	Name    string        `wanf:"name" default:"svc"`
+	Timeout time.Duration `wanf:"timeout" default:"5s"`
+	Tags    []string      `wanf:"tags"`
+	Labels  map[string]string
+	// Database connection.
+	Database struct {
+		Host string `wanf:"host"`
+	} `wanf:"database"`
+	Routes  map[string]Route `wanf:"route"`
+	Servers []Route          `wanf:"server"`
+}
+
+// Route forwards requests to a target.
+type Route struct {
+	// Target URL, e.g. <http://a|b>.
+	Target string `wanf:"target"`
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// validateFiles checks every file matched by paths against the schema file
+// and reports violations like lint does. Schema files ending in .json are read
+// as JSON Schema, anything else as a WANF schema.
+func validateFiles(paths []string, schemaPath, format string) error {
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if schemaPath == "" {
+		return fmt.Errorf("missing -schema")
+	}
+	schema, err := loadSchemaFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	paths, err = expandPaths(paths)
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	var reports []wanf.LintReport
+	failures, violations := 0, 0
+	for _, path := range paths {
+		data, err := readInput(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", displayPath(path), err)
+			failures++
+			continue
+		}
+		program, err := parseFile(data, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failures++
+			continue
+		}
+		if errs := wanf.ValidateAgainstSchema(program, schema); len(errs) > 0 {
+			reports = append(reports, wanf.LintReport{Path: displayPath(path), Errors: errs})
+			violations += len(errs)
+		}
+	}
+
+	if err := writeReports(format, reports); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d file(s) could not be read or parsed", failures)
+	}
+	if violations > 0 {
+		return &findingsError{fmt.Sprintf("found %d schema violation(s)", violations)}
+	}
+	return nil
+}
+
+// loadSchemaFile reads a schema file: JSON Schema when the name ends in .json,
+// a WANF schema otherwise.
+func loadSchemaFile(path string) (*wanf.Schema, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return wanf.LoadJSONSchema(path)
+	}
+	return wanf.LoadSchema(path)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+const defaultVendorDir = "wanf_vendor"
+
+// vendorer copies the import tree of an entry file into a local vendor directory
+// and rewrites every import path so that the tree resolves without the original sources.
+type vendorer struct {
+	root      string            // directory of the entry file
+	vendorDir string            // absolute path of the vendor directory
+	copied    map[string]string // absolute source path -> absolute vendored path
+}
+
+func vendorFiles(paths []string, dirName string) error {
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("could not get absolute path for %s: %w", path, err)
+		}
+		root := filepath.Dir(absPath)
+		v := &vendorer{
+			root:      root,
+			vendorDir: filepath.Join(root, dirName),
+			copied:    make(map[string]string),
+		}
+		if err := v.vendorEntry(absPath); err != nil {
+			return err
+		}
+		if len(v.copied) > 0 {
+			fmt.Printf("Vendored %d imports for %s into %s\n", len(v.copied), path, v.vendorDir)
+		}
+	}
+	return nil
+}
+
+func (v *vendorer) vendorEntry(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read file %s: %w", path, err)
+	}
+	rewritten, err := v.rewriteImports(data, path, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if len(v.copied) == 0 {
+		return nil
+	}
+	if !bytes.Equal(data, rewritten) {
+		if err := os.WriteFile(path, rewritten, 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+	}
+	return v.writeManifest()
+}
+
+// vendorFile copies a single imported file into the vendor directory, recursively
+// vendoring its own imports first. It returns the absolute vendored path.
+func (v *vendorer) vendorFile(src string) (string, error) {
+	if dest, ok := v.copied[src]; ok {
+		return dest, nil
+	}
+	dest := v.vendorPath(src)
+	// Register before recursing so that import cycles terminate.
+	v.copied[src] = dest
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", fmt.Errorf("could not read imported file %s: %w", src, err)
+	}
+	rewritten, err := v.rewriteImports(data, src, filepath.Dir(dest))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("could not create vendor directory: %w", err)
+	}
+	if err := os.WriteFile(dest, rewritten, 0644); err != nil {
+		return "", fmt.Errorf("failed to write vendored file %s: %w", dest, err)
+	}
+	return dest, nil
+}
+
+// rewriteImports vendors every import of the file at srcPath and returns the file
+// content with import paths rewritten relative to outDir, the directory the
+// content will finally be written to.
+func (v *vendorer) rewriteImports(data []byte, srcPath, outDir string) ([]byte, error) {
+	editor, err := wanf.NewEditor(data)
+	if err != nil {
+		return nil, fmt.Errorf("parser errors in %s: %w", srcPath, err)
+	}
+	changed := false
+	for _, stmt := range editor.Program().Statements {
+		imp, ok := stmt.(*wanf.ImportStatement)
+		if !ok {
+			continue
+		}
+		target := filepath.Join(filepath.Dir(srcPath), string(imp.Path.Value))
+		target, err := filepath.Abs(target)
+		if err != nil {
+			return nil, fmt.Errorf("could not get absolute path for import %q: %w", string(imp.Path.Value), err)
+		}
+		if isWithin(v.vendorDir, target) {
+			// Already vendored by a previous run.
+			continue
+		}
+		vendored, err := v.vendorFile(target)
+		if err != nil {
+			return nil, err
+		}
+		newPath, err := importPath(outDir, vendored)
+		if err != nil {
+			return nil, err
+		}
+		if newPath == string(imp.Path.Value) {
+			continue
+		}
+		if err := editor.Replace(imp.Path, newPath); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if !changed {
+		return data, nil
+	}
+	return editor.Bytes()
+}
+
+func (v *vendorer) vendorPath(src string) string {
+	if rel, err := filepath.Rel(v.root, src); err == nil && isLocal(rel) {
+		return filepath.Join(v.vendorDir, rel)
+	}
+	// Files outside the entry directory are mirrored by their absolute path.
+	abs := strings.TrimPrefix(src, filepath.VolumeName(src))
+	return filepath.Join(v.vendorDir, "_external", strings.TrimLeft(abs, `/\`))
+}
+
+// writeManifest records which source file each vendored file was copied from.
+func (v *vendorer) writeManifest() error {
+	var entries []string
+	for src, dest := range v.copied {
+		relDest, err := filepath.Rel(v.vendorDir, dest)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fmt.Sprintf("%s => %s", filepath.ToSlash(src), filepath.ToSlash(relDest)))
+	}
+	sort.Strings(entries)
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by wanflint vendor. DO NOT EDIT.\n")
+	for _, e := range entries {
+		buf.WriteString(e)
+		buf.WriteString("\n")
+	}
+	return os.WriteFile(filepath.Join(v.vendorDir, "imports.txt"), buf.Bytes(), 0644)
+}
+
+func importPath(fromDir, target string) (string, error) {
+	rel, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return "", fmt.Errorf("could not compute import path for %s: %w", target, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return rel, nil
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && isLocal(rel)
+}
+
+func isLocal(rel string) bool {
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
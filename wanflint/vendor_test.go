@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+type vendorTestConfig struct {
+	Name string `wanf:"name"`
+	Port int    `wanf:"port"`
+	Host string `wanf:"host"`
+}
+
+func TestVendorFiles(t *testing.T) {
+	root := t.TempDir()
+	paths := writeFiles(t, root, map[string]string{
+		"app/main.wanf":      "import \"./conf/base.wanf\" // base\nimport `../shared/host.wanf`\nname = \"svc\"\n",
+		"app/conf/base.wanf": "import \"../port.wanf\"\n",
+		"app/port.wanf":      "port = 8080\n",
+		"shared/host.wanf":   "host = \"localhost\"\n",
+	})
+	entry := paths["app/main.wanf"]
+
+	if err := vendorFiles([]string{entry}, defaultVendorDir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`import "./wanf_vendor/conf/base.wanf" // base`,
+		`import "./wanf_vendor/_external/`,
+		`name = "svc"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("entry file missing %q:\n%s", want, data)
+		}
+	}
+	vendored, err := os.ReadFile(filepath.Join(root, "app", defaultVendorDir, "conf", "base.wanf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "import \"../port.wanf\"\n"; string(vendored) != want {
+		t.Errorf("vendored base.wanf = %q, want %q", vendored, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "app", defaultVendorDir, "imports.txt")); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+
+	// The vendored tree decodes on its own, without the original imports.
+	for _, p := range []string{paths["app/port.wanf"], paths["app/conf/base.wanf"], paths["shared/host.wanf"]} {
+		if err := os.Remove(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var c vendorTestConfig
+	if err := wanf.DecodeFile(entry, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c != (vendorTestConfig{Name: "svc", Port: 8080, Host: "localhost"}) {
+		t.Errorf("decoded %+v", c)
+	}
+
+	// Running again leaves an already vendored tree unchanged.
+	if err := vendorFiles([]string{entry}, defaultVendorDir); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("second run changed the entry file:\n%s", again)
+	}
+}
+
+func TestVendorSyntaxError(t *testing.T) {
+	paths := writeFiles(t, t.TempDir(), map[string]string{"main.wanf": "import \"./a.wanf\"\nsyntax error here {\n"})
+	err := vendorFiles([]string{paths["main.wanf"]}, defaultVendorDir)
+	if err == nil || !strings.Contains(err.Error(), "parser errors") {
+		t.Errorf("got %v, want a parser error", err)
+	}
+}
+
+func TestBundleFile(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeFiles(t, dir, map[string]string{
+		"main.wanf": "import `./a.wanf`\nimport \"./b.wanf\"\nname = \"svc\"\n",
+		"a.wanf":    "import \"./b.wanf\"\n",
+		"b.wanf":    "port = 8080\n",
+	})
+	out := filepath.Join(dir, "bundle.wanf")
+	if err := bundleFile(paths["main.wanf"], out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// --- begin import "./a.wanf" (a.wanf) ---
+// --- begin import "./b.wanf" (b.wanf) ---
+port = 8080
+// --- end import "./b.wanf" ---
+// --- end import "./a.wanf" ---
+// import "./b.wanf": already included above
+name = "svc"
+`
+	if string(data) != want {
+		t.Errorf("bundle =\n%s\nwant\n%s", data, want)
+	}
+}
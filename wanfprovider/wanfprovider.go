@@ -0,0 +1,88 @@
+// Package wanfprovider 让 WANF 接入 koanf 与 viper 等已有的配置库, 无需额外的胶水代码.
+//
+// 这里的类型按结构满足相应接口, 本包不依赖 koanf 或 viper:
+//   - Parser 实现 koanf 的 Parser (Unmarshal/Marshal), 用于 koanf.Load(file.Provider(path), wanfprovider.Parser());
+//   - Provider 实现 koanf 的 Provider (ReadBytes/Read), 相对 import 按文件所在目录解析;
+//   - Codec 实现 viper 的 encoding.Codec (Encode/Decode), 用于 viper.WithCodecRegistry 注册 "wanf".
+//
+// 读取时文档按解码的流程求值 (变量、env() 与 import), duration 成为字符串
+// (如 "1m30s"), koanf 与 viper 在解码到 time.Duration 时会重新解析它.
+package wanfprovider
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/WJQSERVER/wanf"
+)
+
+// WANFParser 将 WANF 文档与 map 相互转换, 见 Parser.
+type WANFParser struct {
+	opts []wanf.DecoderOption
+}
+
+// Parser 返回一个 koanf 解析器. opts 在求值时使用, 例如 wanf.WithBasePath
+// 解析相对 import, wanf.WithProfile 选择配置剖面.
+func Parser(opts ...wanf.DecoderOption) *WANFParser {
+	return &WANFParser{opts: opts}
+}
+
+// Unmarshal 求值 WANF 文档并返回其值树, 块成为嵌套的 map.
+func (p *WANFParser) Unmarshal(b []byte) (map[string]interface{}, error) {
+	return wanf.ToValue(b, p.opts...)
+}
+
+// Marshal 将值树转换为格式化后的 WANF 文档.
+func (p *WANFParser) Marshal(m map[string]interface{}) ([]byte, error) {
+	return wanf.FromValue(m)
+}
+
+// FileProvider 从文件读取 WANF 文档, 见 Provider.
+type FileProvider struct {
+	path string
+	opts []wanf.DecoderOption
+}
+
+// Provider 返回读取 path 的 koanf Provider. 文件中的相对 import 按 path
+// 所在目录解析, opts 追加在其后.
+func Provider(path string, opts ...wanf.DecoderOption) *FileProvider {
+	return &FileProvider{path: path, opts: opts}
+}
+
+// ReadBytes 返回文件的原始内容, 供与 Parser 搭配使用.
+func (f *FileProvider) ReadBytes() ([]byte, error) {
+	return os.ReadFile(f.path)
+}
+
+// Read 读取并求值文件, 返回其值树. 使用 Read 时 koanf.Load 的解析器应为 nil.
+func (f *FileProvider) Read() (map[string]interface{}, error) {
+	data, err := f.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]wanf.DecoderOption{wanf.WithBasePath(filepath.Dir(f.path))}, f.opts...)
+	return wanf.ToValue(data, opts...)
+}
+
+// Codec 是 viper 的编解码器, 零值即可使用.
+type Codec struct {
+	// Options 在解码时使用, 例如 wanf.WithBasePath.
+	Options []wanf.DecoderOption
+}
+
+// Encode 将 viper 的设置转换为 WANF 文档.
+func (c Codec) Encode(v map[string]interface{}) ([]byte, error) {
+	return wanf.FromValue(v)
+}
+
+// Decode 求值 WANF 文档并将结果写入 v.
+func (c Codec) Decode(b []byte, v map[string]interface{}) error {
+	m, err := wanf.ToValue(b, c.Options...)
+	if err != nil {
+		return err
+	}
+	for k, val := range m {
+		v[k] = val
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package wanfprovider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 与 koanf 及 viper 中的接口定义一致, 确保类型按结构满足它们.
+type koanfParser interface {
+	Unmarshal([]byte) (map[string]interface{}, error)
+	Marshal(map[string]interface{}) ([]byte, error)
+}
+
+type koanfProvider interface {
+	ReadBytes() ([]byte, error)
+	Read() (map[string]interface{}, error)
+}
+
+type viperCodec interface {
+	Encode(v map[string]interface{}) ([]byte, error)
+	Decode(b []byte, v map[string]interface{}) error
+}
+
+var (
+	_ koanfParser   = Parser()
+	_ koanfProvider = Provider("")
+	_ viperCodec    = Codec{}
+)
+
+func TestParser(t *testing.T) {
+	src := []byte(`var region = "eu"
+name = "app"
+server "main" {
+	zone = "${region}"
+	ports = [80, 443]
+	timeout = 90s
+}
+`)
+	m, err := Parser().Unmarshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name": "app",
+		"server": map[string]interface{}{
+			"main": map[string]interface{}{
+				"zone":    "eu",
+				"ports":   []interface{}{int64(80), int64(443)},
+				"timeout": "1m30s",
+			},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Unmarshal = %#v, want %#v", m, want)
+	}
+	out, err := Parser().Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parser().Unmarshal(out)
+	if err != nil {
+		t.Fatalf("Unmarshal(Marshal()) failed: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Errorf("round trip = %#v, want %#v", again, want)
+	}
+}
+
+func TestProviderResolvesImports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.wanf"), []byte("port = 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "app.wanf")
+	if err := os.WriteFile(path, []byte("import \"base.wanf\"\nname = \"app\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Provider(path).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["port"] != int64(8080) || m["name"] != "app" {
+		t.Errorf("Read = %v", m)
+	}
+	raw, err := Provider(path).ReadBytes()
+	if err != nil || len(raw) == 0 {
+		t.Errorf("ReadBytes = %q, %v", raw, err)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	v := map[string]interface{}{"stale": true}
+	if err := (Codec{}).Decode([]byte("debug = true\nlog {\n\tlevel = \"info\"\n}\n"), v); err != nil {
+		t.Fatal(err)
+	}
+	if v["debug"] != true || v["stale"] != true {
+		t.Errorf("Decode = %v", v)
+	}
+	if log, ok := v["log"].(map[string]interface{}); !ok || log["level"] != "info" {
+		t.Errorf("Decode log = %v", v["log"])
+	}
+	out, err := (Codec{}).Encode(map[string]interface{}{"debug": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "debug = true\n" {
+		t.Errorf("Encode = %q", out)
+	}
+}
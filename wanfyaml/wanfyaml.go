@@ -0,0 +1,481 @@
+// Package wanfyaml 在 WANF 语法树与 YAML 文档之间转换, 保留嵌套结构、列表
+// 与注释, 便于已有大量 YAML 配置 (如 Kubernetes 清单) 的团队逐步迁移到 WANF.
+//
+// 与 wanf.ToJSON 不同, 这里的转换在语法树上进行, 不求值文档:
+//   - 块成为映射, 带标签的块按标签逐层嵌套, 同名的块会被合并;
+//   - var 在引用处展开, 字符串中的 ${...} 保持原样;
+//   - duration 成为字符串 (如 "90s"), 转回 WANF 时仍是字符串;
+//   - import 与 env() 需要求值, 不受支持;
+//   - 前置注释、行尾注释与列表元素的注释转为 YAML 注释, 反之亦然.
+//
+// YAML 转为 WANF 时, 映射成为块 (列表中的映射成为块字面量), 键必须是合法的
+// 标识符; WANF 没有负数与 null, 遇到时返回错误.
+package wanfyaml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/WJQSERVER/wanf"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML 将 WANF 源码转换为 YAML 文档.
+func ToYAML(data []byte) ([]byte, error) {
+	root, err := wanf.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := FromAST(root)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromYAML 将 YAML 文档转换为格式化后的 WANF 源码, 保持键的原有顺序.
+func FromYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("wanfyaml: invalid YAML: %w", err)
+	}
+	root, err := ToAST(&doc)
+	if err != nil {
+		return nil, err
+	}
+	out := wanf.Format(root, wanf.FormatOptions{Style: wanf.StyleBlockSorted, EmptyLines: true, NoSort: true})
+	return append(out, '\n'), nil
+}
+
+// FromAST 将语法树转换为 YAML 文档节点.
+func FromAST(root *wanf.RootNode) (*yaml.Node, error) {
+	e := &encoder{vars: map[string]wanf.Expression{}}
+	body := &yaml.Node{Kind: yaml.MappingNode}
+	if err := e.statements(body, root, ""); err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{body}}
+	doc.FootComment = yamlComment(root.TrailingComments)
+	return doc, nil
+}
+
+// encoder 将语法树转换为 YAML 节点. vars 保存已声明的变量, 供 ${...} 展开.
+type encoder struct {
+	vars    map[string]wanf.Expression
+	pending []*wanf.Comment // 被省略的 var 语句的前置注释, 移到下一个键上
+}
+
+// leading 返回下一个键的前置注释, 包括之前被省略的语句的注释.
+func (e *encoder) leading(comments []*wanf.Comment) string {
+	text := yamlComment(append(e.pending, comments...))
+	e.pending = nil
+	return text
+}
+
+// statements 将 body 中的语句并入映射 m. path 用于错误信息.
+func (e *encoder) statements(m *yaml.Node, body *wanf.RootNode, path string) error {
+	for _, stmt := range body.Statements {
+		switch s := stmt.(type) {
+		case *wanf.VarStatement:
+			e.vars[string(s.Name.Value)] = s.Value
+			e.pending = append(e.pending, s.LeadingComments...)
+		case *wanf.ImportStatement:
+			return fmt.Errorf("wanfyaml: line %d: import is not supported; convert the imported file separately", s.Token.Line)
+		case *wanf.AssignStatement:
+			key := joinPath(path, string(s.Name.Value))
+			v, err := e.value(s.Value, key)
+			if err != nil {
+				return err
+			}
+			if s.LineComment != nil {
+				v.LineComment = yamlComment([]*wanf.Comment{s.LineComment})
+			}
+			k := setKey(m, string(s.Name.Value), v)
+			k.HeadComment = e.leading(s.LeadingComments)
+		case *wanf.BlockStatement:
+			target, k, err := blockTarget(m, s, path)
+			if err != nil {
+				return err
+			}
+			if head := e.leading(s.LeadingComments); head != "" {
+				k.HeadComment = head
+			}
+			if err := e.statements(target, s.Body, joinPath(path, strings.Join(append([]string{string(s.Name.Value)}, s.Labels()...), "."))); err != nil {
+				return err
+			}
+		}
+	}
+	if foot := yamlComment(body.TrailingComments); foot != "" && len(m.Content) > 0 && path != "" {
+		m.Content[len(m.Content)-2].FootComment = foot
+	}
+	return nil
+}
+
+// blockTarget 返回块 s 的内容应并入的映射, 以及其在 m 中的键节点. 带标签的块
+// 按标签逐层嵌套, 已存在的映射被复用, 使同名的块合并.
+func blockTarget(m *yaml.Node, s *wanf.BlockStatement, path string) (*yaml.Node, *yaml.Node, error) {
+	keys := append([]string{string(s.Name.Value)}, s.Labels()...)
+	var first *yaml.Node
+	for i, key := range keys {
+		k, v := lookupKey(m, key)
+		if v == nil {
+			v = &yaml.Node{Kind: yaml.MappingNode}
+			k = setKey(m, key, v)
+		} else if v.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("wanfyaml: line %d: block %s conflicts with the value of %s", s.Token.Line, joinPath(path, strings.Join(keys, ".")), joinPath(path, strings.Join(keys[:i+1], ".")))
+		}
+		if first == nil {
+			first = k
+		}
+		m = v
+	}
+	return m, first, nil
+}
+
+// value 将表达式转换为 YAML 节点.
+func (e *encoder) value(expr wanf.Expression, path string) (*yaml.Node, error) {
+	switch v := expr.(type) {
+	case *wanf.StringLiteral:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v.Value)}
+		if strings.Contains(n.Value, "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+		return n, nil
+	case *wanf.IntegerLiteral:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v.Value, 10)}, nil
+	case *wanf.FloatLiteral:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: string(v.Token.Literal)}, nil
+	case *wanf.BoolLiteral:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v.Value)}, nil
+	case *wanf.DurationLiteral:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v.Value)}, nil
+	case *wanf.VarExpression:
+		ref, ok := e.vars[string(v.Name)]
+		if !ok {
+			return nil, fmt.Errorf("wanfyaml: %s: undefined variable %q", path, v.Name)
+		}
+		return e.value(ref, path)
+	case *wanf.EnvExpression:
+		return nil, fmt.Errorf("wanfyaml: %s: env() is not supported; evaluate the document with wanf.ToValue instead", path)
+	case *wanf.SecretExpression:
+		return nil, fmt.Errorf("wanfyaml: %s: secret() is not supported", path)
+	case *wanf.ListLiteral:
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for i, el := range v.Elements {
+			n, err := e.value(el, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			if i < len(v.Comments) {
+				n.HeadComment = yamlComment(v.Comments[i].Leading)
+				if v.Comments[i].Line != nil {
+					n.LineComment = yamlComment([]*wanf.Comment{v.Comments[i].Line})
+				}
+			}
+			seq.Content = append(seq.Content, n)
+		}
+		return seq, nil
+	case *wanf.BlockLiteral:
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		if err := e.statements(m, v.Body, path); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case *wanf.MapLiteral:
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		if err := e.statements(m, &wanf.RootNode{Statements: v.Elements, TrailingComments: v.TrailingComments}, path); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("wanfyaml: %s: unsupported expression %T", path, expr)
+}
+
+// lookupKey 返回映射 m 中 key 的键与值节点.
+func lookupKey(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// setKey 设置 m 中 key 的值, 已存在时替换 (与解码时后出现的赋值生效一致), 返回键节点.
+func setKey(m *yaml.Node, key string, v *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = v
+			return m.Content[i]
+		}
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	m.Content = append(m.Content, k, v)
+	return k
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlComment 将 WANF 注释转换为 YAML 注释文本, 每行以 "# " 开头.
+func yamlComment(comments []*wanf.Comment) string {
+	var lines []string
+	for _, c := range comments {
+		text := string(c.Text)
+		switch {
+		case strings.HasPrefix(text, "//"):
+			text = text[2:]
+		case strings.HasPrefix(text, "/*"):
+			text = strings.TrimSuffix(text[2:], "*/")
+		case strings.HasPrefix(text, "#"):
+			text = text[1:]
+		}
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			lines = append(lines, "# "+line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToAST 将 YAML 文档节点转换为语法树. 文档的顶层必须是映射.
+func ToAST(doc *yaml.Node) (*wanf.RootNode, error) {
+	w := &writer{}
+	body := doc
+	if doc.Kind == yaml.DocumentNode {
+		w.comments("", doc.HeadComment)
+		if len(doc.Content) == 0 {
+			return wanf.Parse(w.buf.Bytes())
+		}
+		body = doc.Content[0]
+	}
+	body = resolveAlias(body)
+	if body.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("wanfyaml: top-level YAML value must be a mapping, got %s", kindName(body))
+	}
+	w.comments("", body.HeadComment)
+	if err := w.mapping(body, "", ""); err != nil {
+		return nil, err
+	}
+	if doc.Kind == yaml.DocumentNode {
+		w.comments("", doc.FootComment)
+	}
+	root, err := wanf.Parse(w.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("wanfyaml: generated invalid WANF: %w", err)
+	}
+	return root, nil
+}
+
+// writer 将 YAML 节点写成 WANF 源码, 再交给解析器生成语法树.
+type writer struct {
+	buf bytes.Buffer
+}
+
+// mapping 将映射 m 的每个键写成一条语句, 映射值写成块.
+func (w *writer) mapping(m *yaml.Node, indent, path string) error {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], resolveAlias(m.Content[i+1])
+		key := k.Value
+		keyPath := joinPath(path, key)
+		if key == "<<" {
+			return fmt.Errorf("wanfyaml: %s: merge keys are not supported", path)
+		}
+		if !isIdentifier(key) {
+			return fmt.Errorf("wanfyaml: %s: key %q is not a valid identifier", path, key)
+		}
+		w.comments(indent, k.HeadComment)
+		w.comments(indent, v.HeadComment)
+		if v.Kind == yaml.MappingNode {
+			w.buf.WriteString(indent + key + " {")
+			w.lineComment(k.LineComment, v.LineComment)
+			w.buf.WriteString("\n")
+			if err := w.mapping(v, indent+"\t", keyPath); err != nil {
+				return err
+			}
+			w.buf.WriteString(indent + "}\n")
+		} else {
+			w.buf.WriteString(indent + key + " = ")
+			if err := w.value(v, indent, keyPath); err != nil {
+				return err
+			}
+			w.lineComment(k.LineComment, v.LineComment)
+			w.buf.WriteString("\n")
+		}
+		w.comments(indent, k.FootComment)
+		w.comments(indent, v.FootComment)
+	}
+	return nil
+}
+
+// value 将 YAML 值写成 WANF 表达式.
+func (w *writer) value(v *yaml.Node, indent, path string) error {
+	v = resolveAlias(v)
+	switch v.Kind {
+	case yaml.MappingNode:
+		w.buf.WriteString("{\n")
+		if err := w.mapping(v, indent+"\t", path); err != nil {
+			return err
+		}
+		w.buf.WriteString(indent + "}")
+		return nil
+	case yaml.SequenceNode:
+		w.buf.WriteString("[\n")
+		for i, el := range v.Content {
+			w.comments(indent+"\t", el.HeadComment)
+			w.buf.WriteString(indent + "\t")
+			if err := w.value(el, indent+"\t", fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+			w.buf.WriteString(",")
+			w.lineComment(el.LineComment)
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString(indent + "]")
+		return nil
+	case yaml.ScalarNode:
+		return w.scalar(v, path)
+	}
+	return fmt.Errorf("wanfyaml: %s: unsupported YAML node %s", path, kindName(v))
+}
+
+// scalar 按 YAML 解析出的类型写出标量.
+func (w *writer) scalar(v *yaml.Node, path string) error {
+	switch v.ShortTag() {
+	case "!!int":
+		var i int64
+		if err := v.Decode(&i); err != nil {
+			return fmt.Errorf("wanfyaml: %s: %w", path, err)
+		}
+		if i < 0 {
+			return fmt.Errorf("wanfyaml: %s: negative number %d is not supported", path, i)
+		}
+		w.buf.WriteString(strconv.FormatInt(i, 10))
+	case "!!float":
+		var f float64
+		if err := v.Decode(&f); err != nil {
+			return fmt.Errorf("wanfyaml: %s: %w", path, err)
+		}
+		if f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("wanfyaml: %s: number %v is not supported", path, f)
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		w.buf.WriteString(s)
+	case "!!bool":
+		var b bool
+		if err := v.Decode(&b); err != nil {
+			return fmt.Errorf("wanfyaml: %s: %w", path, err)
+		}
+		w.buf.WriteString(strconv.FormatBool(b))
+	case "!!null":
+		return fmt.Errorf("wanfyaml: %s: null is not supported", path)
+	default:
+		// 字符串, 以及时间戳等没有对应 WANF 类型的值, 保留原文.
+		return w.str(v.Value, path)
+	}
+	return nil
+}
+
+// str 写出字符串字面量. WANF 字符串没有转义, 规则与 wanf.FromValue 相同:
+// 多行字符串使用反引号, 其余使用双引号, 内容中不能出现所用的引号.
+func (w *writer) str(s, path string) error {
+	quote := byte('"')
+	if strings.Contains(s, "\n") {
+		quote = '`'
+	}
+	if strings.IndexByte(s, quote) >= 0 {
+		return fmt.Errorf("wanfyaml: %s: string %q cannot be represented without escapes", path, s)
+	}
+	w.buf.WriteByte(quote)
+	w.buf.WriteString(s)
+	w.buf.WriteByte(quote)
+	return nil
+}
+
+// comments 将 YAML 注释写成独占一行的 WANF 注释.
+func (w *writer) comments(indent, text string) {
+	for _, line := range commentLines(text) {
+		w.buf.WriteString(indent + "// " + line + "\n")
+	}
+}
+
+// lineComment 将 YAML 注释写成行尾注释, 多行时合并为一行.
+func (w *writer) lineComment(texts ...string) {
+	var lines []string
+	for _, text := range texts {
+		lines = append(lines, commentLines(text)...)
+	}
+	if len(lines) > 0 {
+		w.buf.WriteString(" // " + strings.Join(lines, " "))
+	}
+}
+
+// commentLines 返回 YAML 注释中每一行去掉 "#" 后的文本, 跳过空行.
+func commentLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return wanf.LookupIdentifier([]byte(s)) == wanf.IDENT
+}
+
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.ScalarNode:
+		return "scalar " + n.ShortTag()
+	case yaml.AliasNode:
+		return "alias"
+	}
+	return "document"
+}
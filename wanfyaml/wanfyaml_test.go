@@ -0,0 +1,176 @@
+package wanfyaml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToYAML(t *testing.T) {
+	src := `// Service config
+var region = "eu"
+name = "svc" // the name
+region = ${region}
+timeout = 90s
+ports = [
+	// http
+	80,
+	443, // https
+]
+labels = {[team = "core"]}
+
+server "a" {
+	port = 1
+}
+server "b" {
+	port = 2
+}
+database {
+	host = "localhost"
+}
+database {
+	user = "admin"
+}
+`
+	got, err := ToYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Service config
+name: svc # the name
+region: eu
+timeout: 90s
+ports:
+  # http
+  - 80
+  - 443 # https
+labels:
+  team: core
+server:
+  a:
+    port: 1
+  b:
+    port: 2
+database:
+  host: localhost
+  user: admin
+`
+	if string(got) != want {
+		t.Errorf("ToYAML mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFromYAML(t *testing.T) {
+	src := `# Deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # app name
+  labels: &labels
+    app: web
+spec:
+  replicas: 3
+  enabled: true
+  ratio: 1.5
+  selector:
+    matchLabels: *labels
+  containers:
+    # main container
+    - name: web
+      ports: [80, 443]
+  script: |
+    echo "hi"
+`
+	got, err := FromYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Deployment\n" +
+		"apiVersion = \"apps/v1\"\n" +
+		"kind = \"Deployment\"\n" +
+		"\n" +
+		"metadata {\n" +
+		"\tname = \"web\" // app name\n" +
+		"\tlabels {\n" +
+		"\t\tapp = \"web\"\n" +
+		"\t}\n" +
+		"}\n" +
+		"\n" +
+		"spec {\n" +
+		"\treplicas = 3\n" +
+		"\tenabled = true\n" +
+		"\tratio = 1.5\n" +
+		"\tselector {\n" +
+		"\t\tmatchLabels {\n" +
+		"\t\t\tapp = \"web\"\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"\tcontainers = [\n" +
+		"\t\t// main container\n" +
+		"\t\t{\n" +
+		"\t\t\tname = \"web\"\n" +
+		"\t\t\tports = [\n" +
+		"\t\t\t\t80,\n" +
+		"\t\t\t\t443,\n" +
+		"\t\t\t]\n" +
+		"\t\t},\n" +
+		"\t]\n" +
+		"\tscript = `echo \"hi\"\n`\n" +
+		"}\n"
+	if string(got) != want {
+		t.Errorf("FromYAML mismatch.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := `// header
+name = "svc"
+
+ports = [
+	80,
+	443, // https
+]
+
+server {
+	host = "localhost" // local
+	port = 8080
+}
+`
+	y, err := ToYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromYAML(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(back) != src {
+		t.Errorf("round trip mismatch.\ngot:\n%s\nwant:\n%s", back, src)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	wanfTests := []struct{ src, want string }{
+		{`import "other.wanf"`, "import is not supported"},
+		{`a = env("HOME")`, "env() is not supported"},
+		{`a = ${missing}`, `undefined variable "missing"`},
+		{"a = 1\na {\n\tb = 2\n}", "conflicts with the value of a"},
+	}
+	for _, tt := range wanfTests {
+		if _, err := ToYAML([]byte(tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ToYAML(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+	yamlTests := []struct{ src, want string }{
+		{"- a\n- b\n", "must be a mapping"},
+		{"a: -1\n", "negative number"},
+		{"a: ~\n", "null is not supported"},
+		{"my-key: 1\n", `key "my-key" is not a valid identifier`},
+		{"base: &b {x: 1}\nc:\n  <<: *b\n", "merge keys are not supported"},
+		{"a: 'say \"hi\"'\n", "cannot be represented without escapes"},
+	}
+	for _, tt := range yamlTests {
+		if _, err := FromYAML([]byte(tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("FromYAML(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}